@@ -1,27 +1,42 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/seed"
 	"github.com/Code-byme/e-commerce/internal/services"
 )
 
 func main() {
 	// Parse command line flags
 	var (
-		limit = flag.Int("limit", 20, "Number of products to fetch from API")
-		stats = flag.Bool("stats", false, "Show database statistics")
-		help  = flag.Bool("help", false, "Show help message")
+		source     = flag.String("source", "fakestore", "Seed source: fakestore, fixtures or synthetic")
+		limit      = flag.Int("limit", 20, "Number of products to fetch from API or generate (0 skips seeding)")
+		fixtures   = flag.String("fixtures", "fixtures", "Fixture file or directory of JSON/YAML files (fixtures source)")
+		apiURL     = flag.String("api-url", seed.DefaultFakeStoreURL, "FakeStore API base URL (fakestore source)")
+		randSeed   = flag.Int64("seed", 42, "Random seed; the same seed and counts produce the same dataset (synthetic source)")
+		users      = flag.Int("users", 10, "Number of users to generate (synthetic source)")
+		categories = flag.Int("categories", 10, "Number of categories to generate (synthetic source)")
+		orders     = flag.Int("orders", 25, "Number of orders to generate (synthetic source)")
+		stats      = flag.Bool("stats", false, "Show database statistics")
+		help       = flag.Bool("help", false, "Show help message")
 	)
 	flag.Parse()
 
 	if *help {
 		fmt.Println("Database Seeding Tool")
 		fmt.Println("=====================")
-		fmt.Println("This tool fetches products from FakeStore API and stores them in your database.")
+		fmt.Println("This tool loads catalog data from a seed source and stores it in your database.")
+		fmt.Println()
+		fmt.Println("Sources:")
+		fmt.Println("  fakestore   Products fetched from the FakeStore API (requires network access)")
+		fmt.Println("  fixtures    Users, categories, products and orders from local JSON/YAML files")
+		fmt.Println("  synthetic   Deterministic generated data, reproducible with a fixed -seed")
 		fmt.Println()
 		fmt.Println("Usage:")
 		fmt.Println("  go run cmd/seed/main.go [options]")
@@ -30,13 +45,40 @@ func main() {
 		flag.PrintDefaults()
 		fmt.Println()
 		fmt.Println("Examples:")
-		fmt.Println("  go run cmd/seed/main.go                    # Seed with 20 products (default)")
-		fmt.Println("  go run cmd/seed/main.go -limit 50          # Seed with 50 products")
-		fmt.Println("  go run cmd/seed/main.go -stats             # Show database statistics")
-		fmt.Println("  go run cmd/seed/main.go -limit 10 -stats   # Seed 10 products and show stats")
+		fmt.Println("  go run cmd/seed/main.go                                      # Seed with 20 products (default)")
+		fmt.Println("  go run cmd/seed/main.go -limit 50                            # Seed with 50 products")
+		fmt.Println("  go run cmd/seed/main.go -stats                               # Show database statistics")
+		fmt.Println("  go run cmd/seed/main.go -limit 10 -stats                     # Seed 10 products and show stats")
+		fmt.Println("  go run cmd/seed/main.go -source fixtures                     # Seed from ./fixtures")
+		fmt.Println("  go run cmd/seed/main.go -source fixtures -fixtures demo.yaml # Seed from a single fixture file")
+		fmt.Println("  go run cmd/seed/main.go -source synthetic -limit 1000 -users 200 -orders 5000 -seed 7")
 		return
 	}
 
+	// Select seed source
+	var seedSource seed.Source
+	switch *source {
+	case "fakestore":
+		if *limit > 0 {
+			seedSource = seed.NewFakeStoreSource(*apiURL, *limit)
+		}
+	case "fixtures":
+		seedSource = seed.NewFixtureSource(*fixtures)
+	case "synthetic":
+		if *limit > 0 || *users > 0 || *categories > 0 || *orders > 0 {
+			seedSource = seed.NewSyntheticSource(seed.SyntheticConfig{
+				Seed:       *randSeed,
+				Users:      *users,
+				Categories: *categories,
+				Products:   *limit,
+				Orders:     *orders,
+			})
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown seed source %q (expected fakestore, fixtures or synthetic)\n", *source)
+		os.Exit(2)
+	}
+
 	// Initialize database
 	if err := database.InitDatabase(); err != nil {
 		log.Fatal("Failed to connect to database:", err)
@@ -61,12 +103,12 @@ func main() {
 		fmt.Println()
 	}
 
-	// Seed database if limit > 0
-	if *limit > 0 {
-		fmt.Printf("Seeding database with %d products from FakeStore API...\n", *limit)
+	// Seed database from the selected source
+	if seedSource != nil {
+		fmt.Printf("Seeding database from %s...\n", seedSource.Name())
 		fmt.Println("==================================================")
 
-		if err := seedService.SeedProducts(*limit); err != nil {
+		if _, err := seedService.Seed(context.Background(), seedSource); err != nil {
 			log.Fatal("Failed to seed database:", err)
 		}
 
@@ -74,7 +116,7 @@ func main() {
 	}
 
 	// Show final stats
-	if *limit > 0 || *stats {
+	if seedSource != nil || *stats {
 		fmt.Println("\nFinal Database Statistics:")
 		fmt.Println("==========================")
 		if err := seedService.GetDatabaseStats(); err != nil {
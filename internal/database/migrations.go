@@ -44,6 +44,151 @@ func RunMigrations() error {
 		return fmt.Errorf("failed to create cart_items table: %w", err)
 	}
 
+	// Create product attribute tables
+	if err := createAttributeTables(); err != nil {
+		return fmt.Errorf("failed to create attribute tables: %w", err)
+	}
+
+	// Create product_images table
+	if err := createProductImagesTable(); err != nil {
+		return fmt.Errorf("failed to create product_images table: %w", err)
+	}
+
+	// Add SKU column used to match products during imports
+	if err := addProductSKUColumn(); err != nil {
+		return fmt.Errorf("failed to add products sku column: %w", err)
+	}
+
+	// Create import_jobs table
+	if err := createImportJobsTable(); err != nil {
+		return fmt.Errorf("failed to create import_jobs table: %w", err)
+	}
+
+	// Add product lifecycle columns and protect order history
+	if err := addProductLifecycleColumns(); err != nil {
+		return fmt.Errorf("failed to add product lifecycle columns: %w", err)
+	}
+
+	// Create product_revisions table
+	if err := createProductRevisionsTable(); err != nil {
+		return fmt.Errorf("failed to create product_revisions table: %w", err)
+	}
+
+	// Add compare-at and sale pricing columns
+	if err := addProductPricingColumns(); err != nil {
+		return fmt.Errorf("failed to add product pricing columns: %w", err)
+	}
+
+	// Create product_price_schedules table
+	if err := createProductPriceSchedulesTable(); err != nil {
+		return fmt.Errorf("failed to create product_price_schedules table: %w", err)
+	}
+
+	// Create coupon tables and attach coupons to carts and orders
+	if err := createCouponTables(); err != nil {
+		return fmt.Errorf("failed to create coupon tables: %w", err)
+	}
+
+	// Create promotions table and per-line order discounts
+	if err := createPromotionsTable(); err != nil {
+		return fmt.Errorf("failed to create promotions table: %w", err)
+	}
+
+	// Create tax classes, rate tables and order tax lines
+	if err := createTaxTables(); err != nil {
+		return fmt.Errorf("failed to create tax tables: %w", err)
+	}
+
+	// Create shipping zones and methods, product dimensions and order shipping columns
+	if err := createShippingTables(); err != nil {
+		return fmt.Errorf("failed to create shipping tables: %w", err)
+	}
+
+	// Store the order subtotal alongside the discount, shipping and tax breakdown
+	if err := addOrderSubtotal(); err != nil {
+		return fmt.Errorf("failed to add order subtotal: %w", err)
+	}
+
+	// Create payments linked to orders
+	if err := createPaymentsTable(); err != nil {
+		return fmt.Errorf("failed to create payments table: %w", err)
+	}
+
+	// Create the payment webhook event log
+	if err := createPaymentWebhookEventsTable(); err != nil {
+		return fmt.Errorf("failed to create payment webhook events table: %w", err)
+	}
+
+	// Create refunds and refunded totals on orders
+	if err := createRefundTables(); err != nil {
+		return fmt.Errorf("failed to create refund tables: %w", err)
+	}
+
+	// Create returns (RMA) tables
+	if err := createReturnTables(); err != nil {
+		return fmt.Errorf("failed to create return tables: %w", err)
+	}
+
+	// Create order status history table
+	if err := createOrderStatusHistoryTable(); err != nil {
+		return fmt.Errorf("failed to create order status history table: %w", err)
+	}
+
+	// Create shipment tables
+	if err := createShipmentTables(); err != nil {
+		return fmt.Errorf("failed to create shipment tables: %w", err)
+	}
+
+	// Create idempotency keys table
+	if err := createIdempotencyKeysTable(); err != nil {
+		return fmt.Errorf("failed to create idempotency keys table: %w", err)
+	}
+
+	// Track the prices cart items were last shown at
+	if err := addCartItemSeenPrice(); err != nil {
+		return fmt.Errorf("failed to add cart item seen price: %w", err)
+	}
+
+	// Allow carts without a user, identified by a guest cart key
+	if err := addGuestCarts(); err != nil {
+		return fmt.Errorf("failed to add guest carts: %w", err)
+	}
+
+	// Keep guest checkout accounts apart from the customer accounts registered with their email
+	if err := separateGuestAccounts(); err != nil {
+		return fmt.Errorf("failed to separate guest accounts: %w", err)
+	}
+
+	// Create abandoned cart recovery table
+	if err := createCartRecoveryTables(); err != nil {
+		return fmt.Errorf("failed to create cart recovery tables: %w", err)
+	}
+
+	// Track the price cart items were added at
+	if err := addCartItemAddedPrice(); err != nil {
+		return fmt.Errorf("failed to add cart item added price: %w", err)
+	}
+
+	// Create wishlist tables
+	if err := createWishlistTables(); err != nil {
+		return fmt.Errorf("failed to create wishlist tables: %w", err)
+	}
+
+	// Scope idempotency keys of guests by their cart key
+	if err := addGuestIdempotencyKeys(); err != nil {
+		return fmt.Errorf("failed to add guest idempotency keys: %w", err)
+	}
+
+	// Track wishlist notification attempts
+	if err := addWishlistAlertAttempts(); err != nil {
+		return fmt.Errorf("failed to add wishlist alert attempts: %w", err)
+	}
+
+	// Create indexes used for sorting and keyset pagination
+	if err := createPaginationIndexes(); err != nil {
+		return fmt.Errorf("failed to create pagination indexes: %w", err)
+	}
+
 	log.Println("Database migrations completed successfully")
 	return nil
 }
@@ -149,7 +294,7 @@ func createOrderItemsTable() error {
 	CREATE TABLE IF NOT EXISTS order_items (
 		id SERIAL PRIMARY KEY,
 		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
-		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE RESTRICT,
 		quantity INTEGER NOT NULL CHECK (quantity > 0),
 		price DECIMAL(10,2) NOT NULL CHECK (price >= 0),
 		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -210,6 +355,980 @@ func createCartItemsTable() error {
 	return nil
 }
 
+// createAttributeTables creates the attribute_definitions, category_attributes and product_attribute_values tables
+func createAttributeTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS attribute_definitions (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(100) UNIQUE NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		type VARCHAR(20) NOT NULL CHECK (type IN ('text', 'number', 'boolean', 'enum')),
+		unit VARCHAR(50),
+		enum_values TEXT[],
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS category_attributes (
+		category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+		attribute_id INTEGER NOT NULL REFERENCES attribute_definitions(id) ON DELETE CASCADE,
+		is_required BOOLEAN NOT NULL DEFAULT false,
+		position INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (category_id, attribute_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS product_attribute_values (
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		attribute_id INTEGER NOT NULL REFERENCES attribute_definitions(id) ON DELETE CASCADE,
+		value_text TEXT,
+		value_number NUMERIC(18,6),
+		value_boolean BOOLEAN,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (product_id, attribute_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_product_attribute_values_number ON product_attribute_values (attribute_id, value_number);
+	CREATE INDEX IF NOT EXISTS idx_product_attribute_values_text ON product_attribute_values (attribute_id, value_text);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create attribute tables: %w", err)
+	}
+
+	log.Println("Attribute tables created successfully")
+	return nil
+}
+
+// createProductImagesTable creates the product_images table
+func createProductImagesTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS product_images (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		storage_key VARCHAR(500) NOT NULL,
+		variant_keys JSONB NOT NULL DEFAULT '{}',
+		alt_text VARCHAR(255) NOT NULL DEFAULT '',
+		position INTEGER NOT NULL DEFAULT 0,
+		content_type VARCHAR(100) NOT NULL,
+		size_bytes BIGINT NOT NULL CHECK (size_bytes >= 0),
+		width INTEGER NOT NULL,
+		height INTEGER NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_product_images_product_position ON product_images (product_id, position);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create product_images table: %w", err)
+	}
+
+	log.Println("Product images table created successfully")
+	return nil
+}
+
+// addProductSKUColumn adds the unique, optional SKU column to products
+func addProductSKUColumn() error {
+	query := `
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(100);
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'products_sku_key') THEN
+			ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+		END IF;
+	END $$;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add products sku column: %w", err)
+	}
+
+	log.Println("Products sku column added successfully")
+	return nil
+}
+
+// createImportJobsTable creates the import_jobs table
+func createImportJobsTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS import_jobs (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		format VARCHAR(20) NOT NULL CHECK (format IN ('csv', 'jsonl')),
+		match_by VARCHAR(20) NOT NULL CHECK (match_by IN ('sku', 'id')),
+		dry_run BOOLEAN NOT NULL DEFAULT false,
+		mapping JSONB NOT NULL DEFAULT '{}',
+		payload BYTEA,
+		status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+		total_rows INTEGER NOT NULL DEFAULT 0,
+		processed_rows INTEGER NOT NULL DEFAULT 0,
+		created_count INTEGER NOT NULL DEFAULT 0,
+		updated_count INTEGER NOT NULL DEFAULT 0,
+		failed_count INTEGER NOT NULL DEFAULT 0,
+		errors JSONB NOT NULL DEFAULT '[]',
+		error_message TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		started_at TIMESTAMP,
+		finished_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_import_jobs_status ON import_jobs (status);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create import_jobs table: %w", err)
+	}
+
+	log.Println("Import jobs table created successfully")
+	return nil
+}
+
+// addProductLifecycleColumns adds product status, publish window and soft delete columns.
+// It also replaces the cascading order_items foreign key so removing a product can never
+// destroy order history.
+func addProductLifecycleColumns() error {
+	query := `
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published';
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMP;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'products_status_check') THEN
+			ALTER TABLE products ADD CONSTRAINT products_status_check
+				CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+		END IF;
+
+		IF EXISTS (
+			SELECT 1 FROM pg_constraint
+			WHERE conname = 'order_items_product_id_fkey' AND confdeltype <> 'r'
+		) THEN
+			ALTER TABLE order_items DROP CONSTRAINT order_items_product_id_fkey;
+			ALTER TABLE order_items ADD CONSTRAINT order_items_product_id_fkey
+				FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT;
+		END IF;
+	END $$;
+
+	CREATE INDEX IF NOT EXISTS idx_products_status ON products (status) WHERE deleted_at IS NULL;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add product lifecycle columns: %w", err)
+	}
+
+	log.Println("Product lifecycle columns added successfully")
+	return nil
+}
+
+// createProductRevisionsTable creates the product_revisions table
+func createProductRevisionsTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS product_revisions (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		revision INTEGER NOT NULL CHECK (revision > 0),
+		action VARCHAR(20) NOT NULL,
+		changes JSONB NOT NULL,
+		snapshot JSONB NOT NULL,
+		actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		rollback_of INTEGER,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (product_id, revision)
+	);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create product_revisions table: %w", err)
+	}
+
+	log.Println("Product revisions table created successfully")
+	return nil
+}
+
+// addProductPricingColumns adds compare-at and sale pricing to products
+func addProductPricingColumns() error {
+	query := `
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS compare_at_price DECIMAL(10,2);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS sale_price DECIMAL(10,2);
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS sale_starts_at TIMESTAMP;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS sale_ends_at TIMESTAMP;
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'products_pricing_check') THEN
+			ALTER TABLE products ADD CONSTRAINT products_pricing_check
+				CHECK ((compare_at_price IS NULL OR compare_at_price > 0)
+					AND (sale_price IS NULL OR sale_price > 0)
+					AND (sale_starts_at IS NULL OR sale_ends_at IS NULL OR sale_ends_at > sale_starts_at));
+		END IF;
+	END $$;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add product pricing columns: %w", err)
+	}
+
+	log.Println("Product pricing columns added successfully")
+	return nil
+}
+
+// createProductPriceSchedulesTable creates the product_price_schedules table
+func createProductPriceSchedulesTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS product_price_schedules (
+		id SERIAL PRIMARY KEY,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		price DECIMAL(10,2) NOT NULL CHECK (price > 0),
+		compare_at_price DECIMAL(10,2) CHECK (compare_at_price > 0),
+		effective_at TIMESTAMP NOT NULL,
+		applied_at TIMESTAMP,
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_product_price_schedules_product_id ON product_price_schedules (product_id, effective_at);
+	CREATE INDEX IF NOT EXISTS idx_product_price_schedules_pending ON product_price_schedules (effective_at) WHERE applied_at IS NULL;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create product_price_schedules table: %w", err)
+	}
+
+	log.Println("Product price schedules table created successfully")
+	return nil
+}
+
+// createCouponTables creates the coupon, redemption and order discount tables
+func createCouponTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS coupons (
+		id SERIAL PRIMARY KEY,
+		code VARCHAR(50) NOT NULL UNIQUE,
+		description TEXT,
+		type VARCHAR(20) NOT NULL CHECK (type IN ('percentage', 'fixed_amount', 'free_shipping')),
+		value DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (value >= 0),
+		min_subtotal DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (min_subtotal >= 0),
+		starts_at TIMESTAMP,
+		ends_at TIMESTAMP,
+		usage_limit INTEGER CHECK (usage_limit > 0),
+		usage_limit_per_user INTEGER CHECK (usage_limit_per_user > 0),
+		usage_count INTEGER NOT NULL DEFAULT 0 CHECK (usage_count >= 0),
+		is_active BOOLEAN NOT NULL DEFAULT true,
+		product_ids INTEGER[] NOT NULL DEFAULT '{}',
+		category_ids INTEGER[] NOT NULL DEFAULT '{}',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS coupon_redemptions (
+		id SERIAL PRIMARY KEY,
+		coupon_id INTEGER NOT NULL REFERENCES coupons(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_coupon_user ON coupon_redemptions (coupon_id, user_id);
+	CREATE INDEX IF NOT EXISTS idx_coupon_redemptions_order_id ON coupon_redemptions (order_id);
+
+	CREATE TABLE IF NOT EXISTS order_discounts (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		source VARCHAR(20) NOT NULL,
+		coupon_id INTEGER REFERENCES coupons(id) ON DELETE SET NULL,
+		code VARCHAR(50),
+		description VARCHAR(255) NOT NULL,
+		type VARCHAR(20) NOT NULL,
+		amount DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_order_discounts_order_id ON order_discounts (order_id);
+
+	ALTER TABLE carts ADD COLUMN IF NOT EXISTS coupon_id INTEGER REFERENCES coupons(id) ON DELETE SET NULL;
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create coupon tables: %w", err)
+	}
+
+	log.Println("Coupon tables created successfully")
+	return nil
+}
+
+// createPromotionsTable creates the promotions table and links promotion discounts to orders
+func createPromotionsTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS promotions (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		type VARCHAR(20) NOT NULL CHECK (type IN ('buy_x_get_y', 'volume_tier', 'bundle', 'spend_threshold')),
+		priority INTEGER NOT NULL DEFAULT 0,
+		stackable BOOLEAN NOT NULL DEFAULT true,
+		is_active BOOLEAN NOT NULL DEFAULT true,
+		starts_at TIMESTAMP,
+		ends_at TIMESTAMP,
+		rules JSONB NOT NULL DEFAULT '{}',
+		product_ids INTEGER[] NOT NULL DEFAULT '{}',
+		category_ids INTEGER[] NOT NULL DEFAULT '{}',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_promotions_active ON promotions (priority DESC, id) WHERE is_active = true;
+
+	ALTER TABLE order_discounts ADD COLUMN IF NOT EXISTS promotion_id INTEGER REFERENCES promotions(id) ON DELETE SET NULL;
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS discount_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create promotions table: %w", err)
+	}
+
+	log.Println("Promotions table created successfully")
+	return nil
+}
+
+// createTaxTables creates tax classes and rates, assigns products a tax class and stores taxes on orders
+func createTaxTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS tax_classes (
+		code VARCHAR(50) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		description TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	INSERT INTO tax_classes (code, name, description)
+	VALUES ('standard', 'Standard', 'Default tax class')
+	ON CONFLICT (code) DO NOTHING;
+
+	CREATE TABLE IF NOT EXISTS tax_rates (
+		id SERIAL PRIMARY KEY,
+		tax_class VARCHAR(50) NOT NULL REFERENCES tax_classes(code) ON DELETE CASCADE,
+		country CHAR(2) NOT NULL,
+		region VARCHAR(50),
+		postal_code_prefix VARCHAR(20),
+		name VARCHAR(100) NOT NULL,
+		rate DECIMAL(7,4) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tax_rates_lookup ON tax_rates (tax_class, country, region);
+
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_class VARCHAR(50) NOT NULL DEFAULT 'standard' REFERENCES tax_classes(code);
+
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_country VARCHAR(2) NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_region VARCHAR(50) NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_postal_code VARCHAR(20) NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS prices_include_tax BOOLEAN NOT NULL DEFAULT false;
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+	CREATE TABLE IF NOT EXISTS order_tax_lines (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		order_item_id INTEGER REFERENCES order_items(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		jurisdiction VARCHAR(100) NOT NULL,
+		rate DECIMAL(7,4) NOT NULL,
+		taxable_amount DECIMAL(10,2) NOT NULL,
+		amount DECIMAL(10,2) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_order_tax_lines_order_id ON order_tax_lines (order_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create tax tables: %w", err)
+	}
+
+	log.Println("Tax tables created successfully")
+	return nil
+}
+
+// createShippingTables creates shipping zones and methods and stores weights, dimensions and shipping on orders
+func createShippingTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS shipping_zones (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS shipping_zone_locations (
+		id SERIAL PRIMARY KEY,
+		zone_id INTEGER NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+		country CHAR(2) NOT NULL,
+		region VARCHAR(50),
+		postal_code_prefix VARCHAR(20)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_shipping_zone_locations_country ON shipping_zone_locations (country);
+
+	CREATE TABLE IF NOT EXISTS shipping_methods (
+		id SERIAL PRIMARY KEY,
+		zone_id INTEGER NOT NULL REFERENCES shipping_zones(id) ON DELETE CASCADE,
+		code VARCHAR(50) UNIQUE NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		carrier VARCHAR(100),
+		type VARCHAR(20) NOT NULL CHECK (type IN ('flat_rate', 'weight_based', 'free_over_threshold')),
+		base_rate DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (base_rate >= 0),
+		per_kg_rate DECIMAL(10,2) NOT NULL DEFAULT 0 CHECK (per_kg_rate >= 0),
+		free_threshold DECIMAL(10,2),
+		min_weight DECIMAL(10,3),
+		max_weight DECIMAL(10,3),
+		estimated_days_min INTEGER,
+		estimated_days_max INTEGER,
+		position INTEGER NOT NULL DEFAULT 0,
+		is_active BOOLEAN NOT NULL DEFAULT true,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_shipping_methods_zone_id ON shipping_methods (zone_id, position, id);
+
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS weight DECIMAL(10,3) NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS length DECIMAL(10,2) NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS width DECIMAL(10,2) NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN IF NOT EXISTS height DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method VARCHAR(50) NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method_name VARCHAR(255) NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_carrier VARCHAR(100) NOT NULL DEFAULT '';
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create shipping tables: %w", err)
+	}
+
+	log.Println("Shipping tables created successfully")
+	return nil
+}
+
+// addOrderSubtotal adds the pre-discount merchandise subtotal to orders, backfilling existing orders from their items
+func addOrderSubtotal() error {
+	query := `
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_name = 'orders' AND column_name = 'subtotal_amount'
+		) THEN
+			ALTER TABLE orders ADD COLUMN subtotal_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+
+			UPDATE orders o SET subtotal_amount = COALESCE((
+				SELECT SUM(oi.price * oi.quantity) FROM order_items oi WHERE oi.order_id = o.id
+			), 0);
+		END IF;
+	END $$;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add order subtotal: %w", err)
+	}
+
+	log.Println("Order subtotal added successfully")
+	return nil
+}
+
+// createPaymentsTable creates the payments table recording provider authorizations, captures and refunds
+func createPaymentsTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS payments (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		provider VARCHAR(50) NOT NULL,
+		provider_reference VARCHAR(255),
+		method VARCHAR(100) NOT NULL DEFAULT '',
+		status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'authorized', 'declined', 'failed', 'captured', 'voided', 'refunded')),
+		amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+		captured_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		currency CHAR(3) NOT NULL,
+		failure_reason TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_provider_reference ON payments (provider, provider_reference);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create payments table: %w", err)
+	}
+
+	log.Println("Payments table created successfully")
+	return nil
+}
+
+// createPaymentWebhookEventsTable creates the table storing raw payment webhooks and their processing state
+func createPaymentWebhookEventsTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS payment_webhook_events (
+		id SERIAL PRIMARY KEY,
+		provider VARCHAR(50) NOT NULL,
+		event_id VARCHAR(255) NOT NULL,
+		event_type VARCHAR(100) NOT NULL,
+		payload TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'processing', 'processed', 'failed')),
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		processed_at TIMESTAMP,
+		UNIQUE (provider, event_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_payment_webhook_events_due ON payment_webhook_events (status, next_attempt_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create payment webhook events table: %w", err)
+	}
+
+	log.Println("Payment webhook events table created successfully")
+	return nil
+}
+
+// createRefundTables creates refunds with their refunded order lines and tracks refunded totals on orders
+func createRefundTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS refunds (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		payment_id INTEGER REFERENCES payments(id) ON DELETE SET NULL,
+		amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+		reason TEXT NOT NULL,
+		status VARCHAR(20) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+		provider_reference VARCHAR(255),
+		failure_reason TEXT,
+		created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds (order_id);
+
+	CREATE TABLE IF NOT EXISTS refund_items (
+		id SERIAL PRIMARY KEY,
+		refund_id INTEGER NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+		order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+		quantity INTEGER NOT NULL CHECK (quantity > 0),
+		amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+		restocked BOOLEAN NOT NULL DEFAULT false
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_refund_items_order_item_id ON refund_items (order_item_id);
+
+	ALTER TABLE orders ADD COLUMN IF NOT EXISTS refunded_amount DECIMAL(10,2) NOT NULL DEFAULT 0;
+	ALTER TABLE order_items ADD COLUMN IF NOT EXISTS refunded_quantity INTEGER NOT NULL DEFAULT 0;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create refund tables: %w", err)
+	}
+
+	log.Println("Refund tables created successfully")
+	return nil
+}
+
+// createReturnTables creates customer returns (RMAs) and their returned order lines
+func createReturnTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS returns (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		status VARCHAR(20) NOT NULL DEFAULT 'requested'
+			CHECK (status IN ('requested', 'approved', 'rejected', 'received', 'completed', 'cancelled')),
+		reason TEXT NOT NULL,
+		admin_note TEXT,
+		label_url TEXT,
+		carrier VARCHAR(100),
+		tracking_number VARCHAR(255),
+		refund_id INTEGER REFERENCES refunds(id) ON DELETE SET NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		approved_at TIMESTAMP,
+		received_at TIMESTAMP,
+		completed_at TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_returns_user_id ON returns (user_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_returns_order_id ON returns (order_id);
+
+	CREATE TABLE IF NOT EXISTS return_items (
+		id SERIAL PRIMARY KEY,
+		return_id INTEGER NOT NULL REFERENCES returns(id) ON DELETE CASCADE,
+		order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		quantity INTEGER NOT NULL CHECK (quantity > 0),
+		reason TEXT,
+		condition VARCHAR(50),
+		restock BOOLEAN NOT NULL DEFAULT false
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_return_items_order_item_id ON return_items (order_item_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create return tables: %w", err)
+	}
+
+	log.Println("Return tables created successfully")
+	return nil
+}
+
+// createOrderStatusHistoryTable creates the audit trail of order status changes. Orders placed
+// before it existed get a single entry for their current status.
+func createOrderStatusHistoryTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS order_status_history (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		from_status VARCHAR(50),
+		to_status VARCHAR(50) NOT NULL,
+		actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		note TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history (order_id, created_at);
+
+	INSERT INTO order_status_history (order_id, to_status, note, created_at)
+	SELECT o.id, o.status, 'recorded before status history was kept', o.updated_at
+	FROM orders o
+	WHERE NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.order_id = o.id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create order status history table: %w", err)
+	}
+
+	log.Println("Order status history table created successfully")
+	return nil
+}
+
+// createShipmentTables creates shipments with their shipped order lines and allows the
+// partially shipped order status
+func createShipmentTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS shipments (
+		id SERIAL PRIMARY KEY,
+		order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+		carrier VARCHAR(100) NOT NULL,
+		tracking_number VARCHAR(255),
+		tracking_url TEXT,
+		status VARCHAR(20) NOT NULL DEFAULT 'shipped' CHECK (status IN ('shipped', 'delivered')),
+		shipped_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		delivered_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_shipments_order_id ON shipments (order_id);
+
+	CREATE TABLE IF NOT EXISTS shipment_items (
+		id SERIAL PRIMARY KEY,
+		shipment_id INTEGER NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+		order_item_id INTEGER NOT NULL REFERENCES order_items(id) ON DELETE CASCADE,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		quantity INTEGER NOT NULL CHECK (quantity > 0)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_shipment_items_order_item_id ON shipment_items (order_item_id);
+
+	DO $$
+	BEGIN
+		IF NOT EXISTS (
+			SELECT 1 FROM pg_constraint
+			WHERE conname = 'orders_status_check' AND pg_get_constraintdef(oid) LIKE '%partially_shipped%'
+		) THEN
+			ALTER TABLE orders DROP CONSTRAINT IF EXISTS orders_status_check;
+			ALTER TABLE orders ADD CONSTRAINT orders_status_check
+				CHECK (status IN ('pending', 'confirmed', 'partially_shipped', 'shipped', 'delivered', 'cancelled'));
+		END IF;
+	END $$;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create shipment tables: %w", err)
+	}
+
+	log.Println("Shipment tables created successfully")
+	return nil
+}
+
+// createIdempotencyKeysTable creates the stored responses of requests made with an Idempotency-Key header
+func createIdempotencyKeysTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		key VARCHAR(255) NOT NULL,
+		method VARCHAR(10) NOT NULL,
+		path VARCHAR(255) NOT NULL,
+		request_hash VARCHAR(64) NOT NULL,
+		status VARCHAR(20) NOT NULL CHECK (status IN ('processing', 'completed')),
+		response_status INTEGER,
+		response_body BYTEA,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		UNIQUE(user_id, key)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create idempotency keys table: %w", err)
+	}
+
+	log.Println("Idempotency keys table created successfully")
+	return nil
+}
+
+// addCartItemSeenPrice adds the price a cart item was last shown at, used by checkout to detect price changes
+func addCartItemSeenPrice() error {
+	query := `ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS seen_price DECIMAL(10,2);`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add cart item seen price: %w", err)
+	}
+
+	log.Println("Cart item seen price column added successfully")
+	return nil
+}
+
+// addGuestCarts lets carts belong to a guest instead of a user; guest carts are found by their key
+func addGuestCarts() error {
+	query := `
+	ALTER TABLE carts ALTER COLUMN user_id DROP NOT NULL;
+	ALTER TABLE carts ADD COLUMN IF NOT EXISTS guest_key VARCHAR(64) UNIQUE;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add guest carts: %w", err)
+	}
+
+	log.Println("Guest carts added successfully")
+	return nil
+}
+
+// separateGuestAccounts lets a customer register with the email of a guest checkout account
+// without taking it over. Each email has at most one guest and one customer account; the guest's
+// orders are attached to the customer through a guest order claim sent to the email.
+func separateGuestAccounts() error {
+	query := `
+	ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email) WHERE role <> 'guest';
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_guest_email ON users(email) WHERE role = 'guest';
+
+	CREATE TABLE IF NOT EXISTS guest_order_claims (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		guest_user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token VARCHAR(64) UNIQUE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL,
+		claimed_at TIMESTAMP
+	);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to separate guest accounts: %w", err)
+	}
+
+	log.Println("Guest accounts separated successfully")
+	return nil
+}
+
+// createCartRecoveryTables creates the table of abandoned cart recovery notifications. Each one
+// keeps a snapshot of the cart's items so its restore link works after the cart has expired.
+func createCartRecoveryTables() error {
+	query := `
+	ALTER TABLE carts ADD COLUMN IF NOT EXISTS recovery_sent_at TIMESTAMP;
+
+	CREATE INDEX IF NOT EXISTS idx_carts_updated_at ON carts(updated_at);
+
+	CREATE TABLE IF NOT EXISTS cart_recoveries (
+		id SERIAL PRIMARY KEY,
+		cart_id INTEGER REFERENCES carts(id) ON DELETE SET NULL,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token VARCHAR(64) UNIQUE NOT NULL,
+		items JSONB NOT NULL DEFAULT '[]',
+		total_value DECIMAL(10,2) NOT NULL DEFAULT 0,
+		restored_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_cart_recoveries_created_at ON cart_recoveries(created_at);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create cart recovery tables: %w", err)
+	}
+
+	log.Println("Cart recovery tables created successfully")
+	return nil
+}
+
+// addCartItemAddedPrice adds the price a cart item was added at, used to warn about price changes
+func addCartItemAddedPrice() error {
+	query := `ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS added_price DECIMAL(10,2);`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add cart item added price: %w", err)
+	}
+
+	log.Println("Cart item added price column added successfully")
+	return nil
+}
+
+// createWishlistTables creates the wishlists and wishlist_items tables. out_of_stock_at marks
+// subscribed items seen out of stock, awaiting a back in stock notification.
+func createWishlistTables() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS wishlists (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name VARCHAR(100) NOT NULL,
+		kind VARCHAR(20) NOT NULL DEFAULT 'wishlist',
+		is_public BOOLEAN NOT NULL DEFAULT false,
+		share_token VARCHAR(64) UNIQUE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_wishlists_user_id ON wishlists(user_id);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_wishlists_saved_for_later ON wishlists(user_id) WHERE kind = 'saved_for_later';
+
+	CREATE TABLE IF NOT EXISTS wishlist_items (
+		id SERIAL PRIMARY KEY,
+		wishlist_id INTEGER NOT NULL REFERENCES wishlists(id) ON DELETE CASCADE,
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+		added_price DECIMAL(10,2),
+		notify_back_in_stock BOOLEAN NOT NULL DEFAULT false,
+		notify_price_drop BOOLEAN NOT NULL DEFAULT false,
+		price_alert_price DECIMAL(10,2),
+		out_of_stock_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(wishlist_id, product_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_wishlist_items_product_id ON wishlist_items(product_id);
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create wishlist tables: %w", err)
+	}
+
+	log.Println("Wishlist tables created successfully")
+	return nil
+}
+
+// addGuestIdempotencyKeys lets idempotency keys belong to a guest, identified by the key of their
+// cart, instead of a user. Keys are unique per user or per guest.
+func addGuestIdempotencyKeys() error {
+	query := `
+	ALTER TABLE idempotency_keys ALTER COLUMN user_id DROP NOT NULL;
+	ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS guest_key VARCHAR(64);
+	ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_user_id_key_key;
+	ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_owner_check;
+	ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_owner_check CHECK (user_id IS NOT NULL OR guest_key IS NOT NULL);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_keys_user_key ON idempotency_keys(user_id, key) WHERE user_id IS NOT NULL;
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_idempotency_keys_guest_key ON idempotency_keys(guest_key, key) WHERE user_id IS NULL;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add guest idempotency keys: %w", err)
+	}
+
+	log.Println("Guest idempotency keys added successfully")
+	return nil
+}
+
+// addWishlistAlertAttempts adds alert_attempted_at to wishlist_items, the last time a notification
+// for the item was tried. Alerts are sent least recently tried first, so items whose delivery keeps
+// failing do not hold back the others.
+func addWishlistAlertAttempts() error {
+	query := `
+	ALTER TABLE wishlist_items ADD COLUMN IF NOT EXISTS alert_attempted_at TIMESTAMP;
+	`
+
+	_, err := DB.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to add wishlist alert attempts: %w", err)
+	}
+
+	log.Println("Wishlist alert attempts added successfully")
+	return nil
+}
+
+// createPaginationIndexes creates indexes backing product and order listing sort keys. Products
+// sort by their effective price, which depends on sale windows and NOW() and so cannot be indexed.
+func createPaginationIndexes() error {
+	queries := []string{
+		"CREATE INDEX IF NOT EXISTS idx_products_created_at_id ON products (created_at, id);",
+		"DROP INDEX IF EXISTS idx_products_price_id;",
+		"CREATE INDEX IF NOT EXISTS idx_products_name_id ON products (name, id);",
+		"CREATE INDEX IF NOT EXISTS idx_order_items_product_id ON order_items (product_id);",
+		"CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders (created_at, id);",
+		"CREATE INDEX IF NOT EXISTS idx_orders_user_id_created_at ON orders (user_id, created_at, id);",
+	}
+
+	for _, query := range queries {
+		if _, err := DB.Exec(query); err != nil {
+			return fmt.Errorf("failed to create index: %w", err)
+		}
+	}
+
+	log.Println("Pagination indexes created successfully")
+	return nil
+}
+
 // DropTables drops all tables (for testing/reset purposes)
 func DropTables() error {
 	log.Println("Dropping all tables...")
@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AttributeHandler handles product attribute HTTP requests
+type AttributeHandler struct {
+	attributeService *services.AttributeService
+}
+
+// NewAttributeHandler creates a new attribute handler
+func NewAttributeHandler() *AttributeHandler {
+	return &AttributeHandler{
+		attributeService: services.NewAttributeService(),
+	}
+}
+
+// CreateAttribute handles attribute definition creation
+func (h *AttributeHandler) CreateAttribute(c *gin.Context) {
+	var req services.CreateAttributeRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Create attribute
+	attr, err := h.attributeService.CreateAttribute(&req)
+	if err != nil {
+		switch err.Error() {
+		case "attribute with this code already exists":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Attribute with this code already exists",
+			})
+			return
+		case "invalid attribute code", "enum attributes require enum values":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create attribute",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Attribute created successfully",
+		"data":    attr,
+	})
+}
+
+// ListAttributes handles attribute definition listing
+func (h *AttributeHandler) ListAttributes(c *gin.Context) {
+	attrs, err := h.attributeService.ListAttributes()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve attributes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": attrs,
+	})
+}
+
+// GetAttribute handles retrieving a single attribute definition
+func (h *AttributeHandler) GetAttribute(c *gin.Context) {
+	// Parse attribute ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid attribute ID",
+		})
+		return
+	}
+
+	attr, err := h.attributeService.GetAttribute(uint(id))
+	if err != nil {
+		if err.Error() == "attribute not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Attribute not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve attribute",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": attr,
+	})
+}
+
+// UpdateAttribute handles attribute definition updates
+func (h *AttributeHandler) UpdateAttribute(c *gin.Context) {
+	// Parse attribute ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid attribute ID",
+		})
+		return
+	}
+
+	var req services.UpdateAttributeRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	attr, err := h.attributeService.UpdateAttribute(uint(id), &req)
+	if err != nil {
+		switch err.Error() {
+		case "attribute not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Attribute not found",
+			})
+			return
+		case "enum values can only be set on enum attributes", "enum attributes require enum values":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update attribute",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attribute updated successfully",
+		"data":    attr,
+	})
+}
+
+// DeleteAttribute handles attribute definition deletion
+func (h *AttributeHandler) DeleteAttribute(c *gin.Context) {
+	// Parse attribute ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid attribute ID",
+		})
+		return
+	}
+
+	if err := h.attributeService.DeleteAttribute(uint(id)); err != nil {
+		if err.Error() == "attribute not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Attribute not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete attribute",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attribute deleted successfully",
+	})
+}
+
+// ListCategoryAttributes handles listing the attributes assigned to a category
+func (h *AttributeHandler) ListCategoryAttributes(c *gin.Context) {
+	// Parse category ID from URL parameter
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	attrs, err := h.attributeService.ListCategoryAttributes(uint(categoryID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve category attributes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": attrs,
+	})
+}
+
+// AssignCategoryAttribute handles assigning an attribute to a category
+func (h *AttributeHandler) AssignCategoryAttribute(c *gin.Context) {
+	// Parse category ID from URL parameter
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	var req services.AssignCategoryAttributeRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := h.attributeService.AssignAttributeToCategory(uint(categoryID), &req); err != nil {
+		switch err.Error() {
+		case "category not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Category not found",
+			})
+			return
+		case "attribute not found":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Attribute not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to assign attribute",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attribute assigned to category successfully",
+	})
+}
+
+// RemoveCategoryAttribute handles removing an attribute from a category
+func (h *AttributeHandler) RemoveCategoryAttribute(c *gin.Context) {
+	// Parse category and attribute IDs from URL parameters
+	categoryID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid category ID",
+		})
+		return
+	}
+
+	attributeID, err := strconv.ParseUint(c.Param("attribute_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid attribute ID",
+		})
+		return
+	}
+
+	if err := h.attributeService.RemoveAttributeFromCategory(uint(categoryID), uint(attributeID)); err != nil {
+		if err.Error() == "attribute not assigned to category" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Attribute not assigned to category",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove attribute",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Attribute removed from category successfully",
+	})
+}
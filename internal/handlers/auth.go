@@ -32,6 +32,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// The guest cart may also be sent in the cart token header
+	if req.CartToken == "" {
+		req.CartToken = c.GetHeader(CartTokenHeader)
+	}
+
 	// Register user
 	response, err := h.authService.Register(&req)
 	if err != nil {
@@ -68,6 +73,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// The guest cart may also be sent in the cart token header
+	if req.CartToken == "" {
+		req.CartToken = c.GetHeader(CartTokenHeader)
+	}
+
 	// Authenticate user
 	response, err := h.authService.Login(&req)
 	if err != nil {
@@ -90,6 +100,56 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// ClaimGuestOrdersRequest represents the request to attach guest checkout orders to the account
+type ClaimGuestOrdersRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ClaimGuestOrders handles attaching the orders placed by guest checkout with the user's email,
+// using the code sent to that email on registration
+func (h *AuthHandler) ClaimGuestOrders(c *gin.Context) {
+	var req ClaimGuestOrdersRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	claimed, err := h.authService.ClaimGuestOrders(c.GetUint("user_id"), req.Token)
+	if err != nil {
+		switch err.Error() {
+		case "guest order claim not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Guest order claim not found",
+			})
+		case "guest order claim already used":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Guest order claim already used",
+			})
+		case "guest order claim has expired":
+			c.JSON(http.StatusGone, gin.H{
+				"error": "Guest order claim has expired",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to claim guest orders",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Guest orders added to your account",
+		"data": gin.H{
+			"orders_claimed": claimed,
+		},
+	})
+}
+
 // GetProfile returns the current user's profile
 func (h *AuthHandler) GetProfile(c *gin.Context) {
 	// Get user from context (set by auth middleware)
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
+	"github.com/Code-byme/e-commerce/internal/models"
 	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/Code-byme/e-commerce/internal/tax"
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,20 +24,58 @@ func NewCartHandler() *CartHandler {
 	}
 }
 
+// CartTokenHeader carries the token of a guest cart. Guests receive the token as cart_token
+// in cart responses once their cart is created.
+const CartTokenHeader = "X-Cart-Token"
+
+// cartOwner identifies the cart of a request: the authenticated user's, or a guest cart from the
+// cart token header. It responds with an error and returns false for an invalid token.
+func cartOwner(c *gin.Context) (services.CartOwner, bool) {
+	if userID, exists := c.Get("user_id"); exists {
+		return services.CartOwner{UserID: userID.(uint)}, true
+	}
+
+	token := c.GetHeader(CartTokenHeader)
+	if token != "" {
+		if _, err := services.VerifyCartToken(token); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid cart token",
+			})
+			return services.CartOwner{}, false
+		}
+	}
+
+	return services.CartOwner{GuestToken: token}, true
+}
+
 // GetCart handles retrieving the user's cart
 func (h *CartHandler) GetCart(c *gin.Context) {
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
 		return
 	}
 
-	// Get cart
-	cart, err := h.cartService.GetCart(userID.(uint))
+	// Get cart, estimating shipping and tax when a destination country is given
+	var cart *models.CartResponse
+	var err error
+	if country := c.Query("country"); country != "" {
+		cart, err = h.cartService.GetCartForAddress(owner, tax.Address{
+			Country:    country,
+			Region:     c.Query("region"),
+			PostalCode: c.Query("postal_code"),
+		}, c.Query("shipping_method"))
+	} else {
+		cart, err = h.cartService.GetCart(owner)
+	}
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "shipping method") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to retrieve cart",
 		})
@@ -47,12 +89,9 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 
 // AddToCart handles adding an item to the cart
 func (h *CartHandler) AddToCart(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
 		return
 	}
 
@@ -68,7 +107,7 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 	}
 
 	// Add item to cart
-	cart, err := h.cartService.AddToCart(userID.(uint), &req)
+	cart, err := h.cartService.AddToCart(owner, &req)
 	if err != nil {
 		if err.Error() == "product not found or inactive" {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -76,7 +115,7 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 			})
 			return
 		}
-		if err.Error() == "insufficient stock for product iPhone 15 Pro (available: 50, requested: 100)" {
+		if strings.HasPrefix(err.Error(), "insufficient stock") {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Insufficient stock for the requested product",
 			})
@@ -97,12 +136,9 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 
 // UpdateCartItem handles updating a cart item quantity
 func (h *CartHandler) UpdateCartItem(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
 		return
 	}
 
@@ -128,7 +164,7 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 	}
 
 	// Update cart item
-	cart, err := h.cartService.UpdateCartItem(userID.(uint), uint(itemID), &req)
+	cart, err := h.cartService.UpdateCartItem(owner, uint(itemID), &req)
 	if err != nil {
 		if err.Error() == "cart item not found or not owned by user" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -142,7 +178,7 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 			})
 			return
 		}
-		if err.Error() == "insufficient stock for product iPhone 15 Pro (available: 50, requested: 100)" {
+		if strings.HasPrefix(err.Error(), "insufficient stock") {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Insufficient stock for the requested quantity",
 			})
@@ -163,12 +199,9 @@ func (h *CartHandler) UpdateCartItem(c *gin.Context) {
 
 // RemoveFromCart handles removing an item from the cart
 func (h *CartHandler) RemoveFromCart(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
 		return
 	}
 
@@ -183,7 +216,7 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 	}
 
 	// Remove item from cart
-	cart, err := h.cartService.RemoveFromCart(userID.(uint), uint(itemID))
+	cart, err := h.cartService.RemoveFromCart(owner, uint(itemID))
 	if err != nil {
 		if err.Error() == "cart item not found or not owned by user" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -206,17 +239,14 @@ func (h *CartHandler) RemoveFromCart(c *gin.Context) {
 
 // ClearCart handles clearing all items from the cart
 func (h *CartHandler) ClearCart(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
-		})
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
 		return
 	}
 
 	// Clear cart
-	err := h.cartService.ClearCart(userID.(uint))
+	err := h.cartService.ClearCart(owner)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to clear cart",
@@ -229,20 +259,41 @@ func (h *CartHandler) ClearCart(c *gin.Context) {
 	})
 }
 
-// CheckoutCart handles the checkout process
-func (h *CartHandler) CheckoutCart(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "User not authenticated",
+// RevalidateCart handles fixing the cart's warnings: unavailable items are removed, quantities are
+// reduced to the available stock and changed prices are accepted
+func (h *CartHandler) RevalidateCart(c *gin.Context) {
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+
+	cart, adjustments, err := h.cartService.RevalidateCart(owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to revalidate cart",
 		})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Cart revalidated successfully",
+		"data":        cart,
+		"adjustments": adjustments,
+	})
+}
+
+// ApplyCoupon handles applying or removing the cart's coupon code
+func (h *CartHandler) ApplyCoupon(c *gin.Context) {
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+
 	var req struct {
-		ShippingAddress string `json:"shipping_address" binding:"required"`
-		PaymentMethod   string `json:"payment_method" binding:"required"`
+		Code   string `json:"code"`
+		Remove bool   `json:"remove"`
 	}
 
 	// Bind and validate request
@@ -254,27 +305,174 @@ func (h *CartHandler) CheckoutCart(c *gin.Context) {
 		return
 	}
 
+	// An empty code removes the coupon
+	if req.Remove || strings.TrimSpace(req.Code) == "" {
+		h.RemoveCoupon(c)
+		return
+	}
+
+	cart, err := h.cartService.ApplyCoupon(owner, req.Code)
+	if err != nil {
+		switch {
+		case err.Error() == "coupon not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Coupon not found",
+			})
+		case err.Error() == "cart is empty":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Cart is empty",
+			})
+		case strings.HasPrefix(err.Error(), "coupon"):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to apply coupon",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon applied successfully",
+		"data":    cart,
+	})
+}
+
+// RemoveCoupon handles removing the coupon from the cart
+func (h *CartHandler) RemoveCoupon(c *gin.Context) {
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.RemoveCoupon(owner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to remove coupon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon removed successfully",
+		"data":    cart,
+	})
+}
+
+// ShippingQuotes handles quoting the shipping options for the cart
+func (h *CartHandler) ShippingQuotes(c *gin.Context) {
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+
+	var req services.ShippingQuoteRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	quotes, err := h.cartService.QuoteShipping(owner, &req)
+	if err != nil {
+		if err.Error() == "cart is empty" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Cart is empty",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to quote shipping",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": quotes,
+	})
+}
+
+// CheckoutCart handles the checkout process
+func (h *CartHandler) CheckoutCart(c *gin.Context) {
+	// Identify the cart by the authenticated user, or by cart token for guests
+	owner, ok := cartOwner(c)
+	if !ok {
+		return
+	}
+
+	var req services.CheckoutRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
 	// Checkout cart
-	order, err := h.cartService.CheckoutCart(userID.(uint), req.ShippingAddress, req.PaymentMethod)
+	order, err := h.cartService.CheckoutCart(owner, &req)
 	if err != nil {
+		// Let the idempotency middleware know if the order was placed before the failure
+		_ = c.Error(err)
 		if err.Error() == "cart is empty" {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Cart is empty",
 			})
 			return
 		}
-		if err.Error() == "product with ID 1 not found or inactive" {
+		if err.Error() == "email is required for guest checkout" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Email is required for guest checkout",
+			})
+			return
+		}
+		if err.Error() == "an account exists for this email; log in to check out" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "An account exists for this email; log in to check out",
+			})
+			return
+		}
+		var priceErr *services.PriceChangeError
+		if errors.As(err, &priceErr) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":         "Prices changed since the cart was last viewed; review the cart or resubmit with accept_price_changes",
+				"price_changes": priceErr.Changes,
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "product with ID") {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "One or more products not found or inactive",
 			})
 			return
 		}
-		if err.Error() == "insufficient stock for product iPhone 15 Pro (available: 50, requested: 100)" {
+		if strings.HasPrefix(err.Error(), "insufficient stock") {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Insufficient stock for one or more products",
 			})
 			return
 		}
+		if strings.HasPrefix(err.Error(), "coupon") || strings.HasPrefix(err.Error(), "shipping") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "payment") {
+			respondPaymentError(c, err, "Failed to checkout cart")
+			return
+		}
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to checkout cart",
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// CartRecoveryHandler handles abandoned cart reporting and restore link HTTP requests
+type CartRecoveryHandler struct {
+	cartRecoveryService *services.CartRecoveryService
+}
+
+// NewCartRecoveryHandler creates a new cart recovery handler
+func NewCartRecoveryHandler() *CartRecoveryHandler {
+	return &CartRecoveryHandler{
+		cartRecoveryService: services.NewCartRecoveryService(),
+	}
+}
+
+// CartRecoveryService returns the cart recovery service used by the handler
+func (h *CartRecoveryHandler) CartRecoveryService() *services.CartRecoveryService {
+	return h.cartRecoveryService
+}
+
+// GetAbandonedCartReport handles reporting the value of abandoned carts (admin only)
+func (h *CartRecoveryHandler) GetAbandonedCartReport(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "days must be between 1 and 365",
+		})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "limit must be between 1 and 100",
+		})
+		return
+	}
+
+	report, err := h.cartRecoveryService.GetAbandonedCartReport(days, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve abandoned cart report",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": report,
+	})
+}
+
+// RestoreCart handles restoring an abandoned cart with the token from its recovery notification,
+// for the cart's user only
+func (h *CartRecoveryHandler) RestoreCart(c *gin.Context) {
+	cart, err := h.cartRecoveryService.RestoreCart(c.GetUint("user_id"), c.Param("token"))
+	if err != nil {
+		switch err.Error() {
+		case "restore link not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Restore link not found",
+			})
+		case "restore link already used":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Restore link already used",
+			})
+		case "restore link has expired":
+			c.JSON(http.StatusGone, gin.H{
+				"error": "Restore link has expired",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to restore cart",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cart restored successfully",
+		"data":    cart,
+	})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// CouponHandler handles coupon management HTTP requests
+type CouponHandler struct {
+	couponService *services.CouponService
+}
+
+// NewCouponHandler creates a new coupon handler
+func NewCouponHandler() *CouponHandler {
+	return &CouponHandler{
+		couponService: services.NewCouponService(),
+	}
+}
+
+// CreateCoupon handles coupon creation
+func (h *CouponHandler) CreateCoupon(c *gin.Context) {
+	var req services.CouponRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	coupon, err := h.couponService.CreateCoupon(&req)
+	if err != nil {
+		if err.Error() == "coupon code already exists" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Coupon code already exists",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid coupon") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create coupon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Coupon created successfully",
+		"data":    coupon,
+	})
+}
+
+// ListCoupons handles coupon listing
+func (h *CouponHandler) ListCoupons(c *gin.Context) {
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	response, err := h.couponService.ListCoupons(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve coupons",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// GetCoupon handles retrieving a single coupon
+func (h *CouponHandler) GetCoupon(c *gin.Context) {
+	// Parse coupon ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid coupon ID",
+		})
+		return
+	}
+
+	coupon, err := h.couponService.GetCoupon(uint(id))
+	if err != nil {
+		if err.Error() == "coupon not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Coupon not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve coupon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": coupon,
+	})
+}
+
+// UpdateCoupon handles replacing a coupon's settings
+func (h *CouponHandler) UpdateCoupon(c *gin.Context) {
+	// Parse coupon ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid coupon ID",
+		})
+		return
+	}
+
+	var req services.CouponRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	coupon, err := h.couponService.UpdateCoupon(uint(id), &req)
+	if err != nil {
+		switch {
+		case err.Error() == "coupon not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Coupon not found",
+			})
+		case err.Error() == "coupon code already exists":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Coupon code already exists",
+			})
+		case strings.HasPrefix(err.Error(), "invalid coupon"):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update coupon",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon updated successfully",
+		"data":    coupon,
+	})
+}
+
+// DeleteCoupon handles coupon deletion
+func (h *CouponHandler) DeleteCoupon(c *gin.Context) {
+	// Parse coupon ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid coupon ID",
+		})
+		return
+	}
+
+	if err := h.couponService.DeleteCoupon(uint(id)); err != nil {
+		if err.Error() == "coupon not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Coupon not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete coupon",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon deleted successfully",
+	})
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileBytes limits the size of an uploaded import file
+const maxImportFileBytes = 50 << 20
+
+// ImportHandler handles bulk product import and export HTTP requests
+type ImportHandler struct {
+	importService *services.ImportService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler() *ImportHandler {
+	return &ImportHandler{
+		importService: services.NewImportService(),
+	}
+}
+
+// ImportService returns the import service used by the handler
+func (h *ImportHandler) ImportService() *services.ImportService {
+	return h.importService
+}
+
+// ImportProducts handles bulk product import submissions
+func (h *ImportHandler) ImportProducts(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "User not authenticated",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileBytes+1<<20)
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Import file is required",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read import file",
+		})
+		return
+	}
+	if len(data) > maxImportFileBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Import file is too large",
+		})
+		return
+	}
+
+	req := &services.CreateImportRequest{
+		Format:  c.PostForm("format"),
+		MatchBy: c.PostForm("match_by"),
+		Data:    data,
+	}
+
+	// Detect format from the file extension when not given
+	if req.Format == "" {
+		switch strings.ToLower(filepath.Ext(header.Filename)) {
+		case ".jsonl", ".ndjson":
+			req.Format = "jsonl"
+		default:
+			req.Format = "csv"
+		}
+	}
+
+	if dryRunStr := c.PostForm("dry_run"); dryRunStr != "" {
+		dryRun, err := strconv.ParseBool(dryRunStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid dry_run value",
+			})
+			return
+		}
+		req.DryRun = dryRun
+	}
+
+	// Column mapping is a JSON object of source column -> product field
+	if mappingStr := c.PostForm("mapping"); mappingStr != "" {
+		if err := json.Unmarshal([]byte(mappingStr), &req.Mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid column mapping",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	// Create import job
+	job, err := h.importService.CreateImportJob(userID.(uint), req)
+	if err != nil {
+		msg := err.Error()
+		if msg == "unsupported import format" || msg == "invalid match_by value" || msg == "import file is empty" ||
+			strings.HasPrefix(msg, "invalid column mapping") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create import job",
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Import job created successfully",
+		"data":    job,
+	})
+}
+
+// GetImportJob handles retrieving an import job's status
+func (h *ImportHandler) GetImportJob(c *gin.Context) {
+	// Parse job ID from URL parameter
+	jobID, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid import job ID",
+		})
+		return
+	}
+
+	job, err := h.importService.GetImportJob(uint(jobID))
+	if err != nil {
+		if err.Error() == "import job not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Import job not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve import job",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": job,
+	})
+}
+
+// DownloadImportReport handles downloading the row error report of an import job
+func (h *ImportHandler) DownloadImportReport(c *gin.Context) {
+	// Parse job ID from URL parameter
+	jobID, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid import job ID",
+		})
+		return
+	}
+
+	if _, err := h.importService.GetImportJob(uint(jobID)); err != nil {
+		if err.Error() == "import job not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Import job not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve import job",
+		})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=import-%d-report.csv", jobID))
+	c.Status(http.StatusOK)
+
+	if err := h.importService.WriteImportReport(c.Writer, uint(jobID)); err != nil {
+		log.Printf("Warning: failed to write import report %d: %v", jobID, err)
+	}
+}
+
+// ExportProducts handles streaming the filtered catalog as CSV or JSON Lines
+func (h *ImportHandler) ExportProducts(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "csv"))
+	if format != "csv" && format != "jsonl" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Unsupported export format (allowed: csv, jsonl)",
+		})
+		return
+	}
+
+	filter := parseProductFilter(c)
+	filter.IncludeHidden = true
+
+	contentType := "text/csv"
+	if format == "jsonl" {
+		contentType = "application/x-ndjson"
+	}
+	filename := fmt.Sprintf("products-%s.%s", time.Now().Format("20060102-150405"), format)
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	// Headers are already sent once streaming starts, so failures can only be logged
+	if err := h.importService.ExportProducts(c.Writer, format, filter); err != nil {
+		log.Printf("Warning: product export failed: %v", err)
+	}
+}
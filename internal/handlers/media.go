@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// MediaHandler handles product image HTTP requests
+type MediaHandler struct {
+	mediaService *services.MediaService
+}
+
+// NewMediaHandler creates a new media handler
+func NewMediaHandler() *MediaHandler {
+	return &MediaHandler{
+		mediaService: services.NewMediaService(),
+	}
+}
+
+// MediaService returns the media service used by the handler
+func (h *MediaHandler) MediaService() *services.MediaService {
+	return h.mediaService
+}
+
+// UploadProductImage handles multipart image uploads to a product gallery
+func (h *MediaHandler) UploadProductImage(c *gin.Context) {
+	// Parse product ID from URL parameter
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	// Limit the request body to the maximum upload size plus room for form fields
+	maxBytes := h.mediaService.MaxUploadBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+1<<20)
+
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Image file is required",
+			"details": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxBytes+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read image file",
+		})
+		return
+	}
+
+	// Optional gallery position
+	var position *int
+	if positionStr := c.PostForm("position"); positionStr != "" {
+		parsed, err := strconv.Atoi(positionStr)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid image position",
+			})
+			return
+		}
+		position = &parsed
+	}
+
+	// Upload image
+	image, err := h.mediaService.UploadProductImage(c.Request.Context(), uint(productID), data, c.PostForm("alt_text"), position)
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		case "image too large":
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Image exceeds the maximum upload size",
+			})
+			return
+		case "unsupported image type":
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{
+				"error": "Unsupported image type (allowed: JPEG, PNG, GIF)",
+			})
+			return
+		case "invalid image data", "image dimensions too large":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to upload image",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Image uploaded successfully",
+		"data":    image,
+	})
+}
+
+// ListProductImages handles retrieving a product's gallery
+func (h *MediaHandler) ListProductImages(c *gin.Context) {
+	// Parse product ID from URL parameter
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	images, err := h.mediaService.ListProductImages(uint(productID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve product images",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": images,
+	})
+}
+
+// UpdateProductImage handles alt text and position updates for a gallery image
+func (h *MediaHandler) UpdateProductImage(c *gin.Context) {
+	// Parse product and image IDs from URL parameters
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("image_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid image ID",
+		})
+		return
+	}
+
+	var req services.UpdateProductImageRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	image, err := h.mediaService.UpdateProductImage(uint(productID), uint(imageID), &req)
+	if err != nil {
+		if err.Error() == "image not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Image not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update image",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Image updated successfully",
+		"data":    image,
+	})
+}
+
+// ReorderProductImages handles reordering a product's gallery
+func (h *MediaHandler) ReorderProductImages(c *gin.Context) {
+	// Parse product ID from URL parameter
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req services.ReorderProductImagesRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	images, err := h.mediaService.ReorderProductImages(uint(productID), req.ImageIDs)
+	if err != nil {
+		if err.Error() == "image list does not match product gallery" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Image list must contain every image of the product exactly once",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reorder images",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Images reordered successfully",
+		"data":    images,
+	})
+}
+
+// DeleteProductImage handles removing an image from a product's gallery
+func (h *MediaHandler) DeleteProductImage(c *gin.Context) {
+	// Parse product and image IDs from URL parameters
+	productID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	imageID, err := strconv.ParseUint(c.Param("image_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid image ID",
+		})
+		return
+	}
+
+	if err := h.mediaService.DeleteProductImage(c.Request.Context(), uint(productID), uint(imageID)); err != nil {
+		if err.Error() == "image not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Image not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete image",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Image deleted successfully",
+	})
+}
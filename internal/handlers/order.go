@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/Code-byme/e-commerce/internal/services"
 	"github.com/gin-gonic/gin"
@@ -45,18 +46,30 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	// Create order
 	order, err := h.orderService.CreateOrder(userID.(uint), &req)
 	if err != nil {
-		if err.Error() == "product with ID 1 not found or inactive" {
+		// Let the idempotency middleware know if the order was placed before the failure
+		_ = c.Error(err)
+		if strings.HasPrefix(err.Error(), "product with ID") {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "One or more products not found or inactive",
 			})
 			return
 		}
-		if err.Error() == "insufficient stock for product iPhone 15 Pro (available: 50, requested: 100)" {
+		if strings.HasPrefix(err.Error(), "insufficient stock") {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error": "Insufficient stock for one or more products",
 			})
 			return
 		}
+		if strings.HasPrefix(err.Error(), "coupon") || strings.HasPrefix(err.Error(), "shipping") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "payment") {
+			respondPaymentError(c, err, "Failed to create order")
+			return
+		}
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create order",
@@ -144,8 +157,9 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
-	// Update order status
-	order, err := h.orderService.UpdateOrderStatus(uint(id), &req)
+	// Update order status, recording the admin who changed it
+	actorID := c.GetUint("user_id")
+	order, err := h.orderService.UpdateOrderStatus(uint(id), &req, &actorID)
 	if err != nil {
 		if err.Error() == "order not found" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -153,6 +167,18 @@ func (h *OrderHandler) UpdateOrderStatus(c *gin.Context) {
 			})
 			return
 		}
+		if strings.HasPrefix(err.Error(), "invalid status transition") ||
+			strings.HasPrefix(err.Error(), "cannot cancel") ||
+			err.Error() == "order is already cancelled" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "payment") {
+			respondPaymentError(c, err, "Failed to update order status")
+			return
+		}
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update order status",
@@ -198,6 +224,16 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 		filter.EndDate = &endDate
 	}
 
+	// Sorting and cursor pagination
+	filter.SortBy = c.Query("sort")
+	filter.SortOrder = c.Query("order")
+	filter.Cursor = c.Query("cursor")
+	if includeTotalStr := c.Query("include_total"); includeTotalStr != "" {
+		if includeTotal, err := strconv.ParseBool(includeTotalStr); err == nil {
+			filter.SkipCount = !includeTotal
+		}
+	}
+
 	// User filter (admin can filter by user, regular users can only see their own orders)
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -225,6 +261,14 @@ func (h *OrderHandler) ListOrders(c *gin.Context) {
 	// Get orders
 	response, err := h.orderService.ListOrders(filter)
 	if err != nil {
+		if msg := err.Error(); msg == "invalid sort field" || msg == "invalid sort order" || msg == "invalid cursor" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid pagination parameters",
+				"details": msg,
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to retrieve orders",
 		})
@@ -288,8 +332,14 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 		return
 	}
 
+	// Only allow users to cancel their own orders (unless admin)
+	if !h.authorizeOrderAccess(c, uint(id)) {
+		return
+	}
+
 	// Cancel order
-	err = h.orderService.CancelOrder(uint(id))
+	actorID := c.GetUint("user_id")
+	err = h.orderService.CancelOrder(uint(id), &actorID, c.Query("reason"))
 	if err != nil {
 		if err.Error() == "order not found" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -303,9 +353,9 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 			})
 			return
 		}
-		if err.Error() == "cannot cancel delivered order" {
+		if strings.HasPrefix(err.Error(), "cannot cancel") {
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Cannot cancel delivered order",
+				"error": err.Error(),
 			})
 			return
 		}
@@ -321,6 +371,71 @@ func (h *OrderHandler) CancelOrder(c *gin.Context) {
 	})
 }
 
+// GetOrderHistory handles retrieving the status history of an order
+func (h *OrderHandler) GetOrderHistory(c *gin.Context) {
+	// Parse order ID from URL parameter
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid order ID",
+		})
+		return
+	}
+
+	// Only allow users to view the history of their own orders (unless admin)
+	if !h.authorizeOrderAccess(c, uint(id)) {
+		return
+	}
+
+	history, err := h.orderService.GetOrderHistory(uint(id))
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Order not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve order history",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": history,
+	})
+}
+
+// authorizeOrderAccess responds with an error unless the order exists and belongs to the
+// current user or the user is an admin
+func (h *OrderHandler) authorizeOrderAccess(c *gin.Context, id uint) bool {
+	order, err := h.orderService.GetOrder(id)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Order not found",
+			})
+			return false
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve order",
+		})
+		return false
+	}
+
+	if c.GetString("user_role") != "admin" && order.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Not authorized to access this order",
+		})
+		return false
+	}
+
+	return true
+}
+
 // GetOrderStatistics handles retrieving order statistics (admin only)
 func (h *OrderHandler) GetOrderStatistics(c *gin.Context) {
 	// Check if user is admin
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/payment"
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxWebhookBodyBytes limits the size of a payment webhook body
+const maxWebhookBodyBytes = 1 << 20
+
+// PaymentHandler handles order payment and payment webhook HTTP requests
+type PaymentHandler struct {
+	paymentService *services.PaymentService
+	webhookService *services.PaymentWebhookService
+}
+
+// NewPaymentHandler creates a new payment handler
+func NewPaymentHandler() *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: services.NewPaymentService(),
+		webhookService: services.NewPaymentWebhookService(),
+	}
+}
+
+// WebhookService returns the payment webhook service used by the handler
+func (h *PaymentHandler) WebhookService() *services.PaymentWebhookService {
+	return h.webhookService
+}
+
+// CapturePayment handles capturing an order's authorized payment (admin only)
+func (h *PaymentHandler) CapturePayment(c *gin.Context) {
+	// Parse order ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid order ID",
+		})
+		return
+	}
+
+	var req services.CapturePaymentRequest
+
+	// Bind and validate request; an empty body captures the full amount
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	p, err := h.paymentService.CapturePayment(uint(id), &req)
+	if err != nil {
+		respondPaymentError(c, err, "Failed to capture payment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment captured successfully",
+		"data":    p,
+	})
+}
+
+// VoidPayment handles voiding an order's authorized payment (admin only)
+func (h *PaymentHandler) VoidPayment(c *gin.Context) {
+	// Parse order ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid order ID",
+		})
+		return
+	}
+
+	p, err := h.paymentService.VoidPayment(uint(id))
+	if err != nil {
+		respondPaymentError(c, err, "Failed to void payment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment voided successfully",
+		"data":    p,
+	})
+}
+
+// ReceivePaymentWebhook handles a provider notification. The raw body is verified and stored,
+// then applied in the background; redelivered events are acknowledged without being applied again.
+func (h *PaymentHandler) ReceivePaymentWebhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read webhook body",
+		})
+		return
+	}
+
+	event, duplicate, err := h.webhookService.ReceiveWebhook(c.Param("provider"), c.Request.Header, body)
+	if err != nil {
+		switch {
+		case err.Error() == "unknown payment provider":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Unknown payment provider",
+			})
+		case errors.Is(err, payment.ErrInvalidSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid webhook signature",
+			})
+		case strings.HasPrefix(err.Error(), "invalid webhook payload"):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to store webhook event",
+			})
+		}
+		return
+	}
+
+	if duplicate {
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Event already received",
+			"duplicate": true,
+			"data":      event,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Event received",
+		"data":    event,
+	})
+}
+
+// ListWebhookEvents handles listing received payment webhook events (admin only)
+func (h *PaymentHandler) ListWebhookEvents(c *gin.Context) {
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	response, err := h.webhookService.ListWebhookEvents(&services.WebhookEventFilter{
+		Provider: c.Query("provider"),
+		Status:   c.Query("status"),
+		Page:     page,
+		Limit:    limit,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve webhook events",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// GetWebhookEvent handles retrieving a payment webhook event (admin only)
+func (h *PaymentHandler) GetWebhookEvent(c *gin.Context) {
+	// Parse event ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid webhook event ID",
+		})
+		return
+	}
+
+	event, err := h.webhookService.GetWebhookEvent(uint(id))
+	if err != nil {
+		if err.Error() == "webhook event not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Webhook event not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve webhook event",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": event,
+	})
+}
+
+// ReplayWebhookEvent handles applying a stored payment webhook event again (admin only)
+func (h *PaymentHandler) ReplayWebhookEvent(c *gin.Context) {
+	// Parse event ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid webhook event ID",
+		})
+		return
+	}
+
+	event, err := h.webhookService.ReplayWebhookEvent(uint(id))
+	if err != nil {
+		switch err.Error() {
+		case "webhook event not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Webhook event not found",
+			})
+		case "webhook event is being processed":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Webhook event is being processed",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to replay webhook event",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook event replayed",
+		"data":    event,
+	})
+}
+
+// respondPaymentError maps payment service errors to responses
+func respondPaymentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err.Error() == "order not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Order not found",
+		})
+	case err.Error() == "no authorized payment for this order":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "No authorized payment for this order",
+		})
+	case strings.HasPrefix(err.Error(), "invalid payment"):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case strings.HasPrefix(err.Error(), "payment declined"):
+		c.JSON(http.StatusPaymentRequired, gin.H{
+			"error": err.Error(),
+		})
+	case err.Error() == "payment provider timed out":
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": "Payment provider timed out",
+		})
+	case strings.HasPrefix(err.Error(), "payment"):
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Payment provider error",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fallback,
+		})
+	}
+}
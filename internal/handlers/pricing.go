@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PricingHandler handles sale pricing and scheduled price change HTTP requests
+type PricingHandler struct {
+	pricingService *services.PricingService
+}
+
+// NewPricingHandler creates a new pricing handler
+func NewPricingHandler() *PricingHandler {
+	return &PricingHandler{
+		pricingService: services.NewPricingService(),
+	}
+}
+
+// PricingService returns the pricing service used by the handler
+func (h *PricingHandler) PricingService() *services.PricingService {
+	return h.pricingService
+}
+
+// UpdateProductPricing handles setting a product's compare-at price and sale
+func (h *PricingHandler) UpdateProductPricing(c *gin.Context) {
+	// Parse product ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req services.ProductPricingRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	product, err := h.pricingService.SetProductPricing(uint(id), &req, c.GetUint("user_id"))
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid pricing") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid product pricing",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update product pricing",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product pricing updated successfully",
+		"data":    product,
+	})
+}
+
+// ListPriceSchedules handles retrieving the scheduled price changes of a product
+func (h *PricingHandler) ListPriceSchedules(c *gin.Context) {
+	// Parse product ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	schedules, err := h.pricingService.ListPriceSchedules(uint(id))
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve price schedules",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": schedules,
+	})
+}
+
+// CreatePriceSchedule handles scheduling a future list price change
+func (h *PricingHandler) CreatePriceSchedule(c *gin.Context) {
+	// Parse product ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req services.CreatePriceScheduleRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	schedule, err := h.pricingService.CreatePriceSchedule(uint(id), &req, c.GetUint("user_id"))
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid price schedule") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid price schedule",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create price schedule",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Price change scheduled successfully",
+		"data":    schedule,
+	})
+}
+
+// DeletePriceSchedule handles cancelling a pending price change
+func (h *PricingHandler) DeletePriceSchedule(c *gin.Context) {
+	// Parse product and schedule IDs from URL parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+	scheduleID, err := strconv.ParseUint(c.Param("schedule_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid price schedule ID",
+		})
+		return
+	}
+
+	if err := h.pricingService.DeletePriceSchedule(uint(id), uint(scheduleID)); err != nil {
+		switch err.Error() {
+		case "price schedule not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Price schedule not found",
+			})
+		case "price schedule already applied":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Price schedule has already been applied",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to delete price schedule",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Price schedule deleted successfully",
+	})
+}
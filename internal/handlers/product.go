@@ -2,12 +2,18 @@ package handlers
 
 import (
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 
+	"github.com/Code-byme/e-commerce/internal/models"
 	"github.com/Code-byme/e-commerce/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
+// attributeFilterPattern matches attribute filter query keys such as attr[screen_size][gte]
+var attributeFilterPattern = regexp.MustCompile(`^attr\[([a-z0-9_]+)\](?:\[(eq|ne|gt|gte|lt|lte|in)\])?$`)
+
 // ProductHandler handles product-related HTTP requests
 type ProductHandler struct {
 	productService *services.ProductService
@@ -34,7 +40,7 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	}
 
 	// Create product
-	product, err := h.productService.CreateProduct(&req)
+	product, err := h.productService.CreateProduct(&req, c.GetUint("user_id"))
 	if err != nil {
 		if err.Error() == "category not found" {
 			c.JSON(http.StatusBadRequest, gin.H{
@@ -42,6 +48,32 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 			})
 			return
 		}
+		if err.Error() == "tax class not found" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Tax class not found",
+			})
+			return
+		}
+		if err.Error() == "product with this SKU already exists" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Product with this SKU already exists",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid attribute") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid product attributes",
+				"details": err.Error(),
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid product") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid product status",
+				"details": err.Error(),
+			})
+			return
+		}
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to create product",
@@ -55,8 +87,18 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 	})
 }
 
-// GetProduct handles retrieving a single product
+// GetProduct handles retrieving a single published product
 func (h *ProductHandler) GetProduct(c *gin.Context) {
+	h.getProduct(c, h.productService.GetProduct)
+}
+
+// AdminGetProduct handles retrieving a single product in any lifecycle state
+func (h *ProductHandler) AdminGetProduct(c *gin.Context) {
+	h.getProduct(c, h.productService.GetProductForAdmin)
+}
+
+// getProduct retrieves a single product using the given lookup
+func (h *ProductHandler) getProduct(c *gin.Context, lookup func(id uint) (*models.Product, error)) {
 	// Parse product ID from URL parameter
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -68,7 +110,7 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	}
 
 	// Get product
-	product, err := h.productService.GetProduct(uint(id))
+	product, err := lookup(uint(id))
 	if err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -112,7 +154,7 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	}
 
 	// Update product
-	product, err := h.productService.UpdateProduct(uint(id), &req)
+	product, err := h.productService.UpdateProduct(uint(id), &req, c.GetUint("user_id"))
 	if err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -126,6 +168,25 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			})
 			return
 		}
+		if err.Error() == "tax class not found" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Tax class not found",
+			})
+			return
+		}
+		if err.Error() == "product with this SKU already exists" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Product with this SKU already exists",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid attribute") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid product attributes",
+				"details": err.Error(),
+			})
+			return
+		}
 
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update product",
@@ -152,7 +213,7 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	}
 
 	// Delete product
-	err = h.productService.DeleteProduct(uint(id))
+	err = h.productService.DeleteProduct(uint(id), c.GetUint("user_id"))
 	if err != nil {
 		if err.Error() == "product not found" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -172,9 +233,233 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	})
 }
 
-// ListProducts handles product listing with filtering and pagination
+// RestoreProduct handles restoring a soft-deleted product
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	// Parse product ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	product, err := h.productService.RestoreProduct(uint(id), c.GetUint("user_id"))
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		case "product is not deleted":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Product is not deleted",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to restore product",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product restored successfully",
+		"data":    product,
+	})
+}
+
+// UpdateProductStatus handles lifecycle status and publish window changes
+func (h *ProductHandler) UpdateProductStatus(c *gin.Context) {
+	// Parse product ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	var req services.ProductStatusRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	product, err := h.productService.SetProductStatus(uint(id), &req, c.GetUint("user_id"))
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid product") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid product status",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update product status",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product status updated successfully",
+		"data":    product,
+	})
+}
+
+// GetProductHistory handles retrieving the revision history of a product
+func (h *ProductHandler) GetProductHistory(c *gin.Context) {
+	// Parse product ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	response, err := h.productService.ListProductRevisions(uint(id), page, limit)
+	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve product history",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// RollbackProduct handles restoring a product to a prior revision
+func (h *ProductHandler) RollbackProduct(c *gin.Context) {
+	// Parse product ID and revision from URL parameters
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid product ID",
+		})
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || revision <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid revision",
+		})
+		return
+	}
+
+	product, err := h.productService.RollbackProduct(uint(id), revision, c.GetUint("user_id"))
+	if err != nil {
+		switch err.Error() {
+		case "product not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		case "revision not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Revision not found",
+			})
+			return
+		case "product with this SKU already exists", "revision category no longer exists", "revision tax class no longer exists":
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Revision can no longer be restored",
+				"details": err.Error(),
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid attribute") {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Revision can no longer be restored",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to roll back product",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product rolled back successfully",
+		"data":    product,
+	})
+}
+
+// ListProducts handles listing published products with filtering and pagination
 func (h *ProductHandler) ListProducts(c *gin.Context) {
-	// Parse query parameters
+	h.listProducts(c, parseProductFilter(c))
+}
+
+// AdminListProducts handles listing products in every lifecycle state
+func (h *ProductHandler) AdminListProducts(c *gin.Context) {
+	filter := parseProductFilter(c)
+	filter.IncludeHidden = true
+	h.listProducts(c, filter)
+}
+
+// listProducts lists products matching the filter
+func (h *ProductHandler) listProducts(c *gin.Context, filter *services.ProductFilter) {
+	// Get products
+	response, err := h.productService.ListProducts(filter)
+	if err != nil {
+		if msg := err.Error(); msg == "invalid sort field" || msg == "invalid sort order" || msg == "invalid cursor" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid pagination parameters",
+				"details": msg,
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid attribute filter") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid attribute filter",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve products",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// parseProductFilter builds a product filter from query parameters
+func parseProductFilter(c *gin.Context) *services.ProductFilter {
 	filter := &services.ProductFilter{}
 
 	// Pagination
@@ -223,18 +508,38 @@ func (h *ProductHandler) ListProducts(c *gin.Context) {
 		}
 	}
 
-	// Get products
-	response, err := h.productService.ListProducts(filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to retrieve products",
+	// Attribute filters, e.g. attr[screen_size][gte]=13 or attr[color]=red
+	for key, values := range c.Request.URL.Query() {
+		matches := attributeFilterPattern.FindStringSubmatch(key)
+		if matches == nil || len(values) == 0 {
+			continue
+		}
+		filter.Attributes = append(filter.Attributes, services.AttributeFilter{
+			Code:     matches[1],
+			Operator: matches[2],
+			Value:    values[0],
 		})
-		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"data": response,
-	})
+	// Lifecycle filters; hidden and deleted products are only listed on admin routes
+	filter.Status = c.Query("status")
+	if includeDeletedStr := c.Query("include_deleted"); includeDeletedStr != "" {
+		if includeDeleted, err := strconv.ParseBool(includeDeletedStr); err == nil {
+			filter.IncludeDeleted = includeDeleted
+		}
+	}
+
+	// Sorting and cursor pagination
+	filter.SortBy = c.Query("sort")
+	filter.SortOrder = c.Query("order")
+	filter.Cursor = c.Query("cursor")
+	if includeTotalStr := c.Query("include_total"); includeTotalStr != "" {
+		if includeTotal, err := strconv.ParseBool(includeTotalStr); err == nil {
+			filter.SkipCount = !includeTotal
+		}
+	}
+
+	return filter
 }
 
 // GetProductsByCategory handles retrieving products by category
@@ -297,8 +602,15 @@ func (h *ProductHandler) UpdateStock(c *gin.Context) {
 	}
 
 	// Update stock
-	err = h.productService.UpdateStock(uint(id), req.Quantity)
+	err = h.productService.UpdateStock(uint(id), req.Quantity, c.GetUint("user_id"))
 	if err != nil {
+		if err.Error() == "product not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Product not found",
+			})
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to update product stock",
 		})
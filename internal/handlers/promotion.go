@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PromotionHandler handles automatic promotion management HTTP requests
+type PromotionHandler struct {
+	promotionService *services.PromotionService
+}
+
+// NewPromotionHandler creates a new promotion handler
+func NewPromotionHandler() *PromotionHandler {
+	return &PromotionHandler{
+		promotionService: services.NewPromotionService(),
+	}
+}
+
+// CreatePromotion handles promotion creation
+func (h *PromotionHandler) CreatePromotion(c *gin.Context) {
+	var req services.PromotionRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	promotion, err := h.promotionService.CreatePromotion(&req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid promotion") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create promotion",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Promotion created successfully",
+		"data":    promotion,
+	})
+}
+
+// ListPromotions handles promotion listing
+func (h *PromotionHandler) ListPromotions(c *gin.Context) {
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	response, err := h.promotionService.ListPromotions(page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve promotions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// GetPromotion handles retrieving a single promotion
+func (h *PromotionHandler) GetPromotion(c *gin.Context) {
+	// Parse promotion ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid promotion ID",
+		})
+		return
+	}
+
+	promotion, err := h.promotionService.GetPromotion(uint(id))
+	if err != nil {
+		if err.Error() == "promotion not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Promotion not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve promotion",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": promotion,
+	})
+}
+
+// UpdatePromotion handles replacing a promotion's settings
+func (h *PromotionHandler) UpdatePromotion(c *gin.Context) {
+	// Parse promotion ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid promotion ID",
+		})
+		return
+	}
+
+	var req services.PromotionRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	promotion, err := h.promotionService.UpdatePromotion(uint(id), &req)
+	if err != nil {
+		switch {
+		case err.Error() == "promotion not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Promotion not found",
+			})
+		case strings.HasPrefix(err.Error(), "invalid promotion"):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to update promotion",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promotion updated successfully",
+		"data":    promotion,
+	})
+}
+
+// DeletePromotion handles promotion deletion
+func (h *PromotionHandler) DeletePromotion(c *gin.Context) {
+	// Parse promotion ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid promotion ID",
+		})
+		return
+	}
+
+	if err := h.promotionService.DeletePromotion(uint(id)); err != nil {
+		if err.Error() == "promotion not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Promotion not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete promotion",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Promotion deleted successfully",
+	})
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RefundHandler handles order refund HTTP requests
+type RefundHandler struct {
+	refundService *services.RefundService
+}
+
+// NewRefundHandler creates a new refund handler
+func NewRefundHandler() *RefundHandler {
+	return &RefundHandler{
+		refundService: services.NewRefundService(),
+	}
+}
+
+// CreateRefund handles refunding an order in full, by line or by amount (admin only)
+func (h *RefundHandler) CreateRefund(c *gin.Context) {
+	// Parse order ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid order ID",
+		})
+		return
+	}
+
+	var req services.CreateRefundRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	refund, err := h.refundService.CreateRefund(uint(id), &req, c.GetUint("user_id"))
+	if err != nil {
+		switch {
+		case err.Error() == "order has no captured payment":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Order has no captured payment",
+			})
+		case strings.HasPrefix(err.Error(), "invalid refund"):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			respondPaymentError(c, err, "Failed to refund order")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Refund issued successfully",
+		"data":    refund,
+	})
+}
+
+// ListOrderRefunds handles listing an order's refunds, including failed attempts (admin only)
+func (h *RefundHandler) ListOrderRefunds(c *gin.Context) {
+	// Parse order ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid order ID",
+		})
+		return
+	}
+
+	refunds, err := h.refundService.ListOrderRefunds(uint(id))
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Order not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve refunds",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": refunds,
+	})
+}
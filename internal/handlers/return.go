@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ReturnHandler handles return (RMA) HTTP requests
+type ReturnHandler struct {
+	returnService *services.ReturnService
+}
+
+// NewReturnHandler creates a new return handler
+func NewReturnHandler() *ReturnHandler {
+	return &ReturnHandler{
+		returnService: services.NewReturnService(),
+	}
+}
+
+// CreateReturn handles a customer's return request
+func (h *ReturnHandler) CreateReturn(c *gin.Context) {
+	var req services.CreateReturnRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ret, err := h.returnService.CreateReturn(c.GetUint("user_id"), &req)
+	if err != nil {
+		respondReturnError(c, err, "Failed to create return")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Return requested successfully",
+		"data":    ret,
+	})
+}
+
+// ListReturns handles return listing; customers see their own returns and admins all of them
+func (h *ReturnHandler) ListReturns(c *gin.Context) {
+	// Parse pagination parameters
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	filter := &services.ReturnFilter{
+		Status: c.Query("status"),
+		Page:   page,
+		Limit:  limit,
+	}
+
+	if orderIDStr := c.Query("order_id"); orderIDStr != "" {
+		if orderID, err := strconv.ParseUint(orderIDStr, 10, 32); err == nil {
+			id := uint(orderID)
+			filter.OrderID = &id
+		}
+	}
+
+	// Admin can filter by user, regular users can only see their own returns
+	if c.GetString("user_role") == "admin" {
+		if userIDStr := c.Query("user_id"); userIDStr != "" {
+			if userID, err := strconv.ParseUint(userIDStr, 10, 32); err == nil {
+				id := uint(userID)
+				filter.UserID = &id
+			}
+		}
+	} else {
+		userID := c.GetUint("user_id")
+		filter.UserID = &userID
+	}
+
+	response, err := h.returnService.ListReturns(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve returns",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// GetReturn handles retrieving a return; customers can only see their own
+func (h *ReturnHandler) GetReturn(c *gin.Context) {
+	ret, ok := h.loadAuthorizedReturn(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": ret,
+	})
+}
+
+// CancelReturn handles withdrawing a return before it is shipped back
+func (h *ReturnHandler) CancelReturn(c *gin.Context) {
+	ret, ok := h.loadAuthorizedReturn(c)
+	if !ok {
+		return
+	}
+
+	ret, err := h.returnService.CancelReturn(ret.ID)
+	if err != nil {
+		respondReturnError(c, err, "Failed to cancel return")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return cancelled successfully",
+		"data":    ret,
+	})
+}
+
+// ApproveReturn handles approving a return and attaching its shipping label (admin only)
+func (h *ReturnHandler) ApproveReturn(c *gin.Context) {
+	id, ok := parseReturnID(c)
+	if !ok {
+		return
+	}
+
+	var req services.ApproveReturnRequest
+
+	// Bind and validate request; the body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	ret, err := h.returnService.ApproveReturn(id, &req)
+	if err != nil {
+		respondReturnError(c, err, "Failed to approve return")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return approved successfully",
+		"data":    ret,
+	})
+}
+
+// RejectReturn handles rejecting a return (admin only)
+func (h *ReturnHandler) RejectReturn(c *gin.Context) {
+	id, ok := parseReturnID(c)
+	if !ok {
+		return
+	}
+
+	var req services.RejectReturnRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ret, err := h.returnService.RejectReturn(id, &req)
+	if err != nil {
+		respondReturnError(c, err, "Failed to reject return")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return rejected successfully",
+		"data":    ret,
+	})
+}
+
+// ReceiveReturn handles recording the receipt and inspection of a return (admin only)
+func (h *ReturnHandler) ReceiveReturn(c *gin.Context) {
+	id, ok := parseReturnID(c)
+	if !ok {
+		return
+	}
+
+	var req services.ReceiveReturnRequest
+
+	// Bind and validate request; the body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request data",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	ret, err := h.returnService.ReceiveReturn(id, &req)
+	if err != nil {
+		respondReturnError(c, err, "Failed to receive return")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return received successfully",
+		"data":    ret,
+	})
+}
+
+// CompleteReturn handles refunding and completing a received return (admin only)
+func (h *ReturnHandler) CompleteReturn(c *gin.Context) {
+	id, ok := parseReturnID(c)
+	if !ok {
+		return
+	}
+
+	ret, err := h.returnService.CompleteReturn(id, c.GetUint("user_id"))
+	if err != nil {
+		respondReturnError(c, err, "Failed to complete return")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Return completed and refunded successfully",
+		"data":    ret,
+	})
+}
+
+// loadAuthorizedReturn loads the return in the URL, responding with an error unless the
+// current user owns it or is an admin
+func (h *ReturnHandler) loadAuthorizedReturn(c *gin.Context) (*models.Return, bool) {
+	id, ok := parseReturnID(c)
+	if !ok {
+		return nil, false
+	}
+
+	ret, err := h.returnService.GetReturn(id)
+	if err != nil {
+		respondReturnError(c, err, "Failed to retrieve return")
+		return nil, false
+	}
+
+	if c.GetString("user_role") != "admin" && ret.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Not authorized to access this return",
+		})
+		return nil, false
+	}
+
+	return ret, true
+}
+
+// parseReturnID parses the return ID URL parameter, responding with an error when it is invalid
+func parseReturnID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid return ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// respondReturnError maps return service errors to responses
+func respondReturnError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err.Error() == "return not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Return not found",
+		})
+	case err.Error() == "order not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Order not found",
+		})
+	case strings.HasPrefix(err.Error(), "invalid return"):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case strings.HasPrefix(err.Error(), "return cannot be"),
+		strings.HasPrefix(err.Error(), "invalid refund"),
+		err.Error() == "order has no captured payment":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		respondPaymentError(c, err, fallback)
+	}
+}
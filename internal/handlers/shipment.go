@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ShipmentHandler handles shipment HTTP requests
+type ShipmentHandler struct {
+	shipmentService *services.ShipmentService
+	orderService    *services.OrderService
+}
+
+// NewShipmentHandler creates a new shipment handler
+func NewShipmentHandler() *ShipmentHandler {
+	return &ShipmentHandler{
+		shipmentService: services.NewShipmentService(),
+		orderService:    services.NewOrderService(),
+	}
+}
+
+// ListOrderShipments handles listing an order's shipments; customers can only see their own
+func (h *ShipmentHandler) ListOrderShipments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid order ID",
+		})
+		return
+	}
+
+	order, err := h.orderService.GetOrder(uint(id))
+	if err != nil {
+		respondShipmentError(c, err, "Failed to retrieve shipments")
+		return
+	}
+	if c.GetString("user_role") != "admin" && order.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Not authorized to access this order",
+		})
+		return
+	}
+
+	shipments, err := h.shipmentService.ListOrderShipments(uint(id))
+	if err != nil {
+		respondShipmentError(c, err, "Failed to retrieve shipments")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": shipments,
+	})
+}
+
+// CreateShipment handles shipping order items (admin only)
+func (h *ShipmentHandler) CreateShipment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid order ID",
+		})
+		return
+	}
+
+	var req services.CreateShipmentRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	shipment, err := h.shipmentService.CreateShipment(uint(id), &req, c.GetUint("user_id"))
+	if err != nil {
+		respondShipmentError(c, err, "Failed to create shipment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Shipment created successfully",
+		"data":    shipment,
+	})
+}
+
+// UpdateShipment handles correcting a shipment's tracking details (admin only)
+func (h *ShipmentHandler) UpdateShipment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipment ID",
+		})
+		return
+	}
+
+	var req services.UpdateShipmentRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	shipment, err := h.shipmentService.UpdateShipment(uint(id), &req)
+	if err != nil {
+		respondShipmentError(c, err, "Failed to update shipment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipment updated successfully",
+		"data":    shipment,
+	})
+}
+
+// MarkShipmentDelivered handles recording a shipment's delivery (admin only)
+func (h *ShipmentHandler) MarkShipmentDelivered(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipment ID",
+		})
+		return
+	}
+
+	shipment, err := h.shipmentService.MarkShipmentDelivered(uint(id), c.GetUint("user_id"))
+	if err != nil {
+		respondShipmentError(c, err, "Failed to mark shipment as delivered")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipment marked as delivered",
+		"data":    shipment,
+	})
+}
+
+// respondShipmentError maps shipment service errors to responses
+func respondShipmentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err.Error() == "order not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Order not found",
+		})
+	case err.Error() == "shipment not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Shipment not found",
+		})
+	case strings.HasPrefix(err.Error(), "invalid shipment"):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	case strings.HasPrefix(err.Error(), "order cannot be shipped"),
+		err.Error() == "shipment is already delivered":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		respondPaymentError(c, err, fallback)
+	}
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ShippingHandler handles shipping zone and method HTTP requests
+type ShippingHandler struct {
+	shippingService *services.ShippingService
+}
+
+// NewShippingHandler creates a new shipping handler
+func NewShippingHandler() *ShippingHandler {
+	return &ShippingHandler{
+		shippingService: services.NewShippingService(),
+	}
+}
+
+// ListShippingZones handles shipping zone listing
+func (h *ShippingHandler) ListShippingZones(c *gin.Context) {
+	zones, err := h.shippingService.ListShippingZones()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve shipping zones",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": zones,
+	})
+}
+
+// GetShippingZone handles retrieving a shipping zone
+func (h *ShippingHandler) GetShippingZone(c *gin.Context) {
+	// Parse zone ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipping zone ID",
+		})
+		return
+	}
+
+	zone, err := h.shippingService.GetShippingZone(uint(id))
+	if err != nil {
+		if err.Error() == "shipping zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Shipping zone not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve shipping zone",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": zone,
+	})
+}
+
+// CreateShippingZone handles shipping zone creation
+func (h *ShippingHandler) CreateShippingZone(c *gin.Context) {
+	var req services.ShippingZoneRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	zone, err := h.shippingService.CreateShippingZone(&req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid shipping zone") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create shipping zone",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Shipping zone created successfully",
+		"data":    zone,
+	})
+}
+
+// UpdateShippingZone handles renaming a shipping zone and replacing its locations
+func (h *ShippingHandler) UpdateShippingZone(c *gin.Context) {
+	// Parse zone ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipping zone ID",
+		})
+		return
+	}
+
+	var req services.ShippingZoneRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	zone, err := h.shippingService.UpdateShippingZone(uint(id), &req)
+	if err != nil {
+		if err.Error() == "shipping zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Shipping zone not found",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid shipping zone") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update shipping zone",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipping zone updated successfully",
+		"data":    zone,
+	})
+}
+
+// DeleteShippingZone handles shipping zone deletion
+func (h *ShippingHandler) DeleteShippingZone(c *gin.Context) {
+	// Parse zone ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipping zone ID",
+		})
+		return
+	}
+
+	if err := h.shippingService.DeleteShippingZone(uint(id)); err != nil {
+		if err.Error() == "shipping zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Shipping zone not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete shipping zone",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipping zone deleted successfully",
+	})
+}
+
+// CreateShippingMethod handles adding a shipping method to a zone
+func (h *ShippingHandler) CreateShippingMethod(c *gin.Context) {
+	// Parse zone ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipping zone ID",
+		})
+		return
+	}
+
+	var req services.ShippingMethodRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	method, err := h.shippingService.CreateShippingMethod(uint(id), &req)
+	if err != nil {
+		if err.Error() == "shipping zone not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Shipping zone not found",
+			})
+			return
+		}
+		h.respondShippingMethodError(c, err, "Failed to create shipping method")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Shipping method created successfully",
+		"data":    method,
+	})
+}
+
+// UpdateShippingMethod handles replacing a shipping method's settings
+func (h *ShippingHandler) UpdateShippingMethod(c *gin.Context) {
+	// Parse method ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipping method ID",
+		})
+		return
+	}
+
+	var req services.ShippingMethodRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	method, err := h.shippingService.UpdateShippingMethod(uint(id), &req)
+	if err != nil {
+		h.respondShippingMethodError(c, err, "Failed to update shipping method")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipping method updated successfully",
+		"data":    method,
+	})
+}
+
+// DeleteShippingMethod handles shipping method deletion
+func (h *ShippingHandler) DeleteShippingMethod(c *gin.Context) {
+	// Parse method ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid shipping method ID",
+		})
+		return
+	}
+
+	if err := h.shippingService.DeleteShippingMethod(uint(id)); err != nil {
+		if err.Error() == "shipping method not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Shipping method not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete shipping method",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Shipping method deleted successfully",
+	})
+}
+
+// respondShippingMethodError maps shipping method service errors to responses
+func (h *ShippingHandler) respondShippingMethodError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err.Error() == "shipping method not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Shipping method not found",
+		})
+	case err.Error() == "shipping method code already exists":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Shipping method code already exists",
+		})
+	case strings.HasPrefix(err.Error(), "invalid shipping method"):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// TaxHandler handles tax class and rate table HTTP requests
+type TaxHandler struct {
+	taxService *services.TaxService
+}
+
+// NewTaxHandler creates a new tax handler
+func NewTaxHandler() *TaxHandler {
+	return &TaxHandler{
+		taxService: services.NewTaxService(),
+	}
+}
+
+// ListTaxClasses handles tax class listing
+func (h *TaxHandler) ListTaxClasses(c *gin.Context) {
+	classes, err := h.taxService.ListTaxClasses()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve tax classes",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": classes,
+	})
+}
+
+// CreateTaxClass handles tax class creation
+func (h *TaxHandler) CreateTaxClass(c *gin.Context) {
+	var req services.TaxClassRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	class, err := h.taxService.CreateTaxClass(&req)
+	if err != nil {
+		if err.Error() == "tax class already exists" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Tax class already exists",
+			})
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid tax class") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create tax class",
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tax class created successfully",
+		"data":    class,
+	})
+}
+
+// DeleteTaxClass handles tax class deletion
+func (h *TaxHandler) DeleteTaxClass(c *gin.Context) {
+	if err := h.taxService.DeleteTaxClass(c.Param("code")); err != nil {
+		switch err.Error() {
+		case "tax class not found":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Tax class not found",
+			})
+		case "tax class is in use":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Tax class is in use",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to delete tax class",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tax class deleted successfully",
+	})
+}
+
+// ListTaxRates handles tax rate listing
+func (h *TaxHandler) ListTaxRates(c *gin.Context) {
+	filter := &services.TaxRateFilter{
+		TaxClass: c.Query("tax_class"),
+		Country:  c.Query("country"),
+	}
+
+	rates, err := h.taxService.ListTaxRates(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve tax rates",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": rates,
+	})
+}
+
+// CreateTaxRate handles tax rate creation
+func (h *TaxHandler) CreateTaxRate(c *gin.Context) {
+	var req services.TaxRateRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rate, err := h.taxService.CreateTaxRate(&req)
+	if err != nil {
+		h.respondTaxRateError(c, err, "Failed to create tax rate")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tax rate created successfully",
+		"data":    rate,
+	})
+}
+
+// UpdateTaxRate handles replacing a tax rate
+func (h *TaxHandler) UpdateTaxRate(c *gin.Context) {
+	// Parse tax rate ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tax rate ID",
+		})
+		return
+	}
+
+	var req services.TaxRateRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rate, err := h.taxService.UpdateTaxRate(uint(id), &req)
+	if err != nil {
+		h.respondTaxRateError(c, err, "Failed to update tax rate")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tax rate updated successfully",
+		"data":    rate,
+	})
+}
+
+// DeleteTaxRate handles tax rate deletion
+func (h *TaxHandler) DeleteTaxRate(c *gin.Context) {
+	// Parse tax rate ID from URL parameter
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid tax rate ID",
+		})
+		return
+	}
+
+	if err := h.taxService.DeleteTaxRate(uint(id)); err != nil {
+		if err.Error() == "tax rate not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Tax rate not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to delete tax rate",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tax rate deleted successfully",
+	})
+}
+
+// respondTaxRateError maps tax rate service errors to responses
+func (h *TaxHandler) respondTaxRateError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err.Error() == "tax rate not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Tax rate not found",
+		})
+	case err.Error() == "tax class not found":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Tax class not found",
+		})
+	case err.Error() == "tax rate already exists":
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Tax rate already exists for this class and jurisdiction",
+		})
+	case strings.HasPrefix(err.Error(), "invalid tax rate"):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// WishlistHandler handles wishlist and saved for later HTTP requests
+type WishlistHandler struct {
+	wishlistService *services.WishlistService
+}
+
+// NewWishlistHandler creates a new wishlist handler
+func NewWishlistHandler() *WishlistHandler {
+	return &WishlistHandler{
+		wishlistService: services.NewWishlistService(),
+	}
+}
+
+// WishlistService returns the wishlist service used by the handler
+func (h *WishlistHandler) WishlistService() *services.WishlistService {
+	return h.wishlistService
+}
+
+// ListWishlists handles listing the user's wishlists
+func (h *WishlistHandler) ListWishlists(c *gin.Context) {
+	wishlists, err := h.wishlistService.ListWishlists(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve wishlists",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": wishlists,
+	})
+}
+
+// CreateWishlist handles creating a named wishlist
+func (h *WishlistHandler) CreateWishlist(c *gin.Context) {
+	var req services.CreateWishlistRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	wishlist, err := h.wishlistService.CreateWishlist(c.GetUint("user_id"), &req)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to create wishlist")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Wishlist created successfully",
+		"data":    wishlist,
+	})
+}
+
+// GetWishlist handles retrieving one of the user's wishlists with its items
+func (h *WishlistHandler) GetWishlist(c *gin.Context) {
+	id, ok := parseWishlistID(c)
+	if !ok {
+		return
+	}
+
+	wishlist, err := h.wishlistService.GetWishlist(c.GetUint("user_id"), id)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to retrieve wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": wishlist,
+	})
+}
+
+// GetSharedWishlist handles viewing a public wishlist through its share link
+func (h *WishlistHandler) GetSharedWishlist(c *gin.Context) {
+	wishlist, err := h.wishlistService.GetSharedWishlist(c.Param("token"))
+	if err != nil {
+		respondWishlistError(c, err, "Failed to retrieve wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": wishlist,
+	})
+}
+
+// UpdateWishlist handles renaming a wishlist or changing its visibility
+func (h *WishlistHandler) UpdateWishlist(c *gin.Context) {
+	id, ok := parseWishlistID(c)
+	if !ok {
+		return
+	}
+
+	var req services.UpdateWishlistRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	wishlist, err := h.wishlistService.UpdateWishlist(c.GetUint("user_id"), id, &req)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to update wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wishlist updated successfully",
+		"data":    wishlist,
+	})
+}
+
+// DeleteWishlist handles deleting a wishlist with its items
+func (h *WishlistHandler) DeleteWishlist(c *gin.Context) {
+	id, ok := parseWishlistID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.wishlistService.DeleteWishlist(c.GetUint("user_id"), id); err != nil {
+		respondWishlistError(c, err, "Failed to delete wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wishlist deleted successfully",
+	})
+}
+
+// AddWishlistItem handles adding a product to a wishlist
+func (h *WishlistHandler) AddWishlistItem(c *gin.Context) {
+	id, ok := parseWishlistID(c)
+	if !ok {
+		return
+	}
+
+	var req services.AddWishlistItemRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	wishlist, err := h.wishlistService.AddItem(c.GetUint("user_id"), id, &req)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to add item to wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item added to wishlist successfully",
+		"data":    wishlist,
+	})
+}
+
+// UpdateWishlistItem handles changing a wishlist item's quantity or notification subscriptions
+func (h *WishlistHandler) UpdateWishlistItem(c *gin.Context) {
+	id, itemID, ok := parseWishlistItemID(c)
+	if !ok {
+		return
+	}
+
+	var req services.UpdateWishlistItemRequest
+
+	// Bind and validate request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	wishlist, err := h.wishlistService.UpdateItem(c.GetUint("user_id"), id, itemID, &req)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to update wishlist item")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wishlist item updated successfully",
+		"data":    wishlist,
+	})
+}
+
+// RemoveWishlistItem handles removing an item from a wishlist
+func (h *WishlistHandler) RemoveWishlistItem(c *gin.Context) {
+	id, itemID, ok := parseWishlistItemID(c)
+	if !ok {
+		return
+	}
+
+	wishlist, err := h.wishlistService.RemoveItem(c.GetUint("user_id"), id, itemID)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to remove item from wishlist")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item removed from wishlist successfully",
+		"data":    wishlist,
+	})
+}
+
+// MoveWishlistItemToCart handles moving a wishlist item into the cart
+func (h *WishlistHandler) MoveWishlistItemToCart(c *gin.Context) {
+	id, itemID, ok := parseWishlistItemID(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.wishlistService.MoveItemToCart(c.GetUint("user_id"), id, itemID)
+	if err != nil {
+		respondWishlistError(c, err, "Failed to move item to cart")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item moved to cart successfully",
+		"data":    cart,
+	})
+}
+
+// SaveForLater handles moving a cart item into the user's saved for later list
+func (h *WishlistHandler) SaveForLater(c *gin.Context) {
+	// Parse item ID from URL parameter
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid item ID",
+		})
+		return
+	}
+
+	cart, err := h.wishlistService.SaveForLater(c.GetUint("user_id"), uint(itemID))
+	if err != nil {
+		if err.Error() == "cart item not found or not owned by user" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Cart item not found",
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save item for later",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item saved for later successfully",
+		"data":    cart,
+	})
+}
+
+// parseWishlistID parses the wishlist ID URL parameter, responding with an error when it is invalid
+func parseWishlistID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid wishlist ID",
+		})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// parseWishlistItemID parses the wishlist and item ID URL parameters, responding with an error
+// when either is invalid
+func parseWishlistItemID(c *gin.Context) (uint, uint, bool) {
+	id, ok := parseWishlistID(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("item_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid item ID",
+		})
+		return 0, 0, false
+	}
+	return id, uint(itemID), true
+}
+
+// respondWishlistError maps wishlist service errors to responses
+func respondWishlistError(c *gin.Context, err error, fallback string) {
+	switch {
+	case err.Error() == "wishlist not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Wishlist not found",
+		})
+	case err.Error() == "wishlist item not found":
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Wishlist item not found",
+		})
+	case err.Error() == "product not found or inactive":
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Product not found or inactive",
+		})
+	case strings.HasPrefix(err.Error(), "insufficient stock"):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Insufficient stock for the requested product",
+		})
+	case strings.HasPrefix(err.Error(), "invalid wishlist"):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fallback,
+		})
+	}
+}
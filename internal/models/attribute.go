@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// Attribute types supported by attribute definitions
+const (
+	AttributeTypeText    = "text"
+	AttributeTypeNumber  = "number"
+	AttributeTypeBoolean = "boolean"
+	AttributeTypeEnum    = "enum"
+)
+
+// AttributeDefinition describes a structured product specification
+type AttributeDefinition struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Code       string    `json:"code" gorm:"unique;not null"`
+	Name       string    `json:"name" gorm:"not null"`
+	Type       string    `json:"type" gorm:"not null"`
+	Unit       string    `json:"unit,omitempty"`
+	EnumValues []string  `json:"enum_values,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// CategoryAttribute assigns an attribute definition to a category
+type CategoryAttribute struct {
+	CategoryID  uint                `json:"category_id"`
+	AttributeID uint                `json:"attribute_id"`
+	Attribute   AttributeDefinition `json:"attribute" gorm:"foreignKey:AttributeID"`
+	IsRequired  bool                `json:"is_required"`
+	Position    int                 `json:"position"`
+}
+
+// ProductAttributeValue represents a typed attribute value stored for a product
+type ProductAttributeValue struct {
+	AttributeID uint        `json:"attribute_id"`
+	Code        string      `json:"code"`
+	Name        string      `json:"name"`
+	Type        string      `json:"type"`
+	Unit        string      `json:"unit,omitempty"`
+	Value       interface{} `json:"value"`
+}
@@ -10,8 +10,12 @@ type Cart struct {
 	UserID    uint       `json:"user_id"`
 	User      User       `json:"user" gorm:"foreignKey:UserID"`
 	CartItems []CartItem `json:"cart_items" gorm:"foreignKey:CartID"`
+	CouponID  *uint      `json:"coupon_id,omitempty"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
+
+	// GuestToken is the signed token of a guest cart, which has no user
+	GuestToken string `json:"-" gorm:"-"`
 }
 
 // CartItem represents an item in the shopping cart
@@ -23,6 +27,52 @@ type CartItem struct {
 	Quantity  int       `json:"quantity"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// DiscountAmount is the share of automatic promotions allocated to this line
+	DiscountAmount float64 `json:"discount_amount"`
+	// TaxAmount is estimated only when the cart is priced for an address
+	TaxAmount float64 `json:"tax_amount"`
+
+	// AddedPrice is the price the product had when it was added to the cart
+	AddedPrice *float64 `json:"added_price,omitempty"`
+	// Warnings report problems checkout would run into, or a price change since the item was added
+	Warnings []CartItemWarning `json:"warnings,omitempty" gorm:"-"`
+}
+
+// Cart item warning codes
+const (
+	CartWarningUnavailable       = "unavailable"
+	CartWarningInsufficientStock = "insufficient_stock"
+	CartWarningPriceChanged      = "price_changed"
+)
+
+// CartItemWarning describes a problem with a cart item. AvailableQuantity is set for insufficient
+// stock; PreviousPrice and CurrentPrice for a price change.
+type CartItemWarning struct {
+	Code              string  `json:"code"`
+	Message           string  `json:"message"`
+	AvailableQuantity *int    `json:"available_quantity,omitempty"`
+	PreviousPrice     float64 `json:"previous_price,omitempty"`
+	CurrentPrice      float64 `json:"current_price,omitempty"`
+}
+
+// Cart revalidation actions
+const (
+	CartAdjustmentRemoved         = "removed"
+	CartAdjustmentQuantityReduced = "quantity_reduced"
+	CartAdjustmentPriceUpdated    = "price_updated"
+)
+
+// CartAdjustment describes a change made to a cart item when the cart was revalidated
+type CartAdjustment struct {
+	CartItemID       uint    `json:"cart_item_id"`
+	ProductID        uint    `json:"product_id"`
+	ProductName      string  `json:"product_name"`
+	Action           string  `json:"action"`
+	PreviousQuantity int     `json:"previous_quantity"`
+	Quantity         int     `json:"quantity"`
+	PreviousPrice    float64 `json:"previous_price,omitempty"`
+	CurrentPrice     float64 `json:"current_price,omitempty"`
 }
 
 // CartResponse represents the cart response with calculated totals
@@ -34,4 +84,61 @@ type CartResponse struct {
 	TotalAmount float64    `json:"total_amount"`
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// HasWarnings is set when any item has warnings; POST /api/cart/revalidate fixes what it can
+	HasWarnings bool `json:"has_warnings"`
+
+	// CartToken identifies a guest cart; guests send it back in the X-Cart-Token header
+	CartToken string `json:"cart_token,omitempty"`
+
+	// Breakdown, priced like the order checkout would create: TotalAmount is the subtotal less
+	// DiscountAmount plus ShippingAmount, plus TaxAmount unless prices include tax. Shipping and
+	// tax are only estimated for the address given with the request. CouponError explains why
+	// an applied coupon currently gives no discount.
+	SubtotalAmount   float64         `json:"subtotal_amount"`
+	DiscountAmount   float64         `json:"discount_amount"`
+	ShippingAmount   float64         `json:"shipping_amount"`
+	ShippingMethod   string          `json:"shipping_method,omitempty"`
+	TaxAmount        float64         `json:"tax_amount"`
+	PricesIncludeTax bool            `json:"prices_include_tax"`
+	CouponCode       string          `json:"coupon_code,omitempty"`
+	CouponError      string          `json:"coupon_error,omitempty"`
+	FreeShipping     bool            `json:"free_shipping"`
+	Discounts        []OrderDiscount `json:"discounts"`
+}
+
+// CartPriceChange describes a cart item whose price changed since the customer last viewed the cart
+type CartPriceChange struct {
+	CartItemID    uint    `json:"cart_item_id"`
+	ProductID     uint    `json:"product_id"`
+	ProductName   string  `json:"product_name"`
+	PreviousPrice float64 `json:"previous_price"`
+	CurrentPrice  float64 `json:"current_price"`
+}
+
+// AbandonedCart is a cart with items that has seen no activity and no order for the abandonment period
+type AbandonedCart struct {
+	CartID         uint       `json:"cart_id"`
+	UserID         *uint      `json:"user_id,omitempty"`
+	Email          string     `json:"email,omitempty"`
+	TotalItems     int        `json:"total_items"`
+	TotalValue     float64    `json:"total_value"`
+	LastActivityAt time.Time  `json:"last_activity_at"`
+	RecoverySentAt *time.Time `json:"recovery_sent_at,omitempty"`
+}
+
+// AbandonedCartReport summarizes the value left in abandoned carts and how much recovery
+// notifications brought back. Recovery figures cover the last RecoveryDays days; Carts lists
+// the most valuable abandoned carts first.
+type AbandonedCartReport struct {
+	AbandonedAfterHours float64         `json:"abandoned_after_hours"`
+	AbandonedCarts      int             `json:"abandoned_carts"`
+	GuestCarts          int             `json:"guest_carts"`
+	TotalItems          int             `json:"total_items"`
+	TotalValue          float64         `json:"total_value"`
+	RecoveryDays        int             `json:"recovery_days"`
+	RecoveriesSent      int             `json:"recoveries_sent"`
+	CartsRestored       int             `json:"carts_restored"`
+	RestoredValue       float64         `json:"restored_value"`
+	Carts               []AbandonedCart `json:"carts"`
 }
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// Coupon types
+const (
+	CouponTypePercentage   = "percentage"
+	CouponTypeFixedAmount  = "fixed_amount"
+	CouponTypeFreeShipping = "free_shipping"
+)
+
+// Discount sources
+const (
+	DiscountSourceCoupon    = "coupon"
+	DiscountSourcePromotion = "promotion"
+)
+
+// Coupon represents a discount code customers can apply to their cart
+type Coupon struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	Code              string     `json:"code" gorm:"unique;not null"`
+	Description       string     `json:"description"`
+	Type              string     `json:"type" gorm:"not null"`
+	Value             float64    `json:"value"`
+	MinSubtotal       float64    `json:"min_subtotal"`
+	StartsAt          *time.Time `json:"starts_at,omitempty"`
+	EndsAt            *time.Time `json:"ends_at,omitempty"`
+	UsageLimit        *int       `json:"usage_limit,omitempty"`
+	UsageLimitPerUser *int       `json:"usage_limit_per_user,omitempty"`
+	UsageCount        int        `json:"usage_count"`
+	IsActive          bool       `json:"is_active" gorm:"default:true"`
+
+	// Restrictions: when set, only matching products count toward the minimum spend and the discount
+	ProductIDs  []int64 `json:"product_ids"`
+	CategoryIDs []int64 `json:"category_ids"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrderDiscount is a discount line applied to a cart or persisted on an order
+type OrderDiscount struct {
+	ID          uint      `json:"id,omitempty" gorm:"primaryKey"`
+	OrderID     uint      `json:"order_id,omitempty"`
+	Source      string    `json:"source"`
+	CouponID    *uint     `json:"coupon_id,omitempty"`
+	PromotionID *uint     `json:"promotion_id,omitempty"`
+	Code        string    `json:"code,omitempty"`
+	Description string    `json:"description"`
+	Type        string    `json:"type"`
+	Amount      float64   `json:"amount"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
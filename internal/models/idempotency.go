@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// Idempotency key statuses
+const (
+	IdempotencyKeyProcessing = "processing"
+	IdempotencyKeyCompleted  = "completed"
+)
+
+// IdempotencyKey stores the response to a request made with an Idempotency-Key header so a
+// retry with the same key replays it instead of performing the request again. Keys are unique
+// per user, or per guest cart key for guests, and expire at ExpiresAt.
+type IdempotencyKey struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id,omitempty"`
+	GuestKey       string    `json:"-"`
+	Key            string    `json:"key" gorm:"not null"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	RequestHash    string    `json:"request_hash"`
+	Status         string    `json:"status" gorm:"not null"`
+	ResponseStatus int       `json:"response_status,omitempty"`
+	ResponseBody   []byte    `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// Import job statuses
+const (
+	ImportStatusPending    = "pending"
+	ImportStatusProcessing = "processing"
+	ImportStatusCompleted  = "completed"
+	ImportStatusFailed     = "failed"
+)
+
+// ImportJob tracks a background product import
+type ImportJob struct {
+	ID            uint              `json:"id" gorm:"primaryKey"`
+	UserID        uint              `json:"user_id"`
+	Format        string            `json:"format"`
+	MatchBy       string            `json:"match_by"`
+	DryRun        bool              `json:"dry_run"`
+	Mapping       map[string]string `json:"mapping,omitempty"`
+	Status        string            `json:"status"`
+	TotalRows     int               `json:"total_rows"`
+	ProcessedRows int               `json:"processed_rows"`
+	CreatedCount  int               `json:"created_count"`
+	UpdatedCount  int               `json:"updated_count"`
+	FailedCount   int               `json:"failed_count"`
+	Errors        []ImportRowError  `json:"errors,omitempty"`
+	ErrorMessage  string            `json:"error_message,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	StartedAt     *time.Time        `json:"started_at,omitempty"`
+	FinishedAt    *time.Time        `json:"finished_at,omitempty"`
+}
+
+// ImportRowError describes why a single import row was rejected
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
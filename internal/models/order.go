@@ -4,6 +4,18 @@ import (
 	"time"
 )
 
+// Order statuses
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusConfirmed = "confirmed"
+	OrderStatusCancelled = "cancelled"
+
+	// Fulfillment statuses are derived from the order's shipments
+	OrderStatusPartiallyShipped = "partially_shipped"
+	OrderStatusShipped          = "shipped"
+	OrderStatusDelivered        = "delivered"
+)
+
 // Order represents an order in the e-commerce system
 type Order struct {
 	ID              uint        `json:"id" gorm:"primaryKey"`
@@ -16,6 +28,38 @@ type Order struct {
 	OrderItems      []OrderItem `json:"order_items" gorm:"foreignKey:OrderID"`
 	CreatedAt       time.Time   `json:"created_at"`
 	UpdatedAt       time.Time   `json:"updated_at"`
+
+	// Totals breakdown: TotalAmount is the grand total, SubtotalAmount - DiscountAmount +
+	// ShippingAmount, plus TaxAmount unless PricesIncludeTax
+	SubtotalAmount float64         `json:"subtotal_amount"`
+	DiscountAmount float64         `json:"discount_amount"`
+	Discounts      []OrderDiscount `json:"discounts,omitempty" gorm:"foreignKey:OrderID"`
+
+	// Tax is calculated for the structured shipping address. With inclusive pricing TaxAmount is
+	// already part of the item prices; otherwise it is added to TotalAmount. TaxLines sums the
+	// item tax lines per tax and jurisdiction.
+	ShippingCountry    string         `json:"shipping_country,omitempty"`
+	ShippingRegion     string         `json:"shipping_region,omitempty"`
+	ShippingPostalCode string         `json:"shipping_postal_code,omitempty"`
+	TaxAmount          float64        `json:"tax_amount"`
+	PricesIncludeTax   bool           `json:"prices_include_tax"`
+	TaxLines           []OrderTaxLine `json:"tax_lines,omitempty" gorm:"-"`
+
+	// Shipping: the chosen method and its cost, which is included in TotalAmount
+	ShippingMethod     string  `json:"shipping_method,omitempty"`
+	ShippingMethodName string  `json:"shipping_method_name,omitempty"`
+	ShippingCarrier    string  `json:"shipping_carrier,omitempty"`
+	ShippingAmount     float64 `json:"shipping_amount"`
+
+	// Payments made for the order; it is confirmed once a payment is authorized
+	Payments []Payment `json:"payments,omitempty" gorm:"foreignKey:OrderID"`
+
+	// Refunds issued for the order; RefundedAmount sums the successful ones
+	RefundedAmount float64  `json:"refunded_amount"`
+	Refunds        []Refund `json:"refunds,omitempty" gorm:"foreignKey:OrderID"`
+
+	// Shipments sending the order's items to the customer
+	Shipments []Shipment `json:"shipments,omitempty" gorm:"foreignKey:OrderID"`
 }
 
 // OrderItem represents an item within an order
@@ -28,4 +72,28 @@ type OrderItem struct {
 	Price     float64   `json:"price"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// DiscountAmount is the share of automatic promotions allocated to this line
+	DiscountAmount float64 `json:"discount_amount"`
+
+	TaxAmount float64        `json:"tax_amount"`
+	TaxLines  []OrderTaxLine `json:"tax_lines,omitempty" gorm:"foreignKey:OrderItemID"`
+
+	// RefundedQuantity is the number of units refunded so far
+	RefundedQuantity int `json:"refunded_quantity"`
+
+	// ShippedQuantity is the number of units sent in shipments so far
+	ShippedQuantity int `json:"shipped_quantity" gorm:"-"`
+}
+
+// OrderStatusHistory records a change of an order's status. FromStatus is empty for the
+// order's creation and ActorID is nil for changes made by the system, e.g. on payment.
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	OrderID    uint      `json:"order_id"`
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	ActorID    *uint     `json:"actor_id,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// Payment statuses
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusDeclined   = "declined"
+	PaymentStatusFailed     = "failed"
+	PaymentStatusCaptured   = "captured"
+	PaymentStatusVoided     = "voided"
+	PaymentStatusRefunded   = "refunded"
+)
+
+// Payment is an attempt to pay for an order through a payment provider.
+// ProviderReference identifies the authorization at the provider.
+type Payment struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	OrderID           uint      `json:"order_id"`
+	Provider          string    `json:"provider" gorm:"not null"`
+	ProviderReference string    `json:"provider_reference,omitempty"`
+	Method            string    `json:"method"`
+	Status            string    `json:"status" gorm:"not null"`
+	Amount            float64   `json:"amount"`
+	CapturedAmount    float64   `json:"captured_amount"`
+	RefundedAmount    float64   `json:"refunded_amount"`
+	Currency          string    `json:"currency"`
+	FailureReason     string    `json:"failure_reason,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Payment webhook event statuses
+const (
+	WebhookEventPending    = "pending"
+	WebhookEventProcessing = "processing"
+	WebhookEventProcessed  = "processed"
+	WebhookEventFailed     = "failed"
+)
+
+// PaymentWebhookEvent is a raw notification received from a payment provider.
+// Events are unique per provider and EventID; failed applications are retried until
+// Attempts reaches the configured maximum.
+type PaymentWebhookEvent struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	Provider      string     `json:"provider" gorm:"not null"`
+	EventID       string     `json:"event_id" gorm:"not null"`
+	EventType     string     `json:"event_type"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status" gorm:"not null"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	ReceivedAt    time.Time  `json:"received_at"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
+}
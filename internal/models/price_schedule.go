@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// PriceSchedule is a future change to a product's list price, applied automatically once due
+type PriceSchedule struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	ProductID      uint       `json:"product_id"`
+	Price          float64    `json:"price"`
+	CompareAtPrice *float64   `json:"compare_at_price,omitempty"`
+	EffectiveAt    time.Time  `json:"effective_at"`
+	AppliedAt      *time.Time `json:"applied_at,omitempty"`
+	CreatedBy      *uint      `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
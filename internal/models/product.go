@@ -4,9 +4,18 @@ import (
 	"time"
 )
 
+// Product lifecycle statuses
+const (
+	ProductStatusDraft     = "draft"
+	ProductStatusScheduled = "scheduled"
+	ProductStatusPublished = "published"
+	ProductStatusArchived  = "archived"
+)
+
 // Product represents a product in the e-commerce system
 type Product struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
+	SKU         string    `json:"sku,omitempty" gorm:"unique"`
 	Name        string    `json:"name" gorm:"not null"`
 	Description string    `json:"description"`
 	Price       float64   `json:"price" gorm:"not null"`
@@ -17,6 +26,50 @@ type Product struct {
 	IsActive    bool      `json:"is_active" gorm:"default:true"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Lifecycle: only published products inside their publish window are visible to customers
+	Status      string     `json:"status,omitempty" gorm:"not null;default:published"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Pricing: the sale price applies while its window is open; the compare-at price is the reference
+	// list price shown struck through. EffectivePrice is what customers pay.
+	CompareAtPrice  *float64   `json:"compare_at_price,omitempty"`
+	SalePrice       *float64   `json:"sale_price,omitempty"`
+	SaleStartsAt    *time.Time `json:"sale_starts_at,omitempty"`
+	SaleEndsAt      *time.Time `json:"sale_ends_at,omitempty"`
+	EffectivePrice  float64    `json:"effective_price" gorm:"-"`
+	DiscountPercent float64    `json:"discount_percent" gorm:"-"`
+
+	// TaxClass selects the tax rates that apply to the product
+	TaxClass string `json:"tax_class" gorm:"not null;default:standard"`
+
+	// Shipping: weight in kilograms and dimensions in centimetres; zero means unknown
+	Weight float64 `json:"weight"`
+	Length float64 `json:"length"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+
+	Attributes []ProductAttributeValue `json:"attributes,omitempty" gorm:"-"`
+	Images     []ProductImage          `json:"images,omitempty" gorm:"foreignKey:ProductID"`
+}
+
+// ProductImage represents an image in a product's gallery
+type ProductImage struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	ProductID   uint              `json:"product_id"`
+	StorageKey  string            `json:"-"`
+	URL         string            `json:"url"`
+	AltText     string            `json:"alt_text"`
+	Position    int               `json:"position"`
+	ContentType string            `json:"content_type"`
+	SizeBytes   int64             `json:"size_bytes"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Variants    map[string]string `json:"variants"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
 }
 
 // Category represents a product category
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Product revision actions
+const (
+	ProductRevisionCreate   = "create"
+	ProductRevisionUpdate   = "update"
+	ProductRevisionStatus   = "status"
+	ProductRevisionStock    = "stock"
+	ProductRevisionDelete   = "delete"
+	ProductRevisionRestore  = "restore"
+	ProductRevisionImport   = "import"
+	ProductRevisionRollback = "rollback"
+	ProductRevisionPricing  = "pricing"
+	ProductRevisionSchedule = "price_schedule"
+)
+
+// ProductRevision records a single change to a product
+type ProductRevision struct {
+	ID         uint                 `json:"id" gorm:"primaryKey"`
+	ProductID  uint                 `json:"product_id"`
+	Revision   int                  `json:"revision"`
+	Action     string               `json:"action"`
+	Changes    []ProductFieldChange `json:"changes"`
+	ActorID    *uint                `json:"actor_id"`
+	RollbackOf *int                 `json:"rollback_of,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+// ProductFieldChange holds the before and after value of a changed product field.
+// Attribute values are reported as "attributes.<code>".
+type ProductFieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
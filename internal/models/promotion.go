@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+)
+
+// Promotion types
+const (
+	PromotionTypeBuyXGetY       = "buy_x_get_y"
+	PromotionTypeVolumeTier     = "volume_tier"
+	PromotionTypeBundle         = "bundle"
+	PromotionTypeSpendThreshold = "spend_threshold"
+)
+
+// Promotion is a rule-based discount applied automatically to matching carts and orders.
+// Promotions are evaluated by descending priority; a non-stackable promotion only applies
+// when no other promotion has applied and stops evaluation of the rest.
+type Promotion struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Name        string         `json:"name" gorm:"not null"`
+	Description string         `json:"description"`
+	Type        string         `json:"type" gorm:"not null"`
+	Priority    int            `json:"priority"`
+	Stackable   bool           `json:"stackable"`
+	IsActive    bool           `json:"is_active" gorm:"default:true"`
+	StartsAt    *time.Time     `json:"starts_at,omitempty"`
+	EndsAt      *time.Time     `json:"ends_at,omitempty"`
+	Rules       PromotionRules `json:"rules"`
+
+	// Restrictions: when set, only matching products take part (not used by bundles)
+	ProductIDs  []int64 `json:"product_ids"`
+	CategoryIDs []int64 `json:"category_ids"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PromotionRules holds the type-specific settings of a promotion
+type PromotionRules struct {
+	// buy_x_get_y: for every BuyQuantity units, GetQuantity more units (the cheapest) get DiscountPercent off
+	BuyQuantity     int     `json:"buy_quantity,omitempty"`
+	GetQuantity     int     `json:"get_quantity,omitempty"`
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
+
+	// volume_tier: a line's quantity selects the highest matching tier
+	Tiers []PromotionTier `json:"tiers,omitempty"`
+
+	// bundle: each complete set of BundleItems costs BundlePrice
+	BundleItems []PromotionBundleItem `json:"bundle_items,omitempty"`
+	BundlePrice float64               `json:"bundle_price,omitempty"`
+
+	// spend_threshold: the eligible subtotal selects the highest matching threshold
+	Thresholds []PromotionThreshold `json:"thresholds,omitempty"`
+}
+
+// PromotionTier is a volume discount for lines of at least MinQuantity units
+type PromotionTier struct {
+	MinQuantity int     `json:"min_quantity"`
+	Percent     float64 `json:"percent"`
+}
+
+// PromotionBundleItem is a product and quantity that is part of a bundle
+type PromotionBundleItem struct {
+	ProductID uint `json:"product_id"`
+	Quantity  int  `json:"quantity"`
+}
+
+// PromotionThreshold is a discount for an eligible subtotal of at least MinSubtotal,
+// either a fixed Amount or a Percent of the subtotal
+type PromotionThreshold struct {
+	MinSubtotal float64 `json:"min_subtotal"`
+	Amount      float64 `json:"amount,omitempty"`
+	Percent     float64 `json:"percent,omitempty"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// Refund statuses
+const (
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+// Refund is money returned to the customer for an order, issued through the payment provider.
+// Items lists the order lines refunded; a refund without items is an order-level amount,
+// e.g. a goodwill gesture or shipping.
+type Refund struct {
+	ID                uint         `json:"id" gorm:"primaryKey"`
+	OrderID           uint         `json:"order_id"`
+	PaymentID         *uint        `json:"payment_id,omitempty"`
+	Amount            float64      `json:"amount"`
+	Reason            string       `json:"reason"`
+	Status            string       `json:"status" gorm:"not null"`
+	ProviderReference string       `json:"provider_reference,omitempty"`
+	FailureReason     string       `json:"failure_reason,omitempty"`
+	CreatedBy         *uint        `json:"created_by,omitempty"`
+	Items             []RefundItem `json:"items,omitempty" gorm:"foreignKey:RefundID"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+// RefundItem is the part of a refund returning units of an order line
+type RefundItem struct {
+	ID          uint    `json:"id" gorm:"primaryKey"`
+	RefundID    uint    `json:"refund_id"`
+	OrderItemID uint    `json:"order_item_id"`
+	Quantity    int     `json:"quantity"`
+	Amount      float64 `json:"amount"`
+	Restocked   bool    `json:"restocked"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// Return statuses. A return is requested by the customer, then approved or rejected; approved
+// returns are received and inspected, and completing a received return refunds it.
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	ReturnStatusReceived  = "received"
+	ReturnStatusCompleted = "completed"
+	ReturnStatusCancelled = "cancelled"
+)
+
+// Return is a customer's request to send back items of a delivered order (an RMA).
+// LabelURL, Carrier and TrackingNumber describe the return shipping label attached on approval.
+type Return struct {
+	ID             uint         `json:"id" gorm:"primaryKey"`
+	RMANumber      string       `json:"rma_number" gorm:"-"`
+	OrderID        uint         `json:"order_id"`
+	UserID         uint         `json:"user_id"`
+	Status         string       `json:"status" gorm:"not null"`
+	Reason         string       `json:"reason"`
+	AdminNote      string       `json:"admin_note,omitempty"`
+	LabelURL       string       `json:"label_url,omitempty"`
+	Carrier        string       `json:"carrier,omitempty"`
+	TrackingNumber string       `json:"tracking_number,omitempty"`
+	RefundID       *uint        `json:"refund_id,omitempty"`
+	Items          []ReturnItem `json:"items" gorm:"foreignKey:ReturnID"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	ApprovedAt     *time.Time   `json:"approved_at,omitempty"`
+	ReceivedAt     *time.Time   `json:"received_at,omitempty"`
+	CompletedAt    *time.Time   `json:"completed_at,omitempty"`
+}
+
+// ReturnItem is an order line, or part of one, being returned.
+// Condition and Restock are recorded when the return is inspected on receipt.
+type ReturnItem struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	ReturnID    uint   `json:"return_id"`
+	OrderItemID uint   `json:"order_item_id"`
+	ProductID   uint   `json:"product_id"`
+	Quantity    int    `json:"quantity"`
+	Reason      string `json:"reason,omitempty"`
+	Condition   string `json:"condition,omitempty"`
+	Restock     bool   `json:"restock"`
+}
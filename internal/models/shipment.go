@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// Shipment statuses
+const (
+	ShipmentStatusShipped   = "shipped"
+	ShipmentStatusDelivered = "delivered"
+)
+
+// Shipment is a package sent to the customer with some or all of an order's items.
+// An order may be split across several shipments.
+type Shipment struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	OrderID        uint           `json:"order_id"`
+	Carrier        string         `json:"carrier" gorm:"not null"`
+	TrackingNumber string         `json:"tracking_number,omitempty"`
+	TrackingURL    string         `json:"tracking_url,omitempty"`
+	Status         string         `json:"status" gorm:"not null"`
+	Items          []ShipmentItem `json:"items" gorm:"foreignKey:ShipmentID"`
+	ShippedAt      time.Time      `json:"shipped_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// ShipmentItem is a quantity of an order line sent in a shipment
+type ShipmentItem struct {
+	ID          uint `json:"id" gorm:"primaryKey"`
+	ShipmentID  uint `json:"shipment_id"`
+	OrderItemID uint `json:"order_item_id"`
+	ProductID   uint `json:"product_id"`
+	Quantity    int  `json:"quantity"`
+}
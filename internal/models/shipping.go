@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// ShippingZone groups destinations that share shipping methods
+type ShippingZone struct {
+	ID        uint                   `json:"id" gorm:"primaryKey"`
+	Name      string                 `json:"name" gorm:"not null"`
+	Locations []ShippingZoneLocation `json:"locations" gorm:"foreignKey:ZoneID"`
+	Methods   []ShippingMethod       `json:"methods" gorm:"foreignKey:ZoneID"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+// ShippingZoneLocation is a destination covered by a zone; an empty region or postal prefix covers the whole country
+type ShippingZoneLocation struct {
+	ID               uint   `json:"id" gorm:"primaryKey"`
+	ZoneID           uint   `json:"zone_id"`
+	Country          string `json:"country" gorm:"not null"`
+	Region           string `json:"region,omitempty"`
+	PostalCodePrefix string `json:"postal_code_prefix,omitempty"`
+}
+
+// ShippingMethod is a way of shipping to a zone and how it is priced.
+// Weight-based methods add PerKgRate for every started kilogram of billable weight to BaseRate;
+// orders reaching FreeThreshold ship free.
+type ShippingMethod struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	ZoneID           uint      `json:"zone_id"`
+	Code             string    `json:"code" gorm:"unique;not null"`
+	Name             string    `json:"name" gorm:"not null"`
+	Carrier          string    `json:"carrier,omitempty"`
+	Type             string    `json:"type" gorm:"not null"`
+	BaseRate         float64   `json:"base_rate"`
+	PerKgRate        float64   `json:"per_kg_rate"`
+	FreeThreshold    *float64  `json:"free_threshold,omitempty"`
+	MinWeight        *float64  `json:"min_weight,omitempty"`
+	MaxWeight        *float64  `json:"max_weight,omitempty"`
+	EstimatedDaysMin *int      `json:"estimated_days_min,omitempty"`
+	EstimatedDaysMax *int      `json:"estimated_days_max,omitempty"`
+	Position         int       `json:"position"`
+	IsActive         bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// TaxClass groups products that are taxed at the same rates
+type TaxClass struct {
+	Code        string    `json:"code" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TaxRate is the rate of a named tax for a tax class in a jurisdiction.
+// Region and PostalCodePrefix narrow the jurisdiction; empty values match any address in the country.
+type TaxRate struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	TaxClass         string    `json:"tax_class" gorm:"not null"`
+	Country          string    `json:"country" gorm:"not null"`
+	Region           string    `json:"region,omitempty"`
+	PostalCodePrefix string    `json:"postal_code_prefix,omitempty"`
+	Name             string    `json:"name" gorm:"not null"`
+	Rate             float64   `json:"rate" gorm:"not null"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// OrderTaxLine records a tax charged on an order. Lines charged on an item carry its OrderItemID.
+type OrderTaxLine struct {
+	ID            uint      `json:"id,omitempty" gorm:"primaryKey"`
+	OrderID       uint      `json:"order_id,omitempty"`
+	OrderItemID   *uint     `json:"order_item_id,omitempty"`
+	Name          string    `json:"name"`
+	Jurisdiction  string    `json:"jurisdiction"`
+	Rate          float64   `json:"rate"`
+	TaxableAmount float64   `json:"taxable_amount"`
+	Amount        float64   `json:"amount"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+}
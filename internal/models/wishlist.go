@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Wishlist kinds. Each user has at most one saved for later list, which receives items moved
+// out of the cart.
+const (
+	WishlistKindWishlist      = "wishlist"
+	WishlistKindSavedForLater = "saved_for_later"
+)
+
+// Wishlist is a named list of products a user saved outside the cart. Public wishlists can be
+// viewed by anyone with their ShareURL; ShareToken and ShareURL are only shown to the owner.
+type Wishlist struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	UserID     uint           `json:"user_id,omitempty"`
+	Name       string         `json:"name" gorm:"not null"`
+	Kind       string         `json:"kind" gorm:"not null"`
+	IsPublic   bool           `json:"is_public"`
+	ShareToken string         `json:"share_token,omitempty"`
+	ShareURL   string         `json:"share_url,omitempty" gorm:"-"`
+	ItemCount  int            `json:"item_count" gorm:"-"`
+	Items      []WishlistItem `json:"items,omitempty" gorm:"foreignKey:WishlistID"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+// WishlistItem is a product on a wishlist. NotifyBackInStock and NotifyPriceDrop subscribe the
+// owner to a notification when the product is for sale again, or gets cheaper than PriceAlertPrice.
+type WishlistItem struct {
+	ID                uint      `json:"id" gorm:"primaryKey"`
+	WishlistID        uint      `json:"wishlist_id"`
+	ProductID         uint      `json:"product_id"`
+	Product           Product   `json:"product" gorm:"foreignKey:ProductID"`
+	Quantity          int       `json:"quantity"`
+	AddedPrice        *float64  `json:"added_price,omitempty"`
+	Available         bool      `json:"available" gorm:"-"`
+	InStock           bool      `json:"in_stock" gorm:"-"`
+	NotifyBackInStock bool      `json:"notify_back_in_stock"`
+	NotifyPriceDrop   bool      `json:"notify_price_drop"`
+	PriceAlertPrice   *float64  `json:"price_alert_price,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
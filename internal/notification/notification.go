@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Message is a notification sent to a customer
+type Message struct {
+	UserID  uint
+	Email   string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to customers, e.g. by email
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewFromEnv creates the notifier selected by the NOTIFIER environment variable
+func NewFromEnv() (Notifier, error) {
+	notifier := strings.ToLower(getEnv("NOTIFIER", "log"))
+
+	switch notifier {
+	case "log":
+		return &LogNotifier{}, nil
+	case "none":
+		return &NoopNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown notifier: %s", notifier)
+	}
+}
+
+// LogNotifier writes messages to the application log instead of delivering them
+type LogNotifier struct{}
+
+// Name returns the notifier name
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+// Send logs the message
+func (n *LogNotifier) Send(ctx context.Context, msg *Message) error {
+	log.Printf("Notification to %s (user %d): %s - %s", msg.Email, msg.UserID, msg.Subject, msg.Body)
+	return nil
+}
+
+// NoopNotifier discards messages
+type NoopNotifier struct{}
+
+// Name returns the notifier name
+func (n *NoopNotifier) Name() string {
+	return "none"
+}
+
+// Send discards the message
+func (n *NoopNotifier) Send(ctx context.Context, msg *Message) error {
+	return nil
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,78 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrDeclined is returned when the provider refuses an operation, e.g. a card authorization
+var ErrDeclined = errors.New("payment declined")
+
+// ErrTimeout is returned when the provider does not answer in time. The outcome of the
+// operation is unknown and may still be reported later by the provider.
+var ErrTimeout = errors.New("payment provider timed out")
+
+// AuthorizeRequest asks the provider to reserve an amount on the customer's payment method
+type AuthorizeRequest struct {
+	OrderID       uint
+	Amount        float64
+	Currency      string
+	PaymentMethod string
+}
+
+// Result is the provider's answer to a payment operation
+type Result struct {
+	// Reference identifies the payment at the provider and is passed to later operations
+	Reference string
+	// Message is the provider's explanation, e.g. a decline reason
+	Message string
+}
+
+// PaymentProvider moves money through a payment gateway. Authorize reserves funds, Capture
+// collects (part of) an authorization, Void releases an uncaptured authorization and Refund
+// returns (part of) a captured amount. Declines are reported as ErrDeclined and timeouts as ErrTimeout.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, req *AuthorizeRequest) (*Result, error)
+	Capture(ctx context.Context, reference string, amount float64) (*Result, error)
+	Void(ctx context.Context, reference string) (*Result, error)
+	Refund(ctx context.Context, reference string, amount float64) (*Result, error)
+}
+
+// NewFromEnv creates the payment provider selected by the PAYMENT_PROVIDER environment variable
+func NewFromEnv() (PaymentProvider, error) {
+	provider := strings.ToLower(getEnv("PAYMENT_PROVIDER", "simulated"))
+
+	switch provider {
+	case "simulated":
+		return NewSimulatedProviderFromEnv()
+	default:
+		return nil, fmt.Errorf("unknown payment provider: %s", provider)
+	}
+}
+
+// Currency returns the currency payments are made in, from PAYMENT_CURRENCY
+func Currency() string {
+	return strings.ToUpper(getEnv("PAYMENT_CURRENCY", "USD"))
+}
+
+// Timeout returns how long to wait for the provider, from PAYMENT_TIMEOUT (e.g. "10s")
+func Timeout() time.Duration {
+	timeout, err := time.ParseDuration(getEnv("PAYMENT_TIMEOUT", "10s"))
+	if err != nil || timeout <= 0 {
+		return 10 * time.Second
+	}
+	return timeout
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,119 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Simulated outcomes of an authorization
+const (
+	OutcomeSucceed = "succeed"
+	OutcomeDecline = "decline"
+	OutcomeTimeout = "timeout"
+)
+
+// SimulatedProvider is a payment gateway for local testing that never moves money.
+// Authorizations follow the configured outcome, unless the payment method is "test_decline"
+// or "test_timeout", which force that outcome for a single order. Captures, voids and refunds succeed.
+type SimulatedProvider struct {
+	outcome string
+}
+
+// NewSimulatedProvider creates a simulated provider with the given authorization outcome
+func NewSimulatedProvider(outcome string) (*SimulatedProvider, error) {
+	outcome = strings.ToLower(strings.TrimSpace(outcome))
+	switch outcome {
+	case OutcomeSucceed, OutcomeDecline, OutcomeTimeout:
+		return &SimulatedProvider{outcome: outcome}, nil
+	default:
+		return nil, fmt.Errorf("unknown simulated payment outcome: %s", outcome)
+	}
+}
+
+// NewSimulatedProviderFromEnv creates a simulated provider with the outcome in PAYMENT_SIMULATED_OUTCOME
+func NewSimulatedProviderFromEnv() (*SimulatedProvider, error) {
+	return NewSimulatedProvider(getEnv("PAYMENT_SIMULATED_OUTCOME", OutcomeSucceed))
+}
+
+// Name returns the provider name stored on payments
+func (p *SimulatedProvider) Name() string {
+	return "simulated"
+}
+
+// Authorize approves, declines or times out according to the configured outcome
+func (p *SimulatedProvider) Authorize(ctx context.Context, req *AuthorizeRequest) (*Result, error) {
+	outcome := p.outcome
+	switch strings.ToLower(req.PaymentMethod) {
+	case "test_decline":
+		outcome = OutcomeDecline
+	case "test_timeout":
+		outcome = OutcomeTimeout
+	}
+
+	switch outcome {
+	case OutcomeDecline:
+		return &Result{Message: "card declined (simulated)"}, ErrDeclined
+	case OutcomeTimeout:
+		// Hang like an unresponsive gateway until the caller gives up
+		<-ctx.Done()
+		return nil, ErrTimeout
+	}
+
+	reference, err := newReference()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Reference: reference, Message: "approved (simulated)"}, nil
+}
+
+// Capture collects an authorized amount
+func (p *SimulatedProvider) Capture(ctx context.Context, reference string, amount float64) (*Result, error) {
+	return &Result{Reference: reference, Message: "captured (simulated)"}, nil
+}
+
+// Void releases an authorization
+func (p *SimulatedProvider) Void(ctx context.Context, reference string) (*Result, error) {
+	return &Result{Reference: reference, Message: "voided (simulated)"}, nil
+}
+
+// Refund returns a captured amount
+func (p *SimulatedProvider) Refund(ctx context.Context, reference string, amount float64) (*Result, error) {
+	refundReference, err := newReference()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Reference: refundReference, Message: "refunded (simulated)"}, nil
+}
+
+// VerifyWebhook checks the X-Simulated-Signature header against PAYMENT_WEBHOOK_SECRET
+func (p *SimulatedProvider) VerifyWebhook(header http.Header, body []byte) error {
+	return VerifyWebhookSignature(WebhookSecret(), header.Get("X-Simulated-Signature"), body, time.Now())
+}
+
+// ParseWebhook decodes a simulated webhook, which is an Event in JSON
+func (p *SimulatedProvider) ParseWebhook(body []byte) (*Event, error) {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.ID == "" || event.Type == "" {
+		return nil, errors.New("invalid webhook payload: id and type are required")
+	}
+	return &event, nil
+}
+
+// newReference generates a random provider reference
+func newReference() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate payment reference: %w", err)
+	}
+	return "sim_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,104 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook event types, normalized across providers
+const (
+	EventAuthorized = "payment.authorized"
+	EventCaptured   = "payment.captured"
+	EventVoided     = "payment.voided"
+	EventRefunded   = "payment.refunded"
+	EventFailed     = "payment.failed"
+)
+
+// ErrInvalidSignature is returned when a webhook is not signed by the provider
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Event is a payment notification sent by a provider.
+// Amount is cumulative: the total captured for captures and the total refunded for refunds,
+// so applying the same event twice has no further effect.
+type Event struct {
+	ID        string  `json:"id"`
+	Type      string  `json:"type"`
+	Reference string  `json:"reference"`
+	OrderID   uint    `json:"order_id,omitempty"`
+	Amount    float64 `json:"amount,omitempty"`
+	Message   string  `json:"message,omitempty"`
+}
+
+// WebhookProvider is implemented by providers that notify payment changes through webhooks
+type WebhookProvider interface {
+	// VerifyWebhook checks that a raw webhook request was sent by the provider
+	VerifyWebhook(header http.Header, body []byte) error
+	// ParseWebhook decodes a verified webhook body into an event
+	ParseWebhook(body []byte) (*Event, error)
+}
+
+// WebhookSecret returns the shared secret webhooks are signed with, from PAYMENT_WEBHOOK_SECRET
+func WebhookSecret() string {
+	return getEnv("PAYMENT_WEBHOOK_SECRET", "")
+}
+
+// WebhookTolerance is how far a webhook timestamp may be from now, limiting replayed requests
+const WebhookTolerance = 5 * time.Minute
+
+// SignWebhook returns the HMAC-SHA256 signature of a webhook body sent at the given Unix timestamp.
+// The signed payload is "<timestamp>.<body>".
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a "t=<timestamp>,v1=<hex signature>" header against the body
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time) error {
+	if secret == "" || header == "" {
+		return ErrInvalidSignature
+	}
+
+	var timestamp int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			timestamp = parsed
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > WebhookTolerance || age < -WebhookTolerance {
+		return ErrInvalidSignature
+	}
+
+	expected := SignWebhook(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
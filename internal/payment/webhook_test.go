@@ -0,0 +1,53 @@
+package payment
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"evt_1","type":"payment.captured","reference":"pay_1","amount":10}`)
+	now := time.Unix(1700000000, 0)
+	sign := func(at time.Time) string {
+		return fmt.Sprintf("t=%d,v1=%s", at.Unix(), SignWebhook(secret, at.Unix(), body))
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   []byte
+		valid  bool
+	}{
+		{name: "valid", secret: secret, header: sign(now), body: body, valid: true},
+		{name: "spaces around parts", secret: secret, header: fmt.Sprintf(" t=%d , v1=%s ", now.Unix(), SignWebhook(secret, now.Unix(), body)), body: body, valid: true},
+		{name: "one of several signatures matches", secret: secret, header: fmt.Sprintf("t=%d,v1=deadbeef,v1=%s", now.Unix(), SignWebhook(secret, now.Unix(), body)), body: body, valid: true},
+		{name: "old at the tolerance", secret: secret, header: sign(now.Add(-WebhookTolerance)), body: body, valid: true},
+		{name: "ahead at the tolerance", secret: secret, header: sign(now.Add(WebhookTolerance)), body: body, valid: true},
+		{name: "replayed after the tolerance", secret: secret, header: sign(now.Add(-WebhookTolerance - time.Second)), body: body},
+		{name: "too far ahead", secret: secret, header: sign(now.Add(WebhookTolerance + time.Second)), body: body},
+		{name: "replayed with a new timestamp", secret: secret, header: fmt.Sprintf("t=%d,v1=%s", now.Unix(), SignWebhook(secret, now.Add(-time.Hour).Unix(), body)), body: body},
+		{name: "tampered body", secret: secret, header: sign(now), body: []byte(`{"id":"evt_1","type":"payment.captured","reference":"pay_1","amount":1000}`)},
+		{name: "wrong secret", secret: "whsec_other", header: sign(now), body: body},
+		{name: "no secret configured", secret: "", header: sign(now), body: body},
+		{name: "no header", secret: secret, header: "", body: body},
+		{name: "no timestamp", secret: secret, header: "v1=" + SignWebhook(secret, now.Unix(), body), body: body},
+		{name: "invalid timestamp", secret: secret, header: "t=soon,v1=" + SignWebhook(secret, now.Unix(), body), body: body},
+		{name: "no signature", secret: secret, header: fmt.Sprintf("t=%d", now.Unix()), body: body},
+		{name: "unknown signature scheme", secret: secret, header: fmt.Sprintf("t=%d,v0=%s", now.Unix(), SignWebhook(secret, now.Unix(), body)), body: body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhookSignature(tt.secret, tt.header, tt.body, now)
+			if tt.valid && err != nil {
+				t.Errorf("got error %v, want valid", err)
+			}
+			if !tt.valid && err != ErrInvalidSignature {
+				t.Errorf("got error %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
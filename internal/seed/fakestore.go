@@ -0,0 +1,120 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultFakeStoreURL is the public FakeStore API endpoint
+const DefaultFakeStoreURL = "https://fakestoreapi.com"
+
+// FakeStoreSource fetches products from the FakeStore API
+type FakeStoreSource struct {
+	baseURL string
+	limit   int
+	client  *http.Client
+}
+
+// NewFakeStoreSource creates a source fetching up to limit products from baseURL
+func NewFakeStoreSource(baseURL string, limit int) *FakeStoreSource {
+	if baseURL == "" {
+		baseURL = DefaultFakeStoreURL
+	}
+	return &FakeStoreSource{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		limit:   limit,
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// fakeStoreProduct represents a product from FakeStore API
+type fakeStoreProduct struct {
+	ID          int     `json:"id"`
+	Title       string  `json:"title"`
+	Price       float64 `json:"price"`
+	Description string  `json:"description"`
+	Category    string  `json:"category"`
+	Image       string  `json:"image"`
+	Rating      struct {
+		Rate  float64 `json:"rate"`
+		Count int     `json:"count"`
+	} `json:"rating"`
+}
+
+// Name identifies the source
+func (s *FakeStoreSource) Name() string {
+	return "FakeStore API"
+}
+
+// Load fetches products and maps them onto the default categories
+func (s *FakeStoreSource) Load(ctx context.Context) (*Dataset, error) {
+	url := fmt.Sprintf("%s/products?limit=%d", s.baseURL, s.limit)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch products from API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("FakeStore API returned status %d", resp.StatusCode)
+	}
+
+	var fakeProducts []fakeStoreProduct
+	if err := json.NewDecoder(resp.Body).Decode(&fakeProducts); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal products: %w", err)
+	}
+
+	dataset := &Dataset{Categories: DefaultCategories()}
+	for _, fakeProduct := range fakeProducts {
+		// Generate realistic stock based on rating count
+		stock := fakeProduct.Rating.Count
+		if stock == 0 {
+			stock = 50 // Default stock
+		}
+		if stock > 200 {
+			stock = 200 // Cap at reasonable amount
+		}
+
+		dataset.Products = append(dataset.Products, Product{
+			Name:        fakeProduct.Title,
+			Description: fakeProduct.Description,
+			Price:       fakeProduct.Price,
+			Stock:       stock,
+			Category:    mapFakeStoreCategory(fakeProduct.Category),
+			ImageURL:    fakeProduct.Image,
+		})
+	}
+
+	return dataset, nil
+}
+
+// mapFakeStoreCategory maps FakeStore categories to our categories
+func mapFakeStoreCategory(fakeCategory string) string {
+	categoryMap := map[string]string{
+		"electronics":      "Electronics",
+		"jewelery":         "Jewelry",
+		"men's clothing":   "Clothing",
+		"women's clothing": "Clothing",
+		"home":             "Home & Garden",
+		"sports":           "Sports",
+		"books":            "Books",
+		"toys":             "Toys",
+		"automotive":       "Automotive",
+		"health":           "Health",
+		"food":             "Food",
+	}
+
+	if mapped, exists := categoryMap[strings.ToLower(fakeCategory)]; exists {
+		return mapped
+	}
+	return "Electronics" // Default fallback
+}
@@ -0,0 +1,112 @@
+package seed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureSource loads a dataset from local JSON or YAML fixture files
+type FixtureSource struct {
+	path string
+}
+
+// NewFixtureSource creates a source reading a fixture file, or every fixture file in a directory
+func NewFixtureSource(path string) *FixtureSource {
+	return &FixtureSource{path: path}
+}
+
+// Name identifies the source
+func (s *FixtureSource) Name() string {
+	return fmt.Sprintf("fixtures (%s)", s.path)
+}
+
+// Load reads the fixture files; files in a directory are merged in name order
+func (s *FixtureSource) Load(ctx context.Context) (*Dataset, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	files := []string{s.path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(s.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture directory: %w", err)
+		}
+
+		files = files[:0]
+		for _, entry := range entries {
+			if !entry.IsDir() && isFixtureFile(entry.Name()) {
+				files = append(files, filepath.Join(s.path, entry.Name()))
+			}
+		}
+		sort.Strings(files)
+
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no fixture files found in %s", s.path)
+		}
+	}
+
+	dataset := &Dataset{}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		fileDataset, err := loadFixtureFile(file)
+		if err != nil {
+			return nil, err
+		}
+		dataset.Merge(fileDataset)
+	}
+
+	return dataset, nil
+}
+
+// isFixtureFile reports whether the file name has a supported fixture extension
+func isFixtureFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// loadFixtureFile decodes a single JSON or YAML fixture file
+func loadFixtureFile(file string) (*Dataset, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture %s: %w", file, err)
+	}
+
+	var dataset Dataset
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&dataset)
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(&dataset)
+		if err == io.EOF {
+			err = nil // Empty YAML document
+		}
+	default:
+		return nil, fmt.Errorf("unsupported fixture format: %s", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixture %s: %w", file, err)
+	}
+
+	return &dataset, nil
+}
@@ -0,0 +1,88 @@
+package seed
+
+import (
+	"context"
+	"time"
+)
+
+// Source produces a dataset to be written to the database
+type Source interface {
+	// Name identifies the source in log output
+	Name() string
+	// Load returns the dataset provided by the source
+	Load(ctx context.Context) (*Dataset, error)
+}
+
+// Dataset is a self-contained set of seed records; records reference each other by natural keys
+type Dataset struct {
+	Users      []User     `json:"users" yaml:"users"`
+	Categories []Category `json:"categories" yaml:"categories"`
+	Products   []Product  `json:"products" yaml:"products"`
+	Orders     []Order    `json:"orders" yaml:"orders"`
+}
+
+// User is a seed user identified by email
+type User struct {
+	Email     string `json:"email" yaml:"email"`
+	Password  string `json:"password" yaml:"password"`
+	FirstName string `json:"first_name" yaml:"first_name"`
+	LastName  string `json:"last_name" yaml:"last_name"`
+	Role      string `json:"role" yaml:"role"`
+}
+
+// Category is a seed category identified by name
+type Category struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// Product is a seed product identified by SKU, or by name when no SKU is given
+type Product struct {
+	SKU         string  `json:"sku" yaml:"sku"`
+	Name        string  `json:"name" yaml:"name"`
+	Description string  `json:"description" yaml:"description"`
+	Price       float64 `json:"price" yaml:"price"`
+	Stock       int     `json:"stock" yaml:"stock"`
+	Category    string  `json:"category" yaml:"category"`
+	ImageURL    string  `json:"image_url" yaml:"image_url"`
+}
+
+// Order is a seed order placed by the user with the given email
+type Order struct {
+	UserEmail       string      `json:"user_email" yaml:"user_email"`
+	Status          string      `json:"status" yaml:"status"`
+	ShippingAddress string      `json:"shipping_address" yaml:"shipping_address"`
+	PaymentMethod   string      `json:"payment_method" yaml:"payment_method"`
+	CreatedAt       *time.Time  `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	Items           []OrderItem `json:"items" yaml:"items"`
+}
+
+// OrderItem references a product by SKU or name
+type OrderItem struct {
+	Product  string `json:"product" yaml:"product"`
+	Quantity int    `json:"quantity" yaml:"quantity"`
+}
+
+// Merge appends the records of other to the dataset
+func (d *Dataset) Merge(other *Dataset) {
+	d.Users = append(d.Users, other.Users...)
+	d.Categories = append(d.Categories, other.Categories...)
+	d.Products = append(d.Products, other.Products...)
+	d.Orders = append(d.Orders, other.Orders...)
+}
+
+// DefaultCategories returns the standard catalog categories
+func DefaultCategories() []Category {
+	return []Category{
+		{Name: "Electronics", Description: "Electronic devices and gadgets"},
+		{Name: "Clothing", Description: "Fashion and apparel"},
+		{Name: "Home & Garden", Description: "Home improvement and garden supplies"},
+		{Name: "Sports", Description: "Sports equipment and accessories"},
+		{Name: "Books", Description: "Books and literature"},
+		{Name: "Toys", Description: "Toys and games"},
+		{Name: "Automotive", Description: "Automotive parts and accessories"},
+		{Name: "Health", Description: "Health and beauty products"},
+		{Name: "Jewelry", Description: "Jewelry and accessories"},
+		{Name: "Food", Description: "Food and beverages"},
+	}
+}
@@ -0,0 +1,155 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// SyntheticConfig controls the size and randomness of a generated dataset
+type SyntheticConfig struct {
+	Seed       int64
+	Users      int
+	Categories int
+	Products   int
+	Orders     int
+	// Password is shared by all generated users
+	Password string
+}
+
+// SyntheticSource generates a deterministic dataset; the same config always yields the same records
+type SyntheticSource struct {
+	config SyntheticConfig
+}
+
+// NewSyntheticSource creates a synthetic data generator
+func NewSyntheticSource(config SyntheticConfig) *SyntheticSource {
+	if config.Password == "" {
+		config.Password = "password123"
+	}
+	return &SyntheticSource{config: config}
+}
+
+// syntheticEpoch anchors generated timestamps so datasets do not depend on the current time
+var syntheticEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+var (
+	syntheticFirstNames = []string{"Alex", "Sam", "Jordan", "Taylor", "Morgan", "Casey", "Riley", "Jamie", "Avery", "Quinn", "Drew", "Robin"}
+	syntheticLastNames  = []string{"Smith", "Garcia", "Chen", "Okafor", "Novak", "Silva", "Khan", "Müller", "Rossi", "Dubois", "Tanaka", "Nguyen"}
+	syntheticAdjectives = []string{"Classic", "Premium", "Compact", "Deluxe", "Ergonomic", "Lightweight", "Rugged", "Smart", "Vintage", "Wireless"}
+	syntheticMaterials  = []string{"Bamboo", "Carbon", "Ceramic", "Cotton", "Leather", "Steel", "Wooden", "Wool", "Glass", "Aluminum"}
+	syntheticNouns      = []string{"Backpack", "Chair", "Headphones", "Jacket", "Lamp", "Mug", "Notebook", "Speaker", "Watch", "Bottle", "Keyboard", "Sneakers"}
+	syntheticStreets    = []string{"Main St", "Oak Ave", "Maple Dr", "Cedar Ln", "Elm St", "Pine Rd", "Lake View", "Hill Crest"}
+	syntheticCities     = []string{"Springfield", "Riverside", "Fairview", "Madison", "Georgetown", "Franklin", "Clinton", "Salem"}
+	syntheticPayments   = []string{"credit_card", "paypal", "bank_transfer"}
+	syntheticStatuses   = []string{"pending", "confirmed", "shipped", "delivered", "delivered", "delivered", "cancelled"}
+)
+
+// Name identifies the source
+func (s *SyntheticSource) Name() string {
+	return fmt.Sprintf("synthetic generator (seed %d)", s.config.Seed)
+}
+
+// Load generates users, categories, products and orders
+func (s *SyntheticSource) Load(ctx context.Context) (*Dataset, error) {
+	config := s.config
+	if config.Users < 0 || config.Categories < 0 || config.Products < 0 || config.Orders < 0 {
+		return nil, fmt.Errorf("synthetic counts must not be negative")
+	}
+	if config.Products > 0 && config.Categories == 0 {
+		return nil, fmt.Errorf("synthetic products require at least one category")
+	}
+	if config.Orders > 0 && (config.Users == 0 || config.Products == 0) {
+		return nil, fmt.Errorf("synthetic orders require at least one user and one product")
+	}
+
+	rng := rand.New(rand.NewSource(config.Seed))
+	pick := func(values []string) string {
+		return values[rng.Intn(len(values))]
+	}
+
+	dataset := &Dataset{}
+
+	// Users
+	for i := 1; i <= config.Users; i++ {
+		dataset.Users = append(dataset.Users, User{
+			Email:     fmt.Sprintf("user%04d@example.com", i),
+			Password:  config.Password,
+			FirstName: pick(syntheticFirstNames),
+			LastName:  pick(syntheticLastNames),
+			Role:      "customer",
+		})
+	}
+
+	// Categories reuse the standard names first
+	defaults := DefaultCategories()
+	for i := 0; i < config.Categories; i++ {
+		if i < len(defaults) {
+			dataset.Categories = append(dataset.Categories, defaults[i])
+			continue
+		}
+		dataset.Categories = append(dataset.Categories, Category{
+			Name:        fmt.Sprintf("Category %d", i+1),
+			Description: fmt.Sprintf("Generated category %d", i+1),
+		})
+	}
+
+	// Products
+	for i := 1; i <= config.Products; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		name := fmt.Sprintf("%s %s %s", pick(syntheticAdjectives), pick(syntheticMaterials), pick(syntheticNouns))
+		category := dataset.Categories[rng.Intn(len(dataset.Categories))].Name
+		price := math.Round((5+rng.Float64()*495)*100) / 100
+
+		dataset.Products = append(dataset.Products, Product{
+			SKU:         fmt.Sprintf("SYN-%06d", i),
+			Name:        fmt.Sprintf("%s #%d", name, i),
+			Description: fmt.Sprintf("A %s item from the %s category.", name, category),
+			Price:       price,
+			Stock:       rng.Intn(201),
+			Category:    category,
+			ImageURL:    fmt.Sprintf("https://picsum.photos/seed/syn-%06d/600/600", i),
+		})
+	}
+
+	// Orders
+	for i := 0; i < config.Orders; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		user := dataset.Users[rng.Intn(len(dataset.Users))]
+		createdAt := syntheticEpoch.Add(time.Duration(rng.Int63n(int64(365 * 24 * time.Hour))))
+
+		itemCount := 1 + rng.Intn(4)
+		used := map[int]bool{}
+		var items []OrderItem
+		for j := 0; j < itemCount && len(used) < len(dataset.Products); j++ {
+			index := rng.Intn(len(dataset.Products))
+			if used[index] {
+				continue
+			}
+			used[index] = true
+			items = append(items, OrderItem{
+				Product:  dataset.Products[index].SKU,
+				Quantity: 1 + rng.Intn(3),
+			})
+		}
+
+		dataset.Orders = append(dataset.Orders, Order{
+			UserEmail:       user.Email,
+			Status:          pick(syntheticStatuses),
+			ShippingAddress: fmt.Sprintf("%d %s, %s", 1+rng.Intn(9999), pick(syntheticStreets), pick(syntheticCities)),
+			PaymentMethod:   pick(syntheticPayments),
+			CreatedAt:       &createdAt,
+			Items:           items,
+		})
+	}
+
+	return dataset, nil
+}
@@ -0,0 +1,641 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/lib/pq"
+)
+
+// attributeCodePattern restricts attribute codes to values usable in query strings
+var attributeCodePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// AttributeService handles product attribute definitions and values
+type AttributeService struct {
+	db *sql.DB
+}
+
+// NewAttributeService creates a new attribute service
+func NewAttributeService() *AttributeService {
+	return &AttributeService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateAttributeRequest represents the request to create an attribute definition
+type CreateAttributeRequest struct {
+	Code       string   `json:"code" binding:"required"`
+	Name       string   `json:"name" binding:"required"`
+	Type       string   `json:"type" binding:"required,oneof=text number boolean enum"`
+	Unit       string   `json:"unit"`
+	EnumValues []string `json:"enum_values"`
+}
+
+// UpdateAttributeRequest represents the request to update an attribute definition
+type UpdateAttributeRequest struct {
+	Name       *string  `json:"name"`
+	Unit       *string  `json:"unit"`
+	EnumValues []string `json:"enum_values"`
+}
+
+// AssignCategoryAttributeRequest represents the request to assign an attribute to a category
+type AssignCategoryAttributeRequest struct {
+	AttributeID uint `json:"attribute_id" binding:"required"`
+	IsRequired  bool `json:"is_required"`
+	Position    int  `json:"position"`
+}
+
+// AttributeFilter filters products by an attribute value
+type AttributeFilter struct {
+	Code     string `json:"code"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+}
+
+// attributeValue is a validated attribute value ready to be stored
+type attributeValue struct {
+	attributeID uint
+	text        sql.NullString
+	number      sql.NullFloat64
+	boolean     sql.NullBool
+	remove      bool
+}
+
+const attributeColumns = "id, code, name, type, COALESCE(unit, ''), enum_values, created_at, updated_at"
+
+// scanAttribute scans an attribute definition row selected with attributeColumns
+func scanAttribute(scanner interface{ Scan(...interface{}) error }, attr *models.AttributeDefinition) error {
+	return scanner.Scan(
+		&attr.ID, &attr.Code, &attr.Name, &attr.Type, &attr.Unit,
+		pq.Array(&attr.EnumValues), &attr.CreatedAt, &attr.UpdatedAt,
+	)
+}
+
+// CreateAttribute creates a new attribute definition
+func (s *AttributeService) CreateAttribute(req *CreateAttributeRequest) (*models.AttributeDefinition, error) {
+	if !attributeCodePattern.MatchString(req.Code) {
+		return nil, errors.New("invalid attribute code")
+	}
+
+	if req.Type == models.AttributeTypeEnum && len(req.EnumValues) == 0 {
+		return nil, errors.New("enum attributes require enum values")
+	}
+	if req.Type != models.AttributeTypeEnum {
+		req.EnumValues = nil
+	}
+
+	// Check if attribute with same code already exists
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM attribute_definitions WHERE code = $1)", req.Code).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if exists {
+		return nil, errors.New("attribute with this code already exists")
+	}
+
+	var attr models.AttributeDefinition
+	query := fmt.Sprintf(`
+		INSERT INTO attribute_definitions (code, name, type, unit, enum_values, created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, NOW(), NOW())
+		RETURNING %s
+	`, attributeColumns)
+
+	err = scanAttribute(s.db.QueryRow(query, req.Code, req.Name, req.Type, req.Unit, pq.Array(req.EnumValues)), &attr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attribute: %w", err)
+	}
+
+	return &attr, nil
+}
+
+// GetAttribute retrieves an attribute definition by ID
+func (s *AttributeService) GetAttribute(id uint) (*models.AttributeDefinition, error) {
+	var attr models.AttributeDefinition
+	query := fmt.Sprintf("SELECT %s FROM attribute_definitions WHERE id = $1", attributeColumns)
+
+	err := scanAttribute(s.db.QueryRow(query, id), &attr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("attribute not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	return &attr, nil
+}
+
+// UpdateAttribute updates an attribute definition. The code and type are immutable
+// because stored product values depend on them.
+func (s *AttributeService) UpdateAttribute(id uint, req *UpdateAttributeRequest) (*models.AttributeDefinition, error) {
+	existing, err := s.GetAttribute(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if req.Name != nil {
+		updates = append(updates, fmt.Sprintf("name = $%d", argIndex))
+		args = append(args, *req.Name)
+		argIndex++
+	}
+
+	if req.Unit != nil {
+		updates = append(updates, fmt.Sprintf("unit = NULLIF($%d, '')", argIndex))
+		args = append(args, *req.Unit)
+		argIndex++
+	}
+
+	if req.EnumValues != nil {
+		if existing.Type != models.AttributeTypeEnum {
+			return nil, errors.New("enum values can only be set on enum attributes")
+		}
+		if len(req.EnumValues) == 0 {
+			return nil, errors.New("enum attributes require enum values")
+		}
+		updates = append(updates, fmt.Sprintf("enum_values = $%d", argIndex))
+		args = append(args, pq.Array(req.EnumValues))
+		argIndex++
+	}
+
+	if len(updates) == 0 {
+		return existing, nil
+	}
+
+	updates = append(updates, "updated_at = NOW()")
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE attribute_definitions SET %s WHERE id = $%d RETURNING %s",
+		strings.Join(updates, ", "), argIndex, attributeColumns)
+
+	var attr models.AttributeDefinition
+	if err := scanAttribute(s.db.QueryRow(query, args...), &attr); err != nil {
+		return nil, fmt.Errorf("failed to update attribute: %w", err)
+	}
+
+	return &attr, nil
+}
+
+// DeleteAttribute deletes an attribute definition along with its category assignments and product values
+func (s *AttributeService) DeleteAttribute(id uint) error {
+	result, err := s.db.Exec("DELETE FROM attribute_definitions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attribute: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("attribute not found")
+	}
+
+	return nil
+}
+
+// ListAttributes retrieves all attribute definitions
+func (s *AttributeService) ListAttributes() ([]models.AttributeDefinition, error) {
+	query := fmt.Sprintf("SELECT %s FROM attribute_definitions ORDER BY name ASC", attributeColumns)
+
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attributes: %w", err)
+	}
+	defer rows.Close()
+
+	var attrs []models.AttributeDefinition
+	for rows.Next() {
+		var attr models.AttributeDefinition
+		if err := scanAttribute(rows, &attr); err != nil {
+			return nil, fmt.Errorf("failed to scan attribute: %w", err)
+		}
+		attrs = append(attrs, attr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attributes: %w", err)
+	}
+
+	return attrs, nil
+}
+
+// AssignAttributeToCategory assigns an attribute to a category or updates an existing assignment
+func (s *AttributeService) AssignAttributeToCategory(categoryID uint, req *AssignCategoryAttributeRequest) error {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)", categoryID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return errors.New("category not found")
+	}
+
+	if _, err := s.GetAttribute(req.AttributeID); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO category_attributes (category_id, attribute_id, is_required, position)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (category_id, attribute_id)
+		DO UPDATE SET is_required = EXCLUDED.is_required, position = EXCLUDED.position
+	`, categoryID, req.AttributeID, req.IsRequired, req.Position)
+	if err != nil {
+		return fmt.Errorf("failed to assign attribute: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveAttributeFromCategory removes an attribute assignment from a category
+func (s *AttributeService) RemoveAttributeFromCategory(categoryID, attributeID uint) error {
+	result, err := s.db.Exec(
+		"DELETE FROM category_attributes WHERE category_id = $1 AND attribute_id = $2",
+		categoryID, attributeID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to remove attribute: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("attribute not assigned to category")
+	}
+
+	return nil
+}
+
+// ListCategoryAttributes retrieves the attributes assigned to a category
+func (s *AttributeService) ListCategoryAttributes(categoryID uint) ([]models.CategoryAttribute, error) {
+	query := `
+		SELECT ca.category_id, ca.attribute_id, ca.is_required, ca.position,
+		       a.id, a.code, a.name, a.type, COALESCE(a.unit, ''), a.enum_values, a.created_at, a.updated_at
+		FROM category_attributes ca
+		JOIN attribute_definitions a ON ca.attribute_id = a.id
+		WHERE ca.category_id = $1
+		ORDER BY ca.position ASC, a.name ASC
+	`
+
+	rows, err := s.db.Query(query, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category attributes: %w", err)
+	}
+	defer rows.Close()
+
+	var attrs []models.CategoryAttribute
+	for rows.Next() {
+		var ca models.CategoryAttribute
+		err := rows.Scan(
+			&ca.CategoryID, &ca.AttributeID, &ca.IsRequired, &ca.Position,
+			&ca.Attribute.ID, &ca.Attribute.Code, &ca.Attribute.Name, &ca.Attribute.Type, &ca.Attribute.Unit,
+			pq.Array(&ca.Attribute.EnumValues), &ca.Attribute.CreatedAt, &ca.Attribute.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category attribute: %w", err)
+		}
+		attrs = append(attrs, ca)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category attributes: %w", err)
+	}
+
+	return attrs, nil
+}
+
+// validateProductAttributes checks attribute values against the attributes assigned to a category.
+// A nil value marks the attribute for removal.
+func validateProductAttributes(q querier, categoryID uint, values map[string]interface{}) ([]attributeValue, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if categoryID == 0 {
+		return nil, errors.New("invalid attribute: product has no category")
+	}
+
+	rows, err := q.Query(`
+		SELECT a.id, a.code, a.type, a.enum_values, ca.is_required
+		FROM category_attributes ca
+		JOIN attribute_definitions a ON ca.attribute_id = a.id
+		WHERE ca.category_id = $1
+	`, categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query category attributes: %w", err)
+	}
+	defer rows.Close()
+
+	type assigned struct {
+		attr     models.AttributeDefinition
+		required bool
+	}
+	byCode := make(map[string]assigned)
+	for rows.Next() {
+		var a assigned
+		if err := rows.Scan(&a.attr.ID, &a.attr.Code, &a.attr.Type, pq.Array(&a.attr.EnumValues), &a.required); err != nil {
+			return nil, fmt.Errorf("failed to scan category attribute: %w", err)
+		}
+		byCode[a.attr.Code] = a
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating category attributes: %w", err)
+	}
+
+	var result []attributeValue
+	for code, raw := range values {
+		a, ok := byCode[code]
+		if !ok {
+			return nil, fmt.Errorf("invalid attribute: %s is not assigned to the product category", code)
+		}
+
+		value := attributeValue{attributeID: a.attr.ID}
+		if raw == nil {
+			if a.required {
+				return nil, fmt.Errorf("invalid attribute: %s is required", code)
+			}
+			value.remove = true
+			result = append(result, value)
+			continue
+		}
+
+		switch a.attr.Type {
+		case models.AttributeTypeNumber:
+			n, ok := raw.(float64)
+			if !ok {
+				return nil, fmt.Errorf("invalid attribute: %s must be a number", code)
+			}
+			value.number = sql.NullFloat64{Float64: n, Valid: true}
+		case models.AttributeTypeBoolean:
+			b, ok := raw.(bool)
+			if !ok {
+				return nil, fmt.Errorf("invalid attribute: %s must be a boolean", code)
+			}
+			value.boolean = sql.NullBool{Bool: b, Valid: true}
+		case models.AttributeTypeEnum:
+			str, ok := raw.(string)
+			if !ok || !containsString(a.attr.EnumValues, str) {
+				return nil, fmt.Errorf("invalid attribute: %s must be one of %s", code, strings.Join(a.attr.EnumValues, ", "))
+			}
+			value.text = sql.NullString{String: str, Valid: true}
+		default:
+			str, ok := raw.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid attribute: %s must be a string", code)
+			}
+			value.text = sql.NullString{String: str, Valid: true}
+		}
+
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// saveProductAttributes stores validated attribute values for a product
+func saveProductAttributes(q querier, productID uint, values []attributeValue) error {
+	for _, v := range values {
+		if v.remove {
+			_, err := q.Exec("DELETE FROM product_attribute_values WHERE product_id = $1 AND attribute_id = $2", productID, v.attributeID)
+			if err != nil {
+				return fmt.Errorf("failed to remove attribute value: %w", err)
+			}
+			continue
+		}
+
+		_, err := q.Exec(`
+			INSERT INTO product_attribute_values (product_id, attribute_id, value_text, value_number, value_boolean, updated_at)
+			VALUES ($1, $2, $3, $4, $5, NOW())
+			ON CONFLICT (product_id, attribute_id)
+			DO UPDATE SET value_text = EXCLUDED.value_text, value_number = EXCLUDED.value_number,
+			              value_boolean = EXCLUDED.value_boolean, updated_at = NOW()
+		`, productID, v.attributeID, v.text, v.number, v.boolean)
+		if err != nil {
+			return fmt.Errorf("failed to save attribute value: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// syncProductAttributesWithCategory drops values for attributes not assigned to the
+// product's category and verifies that every required attribute has a value
+func syncProductAttributesWithCategory(q querier, productID, categoryID uint) error {
+	_, err := q.Exec(`
+		DELETE FROM product_attribute_values pav
+		WHERE pav.product_id = $1
+		  AND NOT EXISTS (
+		      SELECT 1 FROM category_attributes ca
+		      WHERE ca.category_id = $2 AND ca.attribute_id = pav.attribute_id
+		  )
+	`, productID, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to clean up attribute values: %w", err)
+	}
+
+	var missing sql.NullString
+	err = q.QueryRow(`
+		SELECT string_agg(a.code, ', ' ORDER BY a.code)
+		FROM category_attributes ca
+		JOIN attribute_definitions a ON ca.attribute_id = a.id
+		WHERE ca.category_id = $1 AND ca.is_required = true
+		  AND NOT EXISTS (
+		      SELECT 1 FROM product_attribute_values pav
+		      WHERE pav.product_id = $2 AND pav.attribute_id = ca.attribute_id
+		  )
+	`, categoryID, productID).Scan(&missing)
+	if err != nil {
+		return fmt.Errorf("failed to check required attributes: %w", err)
+	}
+
+	if missing.Valid && missing.String != "" {
+		return fmt.Errorf("invalid attribute: missing required attributes %s", missing.String)
+	}
+
+	return nil
+}
+
+// loadProductAttributes retrieves attribute values for the given products keyed by product ID
+func loadProductAttributes(q querier, productIDs []uint) (map[uint][]models.ProductAttributeValue, error) {
+	result := make(map[uint][]models.ProductAttributeValue)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	ids := make([]int64, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := q.Query(`
+		SELECT pav.product_id, a.id, a.code, a.name, a.type, COALESCE(a.unit, ''),
+		       pav.value_text, pav.value_number, pav.value_boolean
+		FROM product_attribute_values pav
+		JOIN attribute_definitions a ON pav.attribute_id = a.id
+		WHERE pav.product_id = ANY($1)
+		ORDER BY a.name ASC
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product attributes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productID uint
+		var value models.ProductAttributeValue
+		var text sql.NullString
+		var number sql.NullFloat64
+		var boolean sql.NullBool
+		err := rows.Scan(
+			&productID, &value.AttributeID, &value.Code, &value.Name, &value.Type, &value.Unit,
+			&text, &number, &boolean,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product attribute: %w", err)
+		}
+
+		switch {
+		case number.Valid:
+			value.Value = number.Float64
+		case boolean.Valid:
+			value.Value = boolean.Bool
+		case text.Valid:
+			value.Value = text.String
+		}
+
+		result[productID] = append(result[productID], value)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product attributes: %w", err)
+	}
+
+	return result, nil
+}
+
+// attributeFilterConditions builds WHERE conditions for attribute filters on the products table
+func attributeFilterConditions(q querier, filters []AttributeFilter, argIndex int) ([]string, []interface{}, error) {
+	if len(filters) == 0 {
+		return nil, nil, nil
+	}
+
+	codes := make([]string, 0, len(filters))
+	for _, f := range filters {
+		codes = append(codes, f.Code)
+	}
+
+	rows, err := q.Query("SELECT id, code, type FROM attribute_definitions WHERE code = ANY($1)", pq.Array(codes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query attributes: %w", err)
+	}
+	defer rows.Close()
+
+	types := make(map[string]string)
+	ids := make(map[string]uint)
+	for rows.Next() {
+		var id uint
+		var code, attrType string
+		if err := rows.Scan(&id, &code, &attrType); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan attribute: %w", err)
+		}
+		types[code] = attrType
+		ids[code] = id
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating attributes: %w", err)
+	}
+
+	operators := map[string]string{"eq": "=", "ne": "<>", "gt": ">", "gte": ">=", "lt": "<", "lte": "<="}
+
+	var conditions []string
+	var args []interface{}
+	for _, f := range filters {
+		attrType, ok := types[f.Code]
+		if !ok {
+			return nil, nil, fmt.Errorf("invalid attribute filter: unknown attribute %s", f.Code)
+		}
+
+		op := f.Operator
+		if op == "" {
+			op = "eq"
+		}
+
+		column := "pav.value_text"
+		var value interface{} = f.Value
+		switch attrType {
+		case models.AttributeTypeNumber:
+			column = "pav.value_number"
+			if op != "in" {
+				n, err := strconv.ParseFloat(f.Value, 64)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid attribute filter: %s must be a number", f.Code)
+				}
+				value = n
+			}
+		case models.AttributeTypeBoolean:
+			column = "pav.value_boolean"
+			if op != "eq" && op != "ne" {
+				return nil, nil, fmt.Errorf("invalid attribute filter: unsupported operator %s for %s", op, f.Code)
+			}
+			b, err := strconv.ParseBool(f.Value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid attribute filter: %s must be a boolean", f.Code)
+			}
+			value = b
+		default:
+			if op != "eq" && op != "ne" && op != "in" {
+				return nil, nil, fmt.Errorf("invalid attribute filter: unsupported operator %s for %s", op, f.Code)
+			}
+		}
+
+		var comparison string
+		if op == "in" {
+			list := strings.Split(f.Value, ",")
+			if attrType == models.AttributeTypeNumber {
+				nums := make([]float64, 0, len(list))
+				for _, item := range list {
+					n, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+					if err != nil {
+						return nil, nil, fmt.Errorf("invalid attribute filter: %s must be a number", f.Code)
+					}
+					nums = append(nums, n)
+				}
+				value = pq.Array(nums)
+			} else {
+				for i := range list {
+					list[i] = strings.TrimSpace(list[i])
+				}
+				value = pq.Array(list)
+			}
+			comparison = fmt.Sprintf("%s = ANY($%d)", column, argIndex+1)
+		} else {
+			sqlOp, ok := operators[op]
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid attribute filter: unsupported operator %s for %s", op, f.Code)
+			}
+			comparison = fmt.Sprintf("%s %s $%d", column, sqlOp, argIndex+1)
+		}
+
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM product_attribute_values pav WHERE pav.product_id = p.id AND pav.attribute_id = $%d AND %s)",
+			argIndex, comparison,
+		))
+		args = append(args, ids[f.Code], value)
+		argIndex += 2
+	}
+
+	return conditions, args, nil
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,17 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"time"
 
 	"github.com/Code-byme/e-commerce/internal/database"
 	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/notification"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -25,18 +28,28 @@ type JWTClaims struct {
 type AuthService struct {
 	db        *sql.DB
 	jwtSecret []byte
+	claimTTL  time.Duration
 }
 
-// NewAuthService creates a new authentication service
+// NewAuthService creates a new authentication service. Guest order claims are valid for
+// GUEST_ORDER_CLAIM_TTL (default 72h).
 func NewAuthService() *AuthService {
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
 		jwtSecret = "your-secret-key" // Default fallback
 	}
 
+	claimTTL := 72 * time.Hour
+	if value := os.Getenv("GUEST_ORDER_CLAIM_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			claimTTL = parsed
+		}
+	}
+
 	return &AuthService{
 		db:        database.GetDB(),
 		jwtSecret: []byte(jwtSecret),
+		claimTTL:  claimTTL,
 	}
 }
 
@@ -46,12 +59,18 @@ type RegisterRequest struct {
 	Password  string `json:"password" binding:"required,min=6"`
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
+
+	// CartToken is the guest cart to merge into the new account's cart
+	CartToken string `json:"cart_token"`
 }
 
 // LoginRequest represents the login request
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+
+	// CartToken is the guest cart to merge into the user's cart
+	CartToken string `json:"cart_token"`
 }
 
 // AuthResponse represents the authentication response
@@ -60,16 +79,19 @@ type AuthResponse struct {
 	Token string              `json:"token"`
 }
 
-// Register creates a new user account
+// Register creates a new customer account. An account created by guest checkout with the same
+// email is left as it is; a guest order claim is sent to the email instead, so only its owner can
+// attach those orders to the new account.
 func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 	// Check if user already exists
-	var existingUser models.User
-	err := s.db.QueryRow("SELECT id FROM users WHERE email = $1", req.Email).Scan(&existingUser.ID)
-	if err == nil {
-		return nil, errors.New("user with this email already exists")
-	} else if err != sql.ErrNoRows {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND role <> 'guest')", req.Email).Scan(&exists)
+	if err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
+	if exists {
+		return nil, errors.New("user with this email already exists")
+	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -89,6 +111,9 @@ func (s *AuthService) Register(req *RegisterRequest) (*AuthResponse, error) {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	s.sendGuestOrderClaim(&user)
+	s.mergeCart(user.ID, req.CartToken)
+
 	// Generate JWT token
 	token, err := s.generateToken(user.ID, user.Email, user.Role)
 	if err != nil {
@@ -113,7 +138,7 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 	// Get user by email
 	var user models.User
 	err := s.db.QueryRow(
-		"SELECT id, email, password, first_name, last_name, role, created_at, updated_at FROM users WHERE email = $1",
+		"SELECT id, email, password, first_name, last_name, role, created_at, updated_at FROM users WHERE email = $1 AND role <> 'guest'",
 		req.Email,
 	).Scan(&user.ID, &user.Email, &user.Password, &user.FirstName, &user.LastName, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
@@ -130,6 +155,8 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 		return nil, errors.New("invalid email or password")
 	}
 
+	s.mergeCart(user.ID, req.CartToken)
+
 	// Generate JWT token
 	token, err := s.generateToken(user.ID, user.Email, user.Role)
 	if err != nil {
@@ -149,6 +176,51 @@ func (s *AuthService) Login(req *LoginRequest) (*AuthResponse, error) {
 	}, nil
 }
 
+// mergeCart merges the guest cart the user built before signing in into their cart. Failing to
+// merge does not fail the sign in; the guest cart is kept.
+func (s *AuthService) mergeCart(userID uint, cartToken string) {
+	if cartToken == "" {
+		return
+	}
+	if err := mergeGuestCart(s.db, userID, cartToken); err != nil {
+		log.Printf("Warning: failed to merge guest cart into cart of user %d: %v", userID, err)
+	}
+}
+
+// sendGuestOrderClaim sends a new customer a code to attach the orders placed by guest checkout
+// with their email. Failing to send it does not fail the registration.
+func (s *AuthService) sendGuestOrderClaim(user *models.User) {
+	token, err := createGuestOrderClaim(s.db, user.ID, user.Email, s.claimTTL)
+	if err != nil {
+		log.Printf("Warning: failed to create guest order claim for user %d: %v", user.ID, err)
+		return
+	}
+	if token == "" {
+		return
+	}
+
+	msg := &notification.Message{
+		UserID:  user.ID,
+		Email:   user.Email,
+		Subject: "Add your guest orders to your account",
+		Body: fmt.Sprintf(
+			"Orders placed as a guest with this email can be added to your new account. Sign in and confirm with this code within %s: %s",
+			s.claimTTL, token,
+		),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := getNotifier().Send(ctx, msg); err != nil {
+		log.Printf("Warning: failed to send guest order claim to user %d: %v", user.ID, err)
+	}
+}
+
+// ClaimGuestOrders attaches the orders of a guest checkout account to the user, given the code
+// sent to the email they registered with
+func (s *AuthService) ClaimGuestOrders(userID uint, token string) (int64, error) {
+	return claimGuestOrders(s.db, userID, token)
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func (s *AuthService) ValidateToken(tokenString string) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
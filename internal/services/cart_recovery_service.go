@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/notification"
+)
+
+// maxRecoveriesPerRun is the most recovery notifications sent in one run of the worker
+const maxRecoveriesPerRun = 100
+
+// CartRecoveryService expires idle carts, detects abandoned carts and sends their owners a
+// notification with a link that restores the cart in one click
+type CartRecoveryService struct {
+	db             *sql.DB
+	userCartTTL    time.Duration
+	guestCartTTL   time.Duration
+	abandonedAfter time.Duration
+	linkTTL        time.Duration
+	interval       time.Duration
+	restoreURL     string
+}
+
+// recoveryItem is a cart line kept with a recovery notification
+type recoveryItem struct {
+	ProductID uint `json:"product_id"`
+	Quantity  int  `json:"quantity"`
+}
+
+// NewCartRecoveryService creates a new cart recovery service. Carts are deleted after CART_TTL
+// (default 90 days) without activity, guest carts after CART_GUEST_TTL (default 30 days). A cart
+// with items is abandoned after CART_ABANDONED_AFTER (default 24h) without activity or an order;
+// its restore link is valid for CART_RECOVERY_LINK_TTL (default 7 days).
+func NewCartRecoveryService() *CartRecoveryService {
+	duration := func(key string, defaultValue time.Duration) time.Duration {
+		if value := os.Getenv(key); value != "" {
+			if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+				return parsed
+			}
+		}
+		return defaultValue
+	}
+
+	restoreURL := os.Getenv("CART_RESTORE_URL")
+	if restoreURL == "" {
+		restoreURL = "http://localhost:3000/cart/restore"
+	}
+
+	return &CartRecoveryService{
+		db:             database.GetDB(),
+		userCartTTL:    duration("CART_TTL", 90*24*time.Hour),
+		guestCartTTL:   duration("CART_GUEST_TTL", 30*24*time.Hour),
+		abandonedAfter: duration("CART_ABANDONED_AFTER", 24*time.Hour),
+		linkTTL:        duration("CART_RECOVERY_LINK_TTL", 7*24*time.Hour),
+		interval:       duration("CART_RECOVERY_INTERVAL", 15*time.Minute),
+		restoreURL:     strings.TrimSuffix(restoreURL, "/"),
+	}
+}
+
+// abandonedCartsQuery selects carts with items that have seen no activity for the abandonment
+// period ($1 seconds) and whose owner has not ordered since, with their item count and value.
+// conditions are added to the WHERE clause.
+func abandonedCartsQuery(conditions string) string {
+	return `
+		SELECT c.id, c.user_id, COALESCE(u.email, ''), c.updated_at, c.recovery_sent_at,
+		       SUM(ci.quantity) AS total_items, SUM(ci.quantity * ` + productEffectivePriceExpr("p.") + `) AS total_value
+		FROM carts c
+		JOIN cart_items ci ON ci.cart_id = c.id
+		JOIN products p ON p.id = ci.product_id
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.updated_at < NOW() - $1 * INTERVAL '1 second'
+		  AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.user_id = c.user_id AND o.created_at >= c.updated_at)
+		  ` + conditions + `
+		GROUP BY c.id, u.email
+	`
+}
+
+// scanAbandonedCart scans a row of abandonedCartsQuery
+func scanAbandonedCart(rows *sql.Rows) (models.AbandonedCart, error) {
+	var cart models.AbandonedCart
+	var userID sql.NullInt64
+	err := rows.Scan(
+		&cart.CartID, &userID, &cart.Email, &cart.LastActivityAt, &cart.RecoverySentAt,
+		&cart.TotalItems, &cart.TotalValue,
+	)
+	if err != nil {
+		return cart, fmt.Errorf("failed to scan abandoned cart: %w", err)
+	}
+	if userID.Valid {
+		id := uint(userID.Int64)
+		cart.UserID = &id
+	}
+	cart.TotalValue = roundCents(cart.TotalValue)
+	return cart, nil
+}
+
+// DeleteExpiredCarts deletes user and guest carts that have seen no activity for their TTL
+func (s *CartRecoveryService) DeleteExpiredCarts() (int64, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM carts
+		WHERE updated_at < NOW() - (CASE WHEN user_id IS NULL THEN $1 ELSE $2 END) * INTERVAL '1 second'
+	`, s.guestCartTTL.Seconds(), s.userCartTTL.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired carts: %w", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return deleted, nil
+}
+
+// NotifyAbandonedCarts sends a recovery notification for each abandoned user cart that has not
+// been notified since its last activity. Guest carts are only reported, as their owner is unknown.
+func (s *CartRecoveryService) NotifyAbandonedCarts() (int, error) {
+	rows, err := s.db.Query(
+		abandonedCartsQuery("AND c.user_id IS NOT NULL AND (c.recovery_sent_at IS NULL OR c.recovery_sent_at < c.updated_at)")+
+			" ORDER BY c.updated_at LIMIT $2",
+		s.abandonedAfter.Seconds(), maxRecoveriesPerRun,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query abandoned carts: %w", err)
+	}
+
+	var carts []models.AbandonedCart
+	for rows.Next() {
+		cart, err := scanAbandonedCart(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		carts = append(carts, cart)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating abandoned carts: %w", err)
+	}
+
+	sent := 0
+	for _, cart := range carts {
+		ok, err := s.sendRecovery(cart)
+		if err != nil {
+			log.Printf("Warning: failed to send recovery notification for cart %d: %v", cart.CartID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// sendRecovery records a recovery with a snapshot of the cart and notifies its owner. It returns
+// false when the cart saw activity or was notified by another worker in the meantime.
+func (s *CartRecoveryService) sendRecovery(cart models.AbandonedCart) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Claim the cart unless it was used or notified since it was selected
+	var cartID uint
+	err = tx.QueryRow(`
+		UPDATE carts SET recovery_sent_at = NOW()
+		WHERE id = $1 AND updated_at < NOW() - $2 * INTERVAL '1 second'
+		  AND (recovery_sent_at IS NULL OR recovery_sent_at < updated_at)
+		RETURNING id
+	`, cart.CartID, s.abandonedAfter.Seconds()).Scan(&cartID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim cart: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT product_id, quantity FROM cart_items WHERE cart_id = $1 ORDER BY created_at", cart.CartID)
+	if err != nil {
+		return false, fmt.Errorf("failed to query cart items: %w", err)
+	}
+	var items []recoveryItem
+	for rows.Next() {
+		var item recoveryItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		items = append(items, item)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return false, fmt.Errorf("error iterating cart items: %w", err)
+	}
+
+	itemsJSON, err := json.Marshal(items)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode cart items: %w", err)
+	}
+
+	token := randomHex(32)
+	_, err = tx.Exec(`
+		INSERT INTO cart_recoveries (cart_id, user_id, token, items, total_value, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW() + $6 * INTERVAL '1 second')
+	`, cart.CartID, *cart.UserID, token, itemsJSON, cart.TotalValue, s.linkTTL.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("failed to record cart recovery: %w", err)
+	}
+
+	// Send before committing so a failed delivery is retried on the next run
+	msg := &notification.Message{
+		UserID:  *cart.UserID,
+		Email:   cart.Email,
+		Subject: "You left something in your cart",
+		Body: fmt.Sprintf(
+			"You have %d item(s) worth %.2f waiting in your cart. Restore it with one click: %s/%s",
+			cart.TotalItems, cart.TotalValue, s.restoreURL, token,
+		),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := getNotifier().Send(ctx, msg); err != nil {
+		return false, fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// RestoreCart puts the items of a recovery notification back into its user's cart, keeping the
+// larger quantity of products still in the cart. Products no longer for sale are skipped. Only the
+// cart's user can restore it, and each link works once.
+func (s *CartRecoveryService) RestoreCart(userID uint, token string) (*models.CartResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Links of other users are not found, so they cannot be probed
+	var recoveryID uint
+	var itemsJSON []byte
+	var expired, restored bool
+	err = tx.QueryRow(`
+		SELECT id, items, expires_at <= NOW(), restored_at IS NOT NULL
+		FROM cart_recoveries WHERE token = $1 AND user_id = $2
+		FOR UPDATE
+	`, token, userID).Scan(&recoveryID, &itemsJSON, &expired, &restored)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("restore link not found")
+		}
+		return nil, fmt.Errorf("failed to load cart recovery: %w", err)
+	}
+	if restored {
+		return nil, errors.New("restore link already used")
+	}
+	if expired {
+		return nil, errors.New("restore link has expired")
+	}
+
+	var items []recoveryItem
+	if err := json.Unmarshal(itemsJSON, &items); err != nil {
+		return nil, fmt.Errorf("failed to decode cart items: %w", err)
+	}
+
+	// The cart may have expired since the notification
+	var cartID uint
+	err = tx.QueryRow(`
+		INSERT INTO carts (user_id, created_at, updated_at) VALUES ($1, NOW(), NOW())
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()
+		RETURNING id
+	`, userID).Scan(&cartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cart: %w", err)
+	}
+
+	for _, item := range items {
+		_, err := tx.Exec(`
+			INSERT INTO cart_items (cart_id, product_id, quantity, added_price, seen_price, created_at, updated_at)
+			SELECT $1, id, $3, `+productEffectivePriceExpr("")+`, `+productEffectivePriceExpr("")+`, NOW(), NOW()
+			FROM products WHERE id = $2 AND `+productVisibleCondition("")+`
+			ON CONFLICT (cart_id, product_id) DO UPDATE SET quantity = GREATEST(cart_items.quantity, EXCLUDED.quantity), updated_at = NOW()
+		`, cartID, item.ProductID, item.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restore cart item: %w", err)
+		}
+	}
+
+	_, err = tx.Exec("UPDATE cart_recoveries SET restored_at = NOW() WHERE id = $1", recoveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update cart recovery: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return NewCartService().GetCart(CartOwner{UserID: userID})
+}
+
+// GetAbandonedCartReport summarizes abandoned carts, listing up to limit of the most valuable,
+// and the recovery notifications of the last days
+func (s *CartRecoveryService) GetAbandonedCartReport(days, limit int) (*models.AbandonedCartReport, error) {
+	report := &models.AbandonedCartReport{
+		AbandonedAfterHours: s.abandonedAfter.Hours(),
+		RecoveryDays:        days,
+		Carts:               []models.AbandonedCart{},
+	}
+
+	err := s.db.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE user_id IS NULL), COALESCE(SUM(total_items), 0), COALESCE(SUM(total_value), 0)
+		FROM (`+abandonedCartsQuery("")+`) AS abandoned
+	`, s.abandonedAfter.Seconds()).Scan(&report.AbandonedCarts, &report.GuestCarts, &report.TotalItems, &report.TotalValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize abandoned carts: %w", err)
+	}
+	report.TotalValue = roundCents(report.TotalValue)
+
+	rows, err := s.db.Query(abandonedCartsQuery("")+" ORDER BY total_value DESC, c.id LIMIT $2", s.abandonedAfter.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query abandoned carts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		cart, err := scanAbandonedCart(rows)
+		if err != nil {
+			return nil, err
+		}
+		report.Carts = append(report.Carts, cart)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating abandoned carts: %w", err)
+	}
+
+	err = s.db.QueryRow(`
+		SELECT COUNT(*), COUNT(restored_at), COALESCE(SUM(total_value) FILTER (WHERE restored_at IS NOT NULL), 0)
+		FROM cart_recoveries
+		WHERE created_at >= NOW() - $1 * INTERVAL '1 day'
+	`, days).Scan(&report.RecoveriesSent, &report.CartsRestored, &report.RestoredValue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize cart recoveries: %w", err)
+	}
+
+	return report, nil
+}
+
+// StartWorker deletes expired carts and sends abandoned cart notifications now and then
+// periodically in the background. The interval is configured with CART_RECOVERY_INTERVAL (default 15m).
+func (s *CartRecoveryService) StartWorker() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			deleted, err := s.DeleteExpiredCarts()
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Deleted %d expired carts", deleted)
+			}
+
+			sent, err := s.NotifyAbandonedCarts()
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			} else if sent > 0 {
+				log.Printf("Sent %d abandoned cart notifications", sent)
+			}
+			<-ticker.C
+		}
+	}()
+}
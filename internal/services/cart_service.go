@@ -4,9 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
 
 	"github.com/Code-byme/e-commerce/internal/database"
 	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/shipping"
+	"github.com/Code-byme/e-commerce/internal/tax"
+	"github.com/lib/pq"
 )
 
 // CartService handles cart operations
@@ -21,6 +25,17 @@ func NewCartService() *CartService {
 	}
 }
 
+// CartOwner identifies a cart: the authenticated user's cart, or a guest cart by its signed token
+type CartOwner struct {
+	UserID     uint
+	GuestToken string
+}
+
+// IsGuest reports whether the cart belongs to an anonymous visitor
+func (o CartOwner) IsGuest() bool {
+	return o.UserID == 0
+}
+
 // AddToCartRequest represents the request to add an item to cart
 type AddToCartRequest struct {
 	ProductID uint `json:"product_id" binding:"required"`
@@ -32,36 +47,122 @@ type UpdateCartItemRequest struct {
 	Quantity int `json:"quantity" binding:"required,gt=0"`
 }
 
-// GetOrCreateCart gets the user's cart or creates a new one
-func (s *CartService) GetOrCreateCart(userID uint) (*models.Cart, error) {
-	// Try to get existing cart
+// CheckoutRequest represents the request to check out the cart
+type CheckoutRequest struct {
+	ShippingAddress string `json:"shipping_address" binding:"required"`
+	PaymentMethod   string `json:"payment_method" binding:"required"`
+
+	// Structured destination used to quote shipping and calculate tax
+	ShippingCountry    string `json:"shipping_country" binding:"required,len=2"`
+	ShippingRegion     string `json:"shipping_region"`
+	ShippingPostalCode string `json:"shipping_postal_code"`
+
+	// ShippingMethod is the code of a quoted shipping method; the cheapest quote is used when omitted
+	ShippingMethod string `json:"shipping_method"`
+
+	// Email is required for guest checkout; the order is recorded under a guest account for it
+	Email string `json:"email" binding:"omitempty,email"`
+
+	// AcceptPriceChanges confirms checkout at current prices when they changed since the cart was last viewed
+	AcceptPriceChanges bool `json:"accept_price_changes"`
+}
+
+// PriceChangeError rejects a checkout whose prices changed since the customer last viewed the cart
+type PriceChangeError struct {
+	Changes []models.CartPriceChange
+}
+
+// Error implements the error interface
+func (e *PriceChangeError) Error() string {
+	return "prices changed since the cart was last viewed"
+}
+
+// ShippingQuoteRequest represents the request to quote shipping for the cart
+type ShippingQuoteRequest struct {
+	Country    string `json:"country" binding:"required,len=2"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+}
+
+// GetOrCreateCart gets the owner's cart or creates a new one. A new guest cart is created when
+// the guest has none, or their token no longer names a cart.
+func (s *CartService) GetOrCreateCart(owner CartOwner) (*models.Cart, error) {
+	return s.findCart(owner, true)
+}
+
+// findCart loads the owner's cart. Users always get a cart; for guests a cart is only created
+// when create is set, and nil is returned when they have none.
+func (s *CartService) findCart(owner CartOwner, create bool) (*models.Cart, error) {
 	var cart models.Cart
-	err := s.db.QueryRow(
-		"SELECT id, user_id, created_at, updated_at FROM carts WHERE user_id = $1",
-		userID,
-	).Scan(&cart.ID, &cart.UserID, &cart.CreatedAt, &cart.UpdatedAt)
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// Create new cart
-			err = s.db.QueryRow(
-				"INSERT INTO carts (user_id, created_at, updated_at) VALUES ($1, NOW(), NOW()) RETURNING id, user_id, created_at, updated_at",
-				userID,
-			).Scan(&cart.ID, &cart.UserID, &cart.CreatedAt, &cart.UpdatedAt)
+	if !owner.IsGuest() {
+		// Try to get existing cart
+		err := s.db.QueryRow(
+			"SELECT id, user_id, coupon_id, created_at, updated_at FROM carts WHERE user_id = $1",
+			owner.UserID,
+		).Scan(&cart.ID, &cart.UserID, &cart.CouponID, &cart.CreatedAt, &cart.UpdatedAt)
 
-			if err != nil {
-				return nil, fmt.Errorf("failed to create cart: %w", err)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				// Create new cart
+				err = s.db.QueryRow(
+					"INSERT INTO carts (user_id, created_at, updated_at) VALUES ($1, NOW(), NOW()) RETURNING id, user_id, created_at, updated_at",
+					owner.UserID,
+				).Scan(&cart.ID, &cart.UserID, &cart.CreatedAt, &cart.UpdatedAt)
+
+				if err != nil {
+					return nil, fmt.Errorf("failed to create cart: %w", err)
+				}
+			} else {
+				return nil, fmt.Errorf("database error: %w", err)
 			}
-		} else {
+		}
+
+		return &cart, nil
+	}
+
+	if owner.GuestToken != "" {
+		key, err := VerifyCartToken(owner.GuestToken)
+		if err != nil {
+			return nil, err
+		}
+
+		err = s.db.QueryRow(
+			"SELECT id, coupon_id, created_at, updated_at FROM carts WHERE guest_key = $1 AND user_id IS NULL",
+			key,
+		).Scan(&cart.ID, &cart.CouponID, &cart.CreatedAt, &cart.UpdatedAt)
+		if err == nil {
+			cart.GuestToken = owner.GuestToken
+			return &cart, nil
+		}
+		if err != sql.ErrNoRows {
 			return nil, fmt.Errorf("database error: %w", err)
 		}
+		// The cart was merged into an account or has expired
+	}
+
+	if !create {
+		return nil, nil
+	}
+
+	key, token, err := newGuestCartToken()
+	if err != nil {
+		return nil, err
 	}
+	err = s.db.QueryRow(
+		"INSERT INTO carts (guest_key, created_at, updated_at) VALUES ($1, NOW(), NOW()) RETURNING id, created_at, updated_at",
+		key,
+	).Scan(&cart.ID, &cart.CreatedAt, &cart.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cart: %w", err)
+	}
+	cart.GuestToken = token
 
 	return &cart, nil
 }
 
-// AddToCart adds an item to the user's cart
-func (s *CartService) AddToCart(userID uint, req *AddToCartRequest) (*models.CartResponse, error) {
+// AddToCart adds an item to the owner's cart
+func (s *CartService) AddToCart(owner CartOwner, req *AddToCartRequest) (*models.CartResponse, error) {
 	// Start a transaction
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -69,96 +170,126 @@ func (s *CartService) AddToCart(userID uint, req *AddToCartRequest) (*models.Car
 	}
 	defer tx.Rollback()
 
-	// Get or create cart
-	cart, err := s.GetOrCreateCart(userID)
+	// Get or create cart; a new guest cart comes with a new token
+	cart, err := s.GetOrCreateCart(owner)
 	if err != nil {
 		return nil, err
 	}
+	owner.GuestToken = cart.GuestToken
+
+	if err := addCartItem(tx, cart.ID, req.ProductID, req.Quantity); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
+	// Return updated cart
+	return s.GetCart(owner)
+}
+
+// addCartItem adds a quantity of a visible product to a cart, merging it into an existing
+// line and checking stock
+func addCartItem(q querier, cartID, productID uint, quantity int) error {
 	// Validate product exists and is active
 	var product models.Product
-	err = tx.QueryRow(
-		"SELECT id, name, price, stock FROM products WHERE id = $1 AND is_active = true",
-		req.ProductID,
-	).Scan(&product.ID, &product.Name, &product.Price, &product.Stock)
+	err := q.QueryRow(
+		"SELECT id, name, price, stock, "+productEffectivePriceExpr("")+" FROM products WHERE id = $1 AND "+productVisibleCondition(""),
+		productID,
+	).Scan(&product.ID, &product.Name, &product.Price, &product.Stock, &product.EffectivePrice)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("product not found or inactive")
+			return errors.New("product not found or inactive")
 		}
-		return nil, fmt.Errorf("failed to get product: %w", err)
+		return fmt.Errorf("failed to get product: %w", err)
 	}
 
 	// Check if item already exists in cart
 	var existingItem models.CartItem
-	err = tx.QueryRow(
+	err = q.QueryRow(
 		"SELECT id, quantity FROM cart_items WHERE cart_id = $1 AND product_id = $2",
-		cart.ID, req.ProductID,
+		cartID, productID,
 	).Scan(&existingItem.ID, &existingItem.Quantity)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// Item doesn't exist, check stock availability
-			if req.Quantity > product.Stock {
-				return nil, fmt.Errorf("insufficient stock for product %s (available: %d, requested: %d)",
-					product.Name, product.Stock, req.Quantity)
+			if quantity > product.Stock {
+				return fmt.Errorf("insufficient stock for product %s (available: %d, requested: %d)",
+					product.Name, product.Stock, quantity)
 			}
 
-			// Add new item to cart
-			_, err = tx.Exec(
-				"INSERT INTO cart_items (cart_id, product_id, quantity, created_at, updated_at) VALUES ($1, $2, $3, NOW(), NOW())",
-				cart.ID, req.ProductID, req.Quantity,
+			// Add new item to cart, remembering its price; checkout compares against it even if
+			// the cart is never viewed
+			_, err = q.Exec(
+				"INSERT INTO cart_items (cart_id, product_id, quantity, added_price, seen_price, created_at, updated_at) VALUES ($1, $2, $3, $4, $4, NOW(), NOW())",
+				cartID, productID, quantity, product.EffectivePrice,
 			)
 			if err != nil {
-				return nil, fmt.Errorf("failed to add item to cart: %w", err)
+				return fmt.Errorf("failed to add item to cart: %w", err)
 			}
 		} else {
-			return nil, fmt.Errorf("database error: %w", err)
+			return fmt.Errorf("database error: %w", err)
 		}
 	} else {
 		// Item exists, update quantity
-		newQuantity := existingItem.Quantity + req.Quantity
+		newQuantity := existingItem.Quantity + quantity
 		if newQuantity > product.Stock {
-			return nil, fmt.Errorf("insufficient stock for product %s (available: %d, requested: %d)",
+			return fmt.Errorf("insufficient stock for product %s (available: %d, requested: %d)",
 				product.Name, product.Stock, newQuantity)
 		}
 
-		_, err = tx.Exec(
-			"UPDATE cart_items SET quantity = $1, updated_at = NOW() WHERE id = $2",
-			newQuantity, existingItem.ID,
+		_, err = q.Exec(
+			"UPDATE cart_items SET quantity = $1, seen_price = COALESCE(seen_price, $2), updated_at = NOW() WHERE id = $3",
+			newQuantity, product.EffectivePrice, existingItem.ID,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update cart item: %w", err)
+			return fmt.Errorf("failed to update cart item: %w", err)
 		}
 	}
 
 	// Update cart timestamp
-	_, err = tx.Exec("UPDATE carts SET updated_at = NOW() WHERE id = $1", cart.ID)
+	_, err = q.Exec("UPDATE carts SET updated_at = NOW() WHERE id = $1", cartID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update cart: %w", err)
+		return fmt.Errorf("failed to update cart: %w", err)
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
+	return nil
+}
 
-	// Return updated cart
-	return s.GetCart(userID)
+// GetCart retrieves the owner's cart with items and calculated totals
+func (s *CartService) GetCart(owner CartOwner) (*models.CartResponse, error) {
+	return s.getCart(owner, nil, "")
 }
 
-// GetCart retrieves the user's cart with items and calculated totals
-func (s *CartService) GetCart(userID uint) (*models.CartResponse, error) {
+// GetCartForAddress retrieves the owner's cart with totals including shipping and tax for an address.
+// The shipping method is chosen by code; the cheapest quote is used when it is empty.
+func (s *CartService) GetCartForAddress(owner CartOwner, address tax.Address, shippingMethod string) (*models.CartResponse, error) {
+	address.Normalize()
+	return s.getCart(owner, &address, shippingMethod)
+}
+
+// getCart retrieves the owner's cart priced by the same pipeline as orders. Shipping and tax are
+// only included when an address is given. A guest without a cart gets an empty one.
+func (s *CartService) getCart(owner CartOwner, address *tax.Address, shippingMethod string) (*models.CartResponse, error) {
 	// Get cart
-	cart, err := s.GetOrCreateCart(userID)
+	cart, err := s.findCart(owner, false)
 	if err != nil {
 		return nil, err
 	}
+	if cart == nil {
+		return &models.CartResponse{PricesIncludeTax: tax.PricesIncludeTax()}, nil
+	}
 
 	// Get cart items with product details
 	itemsQuery := `
-		SELECT ci.id, ci.cart_id, ci.product_id, ci.quantity, ci.created_at, ci.updated_at,
-		       p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, p.is_active, p.created_at, p.updated_at
+		SELECT ci.id, ci.cart_id, ci.product_id, ci.quantity, ci.added_price, ci.created_at, ci.updated_at,
+		       p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, p.is_active, p.created_at, p.updated_at,
+		       p.compare_at_price, p.sale_price, p.sale_starts_at, p.sale_ends_at, p.tax_class, p.weight, p.length, p.width, p.height,
+		       ` + productVisibleCondition("p.") + `
 		FROM cart_items ci
 		JOIN products p ON ci.product_id = p.id
 		WHERE ci.cart_id = $1
@@ -173,43 +304,192 @@ func (s *CartService) GetCart(userID uint) (*models.CartResponse, error) {
 
 	var cartItems []models.CartItem
 	var totalItems int
-	var totalAmount float64
+	var items []pricingItem
+	var hasWarnings bool
 
 	for rows.Next() {
 		var item models.CartItem
+		var available bool
 		err := rows.Scan(
-			&item.ID, &item.CartID, &item.ProductID, &item.Quantity,
+			&item.ID, &item.CartID, &item.ProductID, &item.Quantity, &item.AddedPrice,
 			&item.CreatedAt, &item.UpdatedAt,
 			&item.Product.ID, &item.Product.Name, &item.Product.Description, &item.Product.Price,
 			&item.Product.Stock, &item.Product.CategoryID, &item.Product.ImageURL, &item.Product.IsActive,
 			&item.Product.CreatedAt, &item.Product.UpdatedAt,
+			&item.Product.CompareAtPrice, &item.Product.SalePrice, &item.Product.SaleStartsAt, &item.Product.SaleEndsAt, &item.Product.TaxClass,
+			&item.Product.Weight, &item.Product.Length, &item.Product.Width, &item.Product.Height,
+			&available,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan cart item: %w", err)
 		}
+		applyEffectivePrice(&item.Product)
+		item.Warnings = cartItemWarnings(&item, available)
+		hasWarnings = hasWarnings || len(item.Warnings) > 0
 
 		cartItems = append(cartItems, item)
 		totalItems += item.Quantity
-		totalAmount += item.Product.Price * float64(item.Quantity)
+		items = append(items, pricingItem{Product: item.Product, Quantity: item.Quantity})
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating cart items: %w", err)
 	}
 
-	return &models.CartResponse{
-		ID:          cart.ID,
-		UserID:      cart.UserID,
-		CartItems:   cartItems,
-		TotalItems:  totalItems,
-		TotalAmount: totalAmount,
-		CreatedAt:   cart.CreatedAt,
-		UpdatedAt:   cart.UpdatedAt,
-	}, nil
+	// Remember the prices shown so checkout can detect changes
+	if err := recordSeenPrices(s.db, cartItems); err != nil {
+		return nil, err
+	}
+
+	// Load the cart's coupon; a coupon that no longer qualifies stays attached and reports why
+	var coupon *models.Coupon
+	if cart.CouponID != nil {
+		coupon, err = loadCoupon(s.db, "id = $1", *cart.CouponID, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pricing, err := priceItems(s.db, &pricingRequest{
+		UserID:         owner.UserID,
+		Items:          items,
+		Coupon:         coupon,
+		Address:        address,
+		ShippingMethod: shippingMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Show each line's share of promotions and tax
+	for i := range cartItems {
+		cartItems[i].DiscountAmount = pricing.Lines[i].Discount
+		cartItems[i].TaxAmount = pricing.lineTaxAmount(i)
+	}
+
+	response := &models.CartResponse{
+		ID:               cart.ID,
+		UserID:           cart.UserID,
+		HasWarnings:      hasWarnings,
+		CartToken:        cart.GuestToken,
+		CartItems:        cartItems,
+		TotalItems:       totalItems,
+		TotalAmount:      pricing.TotalAmount,
+		SubtotalAmount:   pricing.SubtotalAmount,
+		DiscountAmount:   pricing.DiscountAmount,
+		FreeShipping:     pricing.FreeShipping,
+		Discounts:        pricing.Discounts,
+		ShippingAmount:   pricing.ShippingAmount,
+		TaxAmount:        pricing.TaxAmount,
+		PricesIncludeTax: pricing.PricesIncludeTax,
+		CreatedAt:        cart.CreatedAt,
+		UpdatedAt:        cart.UpdatedAt,
+	}
+	if pricing.Shipping != nil {
+		response.ShippingMethod = pricing.Shipping.Method
+	}
+	if coupon != nil {
+		response.CouponCode = coupon.Code
+		if pricing.CouponError != nil {
+			response.CouponError = pricing.CouponError.Error()
+		}
+	}
+
+	return response, nil
+}
+
+// ApplyCoupon validates a coupon code against the owner's cart and attaches it. Per-customer
+// limits are checked for guests again at checkout, once their account is known.
+func (s *CartService) ApplyCoupon(owner CartOwner, code string) (*models.CartResponse, error) {
+	cart, err := s.GetCart(owner)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.CartItems) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	coupon, err := loadCoupon(s.db, "code = $1", normalizeCouponCode(code), false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Evaluate against the cart lines after promotions
+	lines := make([]pricedLine, 0, len(cart.CartItems))
+	for _, item := range cart.CartItems {
+		lines = append(lines, pricedLine{
+			ProductID:  item.ProductID,
+			CategoryID: item.Product.CategoryID,
+			UnitPrice:  item.Product.EffectivePrice,
+			Quantity:   item.Quantity,
+			Amount:     item.Product.EffectivePrice * float64(item.Quantity),
+			Discount:   item.DiscountAmount,
+		})
+	}
+	if _, err := evaluateUserCoupon(s.db, coupon, owner.UserID, lines); err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec("UPDATE carts SET coupon_id = $1, updated_at = NOW() WHERE id = $2", coupon.ID, cart.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply coupon: %w", err)
+	}
+
+	return s.GetCart(owner)
+}
+
+// RemoveCoupon detaches the coupon from the owner's cart
+func (s *CartService) RemoveCoupon(owner CartOwner) (*models.CartResponse, error) {
+	cart, err := s.findCart(owner, false)
+	if err != nil {
+		return nil, err
+	}
+	if cart != nil {
+		_, err = s.db.Exec("UPDATE carts SET coupon_id = NULL, updated_at = NOW() WHERE id = $1", cart.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove coupon: %w", err)
+		}
+	}
+
+	return s.GetCart(owner)
+}
+
+// QuoteShipping returns the shipping options for the owner's cart at an address
+func (s *CartService) QuoteShipping(owner CartOwner, req *ShippingQuoteRequest) ([]ShippingQuote, error) {
+	cart, err := s.GetCart(owner)
+	if err != nil {
+		return nil, err
+	}
+	if len(cart.CartItems) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	items := make([]shipping.Item, 0, len(cart.CartItems))
+	for _, item := range cart.CartItems {
+		items = append(items, shipping.Item{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Weight:    item.Product.Weight,
+			Length:    item.Product.Length,
+			Width:     item.Product.Width,
+			Height:    item.Product.Height,
+		})
+	}
+
+	address := shipping.Address{Country: req.Country, Region: req.Region, PostalCode: req.PostalCode}
+	return quoteShipping(address, items, cart.SubtotalAmount-cart.DiscountAmount, cart.FreeShipping)
 }
 
 // UpdateCartItem updates the quantity of a cart item
-func (s *CartService) UpdateCartItem(userID uint, itemID uint, req *UpdateCartItemRequest) (*models.CartResponse, error) {
+func (s *CartService) UpdateCartItem(owner CartOwner, itemID uint, req *UpdateCartItemRequest) (*models.CartResponse, error) {
+	cart, err := s.findCart(owner, false)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, errors.New("cart item not found or not owned by user")
+	}
+
 	// Start a transaction
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -223,9 +503,8 @@ func (s *CartService) UpdateCartItem(userID uint, itemID uint, req *UpdateCartIt
 	err = tx.QueryRow(`
 		SELECT ci.id, ci.cart_id, ci.product_id, ci.quantity
 		FROM cart_items ci
-		JOIN carts c ON ci.cart_id = c.id
-		WHERE ci.id = $1 AND c.user_id = $2
-	`, itemID, userID).Scan(&cartItem.ID, &cartID, &cartItem.ProductID, &cartItem.Quantity)
+		WHERE ci.id = $1 AND ci.cart_id = $2
+	`, itemID, cart.ID).Scan(&cartItem.ID, &cartID, &cartItem.ProductID, &cartItem.Quantity)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -237,7 +516,7 @@ func (s *CartService) UpdateCartItem(userID uint, itemID uint, req *UpdateCartIt
 	// Check product stock
 	var product models.Product
 	err = tx.QueryRow(
-		"SELECT id, name, price, stock FROM products WHERE id = $1 AND is_active = true",
+		"SELECT id, name, price, stock FROM products WHERE id = $1 AND "+productVisibleCondition(""),
 		cartItem.ProductID,
 	).Scan(&product.ID, &product.Name, &product.Price, &product.Stock)
 
@@ -275,11 +554,19 @@ func (s *CartService) UpdateCartItem(userID uint, itemID uint, req *UpdateCartIt
 	}
 
 	// Return updated cart
-	return s.GetCart(userID)
+	return s.GetCart(owner)
 }
 
 // RemoveFromCart removes an item from the cart
-func (s *CartService) RemoveFromCart(userID uint, itemID uint) (*models.CartResponse, error) {
+func (s *CartService) RemoveFromCart(owner CartOwner, itemID uint) (*models.CartResponse, error) {
+	cart, err := s.findCart(owner, false)
+	if err != nil {
+		return nil, err
+	}
+	if cart == nil {
+		return nil, errors.New("cart item not found or not owned by user")
+	}
+
 	// Start a transaction
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -292,9 +579,8 @@ func (s *CartService) RemoveFromCart(userID uint, itemID uint) (*models.CartResp
 	err = tx.QueryRow(`
 		SELECT ci.cart_id
 		FROM cart_items ci
-		JOIN carts c ON ci.cart_id = c.id
-		WHERE ci.id = $1 AND c.user_id = $2
-	`, itemID, userID).Scan(&cartID)
+		WHERE ci.id = $1 AND ci.cart_id = $2
+	`, itemID, cart.ID).Scan(&cartID)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -321,74 +607,375 @@ func (s *CartService) RemoveFromCart(userID uint, itemID uint) (*models.CartResp
 	}
 
 	// Return updated cart
-	return s.GetCart(userID)
+	return s.GetCart(owner)
 }
 
-// ClearCart removes all items from the user's cart
-func (s *CartService) ClearCart(userID uint) error {
+// ClearCart removes all items from the owner's cart
+func (s *CartService) ClearCart(owner CartOwner) error {
 	// Get cart
-	cart, err := s.GetOrCreateCart(userID)
-	if err != nil {
+	cart, err := s.findCart(owner, false)
+	if err != nil || cart == nil {
 		return err
 	}
 
-	// Remove all cart items
-	_, err = s.db.Exec("DELETE FROM cart_items WHERE cart_id = $1", cart.ID)
+	return clearCart(s.db, cart.ID)
+}
+
+// CheckoutCart converts the cart into an order in a single transaction: prices are revalidated,
+// stock is reserved, the order is created and the cart is cleared together. Checkout is rejected
+// with a PriceChangeError when prices changed since the cart was last viewed, unless the request
+// accepts them. The cart is restored if the payment then fails. Guests check out with an email
+// address, and the order is placed under a guest account for it.
+func (s *CartService) CheckoutCart(owner CartOwner, req *CheckoutRequest) (*models.Order, error) {
+	if owner.IsGuest() && req.Email == "" {
+		return nil, errors.New("email is required for guest checkout")
+	}
+
+	cart, err := s.findCart(owner, false)
 	if err != nil {
-		return fmt.Errorf("failed to clear cart: %w", err)
+		return nil, err
+	}
+	if cart == nil {
+		return nil, errors.New("cart is empty")
 	}
 
-	// Update cart timestamp
-	_, err = s.db.Exec("UPDATE carts SET updated_at = NOW() WHERE id = $1", cart.ID)
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to update cart: %w", err)
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	return nil
-}
+	// Lock the cart so concurrent checkouts cannot order it twice
+	var couponID *uint
+	if err := tx.QueryRow("SELECT coupon_id FROM carts WHERE id = $1 FOR UPDATE", cart.ID).Scan(&couponID); err != nil {
+		return nil, fmt.Errorf("failed to lock cart: %w", err)
+	}
 
-// CheckoutCart converts cart items to order items and clears the cart
-func (s *CartService) CheckoutCart(userID uint, shippingAddress, paymentMethod string) (*models.Order, error) {
-	// Get cart with items
-	cart, err := s.GetCart(userID)
+	// Revalidate prices against the ones the customer last saw, locking the products
+	rows, err := tx.Query(`
+		SELECT ci.id, ci.product_id, ci.quantity, ci.seen_price, ci.added_price, p.name, `+productEffectivePriceExpr("p.")+`
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		WHERE ci.cart_id = $1
+		ORDER BY p.id
+		FOR UPDATE OF p
+	`, cart.ID)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to query cart items: %w", err)
+	}
+
+	var orderItems []CreateOrderItemRequest
+	var checkedOut []checkedOutItem
+	var changes []models.CartPriceChange
+	for rows.Next() {
+		var change models.CartPriceChange
+		var quantity int
+		var seenPrice, addedPrice sql.NullFloat64
+		err := rows.Scan(&change.CartItemID, &change.ProductID, &quantity, &seenPrice, &addedPrice, &change.ProductName, &change.CurrentPrice)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		orderItems = append(orderItems, CreateOrderItemRequest{ProductID: change.ProductID, Quantity: quantity})
+		checkedOut = append(checkedOut, checkedOutItem{
+			productID: change.ProductID, quantity: quantity, seenPrice: seenPrice, addedPrice: addedPrice,
+		})
+		if seenPrice.Valid && roundCents(seenPrice.Float64) != roundCents(change.CurrentPrice) {
+			change.PreviousPrice = seenPrice.Float64
+			changes = append(changes, change)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cart items: %w", err)
 	}
 
 	// Check if cart is empty
-	if len(cart.CartItems) == 0 {
+	if len(orderItems) == 0 {
 		return nil, errors.New("cart is empty")
 	}
 
-	// Convert cart items to order items
-	var orderItems []CreateOrderItemRequest
-	for _, item := range cart.CartItems {
-		orderItems = append(orderItems, CreateOrderItemRequest{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-		})
+	if len(changes) > 0 && !req.AcceptPriceChanges {
+		return nil, &PriceChangeError{Changes: changes}
 	}
 
-	// Create order request
-	orderReq := &CreateOrderRequest{
-		ShippingAddress: shippingAddress,
-		PaymentMethod:   paymentMethod,
-		Items:           orderItems,
+	var couponCode string
+	if couponID != nil {
+		if err := tx.QueryRow("SELECT code FROM coupons WHERE id = $1", *couponID).Scan(&couponCode); err != nil {
+			return nil, fmt.Errorf("failed to load cart coupon: %w", err)
+		}
+	}
+
+	userID := owner.UserID
+	if owner.IsGuest() {
+		userID, err = guestCheckoutUser(tx, req.Email)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Create order using order service
+	// Create the order in the checkout transaction
 	orderService := NewOrderService()
-	order, err := orderService.CreateOrder(userID, orderReq)
+	order, err := orderService.placeOrder(tx, userID, &CreateOrderRequest{
+		ShippingAddress:    req.ShippingAddress,
+		PaymentMethod:      req.PaymentMethod,
+		Items:              orderItems,
+		CouponCode:         couponCode,
+		ShippingCountry:    req.ShippingCountry,
+		ShippingRegion:     req.ShippingRegion,
+		ShippingPostalCode: req.ShippingPostalCode,
+		ShippingMethod:     req.ShippingMethod,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Clear the cart after successful order creation
-	err = s.ClearCart(userID)
+	if err := clearCart(tx, cart.ID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	finalized, err := orderService.finalizeOrder(order)
+	if err != nil {
+		// If the order was cancelled, give the customer their cart back to try again
+		var placedErr *OrderPlacedError
+		if errors.As(err, &placedErr) && placedErr.Cancelled {
+			if restoreErr := restoreCart(s.db, cart.ID, couponID, checkedOut); restoreErr != nil {
+				log.Printf("Warning: failed to restore cart %d after failed checkout: %v", cart.ID, restoreErr)
+			}
+		}
+		return nil, err
+	}
+
+	return finalized, nil
+}
+
+// RevalidateCart fixes what the cart's warnings report: items no longer for sale or out of stock
+// are removed, quantities are reduced to the stock available and changed prices are accepted as
+// the items' new added prices. It returns the cart with the adjustments made.
+func (s *CartService) RevalidateCart(owner CartOwner) (*models.CartResponse, []models.CartAdjustment, error) {
+	adjustments := []models.CartAdjustment{}
+
+	cart, err := s.findCart(owner, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cart == nil {
+		response, err := s.GetCart(owner)
+		return response, adjustments, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT ci.id, ci.product_id, ci.quantity, ci.added_price, p.name, p.stock, `+productEffectivePriceExpr("p.")+`,
+		       `+productVisibleCondition("p.")+`
+		FROM cart_items ci
+		JOIN products p ON ci.product_id = p.id
+		WHERE ci.cart_id = $1
+		ORDER BY ci.created_at ASC
+		FOR UPDATE OF ci
+	`, cart.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query cart items: %w", err)
+	}
+
+	for rows.Next() {
+		var adjustment models.CartAdjustment
+		var addedPrice sql.NullFloat64
+		var stock int
+		var currentPrice float64
+		var available bool
+		err := rows.Scan(
+			&adjustment.CartItemID, &adjustment.ProductID, &adjustment.PreviousQuantity, &addedPrice,
+			&adjustment.ProductName, &stock, &currentPrice, &available,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan cart item: %w", err)
+		}
+		adjustment.Quantity = adjustment.PreviousQuantity
+		priceChanged := addedPrice.Valid && roundCents(addedPrice.Float64) != roundCents(currentPrice)
+		if priceChanged {
+			adjustment.PreviousPrice = addedPrice.Float64
+			adjustment.CurrentPrice = currentPrice
+		}
+
+		switch {
+		case !available || stock <= 0:
+			adjustment.Action = models.CartAdjustmentRemoved
+			adjustment.Quantity = 0
+		case adjustment.PreviousQuantity > stock:
+			adjustment.Action = models.CartAdjustmentQuantityReduced
+			adjustment.Quantity = stock
+		case priceChanged:
+			adjustment.Action = models.CartAdjustmentPriceUpdated
+		default:
+			continue
+		}
+		adjustments = append(adjustments, adjustment)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error iterating cart items: %w", err)
+	}
+
+	for _, adjustment := range adjustments {
+		if adjustment.Action == models.CartAdjustmentRemoved {
+			_, err = tx.Exec("DELETE FROM cart_items WHERE id = $1", adjustment.CartItemID)
+		} else {
+			// Accept the current price along with any quantity change
+			_, err = tx.Exec(`
+				UPDATE cart_items SET quantity = $1, added_price = `+productEffectivePriceExpr("p.")+`, updated_at = NOW()
+				FROM products p
+				WHERE cart_items.id = $2 AND p.id = cart_items.product_id
+			`, adjustment.Quantity, adjustment.CartItemID)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to update cart item: %w", err)
+		}
+	}
+
+	if len(adjustments) > 0 {
+		if _, err = tx.Exec("UPDATE carts SET updated_at = NOW() WHERE id = $1", cart.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to update cart: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	response, err := s.GetCart(owner)
+	if err != nil {
+		return nil, nil, err
+	}
+	return response, adjustments, nil
+}
+
+// cartItemWarnings reports the problems checkout would run into for a cart item, and a change of
+// its price since it was added. available tells whether the product is still for sale.
+func cartItemWarnings(item *models.CartItem, available bool) []models.CartItemWarning {
+	var warnings []models.CartItemWarning
+
+	if !available {
+		warnings = append(warnings, models.CartItemWarning{
+			Code:    models.CartWarningUnavailable,
+			Message: fmt.Sprintf("%s is no longer available", item.Product.Name),
+		})
+	} else if item.Quantity > item.Product.Stock {
+		stock := item.Product.Stock
+		if stock < 0 {
+			stock = 0
+		}
+		warnings = append(warnings, models.CartItemWarning{
+			Code:              models.CartWarningInsufficientStock,
+			Message:           fmt.Sprintf("only %d of %s available", stock, item.Product.Name),
+			AvailableQuantity: &stock,
+		})
+	}
+
+	if item.AddedPrice != nil && roundCents(*item.AddedPrice) != roundCents(item.Product.EffectivePrice) {
+		warnings = append(warnings, models.CartItemWarning{
+			Code:          models.CartWarningPriceChanged,
+			Message:       fmt.Sprintf("the price of %s changed since it was added", item.Product.Name),
+			PreviousPrice: *item.AddedPrice,
+			CurrentPrice:  item.Product.EffectivePrice,
+		})
+	}
+
+	return warnings
+}
+
+// clearCart removes all items and the coupon from a cart
+func clearCart(q querier, cartID uint) error {
+	// Remove all cart items
+	_, err := q.Exec("DELETE FROM cart_items WHERE cart_id = $1", cartID)
 	if err != nil {
-		// Log error but don't fail the checkout
-		fmt.Printf("Warning: Failed to clear cart after checkout: %v\n", err)
+		return fmt.Errorf("failed to clear cart: %w", err)
 	}
 
-	return order, nil
+	// Update cart timestamp and drop the coupon
+	_, err = q.Exec("UPDATE carts SET coupon_id = NULL, updated_at = NOW() WHERE id = $1", cartID)
+	if err != nil {
+		return fmt.Errorf("failed to update cart: %w", err)
+	}
+
+	return nil
+}
+
+// checkedOutItem is a cart line taken out of the cart by checkout, with the prices it was shown
+// and added at so a failed checkout can put it back as it was
+type checkedOutItem struct {
+	productID  uint
+	quantity   int
+	seenPrice  sql.NullFloat64
+	addedPrice sql.NullFloat64
+}
+
+// restoreCart puts checked out items and the coupon back into a cart, adding to anything
+// the customer placed in it since. Restored items keep their seen and added prices, so price
+// changes are still reported; products added again since keep the newer prices.
+func restoreCart(db *sql.DB, cartID uint, couponID *uint, items []checkedOutItem) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		_, err := tx.Exec(`
+			INSERT INTO cart_items (cart_id, product_id, quantity, seen_price, added_price, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+			ON CONFLICT (cart_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()
+		`, cartID, item.productID, item.quantity, item.seenPrice, item.addedPrice)
+		if err != nil {
+			return fmt.Errorf("failed to restore cart item: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE carts SET coupon_id = COALESCE(coupon_id, $1), updated_at = NOW() WHERE id = $2", couponID, cartID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update cart: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// recordSeenPrices stores the price each cart item was last shown at
+func recordSeenPrices(q querier, items []models.CartItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(items))
+	prices := make([]float64, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, int64(item.ID))
+		prices = append(prices, item.Product.EffectivePrice)
+	}
+
+	_, err := q.Exec(`
+		UPDATE cart_items ci
+		SET seen_price = v.price
+		FROM unnest($1::int[], $2::numeric[]) AS v(id, price)
+		WHERE ci.id = v.id AND ci.seen_price IS DISTINCT FROM v.price
+	`, pq.Array(ids), pq.Array(prices))
+	if err != nil {
+		return fmt.Errorf("failed to record cart prices: %w", err)
+	}
+	return nil
 }
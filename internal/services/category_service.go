@@ -219,7 +219,7 @@ func (s *CategoryService) GetCategoryWithProductCount(id uint) (*models.Category
 		SELECT c.id, c.name, c.description, c.created_at, c.updated_at,
 		       COUNT(p.id) as product_count
 		FROM categories c
-		LEFT JOIN products p ON c.id = p.category_id AND p.is_active = true
+		LEFT JOIN products p ON c.id = p.category_id AND ` + productVisibleCondition("p.") + `
 		WHERE c.id = $1
 		GROUP BY c.id, c.name, c.description, c.created_at, c.updated_at
 	`
@@ -0,0 +1,522 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/lib/pq"
+)
+
+// CouponService handles coupon management
+type CouponService struct {
+	db *sql.DB
+}
+
+// NewCouponService creates a new coupon service
+func NewCouponService() *CouponService {
+	return &CouponService{
+		db: database.GetDB(),
+	}
+}
+
+// CouponRequest represents the request to create or replace a coupon
+type CouponRequest struct {
+	Code              string     `json:"code" binding:"required,max=50"`
+	Description       string     `json:"description"`
+	Type              string     `json:"type" binding:"required,oneof=percentage fixed_amount free_shipping"`
+	Value             float64    `json:"value" binding:"gte=0"`
+	MinSubtotal       float64    `json:"min_subtotal" binding:"gte=0"`
+	StartsAt          *time.Time `json:"starts_at"`
+	EndsAt            *time.Time `json:"ends_at"`
+	UsageLimit        *int       `json:"usage_limit" binding:"omitempty,gt=0"`
+	UsageLimitPerUser *int       `json:"usage_limit_per_user" binding:"omitempty,gt=0"`
+	IsActive          *bool      `json:"is_active"`
+	ProductIDs        []int64    `json:"product_ids"`
+	CategoryIDs       []int64    `json:"category_ids"`
+}
+
+// CouponListResponse represents the paginated coupon list response
+type CouponListResponse struct {
+	Coupons []models.Coupon `json:"coupons"`
+	Total   int             `json:"total"`
+	Page    int             `json:"page"`
+	Limit   int             `json:"limit"`
+	Pages   int             `json:"pages"`
+}
+
+const couponColumns = `id, code, COALESCE(description, ''), type, value, min_subtotal, starts_at, ends_at,
+	usage_limit, usage_limit_per_user, usage_count, is_active, product_ids, category_ids, created_at, updated_at`
+
+// scanCoupon scans a coupon row selected with couponColumns
+func scanCoupon(scanner interface{ Scan(...interface{}) error }, coupon *models.Coupon) error {
+	var usageLimit, usageLimitPerUser sql.NullInt64
+	err := scanner.Scan(
+		&coupon.ID, &coupon.Code, &coupon.Description, &coupon.Type, &coupon.Value, &coupon.MinSubtotal,
+		&coupon.StartsAt, &coupon.EndsAt, &usageLimit, &usageLimitPerUser, &coupon.UsageCount, &coupon.IsActive,
+		pq.Array(&coupon.ProductIDs), pq.Array(&coupon.CategoryIDs), &coupon.CreatedAt, &coupon.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if usageLimit.Valid {
+		n := int(usageLimit.Int64)
+		coupon.UsageLimit = &n
+	}
+	if usageLimitPerUser.Valid {
+		n := int(usageLimitPerUser.Int64)
+		coupon.UsageLimitPerUser = &n
+	}
+	if coupon.ProductIDs == nil {
+		coupon.ProductIDs = []int64{}
+	}
+	if coupon.CategoryIDs == nil {
+		coupon.CategoryIDs = []int64{}
+	}
+	return nil
+}
+
+// normalizeCouponCode makes coupon codes case-insensitive
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validateCouponRequest checks the values of a coupon request
+func validateCouponRequest(req *CouponRequest) error {
+	req.Code = normalizeCouponCode(req.Code)
+	if req.Code == "" {
+		return errors.New("invalid coupon: code is required")
+	}
+
+	switch req.Type {
+	case models.CouponTypePercentage:
+		if req.Value <= 0 || req.Value > 100 {
+			return errors.New("invalid coupon: percentage value must be between 0 and 100")
+		}
+	case models.CouponTypeFixedAmount:
+		if req.Value <= 0 {
+			return errors.New("invalid coupon: fixed amount value must be greater than 0")
+		}
+	case models.CouponTypeFreeShipping:
+		req.Value = 0
+	default:
+		return errors.New("invalid coupon: unknown type")
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return errors.New("invalid coupon: ends_at must be after starts_at")
+	}
+	if req.ProductIDs == nil {
+		req.ProductIDs = []int64{}
+	}
+	if req.CategoryIDs == nil {
+		req.CategoryIDs = []int64{}
+	}
+
+	return nil
+}
+
+// CreateCoupon creates a new coupon
+func (s *CouponService) CreateCoupon(req *CouponRequest) (*models.Coupon, error) {
+	if err := validateCouponRequest(req); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM coupons WHERE code = $1)", req.Code).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if exists {
+		return nil, errors.New("coupon code already exists")
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var coupon models.Coupon
+	query := fmt.Sprintf(`
+		INSERT INTO coupons (code, description, type, value, min_subtotal, starts_at, ends_at, usage_limit,
+		                     usage_limit_per_user, is_active, product_ids, category_ids, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW())
+		RETURNING %s
+	`, couponColumns)
+	err := scanCoupon(s.db.QueryRow(
+		query,
+		req.Code, req.Description, req.Type, req.Value, req.MinSubtotal, req.StartsAt, req.EndsAt, req.UsageLimit,
+		req.UsageLimitPerUser, isActive, pq.Array(req.ProductIDs), pq.Array(req.CategoryIDs),
+	), &coupon)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+
+	return &coupon, nil
+}
+
+// GetCoupon retrieves a coupon by ID
+func (s *CouponService) GetCoupon(id uint) (*models.Coupon, error) {
+	return loadCoupon(s.db, "id = $1", id, false)
+}
+
+// UpdateCoupon replaces a coupon's settings; its usage count is kept
+func (s *CouponService) UpdateCoupon(id uint, req *CouponRequest) (*models.Coupon, error) {
+	if err := validateCouponRequest(req); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetCoupon(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Code != existing.Code {
+		var exists bool
+		if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM coupons WHERE code = $1 AND id != $2)", req.Code, id).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if exists {
+			return nil, errors.New("coupon code already exists")
+		}
+	}
+
+	isActive := existing.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var coupon models.Coupon
+	query := fmt.Sprintf(`
+		UPDATE coupons
+		SET code = $1, description = $2, type = $3, value = $4, min_subtotal = $5, starts_at = $6, ends_at = $7,
+		    usage_limit = $8, usage_limit_per_user = $9, is_active = $10, product_ids = $11, category_ids = $12,
+		    updated_at = NOW()
+		WHERE id = $13
+		RETURNING %s
+	`, couponColumns)
+	err = scanCoupon(s.db.QueryRow(
+		query,
+		req.Code, req.Description, req.Type, req.Value, req.MinSubtotal, req.StartsAt, req.EndsAt, req.UsageLimit,
+		req.UsageLimitPerUser, isActive, pq.Array(req.ProductIDs), pq.Array(req.CategoryIDs), id,
+	), &coupon)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, fmt.Errorf("failed to update coupon: %w", err)
+	}
+
+	return &coupon, nil
+}
+
+// DeleteCoupon deletes a coupon. Orders keep their discount lines.
+func (s *CouponService) DeleteCoupon(id uint) error {
+	result, err := s.db.Exec("DELETE FROM coupons WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete coupon: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("coupon not found")
+	}
+
+	return nil
+}
+
+// ListCoupons retrieves a paginated list of coupons, newest first
+func (s *CouponService) ListCoupons(page, limit int) (*CouponListResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM coupons").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count coupons: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM coupons ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2", couponColumns)
+	rows, err := s.db.Query(query, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query coupons: %w", err)
+	}
+	defer rows.Close()
+
+	coupons := []models.Coupon{}
+	for rows.Next() {
+		var coupon models.Coupon
+		if err := scanCoupon(rows, &coupon); err != nil {
+			return nil, fmt.Errorf("failed to scan coupon: %w", err)
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating coupons: %w", err)
+	}
+
+	return &CouponListResponse{
+		Coupons: coupons,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+		Pages:   (total + limit - 1) / limit,
+	}, nil
+}
+
+// loadCoupon loads a single coupon matching condition, optionally locking its row until the transaction ends
+func loadCoupon(q querier, condition string, arg interface{}, lock bool) (*models.Coupon, error) {
+	query := fmt.Sprintf("SELECT %s FROM coupons WHERE %s", couponColumns, condition)
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	var coupon models.Coupon
+	if err := scanCoupon(q.QueryRow(query, arg), &coupon); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("coupon not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &coupon, nil
+}
+
+// checkCouponUsable verifies that a coupon is active, inside its validity window and below its usage limits for the user
+func checkCouponUsable(q querier, coupon *models.Coupon, userID uint) error {
+	now := time.Now()
+	if !coupon.IsActive {
+		return errors.New("coupon is not active")
+	}
+	if coupon.StartsAt != nil && coupon.StartsAt.After(now) {
+		return errors.New("coupon is not valid yet")
+	}
+	if coupon.EndsAt != nil && !coupon.EndsAt.After(now) {
+		return errors.New("coupon has expired")
+	}
+	if coupon.UsageLimit != nil && coupon.UsageCount >= *coupon.UsageLimit {
+		return errors.New("coupon usage limit reached")
+	}
+
+	if coupon.UsageLimitPerUser != nil {
+		var used int
+		err := q.QueryRow(
+			"SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id = $1 AND user_id = $2",
+			coupon.ID, userID,
+		).Scan(&used)
+		if err != nil {
+			return fmt.Errorf("failed to count coupon redemptions: %w", err)
+		}
+		if used >= *coupon.UsageLimitPerUser {
+			return errors.New("coupon usage limit per user reached")
+		}
+	}
+
+	return nil
+}
+
+// evaluateCoupon returns the discount line a coupon gives on the given lines.
+// Only lines matching the coupon's product and category restrictions count toward the minimum spend and the discount,
+// at their amount after promotions.
+func evaluateCoupon(coupon *models.Coupon, lines []pricedLine) (*models.OrderDiscount, error) {
+	eligible := 0.0
+	for _, line := range lines {
+		if couponAppliesTo(coupon, line) {
+			eligible += line.remaining()
+		}
+	}
+
+	if eligible == 0 {
+		return nil, errors.New("coupon does not apply to any items")
+	}
+	if eligible < coupon.MinSubtotal {
+		return nil, fmt.Errorf("coupon requires a minimum spend of %.2f", coupon.MinSubtotal)
+	}
+
+	var amount float64
+	switch coupon.Type {
+	case models.CouponTypePercentage:
+		amount = roundCents(eligible * coupon.Value / 100)
+	case models.CouponTypeFixedAmount:
+		amount = math.Min(coupon.Value, eligible)
+	}
+
+	description := coupon.Description
+	if description == "" {
+		description = "Coupon " + coupon.Code
+	}
+	couponID := coupon.ID
+
+	return &models.OrderDiscount{
+		Source:      models.DiscountSourceCoupon,
+		CouponID:    &couponID,
+		Code:        coupon.Code,
+		Description: description,
+		Type:        coupon.Type,
+		Amount:      amount,
+	}, nil
+}
+
+// evaluateUserCoupon checks a coupon against the user's usage and evaluates it on the lines
+func evaluateUserCoupon(q querier, coupon *models.Coupon, userID uint, lines []pricedLine) (*models.OrderDiscount, error) {
+	if err := checkCouponUsable(q, coupon, userID); err != nil {
+		return nil, err
+	}
+	return evaluateCoupon(coupon, lines)
+}
+
+// allocateCouponDiscount spreads a coupon discount over the eligible lines in proportion to
+// their remaining amounts; the last eligible line absorbs the rounding difference
+func allocateCouponDiscount(coupon *models.Coupon, lines []pricedLine, amount float64) {
+	eligible := 0.0
+	last := -1
+	for i, line := range lines {
+		if couponAppliesTo(coupon, line) && line.remaining() > 0 {
+			eligible += line.remaining()
+			last = i
+		}
+	}
+	if eligible == 0 || amount == 0 {
+		return
+	}
+
+	allocated := 0.0
+	for i := range lines {
+		if !couponAppliesTo(coupon, lines[i]) || lines[i].remaining() <= 0 {
+			continue
+		}
+		share := roundCents(amount * lines[i].remaining() / eligible)
+		if i == last {
+			share = roundCents(amount - allocated)
+		}
+		lines[i].CouponDiscount = share
+		allocated += share
+	}
+}
+
+// couponAppliesTo reports whether a line matches a coupon's restrictions
+func couponAppliesTo(coupon *models.Coupon, line pricedLine) bool {
+	if len(coupon.ProductIDs) == 0 && len(coupon.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range coupon.ProductIDs {
+		if uint(id) == line.ProductID {
+			return true
+		}
+	}
+	for _, id := range coupon.CategoryIDs {
+		if uint(id) == line.CategoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// redeemCoupon counts a coupon use for an order. The increment is conditional on the global limit,
+// so concurrent checkouts can never exceed it; callers lock the coupon row to serialize per-user checks.
+func redeemCoupon(tx *sql.Tx, coupon *models.Coupon, userID, orderID uint, amount float64) error {
+	result, err := tx.Exec(`
+		UPDATE coupons
+		SET usage_count = usage_count + 1, updated_at = NOW()
+		WHERE id = $1 AND (usage_limit IS NULL OR usage_count < usage_limit)
+	`, coupon.ID)
+	if err != nil {
+		return fmt.Errorf("failed to count coupon usage: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("coupon usage limit reached")
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO coupon_redemptions (coupon_id, user_id, order_id, discount_amount, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+	`, coupon.ID, userID, orderID, amount)
+	if err != nil {
+		return fmt.Errorf("failed to record coupon redemption: %w", err)
+	}
+
+	return nil
+}
+
+// releaseCouponRedemptions gives back the coupon uses of a cancelled order
+func releaseCouponRedemptions(tx *sql.Tx, orderID uint) error {
+	_, err := tx.Exec(`
+		WITH released AS (
+			DELETE FROM coupon_redemptions WHERE order_id = $1 RETURNING coupon_id
+		)
+		UPDATE coupons c
+		SET usage_count = GREATEST(c.usage_count - r.uses, 0), updated_at = NOW()
+		FROM (SELECT coupon_id, COUNT(*) AS uses FROM released GROUP BY coupon_id) r
+		WHERE c.id = r.coupon_id
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to release coupon usage: %w", err)
+	}
+	return nil
+}
+
+// insertOrderDiscounts persists the discount lines of an order
+func insertOrderDiscounts(tx *sql.Tx, orderID uint, discounts []models.OrderDiscount) error {
+	for _, discount := range discounts {
+		_, err := tx.Exec(`
+			INSERT INTO order_discounts (order_id, source, coupon_id, promotion_id, code, description, type, amount, created_at)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, NOW())
+		`, orderID, discount.Source, discount.CouponID, discount.PromotionID, discount.Code, discount.Description,
+			discount.Type, discount.Amount)
+		if err != nil {
+			return fmt.Errorf("failed to create order discount: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadOrderDiscounts loads the discount lines of an order
+func loadOrderDiscounts(q querier, orderID uint) ([]models.OrderDiscount, error) {
+	rows, err := q.Query(`
+		SELECT id, order_id, source, coupon_id, promotion_id, COALESCE(code, ''), description, type, amount, created_at
+		FROM order_discounts
+		WHERE order_id = $1
+		ORDER BY id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order discounts: %w", err)
+	}
+	defer rows.Close()
+
+	var discounts []models.OrderDiscount
+	for rows.Next() {
+		var discount models.OrderDiscount
+		var couponID, promotionID sql.NullInt64
+		err := rows.Scan(
+			&discount.ID, &discount.OrderID, &discount.Source, &couponID, &promotionID, &discount.Code,
+			&discount.Description, &discount.Type, &discount.Amount, &discount.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order discount: %w", err)
+		}
+		if couponID.Valid {
+			id := uint(couponID.Int64)
+			discount.CouponID = &id
+		}
+		if promotionID.Valid {
+			id := uint(promotionID.Int64)
+			discount.PromotionID = &id
+		}
+		discounts = append(discounts, discount)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order discounts: %w", err)
+	}
+
+	return discounts, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+func TestEvaluateCoupon(t *testing.T) {
+	tests := []struct {
+		name   string
+		coupon models.Coupon
+		// promoted is the promotion discount already taken off the first line
+		promoted float64
+		amount   float64
+		err      string
+	}{
+		{
+			name:   "percentage of all lines",
+			coupon: models.Coupon{Type: models.CouponTypePercentage, Value: 10},
+			amount: 15,
+		},
+		{
+			name:   "percentage rounds to cents",
+			coupon: models.Coupon{Type: models.CouponTypePercentage, Value: 3.333},
+			amount: 5,
+		},
+		{
+			name:   "fixed amount",
+			coupon: models.Coupon{Type: models.CouponTypeFixedAmount, Value: 20},
+			amount: 20,
+		},
+		{
+			name:   "fixed amount is capped at the eligible lines",
+			coupon: models.Coupon{Type: models.CouponTypeFixedAmount, Value: 80, ProductIDs: []int64{2}},
+			amount: 50,
+		},
+		{
+			name:   "category restriction",
+			coupon: models.Coupon{Type: models.CouponTypePercentage, Value: 10, CategoryIDs: []int64{10}},
+			amount: 10,
+		},
+		{
+			name:   "product or category restriction",
+			coupon: models.Coupon{Type: models.CouponTypePercentage, Value: 10, ProductIDs: []int64{2}, CategoryIDs: []int64{10}},
+			amount: 15,
+		},
+		{
+			name:   "free shipping gives no item discount",
+			coupon: models.Coupon{Type: models.CouponTypeFreeShipping},
+			amount: 0,
+		},
+		{
+			name:     "promotion discounts are not discounted again",
+			coupon:   models.Coupon{Type: models.CouponTypePercentage, Value: 10},
+			promoted: 30,
+			amount:   12,
+		},
+		{
+			name:     "minimum spend counts the amount after promotions",
+			coupon:   models.Coupon{Type: models.CouponTypeFixedAmount, Value: 5, MinSubtotal: 150},
+			promoted: 0.01,
+			err:      "coupon requires a minimum spend of 150.00",
+		},
+		{
+			name:   "minimum spend counts eligible lines only",
+			coupon: models.Coupon{Type: models.CouponTypeFixedAmount, Value: 5, MinSubtotal: 60, ProductIDs: []int64{2}},
+			err:    "coupon requires a minimum spend of 60.00",
+		},
+		{
+			name:   "minimum spend met",
+			coupon: models.Coupon{Type: models.CouponTypeFixedAmount, Value: 5, MinSubtotal: 150},
+			amount: 5,
+		},
+		{
+			name:   "no matching lines",
+			coupon: models.Coupon{Type: models.CouponTypePercentage, Value: 10, ProductIDs: []int64{3}},
+			err:    "coupon does not apply to any items",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coupon := tt.coupon
+			coupon.ID = 7
+			coupon.Code = "SAVE"
+			lines := []pricedLine{
+				{ProductID: 1, CategoryID: 10, UnitPrice: 50, Quantity: 2, Amount: 100, Discount: tt.promoted},
+				{ProductID: 2, CategoryID: 20, UnitPrice: 50, Quantity: 1, Amount: 50},
+			}
+
+			discount, err := evaluateCoupon(&coupon, lines)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v", err)
+			}
+			if discount.Amount != tt.amount {
+				t.Errorf("amount = %v, want %v", discount.Amount, tt.amount)
+			}
+			if discount.Source != models.DiscountSourceCoupon || discount.CouponID == nil || *discount.CouponID != 7 {
+				t.Errorf("discount = %+v, want a coupon discount for coupon 7", discount)
+			}
+			if discount.Code != "SAVE" || discount.Description != "Coupon SAVE" {
+				t.Errorf("code and description = %q, %q, want %q, %q", discount.Code, discount.Description, "SAVE", "Coupon SAVE")
+			}
+		})
+	}
+}
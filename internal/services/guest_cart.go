@@ -0,0 +1,251 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Guest carts are identified by a token made of a random key stored on the cart and its
+// HMAC-SHA256 signature, so tokens cannot be guessed or forged from cart IDs.
+
+// cartTokenSecret returns the key guest cart tokens are signed with, from CART_TOKEN_SECRET,
+// falling back to JWT_SECRET
+func cartTokenSecret() []byte {
+	if secret := os.Getenv("CART_TOKEN_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return []byte("your-secret-key")
+}
+
+// newGuestCartToken generates a guest cart key and the signed token handed to the client
+func newGuestCartToken() (key, token string, err error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate cart token: %w", err)
+	}
+	key = hex.EncodeToString(raw)
+	return key, key + "." + signCartKey(key), nil
+}
+
+// VerifyCartToken checks the signature of a guest cart token and returns its key
+func VerifyCartToken(token string) (string, error) {
+	key, signature, ok := strings.Cut(token, ".")
+	if !ok || key == "" || !hmac.Equal([]byte(signature), []byte(signCartKey(key))) {
+		return "", errors.New("invalid cart token")
+	}
+	return key, nil
+}
+
+// signCartKey returns the hex HMAC-SHA256 of a guest cart key
+func signCartKey(key string) string {
+	mac := hmac.New(sha256.New, cartTokenSecret())
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Cart merge strategies decide the quantity of a product that is in both the guest cart and the
+// user's cart when the guest logs in
+const (
+	CartMergeSum   = "sum"   // add the quantities
+	CartMergeMax   = "max"   // keep the larger quantity
+	CartMergeGuest = "guest" // the guest cart wins
+	CartMergeUser  = "user"  // the user's cart wins
+)
+
+// cartMergeStrategy returns the strategy configured by CART_MERGE_STRATEGY, defaulting to sum
+func cartMergeStrategy() string {
+	strategy := strings.ToLower(os.Getenv("CART_MERGE_STRATEGY"))
+	switch strategy {
+	case "":
+		return CartMergeSum
+	case CartMergeSum, CartMergeMax, CartMergeGuest, CartMergeUser:
+		return strategy
+	}
+	log.Printf("Warning: unknown CART_MERGE_STRATEGY %q, using %s", strategy, CartMergeSum)
+	return CartMergeSum
+}
+
+// mergeGuestCart moves the items and coupon of a guest cart into the user's cart and deletes the
+// guest cart. Tokens of carts that no longer exist are ignored.
+func mergeGuestCart(db *sql.DB, userID uint, token string) error {
+	key, err := VerifyCartToken(token)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var guestCartID uint
+	var guestCouponID *uint
+	err = tx.QueryRow(
+		"SELECT id, coupon_id FROM carts WHERE guest_key = $1 AND user_id IS NULL FOR UPDATE", key,
+	).Scan(&guestCartID, &guestCouponID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("failed to load guest cart: %w", err)
+	}
+
+	var cartID uint
+	err = tx.QueryRow(`
+		INSERT INTO carts (user_id, created_at, updated_at) VALUES ($1, NOW(), NOW())
+		ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()
+		RETURNING id
+	`, userID).Scan(&cartID)
+	if err != nil {
+		return fmt.Errorf("failed to load cart: %w", err)
+	}
+
+	strategy := cartMergeStrategy()
+	onConflict := "DO NOTHING"
+	switch strategy {
+	case CartMergeSum:
+		onConflict = "DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity, updated_at = NOW()"
+	case CartMergeMax:
+		onConflict = "DO UPDATE SET quantity = GREATEST(cart_items.quantity, EXCLUDED.quantity), updated_at = NOW()"
+	case CartMergeGuest:
+		onConflict = "DO UPDATE SET quantity = EXCLUDED.quantity, seen_price = EXCLUDED.seen_price, added_price = EXCLUDED.added_price, updated_at = NOW()"
+	}
+	_, err = tx.Exec(`
+		INSERT INTO cart_items (cart_id, product_id, quantity, seen_price, added_price, created_at, updated_at)
+		SELECT $1, product_id, quantity, seen_price, added_price, created_at, NOW()
+		FROM cart_items
+		WHERE cart_id = $2
+		ON CONFLICT (cart_id, product_id) `+onConflict, cartID, guestCartID)
+	if err != nil {
+		return fmt.Errorf("failed to merge cart items: %w", err)
+	}
+
+	// Keep the user's coupon unless the guest cart wins
+	couponExpr := "COALESCE(coupon_id, $2)"
+	if strategy == CartMergeGuest {
+		couponExpr = "COALESCE($2, coupon_id)"
+	}
+	_, err = tx.Exec("UPDATE carts SET coupon_id = "+couponExpr+", updated_at = NOW() WHERE id = $1", cartID, guestCouponID)
+	if err != nil {
+		return fmt.Errorf("failed to update cart: %w", err)
+	}
+
+	if _, err = tx.Exec("DELETE FROM carts WHERE id = $1", guestCartID); err != nil {
+		return fmt.Errorf("failed to delete guest cart: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// guestCheckoutUser returns the guest account orders placed with an email are recorded under,
+// creating it on first checkout. Emails of registered customers must log in instead.
+func guestCheckoutUser(q querier, email string) (uint, error) {
+	var exists bool
+	err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1 AND role <> 'guest')", email).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check account: %w", err)
+	}
+	if exists {
+		return 0, errors.New("an account exists for this email; log in to check out")
+	}
+
+	var userID uint
+	err = q.QueryRow(`
+		INSERT INTO users (email, password, first_name, last_name, role, created_at, updated_at)
+		VALUES ($1, '', '', '', 'guest', NOW(), NOW())
+		ON CONFLICT (email) WHERE role = 'guest' DO UPDATE SET updated_at = users.updated_at
+		RETURNING id
+	`, email).Scan(&userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create guest account: %w", err)
+	}
+
+	return userID, nil
+}
+
+// createGuestOrderClaim records a claim on the orders of the guest account with the user's email
+// and returns its code, or an empty code when the email has no guest account
+func createGuestOrderClaim(db *sql.DB, userID uint, email string, ttl time.Duration) (string, error) {
+	token := randomHex(32)
+	result, err := db.Exec(`
+		INSERT INTO guest_order_claims (user_id, guest_user_id, token, created_at, expires_at)
+		SELECT $1, id, $3, NOW(), NOW() + $4 * INTERVAL '1 second'
+		FROM users WHERE email = $2 AND role = 'guest'
+	`, userID, email, token, ttl.Seconds())
+	if err != nil {
+		return "", fmt.Errorf("failed to record guest order claim: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return "", nil
+	}
+	return token, nil
+}
+
+// claimGuestOrders moves the orders, coupon redemptions and returns of a claimed guest account to
+// the user and returns the number of orders moved. Each claim can be used once.
+func claimGuestOrders(db *sql.DB, userID uint, token string) (int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var claimID, guestUserID uint
+	var expired, claimed bool
+	err = tx.QueryRow(`
+		SELECT id, guest_user_id, expires_at <= NOW(), claimed_at IS NOT NULL
+		FROM guest_order_claims WHERE token = $1 AND user_id = $2
+		FOR UPDATE
+	`, token, userID).Scan(&claimID, &guestUserID, &expired, &claimed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errors.New("guest order claim not found")
+		}
+		return 0, fmt.Errorf("failed to load guest order claim: %w", err)
+	}
+	if claimed {
+		return 0, errors.New("guest order claim already used")
+	}
+	if expired {
+		return 0, errors.New("guest order claim has expired")
+	}
+
+	result, err := tx.Exec("UPDATE orders SET user_id = $1, updated_at = NOW() WHERE user_id = $2", userID, guestUserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move guest orders: %w", err)
+	}
+	moved, _ := result.RowsAffected()
+
+	for _, table := range []string{"coupon_redemptions", "returns"} {
+		if _, err := tx.Exec("UPDATE "+table+" SET user_id = $1 WHERE user_id = $2", userID, guestUserID); err != nil {
+			return 0, fmt.Errorf("failed to move guest %s: %w", table, err)
+		}
+	}
+
+	if _, err = tx.Exec("UPDATE guest_order_claims SET claimed_at = NOW() WHERE id = $1", claimID); err != nil {
+		return 0, fmt.Errorf("failed to update guest order claim: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return moved, nil
+}
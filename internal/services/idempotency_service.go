@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+// IdempotencyService stores responses to requests made with an Idempotency-Key header
+type IdempotencyService struct {
+	db       *sql.DB
+	ttl      time.Duration
+	lease    time.Duration
+	wait     time.Duration
+	interval time.Duration
+}
+
+// NewIdempotencyService creates a new idempotency service. Keys are kept for IDEMPOTENCY_KEY_TTL
+// (default 24h); a duplicate of a request still in progress waits up to IDEMPOTENCY_WAIT
+// (default 5s, 0 to reject it right away) for its response. A key left in progress for longer
+// than IDEMPOTENCY_LEASE (default 5m), e.g. by a crashed server, can be claimed again.
+func NewIdempotencyService() *IdempotencyService {
+	ttl := 24 * time.Hour
+	if value := os.Getenv("IDEMPOTENCY_KEY_TTL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			ttl = parsed
+		}
+	}
+
+	lease := 5 * time.Minute
+	if value := os.Getenv("IDEMPOTENCY_LEASE"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			lease = parsed
+		}
+	}
+
+	wait := 5 * time.Second
+	if value := os.Getenv("IDEMPOTENCY_WAIT"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed >= 0 {
+			wait = parsed
+		}
+	}
+
+	interval := time.Hour
+	if value := os.Getenv("IDEMPOTENCY_CLEANUP_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			interval = parsed
+		}
+	}
+
+	return &IdempotencyService{
+		db:       database.GetDB(),
+		ttl:      ttl,
+		lease:    lease,
+		wait:     wait,
+		interval: interval,
+	}
+}
+
+// IdempotencyScope identifies whose keys a key is looked up among: a user's, or for guests those
+// of the cart key their cart token carries
+type IdempotencyScope struct {
+	UserID   uint
+	GuestKey string
+}
+
+// condition returns the SQL condition selecting the scope's keys, with its argument at argIndex
+func (s IdempotencyScope) condition(argIndex int) (string, interface{}) {
+	if s.UserID != 0 {
+		return fmt.Sprintf("user_id = $%d", argIndex), s.UserID
+	}
+	return fmt.Sprintf("user_id IS NULL AND guest_key = $%d", argIndex), s.GuestKey
+}
+
+// owner returns the user_id and guest_key columns of the scope's keys
+func (s IdempotencyScope) owner() (sql.NullInt64, sql.NullString) {
+	if s.UserID != 0 {
+		return sql.NullInt64{Int64: int64(s.UserID), Valid: true}, sql.NullString{}
+	}
+	return sql.NullInt64{}, sql.NullString{String: s.GuestKey, Valid: true}
+}
+
+// BeginRequest claims a key for a request. It returns a processing key when the request should
+// be performed, or the completed key whose response should be replayed. A key reused for a
+// different request is rejected, and a duplicate of a request still in progress waits for it.
+func (s *IdempotencyService) BeginRequest(ctx context.Context, scope IdempotencyScope, key, method, path, requestHash string) (*models.IdempotencyKey, error) {
+	deadline := time.Now().Add(s.wait)
+	scopeCondition, scopeArg := scope.condition(1)
+	userID, guestKey := scope.owner()
+
+	for {
+		// Expired keys, and keys whose request outlived its lease, can be used again
+		if _, err := s.db.Exec(`
+			DELETE FROM idempotency_keys
+			WHERE `+scopeCondition+` AND key = $2
+				AND (expires_at <= NOW() OR (status = $3 AND created_at <= NOW() - $4 * INTERVAL '1 second'))
+		`, scopeArg, key, models.IdempotencyKeyProcessing, s.lease.Seconds()); err != nil {
+			return nil, fmt.Errorf("failed to expire idempotency key: %w", err)
+		}
+
+		record := &models.IdempotencyKey{
+			UserID:      scope.UserID,
+			GuestKey:    scope.GuestKey,
+			Key:         key,
+			Method:      method,
+			Path:        path,
+			RequestHash: requestHash,
+			Status:      models.IdempotencyKeyProcessing,
+		}
+		err := s.db.QueryRow(`
+			INSERT INTO idempotency_keys (user_id, guest_key, key, method, path, request_hash, status, created_at, expires_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW() + $8 * INTERVAL '1 second')
+			ON CONFLICT DO NOTHING
+			RETURNING id, created_at, expires_at
+		`, userID, guestKey, key, method, path, requestHash, record.Status, s.ttl.Seconds()).Scan(&record.ID, &record.CreatedAt, &record.ExpiresAt)
+		if err == nil {
+			return record, nil
+		}
+		if err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to store idempotency key: %w", err)
+		}
+
+		existing, err := s.loadKey(scope, key)
+		if err == sql.ErrNoRows {
+			continue // released, expired or reclaimed in the meantime
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if existing.Method != method || existing.Path != path || existing.RequestHash != requestHash {
+			return nil, errors.New("idempotency key was already used for a different request")
+		}
+		if existing.Status == models.IdempotencyKeyCompleted {
+			return existing, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, errors.New("a request with this idempotency key is still in progress")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// CompleteRequest stores the response to a claimed key for replay
+func (s *IdempotencyService) CompleteRequest(id uint, status int, body []byte) error {
+	_, err := s.db.Exec(`
+		UPDATE idempotency_keys SET status = $1, response_status = $2, response_body = $3 WHERE id = $4
+	`, models.IdempotencyKeyCompleted, status, body, id)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+	return nil
+}
+
+// ReleaseRequest forgets a claimed key, e.g. after a server error, so the request can be retried
+func (s *IdempotencyService) ReleaseRequest(id uint) error {
+	if _, err := s.db.Exec("DELETE FROM idempotency_keys WHERE id = $1", id); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredKeys removes keys past their expiry or lease and returns how many were removed
+func (s *IdempotencyService) DeleteExpiredKeys() (int64, error) {
+	result, err := s.db.Exec(`
+		DELETE FROM idempotency_keys
+		WHERE expires_at <= NOW() OR (status = $1 AND created_at <= NOW() - $2 * INTERVAL '1 second')
+	`, models.IdempotencyKeyProcessing, s.lease.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// StartCleanupWorker deletes expired keys periodically in the background.
+// The interval is configured with IDEMPOTENCY_CLEANUP_INTERVAL (default 1h).
+func (s *IdempotencyService) StartCleanupWorker() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			deleted, err := s.DeleteExpiredKeys()
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Deleted %d expired idempotency keys", deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// loadKey retrieves an idempotency key of a user or guest
+func (s *IdempotencyService) loadKey(scope IdempotencyScope, key string) (*models.IdempotencyKey, error) {
+	scopeCondition, scopeArg := scope.condition(1)
+	var record models.IdempotencyKey
+	var responseStatus sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT id, COALESCE(user_id, 0), COALESCE(guest_key, ''), key, method, path, request_hash, status, response_status, response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE `+scopeCondition+` AND key = $2
+	`, scopeArg, key).Scan(
+		&record.ID, &record.UserID, &record.GuestKey, &record.Key, &record.Method, &record.Path, &record.RequestHash,
+		&record.Status, &responseStatus, &record.ResponseBody, &record.CreatedAt, &record.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+	record.ResponseStatus = int(responseStatus.Int64)
+	return &record, nil
+}
@@ -0,0 +1,772 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+// importableFields lists the product fields that can be set through an import
+var importableFields = map[string]bool{
+	"id":          true,
+	"sku":         true,
+	"name":        true,
+	"description": true,
+	"price":       true,
+	"stock":       true,
+	"category_id": true,
+	"category":    true,
+	"image_url":   true,
+	"is_active":   true,
+	"attributes":  true,
+}
+
+// maxReportedImportErrors caps the row errors returned with a job; the report has all of them
+const maxReportedImportErrors = 100
+
+// importProgressInterval is how often, in rows, job progress is persisted
+const importProgressInterval = 100
+
+// ImportService handles bulk product import and export
+type ImportService struct {
+	db             *sql.DB
+	productService *ProductService
+}
+
+// NewImportService creates a new import service
+func NewImportService() *ImportService {
+	return &ImportService{
+		db:             database.GetDB(),
+		productService: NewProductService(),
+	}
+}
+
+// CreateImportRequest represents a bulk product import submission
+type CreateImportRequest struct {
+	Format  string            // csv or jsonl
+	MatchBy string            // sku or id
+	DryRun  bool              // validate without writing
+	Mapping map[string]string // source column -> product field
+	Data    []byte
+}
+
+// importRecord is a single parsed row mapped onto product fields
+type importRecord struct {
+	row    int
+	fields map[string]string
+	err    error
+}
+
+// CreateImportJob validates an import submission, stores it and starts processing in the background
+func (s *ImportService) CreateImportJob(userID uint, req *CreateImportRequest) (*models.ImportJob, error) {
+	req.Format = strings.ToLower(req.Format)
+	if req.Format != "csv" && req.Format != "jsonl" {
+		return nil, errors.New("unsupported import format")
+	}
+
+	if req.MatchBy == "" {
+		req.MatchBy = "sku"
+	}
+	if req.MatchBy != "sku" && req.MatchBy != "id" {
+		return nil, errors.New("invalid match_by value")
+	}
+
+	for source, field := range req.Mapping {
+		if !importableFields[field] {
+			return nil, fmt.Errorf("invalid column mapping: %s -> %s", source, field)
+		}
+	}
+
+	if len(bytes.TrimSpace(req.Data)) == 0 {
+		return nil, errors.New("import file is empty")
+	}
+
+	mappingJSON, err := json.Marshal(req.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode mapping: %w", err)
+	}
+
+	var id uint
+	err = s.db.QueryRow(`
+		INSERT INTO import_jobs (user_id, format, match_by, dry_run, mapping, payload, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+		RETURNING id
+	`, userID, req.Format, req.MatchBy, req.DryRun, mappingJSON, req.Data, models.ImportStatusPending).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	go s.processJob(id)
+
+	return s.GetImportJob(id)
+}
+
+// GetImportJob retrieves an import job; at most maxReportedImportErrors row errors are included
+func (s *ImportService) GetImportJob(id uint) (*models.ImportJob, error) {
+	job, err := s.loadJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(job.Errors) > maxReportedImportErrors {
+		job.Errors = job.Errors[:maxReportedImportErrors]
+	}
+	return job, nil
+}
+
+// WriteImportReport writes every row error of an import job as CSV
+func (s *ImportService) WriteImportReport(w io.Writer, id uint) error {
+	job, err := s.loadJob(id)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"row", "field", "message"}); err != nil {
+		return err
+	}
+	for _, rowErr := range job.Errors {
+		if err := writer.Write([]string{strconv.Itoa(rowErr.Row), rowErr.Field, rowErr.Message}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ResumePendingJobs restarts jobs that were interrupted, e.g. by a server restart
+func (s *ImportService) ResumePendingJobs() {
+	rows, err := s.db.Query("SELECT id FROM import_jobs WHERE status IN ($1, $2) ORDER BY id",
+		models.ImportStatusPending, models.ImportStatusProcessing)
+	if err != nil {
+		log.Printf("Warning: failed to query pending import jobs: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			log.Printf("Warning: failed to scan import job: %v", err)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	for _, id := range ids {
+		go s.processJob(id)
+	}
+}
+
+// loadJob retrieves an import job with all of its row errors
+func (s *ImportService) loadJob(id uint) (*models.ImportJob, error) {
+	var job models.ImportJob
+	var mappingJSON, errorsJSON []byte
+	var errorMessage sql.NullString
+	var startedAt, finishedAt sql.NullTime
+
+	err := s.db.QueryRow(`
+		SELECT id, user_id, format, match_by, dry_run, mapping, status, total_rows, processed_rows,
+		       created_count, updated_count, failed_count, errors, error_message, created_at, started_at, finished_at
+		FROM import_jobs
+		WHERE id = $1
+	`, id).Scan(
+		&job.ID, &job.UserID, &job.Format, &job.MatchBy, &job.DryRun, &mappingJSON, &job.Status,
+		&job.TotalRows, &job.ProcessedRows, &job.CreatedCount, &job.UpdatedCount, &job.FailedCount,
+		&errorsJSON, &errorMessage, &job.CreatedAt, &startedAt, &finishedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("import job not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	json.Unmarshal(mappingJSON, &job.Mapping)
+	json.Unmarshal(errorsJSON, &job.Errors)
+	job.ErrorMessage = errorMessage.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+
+	return &job, nil
+}
+
+// processJob parses and applies every row of an import job
+func (s *ImportService) processJob(id uint) {
+	// Claim the job so it is only processed once
+	var userID uint
+	var format, matchBy string
+	var dryRun bool
+	var mappingJSON, payload []byte
+	err := s.db.QueryRow(`
+		UPDATE import_jobs SET status = $1, started_at = NOW()
+		WHERE id = $2 AND status IN ($3, $1)
+		RETURNING user_id, format, match_by, dry_run, mapping, payload
+	`, models.ImportStatusProcessing, id, models.ImportStatusPending).Scan(&userID, &format, &matchBy, &dryRun, &mappingJSON, &payload)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Warning: failed to start import job %d: %v", id, err)
+		}
+		return
+	}
+
+	var mapping map[string]string
+	json.Unmarshal(mappingJSON, &mapping)
+
+	records, err := parseImportRecords(format, payload, mapping)
+	if err != nil {
+		s.failJob(id, err)
+		return
+	}
+
+	if _, err := s.db.Exec("UPDATE import_jobs SET total_rows = $1 WHERE id = $2", len(records), id); err != nil {
+		log.Printf("Warning: failed to update import job %d: %v", id, err)
+	}
+
+	var created, updated, failed int
+	var rowErrors []models.ImportRowError
+	for i, record := range records {
+		if record.err != nil {
+			failed++
+			rowErrors = append(rowErrors, models.ImportRowError{Row: record.row, Message: record.err.Error()})
+		} else {
+			isNew, rowErr := s.applyRecord(record, matchBy, dryRun, userID)
+			switch {
+			case rowErr != nil:
+				failed++
+				rowErr.Row = record.row
+				rowErrors = append(rowErrors, *rowErr)
+			case isNew:
+				created++
+			default:
+				updated++
+			}
+		}
+
+		if (i+1)%importProgressInterval == 0 {
+			_, err := s.db.Exec(`
+				UPDATE import_jobs SET processed_rows = $1, created_count = $2, updated_count = $3, failed_count = $4
+				WHERE id = $5
+			`, i+1, created, updated, failed, id)
+			if err != nil {
+				log.Printf("Warning: failed to update import job %d progress: %v", id, err)
+			}
+		}
+	}
+
+	errorsJSON, _ := json.Marshal(rowErrors)
+	_, err = s.db.Exec(`
+		UPDATE import_jobs
+		SET status = $1, processed_rows = $2, created_count = $3, updated_count = $4, failed_count = $5,
+		    errors = $6, payload = NULL, finished_at = NOW()
+		WHERE id = $7
+	`, models.ImportStatusCompleted, len(records), created, updated, failed, errorsJSON, id)
+	if err != nil {
+		log.Printf("Warning: failed to complete import job %d: %v", id, err)
+	}
+}
+
+// failJob marks an import job as failed
+func (s *ImportService) failJob(id uint, cause error) {
+	_, err := s.db.Exec(
+		"UPDATE import_jobs SET status = $1, error_message = $2, payload = NULL, finished_at = NOW() WHERE id = $3",
+		models.ImportStatusFailed, cause.Error(), id,
+	)
+	if err != nil {
+		log.Printf("Warning: failed to mark import job %d as failed: %v", id, err)
+	}
+}
+
+// applyRecord creates or updates the product described by a record in its own transaction.
+// Dry runs roll the transaction back so constraint checks still run.
+func (s *ImportService) applyRecord(record importRecord, matchBy string, dryRun bool, actorID uint) (bool, *models.ImportRowError) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, &models.ImportRowError{Message: fmt.Sprintf("failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+
+	created, rowErr := applyImportFields(tx, record.fields, matchBy, actorID)
+	if rowErr != nil {
+		return false, rowErr
+	}
+
+	if !dryRun {
+		if err := tx.Commit(); err != nil {
+			return false, &models.ImportRowError{Message: fmt.Sprintf("failed to commit transaction: %v", err)}
+		}
+	}
+
+	return created, nil
+}
+
+// applyImportFields validates the fields of a record, upserts the product and records its revision
+func applyImportFields(q querier, fields map[string]string, matchBy string, actorID uint) (bool, *models.ImportRowError) {
+	columns := []string{}
+	values := []interface{}{}
+
+	set := func(column string, value interface{}) {
+		columns = append(columns, column)
+		values = append(values, value)
+	}
+
+	if v, ok := fields["sku"]; ok && v != "" {
+		set("sku", v)
+	}
+	if v, ok := fields["name"]; ok && v != "" {
+		set("name", v)
+	}
+	if v, ok := fields["description"]; ok && v != "" {
+		set("description", v)
+	}
+	if v, ok := fields["image_url"]; ok && v != "" {
+		set("image_url", v)
+	}
+	if v, ok := fields["price"]; ok && v != "" {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price <= 0 {
+			return false, &models.ImportRowError{Field: "price", Message: "price must be a number greater than 0"}
+		}
+		set("price", price)
+	}
+	if v, ok := fields["stock"]; ok && v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil || stock < 0 {
+			return false, &models.ImportRowError{Field: "stock", Message: "stock must be a non-negative integer"}
+		}
+		set("stock", stock)
+	}
+	if v, ok := fields["is_active"]; ok && v != "" {
+		isActive, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, &models.ImportRowError{Field: "is_active", Message: "is_active must be true or false"}
+		}
+		set("is_active", isActive)
+	}
+
+	// Resolve category by ID or name; a category_id of 0, as in older exports, means none was given
+	var categoryID uint
+	categorySet := false
+	if v, ok := fields["category_id"]; ok && v != "" && v != "0" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return false, &models.ImportRowError{Field: "category_id", Message: "category_id must be an integer"}
+		}
+		var exists bool
+		if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)", id).Scan(&exists); err != nil {
+			return false, &models.ImportRowError{Message: fmt.Sprintf("database error: %v", err)}
+		}
+		if !exists {
+			return false, &models.ImportRowError{Field: "category_id", Message: "category not found"}
+		}
+		categoryID, categorySet = uint(id), true
+		set("category_id", categoryID)
+	} else if v, ok := fields["category"]; ok && v != "" {
+		err := q.QueryRow("SELECT id FROM categories WHERE LOWER(name) = LOWER($1)", v).Scan(&categoryID)
+		if err == sql.ErrNoRows {
+			return false, &models.ImportRowError{Field: "category", Message: "category not found"}
+		}
+		if err != nil {
+			return false, &models.ImportRowError{Message: fmt.Sprintf("database error: %v", err)}
+		}
+		categorySet = true
+		set("category_id", categoryID)
+	}
+
+	// Typed attribute values, as a JSON object keyed by attribute code
+	var attributes map[string]interface{}
+	if v, ok := fields["attributes"]; ok && v != "" {
+		if err := json.Unmarshal([]byte(v), &attributes); err != nil {
+			return false, &models.ImportRowError{Field: "attributes", Message: "attributes must be a JSON object keyed by attribute code"}
+		}
+	}
+
+	// Find the existing product
+	var existingID uint
+	var err error
+	switch matchBy {
+	case "id":
+		// Rows without an id create new products; IDs are assigned by the database
+		idStr := fields["id"]
+		if idStr == "" {
+			break
+		}
+		id, parseErr := strconv.ParseUint(idStr, 10, 32)
+		if parseErr != nil {
+			return false, &models.ImportRowError{Field: "id", Message: "id must be an integer"}
+		}
+		err = q.QueryRow("SELECT id FROM products WHERE id = $1", id).Scan(&existingID)
+		if err == sql.ErrNoRows {
+			return false, &models.ImportRowError{
+				Field:   "id",
+				Message: fmt.Sprintf("product %d not found; leave id empty to create a new product", id),
+			}
+		}
+	default:
+		sku := fields["sku"]
+		if sku == "" {
+			return false, &models.ImportRowError{Field: "sku", Message: "sku is required when matching by sku"}
+		}
+		err = q.QueryRow("SELECT id FROM products WHERE sku = $1", sku).Scan(&existingID)
+		if err == sql.ErrNoRows {
+			err = nil
+		}
+	}
+	if err != nil {
+		return false, &models.ImportRowError{Message: fmt.Sprintf("database error: %v", err)}
+	}
+
+	// Update the existing product
+	if existingID != 0 {
+		if len(columns) == 0 && len(attributes) == 0 {
+			return false, nil
+		}
+
+		before, err := loadProductState(q, existingID)
+		if err != nil {
+			return false, &models.ImportRowError{Message: err.Error()}
+		}
+
+		// Validate attribute values against the category the product ends up in
+		if !categorySet {
+			if err := q.QueryRow("SELECT COALESCE(category_id, 0) FROM products WHERE id = $1", existingID).Scan(&categoryID); err != nil {
+				return false, &models.ImportRowError{Message: fmt.Sprintf("database error: %v", err)}
+			}
+		}
+		attrValues, err := validateProductAttributes(q, categoryID, attributes)
+		if err != nil {
+			return false, &models.ImportRowError{Field: "attributes", Message: err.Error()}
+		}
+
+		if len(columns) > 0 {
+			assignments := make([]string, len(columns))
+			for i, column := range columns {
+				assignments[i] = fmt.Sprintf("%s = $%d", column, i+1)
+			}
+			values = append(values, existingID)
+
+			query := fmt.Sprintf("UPDATE products SET %s, updated_at = NOW() WHERE id = $%d",
+				strings.Join(assignments, ", "), len(values))
+			if _, err := q.Exec(query, values...); err != nil {
+				return false, &models.ImportRowError{Message: importDatabaseMessage(err)}
+			}
+		}
+
+		sync := len(attrValues) > 0 || categorySet
+		if rowErr := applyImportAttributes(q, existingID, categoryID, attrValues, sync); rowErr != nil {
+			return false, rowErr
+		}
+
+		if rowErr := recordImportRevision(q, existingID, actorID, before); rowErr != nil {
+			return false, rowErr
+		}
+		return false, nil
+	}
+
+	// Create a new product
+	if fields["name"] == "" {
+		return false, &models.ImportRowError{Field: "name", Message: "name is required for new products"}
+	}
+	if fields["price"] == "" {
+		return false, &models.ImportRowError{Field: "price", Message: "price is required for new products"}
+	}
+	attrValues, err := validateProductAttributes(q, categoryID, attributes)
+	if err != nil {
+		return false, &models.ImportRowError{Field: "attributes", Message: err.Error()}
+	}
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	query := fmt.Sprintf("INSERT INTO products (%s, created_at, updated_at) VALUES (%s, NOW(), NOW()) RETURNING id",
+		strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	var productID uint
+	if err := q.QueryRow(query, values...).Scan(&productID); err != nil {
+		return false, &models.ImportRowError{Message: importDatabaseMessage(err)}
+	}
+
+	if rowErr := applyImportAttributes(q, productID, categoryID, attrValues, categoryID > 0); rowErr != nil {
+		return false, rowErr
+	}
+
+	if rowErr := recordImportRevision(q, productID, actorID, nil); rowErr != nil {
+		return false, rowErr
+	}
+	return true, nil
+}
+
+// applyImportAttributes stores the attribute values of an imported product. With sync, values the
+// category does not have are dropped and every required attribute must be set, as when products
+// are created or updated through the API.
+func applyImportAttributes(q querier, productID, categoryID uint, values []attributeValue, sync bool) *models.ImportRowError {
+	if err := saveProductAttributes(q, productID, values); err != nil {
+		return &models.ImportRowError{Field: "attributes", Message: err.Error()}
+	}
+	if sync {
+		if err := syncProductAttributesWithCategory(q, productID, categoryID); err != nil {
+			return &models.ImportRowError{Field: "attributes", Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// recordImportRevision records the revision of a product changed by an import row
+func recordImportRevision(q querier, productID, actorID uint, before productState) *models.ImportRowError {
+	after, err := loadProductState(q, productID)
+	if err != nil {
+		return &models.ImportRowError{Message: err.Error()}
+	}
+	if err := recordProductRevision(q, productID, models.ProductRevisionImport, actorID, before, after, nil); err != nil {
+		return &models.ImportRowError{Message: err.Error()}
+	}
+	return nil
+}
+
+// importDatabaseMessage turns a database error into a row error message
+func importDatabaseMessage(err error) string {
+	if strings.Contains(err.Error(), "products_sku_key") {
+		return "sku is already used by another product"
+	}
+	return fmt.Sprintf("database error: %v", err)
+}
+
+// parseImportRecords parses CSV (with a header row) or JSON Lines into records keyed by product field
+func parseImportRecords(format string, data []byte, mapping map[string]string) ([]importRecord, error) {
+	mapKey := func(source string) (string, bool) {
+		source = strings.TrimSpace(source)
+		if len(mapping) > 0 {
+			field, ok := mapping[source]
+			return field, ok
+		}
+		field := strings.ToLower(source)
+		return field, importableFields[field]
+	}
+
+	var records []importRecord
+
+	switch format {
+	case "csv":
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+
+		header, err := reader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV header: %w", err)
+		}
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+
+		fieldsByColumn := make([]string, len(header))
+		mapped := 0
+		for i, column := range header {
+			if field, ok := mapKey(column); ok {
+				fieldsByColumn[i] = field
+				mapped++
+			}
+		}
+		if mapped == 0 {
+			return nil, errors.New("CSV header does not contain any importable columns")
+		}
+
+		row := 1
+		for {
+			values, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			row++
+			if err != nil {
+				records = append(records, importRecord{row: row, err: fmt.Errorf("malformed CSV row: %v", err)})
+				continue
+			}
+
+			fields := make(map[string]string)
+			for i, value := range values {
+				if i < len(fieldsByColumn) && fieldsByColumn[i] != "" {
+					fields[fieldsByColumn[i]] = strings.TrimSpace(value)
+				}
+			}
+			records = append(records, importRecord{row: row, fields: fields})
+		}
+
+	case "jsonl":
+		lines := bytes.Split(data, []byte("\n"))
+		for i, line := range lines {
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+
+			var object map[string]interface{}
+			if err := json.Unmarshal(line, &object); err != nil {
+				records = append(records, importRecord{row: i + 1, err: fmt.Errorf("malformed JSON: %v", err)})
+				continue
+			}
+
+			fields := make(map[string]string)
+			for key, value := range object {
+				field, ok := mapKey(key)
+				if !ok || value == nil {
+					continue
+				}
+				switch v := value.(type) {
+				case string:
+					fields[field] = strings.TrimSpace(v)
+				case float64:
+					fields[field] = strconv.FormatFloat(v, 'f', -1, 64)
+				case bool:
+					fields[field] = strconv.FormatBool(v)
+				default:
+					encoded, _ := json.Marshal(v)
+					fields[field] = string(encoded)
+				}
+			}
+			records = append(records, importRecord{row: i + 1, fields: fields})
+		}
+
+	default:
+		return nil, errors.New("unsupported import format")
+	}
+
+	return records, nil
+}
+
+// exportColumns lists the columns written by ExportProducts
+var exportColumns = []string{
+	"id", "sku", "name", "description", "price", "stock", "category_id", "category",
+	"image_url", "is_active", "attributes", "created_at", "updated_at",
+}
+
+// ExportProducts streams the products matching filter as CSV or JSON Lines
+func (s *ImportService) ExportProducts(w io.Writer, format string, filter *ProductFilter) error {
+	if format != "csv" && format != "jsonl" {
+		return errors.New("unsupported export format")
+	}
+
+	whereConditions, args, err := s.productService.buildProductConditions(filter)
+	if err != nil {
+		return err
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT p.id, COALESCE(p.sku, ''), p.name, COALESCE(p.description, ''), p.price, p.stock,
+		       COALESCE(p.category_id, 0), COALESCE(c.name, ''), COALESCE(p.image_url, ''), p.is_active,
+		       COALESCE((
+		           SELECT json_object_agg(a.code, COALESCE(to_json(pav.value_number), to_json(pav.value_boolean), to_json(pav.value_text)))
+		           FROM product_attribute_values pav
+		           JOIN attribute_definitions a ON a.id = pav.attribute_id
+		           WHERE pav.product_id = p.id
+		       ), '{}'),
+		       p.created_at, p.updated_at
+		FROM products p
+		LEFT JOIN categories c ON p.category_id = c.id
+		%s
+		ORDER BY p.id ASC
+	`, whereClause)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	if format == "csv" {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(exportColumns); err != nil {
+			return err
+		}
+	} else {
+		jsonEncoder = json.NewEncoder(w)
+	}
+
+	count := 0
+	for rows.Next() {
+		var id, categoryID uint
+		var sku, name, description, categoryName, imageURL string
+		var price float64
+		var stock int
+		var isActive bool
+		var attributes []byte
+		var createdAt, updatedAt time.Time
+		err := rows.Scan(&id, &sku, &name, &description, &price, &stock, &categoryID, &categoryName,
+			&imageURL, &isActive, &attributes, &createdAt, &updatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to scan product: %w", err)
+		}
+
+		if csvWriter != nil {
+			// Products without a category or attributes get empty cells, so the file imports back
+			categoryCell := ""
+			if categoryID != 0 {
+				categoryCell = strconv.FormatUint(uint64(categoryID), 10)
+			}
+			attributesCell := string(attributes)
+			if attributesCell == "{}" {
+				attributesCell = ""
+			}
+			err = csvWriter.Write([]string{
+				strconv.FormatUint(uint64(id), 10), sku, name, description,
+				strconv.FormatFloat(price, 'f', 2, 64), strconv.Itoa(stock),
+				categoryCell, categoryName, imageURL,
+				strconv.FormatBool(isActive), attributesCell, createdAt.Format(time.RFC3339), updatedAt.Format(time.RFC3339),
+			})
+		} else {
+			var category interface{}
+			if categoryID != 0 {
+				category = categoryID
+			}
+			err = jsonEncoder.Encode(map[string]interface{}{
+				"id": id, "sku": sku, "name": name, "description": description,
+				"price": price, "stock": stock, "category_id": category, "category": categoryName,
+				"image_url": imageURL, "is_active": isActive, "attributes": json.RawMessage(attributes),
+				"created_at": createdAt, "updated_at": updatedAt,
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write product: %w", err)
+		}
+
+		// Flush periodically so large exports stream to the client
+		count++
+		if csvWriter != nil && count%500 == 0 {
+			csvWriter.Flush()
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating products: %w", err)
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
@@ -0,0 +1,584 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/storage"
+	"github.com/Code-byme/e-commerce/pkg/utils"
+	"github.com/lib/pq"
+)
+
+// allowedImageTypes maps accepted image content types to file extensions
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// imageVariant describes a resized copy generated for every uploaded image
+type imageVariant struct {
+	name      string
+	maxWidth  int
+	maxHeight int
+}
+
+// imageVariants lists the resized copies generated on upload
+var imageVariants = []imageVariant{
+	{name: "thumbnail", maxWidth: 150, maxHeight: 150},
+	{name: "medium", maxWidth: 600, maxHeight: 600},
+	{name: "large", maxWidth: 1200, maxHeight: 1200},
+}
+
+// maxImagePixels guards against decompression bombs
+const maxImagePixels = 50_000_000
+
+var (
+	mediaStorageOnce sync.Once
+	mediaStorage     storage.Storage
+)
+
+// getMediaStorage returns the storage backend configured for media, falling back to local storage
+func getMediaStorage() storage.Storage {
+	mediaStorageOnce.Do(func() {
+		store, err := storage.NewFromEnv()
+		if err != nil {
+			log.Printf("Warning: failed to configure media storage: %v", err)
+			log.Println("Falling back to local media storage")
+			store = storage.NewLocalStorage("./uploads", "/media")
+		}
+		mediaStorage = store
+	})
+	return mediaStorage
+}
+
+// MediaService handles product image uploads and galleries
+type MediaService struct {
+	db             *sql.DB
+	storage        storage.Storage
+	maxUploadBytes int64
+}
+
+// NewMediaService creates a new media service
+func NewMediaService() *MediaService {
+	maxUploadMB := int64(10)
+	if value := os.Getenv("MEDIA_MAX_UPLOAD_MB"); value != "" {
+		if parsed, err := strconv.ParseInt(value, 10, 64); err == nil && parsed > 0 {
+			maxUploadMB = parsed
+		}
+	}
+
+	return &MediaService{
+		db:             database.GetDB(),
+		storage:        getMediaStorage(),
+		maxUploadBytes: maxUploadMB << 20,
+	}
+}
+
+// Storage returns the storage backend used for media
+func (s *MediaService) Storage() storage.Storage {
+	return s.storage
+}
+
+// MaxUploadBytes returns the maximum accepted upload size
+func (s *MediaService) MaxUploadBytes() int64 {
+	return s.maxUploadBytes
+}
+
+// UpdateProductImageRequest represents the request to update a gallery image
+type UpdateProductImageRequest struct {
+	AltText  *string `json:"alt_text"`
+	Position *int    `json:"position" binding:"omitempty,gte=0"`
+}
+
+// ReorderProductImagesRequest represents the request to reorder a product gallery
+type ReorderProductImagesRequest struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1"`
+}
+
+// UploadProductImage validates an uploaded image, stores it with its resized variants
+// and adds it to the product gallery. A nil position appends the image.
+func (s *MediaService) UploadProductImage(ctx context.Context, productID uint, data []byte, altText string, position *int) (*models.ProductImage, error) {
+	// Check if product exists
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("product not found")
+	}
+
+	// Validate size and content type
+	if int64(len(data)) > s.maxUploadBytes {
+		return nil, errors.New("image too large")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("invalid image data")
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		return nil, errors.New("unsupported image type")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image data")
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, errors.New("image dimensions too large")
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("invalid image data")
+	}
+
+	// Store the original and its variants
+	baseKey := fmt.Sprintf("products/%d/%s", productID, randomHex(16))
+	originalKey := baseKey + ext
+	storedKeys := []string{}
+	cleanup := func() {
+		for _, key := range storedKeys {
+			if err := s.storage.Delete(context.Background(), key); err != nil {
+				log.Printf("Warning: failed to delete media object %s: %v", key, err)
+			}
+		}
+	}
+
+	if err := s.storage.Put(ctx, originalKey, data, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	storedKeys = append(storedKeys, originalKey)
+
+	variantKeys := make(map[string]string)
+	for _, variant := range imageVariants {
+		resized := utils.ResizeToFit(img, variant.maxWidth, variant.maxHeight)
+
+		variantData, variantType, variantExt, err := encodeImage(resized, contentType)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to encode %s variant: %w", variant.name, err)
+		}
+
+		key := baseKey + "_" + variant.name + variantExt
+		if err := s.storage.Put(ctx, key, variantData, variantType); err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to store %s variant: %w", variant.name, err)
+		}
+		storedKeys = append(storedKeys, key)
+		variantKeys[variant.name] = key
+	}
+
+	productImage, err := s.insertProductImage(productID, originalKey, variantKeys, altText, position, contentType, int64(len(data)), config.Width, config.Height)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	return productImage, nil
+}
+
+// insertProductImage records an uploaded image in the gallery
+func (s *MediaService) insertProductImage(productID uint, key string, variantKeys map[string]string, altText string, position *int,
+	contentType string, size int64, width, height int) (*models.ProductImage, error) {
+	variantsJSON, err := json.Marshal(variantKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode variants: %w", err)
+	}
+
+	// Start a transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the product so concurrent uploads get distinct positions
+	if _, err := tx.Exec("SELECT id FROM products WHERE id = $1 FOR UPDATE", productID); err != nil {
+		return nil, fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	var count int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM product_images WHERE product_id = $1", productID).Scan(&count); err != nil {
+		return nil, fmt.Errorf("failed to count product images: %w", err)
+	}
+
+	pos := count
+	if position != nil && *position < count {
+		pos = *position
+		_, err = tx.Exec("UPDATE product_images SET position = position + 1 WHERE product_id = $1 AND position >= $2", productID, pos)
+		if err != nil {
+			return nil, fmt.Errorf("failed to shift product images: %w", err)
+		}
+	}
+
+	var id uint
+	err = tx.QueryRow(`
+		INSERT INTO product_images (product_id, storage_key, variant_keys, alt_text, position, content_type, size_bytes, width, height, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING id
+	`, productID, key, variantsJSON, altText, pos, contentType, size, width, height).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create product image: %w", err)
+	}
+
+	if err := s.syncPrimaryImage(tx, productID, ""); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetProductImage(productID, id)
+}
+
+// GetProductImage retrieves a single gallery image
+func (s *MediaService) GetProductImage(productID, imageID uint) (*models.ProductImage, error) {
+	images, err := loadProductImages(s.db, s.storage, []uint{productID})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range images[productID] {
+		if images[productID][i].ID == imageID {
+			return &images[productID][i], nil
+		}
+	}
+
+	return nil, errors.New("image not found")
+}
+
+// ListProductImages retrieves a product's gallery in display order
+func (s *MediaService) ListProductImages(productID uint) ([]models.ProductImage, error) {
+	images, err := loadProductImages(s.db, s.storage, []uint{productID})
+	if err != nil {
+		return nil, err
+	}
+	return images[productID], nil
+}
+
+// UpdateProductImage updates an image's alt text and position
+func (s *MediaService) UpdateProductImage(productID, imageID uint, req *UpdateProductImageRequest) (*models.ProductImage, error) {
+	// Start a transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids, err := lockGalleryIDs(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := -1
+	for i, id := range ids {
+		if id == imageID {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return nil, errors.New("image not found")
+	}
+
+	if req.AltText != nil {
+		_, err = tx.Exec("UPDATE product_images SET alt_text = $1, updated_at = NOW() WHERE id = $2", *req.AltText, imageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update product image: %w", err)
+		}
+	}
+
+	if req.Position != nil && *req.Position != index {
+		target := *req.Position
+		if target >= len(ids) {
+			target = len(ids) - 1
+		}
+
+		// Move the image within the ordered list and rewrite positions
+		reordered := append([]uint{}, ids[:index]...)
+		reordered = append(reordered, ids[index+1:]...)
+		reordered = append(reordered[:target], append([]uint{imageID}, reordered[target:]...)...)
+
+		if err := writeGalleryPositions(tx, reordered); err != nil {
+			return nil, err
+		}
+		if err := s.syncPrimaryImage(tx, productID, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetProductImage(productID, imageID)
+}
+
+// ReorderProductImages sets the gallery order; imageIDs must list every image of the product
+func (s *MediaService) ReorderProductImages(productID uint, imageIDs []uint) ([]models.ProductImage, error) {
+	// Start a transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids, err := lockGalleryIDs(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) != len(imageIDs) {
+		return nil, errors.New("image list does not match product gallery")
+	}
+	current := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		current[id] = true
+	}
+	for _, id := range imageIDs {
+		if !current[id] {
+			return nil, errors.New("image list does not match product gallery")
+		}
+		delete(current, id)
+	}
+
+	if err := writeGalleryPositions(tx, imageIDs); err != nil {
+		return nil, err
+	}
+	if err := s.syncPrimaryImage(tx, productID, ""); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.ListProductImages(productID)
+}
+
+// DeleteProductImage removes an image from the gallery and deletes its stored objects
+func (s *MediaService) DeleteProductImage(ctx context.Context, productID, imageID uint) error {
+	// Start a transaction
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var key string
+	var variantsJSON []byte
+	err = tx.QueryRow(
+		"DELETE FROM product_images WHERE id = $1 AND product_id = $2 RETURNING storage_key, variant_keys",
+		imageID, productID,
+	).Scan(&key, &variantsJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("image not found")
+		}
+		return fmt.Errorf("failed to delete product image: %w", err)
+	}
+
+	ids, err := lockGalleryIDs(tx, productID)
+	if err != nil {
+		return err
+	}
+	if err := writeGalleryPositions(tx, ids); err != nil {
+		return err
+	}
+	if err := s.syncPrimaryImage(tx, productID, s.storage.URL(key)); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Remove stored objects; failures leave orphaned files but do not affect the gallery
+	keys := []string{key}
+	var variantKeys map[string]string
+	if err := json.Unmarshal(variantsJSON, &variantKeys); err == nil {
+		for _, variantKey := range variantKeys {
+			keys = append(keys, variantKey)
+		}
+	}
+	for _, k := range keys {
+		if err := s.storage.Delete(ctx, k); err != nil {
+			log.Printf("Warning: failed to delete media object %s: %v", k, err)
+		}
+	}
+
+	return nil
+}
+
+// syncPrimaryImage keeps products.image_url pointing at the first gallery image. When the
+// gallery is empty, image_url is cleared only if it still points at removedURL.
+func (s *MediaService) syncPrimaryImage(q querier, productID uint, removedURL string) error {
+	var key string
+	err := q.QueryRow(
+		"SELECT storage_key FROM product_images WHERE product_id = $1 ORDER BY position ASC, id ASC LIMIT 1",
+		productID,
+	).Scan(&key)
+
+	if err == sql.ErrNoRows {
+		if removedURL == "" {
+			return nil
+		}
+		_, err = q.Exec("UPDATE products SET image_url = '', updated_at = NOW() WHERE id = $1 AND image_url = $2", productID, removedURL)
+		if err != nil {
+			return fmt.Errorf("failed to update product image: %w", err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query primary image: %w", err)
+	}
+
+	_, err = q.Exec("UPDATE products SET image_url = $1, updated_at = NOW() WHERE id = $2", s.storage.URL(key), productID)
+	if err != nil {
+		return fmt.Errorf("failed to update product image: %w", err)
+	}
+	return nil
+}
+
+// lockGalleryIDs locks and returns a product's image IDs in display order
+func lockGalleryIDs(q querier, productID uint) ([]uint, error) {
+	rows, err := q.Query(
+		"SELECT id FROM product_images WHERE product_id = $1 ORDER BY position ASC, id ASC FOR UPDATE",
+		productID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product images: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan product image: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product images: %w", err)
+	}
+
+	return ids, nil
+}
+
+// writeGalleryPositions assigns sequential positions following the order of ids
+func writeGalleryPositions(q querier, ids []uint) error {
+	for position, id := range ids {
+		_, err := q.Exec("UPDATE product_images SET position = $1, updated_at = NOW() WHERE id = $2", position, id)
+		if err != nil {
+			return fmt.Errorf("failed to reorder product images: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadProductImages retrieves gallery images for the given products keyed by product ID
+func loadProductImages(q querier, store storage.Storage, productIDs []uint) (map[uint][]models.ProductImage, error) {
+	result := make(map[uint][]models.ProductImage)
+	if len(productIDs) == 0 {
+		return result, nil
+	}
+
+	ids := make([]int64, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = int64(id)
+	}
+
+	rows, err := q.Query(`
+		SELECT id, product_id, storage_key, variant_keys, alt_text, position, content_type, size_bytes, width, height, created_at, updated_at
+		FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position ASC, id ASC
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product images: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var productImage models.ProductImage
+		var variantsJSON []byte
+		err := rows.Scan(
+			&productImage.ID, &productImage.ProductID, &productImage.StorageKey, &variantsJSON, &productImage.AltText, &productImage.Position,
+			&productImage.ContentType, &productImage.SizeBytes, &productImage.Width, &productImage.Height, &productImage.CreatedAt, &productImage.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product image: %w", err)
+		}
+
+		productImage.URL = store.URL(productImage.StorageKey)
+		productImage.Variants = make(map[string]string)
+		var variantKeys map[string]string
+		if err := json.Unmarshal(variantsJSON, &variantKeys); err == nil {
+			for name, key := range variantKeys {
+				productImage.Variants[name] = store.URL(key)
+			}
+		}
+
+		result[productImage.ProductID] = append(result[productImage.ProductID], productImage)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product images: %w", err)
+	}
+
+	return result, nil
+}
+
+// encodeImage encodes a resized variant, keeping JPEG sources as JPEG and everything else as PNG
+func encodeImage(img image.Image, sourceType string) ([]byte, string, string, error) {
+	var buf bytes.Buffer
+
+	if sourceType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+			return nil, "", "", err
+		}
+		return buf.Bytes(), "image/jpeg", ".jpg", nil
+	}
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", "", err
+	}
+	return buf.Bytes(), "image/png", ".png", nil
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/shipping"
+	"github.com/Code-byme/e-commerce/internal/tax"
+)
+
+// pricingItem is a product and quantity to price. The product's effective price must be set.
+type pricingItem struct {
+	Product  models.Product
+	Quantity int
+}
+
+// pricingRequest describes a cart or order to price
+type pricingRequest struct {
+	UserID uint
+	Items  []pricingItem
+	Coupon *models.Coupon
+
+	// Address enables shipping and tax; without it neither is charged
+	Address        *tax.Address
+	ShippingMethod string
+	// RequireShipping rejects an address no shipping method is available for, as orders must ship
+	RequireShipping bool
+}
+
+// pricingResult is the price breakdown of a cart or order.
+// TotalAmount is the grand total: subtotal - discounts + shipping, plus tax unless prices include it.
+type pricingResult struct {
+	Lines     []pricedLine
+	Discounts []models.OrderDiscount
+
+	// CouponError explains why the requested coupon gives no discount
+	CouponError  error
+	CouponAmount float64
+	FreeShipping bool
+
+	Shipping *ShippingQuote
+	Tax      *tax.Result
+
+	SubtotalAmount   float64
+	DiscountAmount   float64
+	ShippingAmount   float64
+	TaxAmount        float64
+	TotalAmount      float64
+	PricesIncludeTax bool
+}
+
+// priceItems runs the pricing pipeline shared by carts and orders: promotions, then the coupon on
+// what promotions left, then shipping on the discounted merchandise, then tax on the discounted lines.
+func priceItems(q querier, req *pricingRequest) (*pricingResult, error) {
+	result := &pricingResult{
+		Lines:            make([]pricedLine, 0, len(req.Items)),
+		PricesIncludeTax: tax.PricesIncludeTax(),
+	}
+
+	for _, item := range req.Items {
+		amount := item.Product.EffectivePrice * float64(item.Quantity)
+		result.SubtotalAmount += amount
+		result.Lines = append(result.Lines, pricedLine{
+			ProductID:  item.Product.ID,
+			CategoryID: item.Product.CategoryID,
+			UnitPrice:  item.Product.EffectivePrice,
+			Quantity:   item.Quantity,
+			Amount:     amount,
+			TaxClass:   item.Product.TaxClass,
+		})
+	}
+	result.SubtotalAmount = roundCents(result.SubtotalAmount)
+
+	// Apply automatic promotions and allocate their discounts to the lines
+	promotions, err := loadActivePromotions(q)
+	if err != nil {
+		return nil, err
+	}
+	result.Discounts = applyPromotions(promotions, result.Lines)
+	for _, line := range result.Lines {
+		result.DiscountAmount += line.Discount
+	}
+
+	// Apply the coupon to the amounts left after promotions
+	couponIndex := -1
+	if req.Coupon != nil {
+		discount, err := evaluateUserCoupon(q, req.Coupon, req.UserID, result.Lines)
+		if err != nil {
+			result.CouponError = err
+		} else {
+			couponIndex = len(result.Discounts)
+			result.Discounts = append(result.Discounts, *discount)
+			result.CouponAmount = discount.Amount
+			result.DiscountAmount += discount.Amount
+			result.FreeShipping = req.Coupon.Type == models.CouponTypeFreeShipping
+			allocateCouponDiscount(req.Coupon, result.Lines, discount.Amount)
+		}
+	}
+	result.DiscountAmount = roundCents(result.DiscountAmount)
+	merchandise := result.SubtotalAmount - result.DiscountAmount
+
+	if req.Address != nil {
+		address := *req.Address
+		address.Normalize()
+
+		// Quote shipping on the discounted merchandise; a free shipping coupon discounts the chosen method
+		items := make([]shipping.Item, 0, len(req.Items))
+		for _, item := range req.Items {
+			items = append(items, shipping.Item{
+				ProductID: item.Product.ID,
+				Quantity:  item.Quantity,
+				Weight:    item.Product.Weight,
+				Length:    item.Product.Length,
+				Width:     item.Product.Width,
+				Height:    item.Product.Height,
+			})
+		}
+		quotes, err := quoteShipping(
+			shipping.Address{Country: address.Country, Region: address.Region, PostalCode: address.PostalCode},
+			items, merchandise, result.FreeShipping,
+		)
+		if err != nil {
+			return nil, err
+		}
+		result.Shipping, err = selectShippingQuote(quotes, req.ShippingMethod)
+		if err != nil {
+			return nil, err
+		}
+		if result.Shipping == nil && req.RequireShipping {
+			return nil, errors.New("shipping not available for this address")
+		}
+		if result.Shipping != nil {
+			result.ShippingAmount = result.Shipping.Cost
+			if result.Shipping.Discount > 0 && couponIndex >= 0 {
+				result.Discounts[couponIndex].Amount = result.Shipping.Discount
+				result.CouponAmount = result.Shipping.Discount
+				result.DiscountAmount = roundCents(result.DiscountAmount + result.Shipping.Discount)
+			}
+		}
+
+		// Tax the discounted lines at the destination
+		result.Tax, err = calculateLineTaxes(address, result.Lines)
+		if err != nil {
+			return nil, err
+		}
+		result.TaxAmount = result.Tax.TaxAmount
+		result.PricesIncludeTax = result.Tax.PricesIncludeTax
+	}
+
+	result.TotalAmount = result.SubtotalAmount - result.DiscountAmount + result.ShippingAmount
+	if !result.PricesIncludeTax {
+		result.TotalAmount += result.TaxAmount
+	}
+	result.TotalAmount = roundCents(result.TotalAmount)
+
+	return result, nil
+}
+
+// lineTaxAmount returns the tax of a priced line, or zero when no tax was calculated
+func (r *pricingResult) lineTaxAmount(i int) float64 {
+	if r.Tax == nil {
+		return 0
+	}
+	return r.Tax.Lines[i].TaxAmount
+}
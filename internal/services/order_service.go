@@ -4,9 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/Code-byme/e-commerce/internal/database"
 	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/tax"
+	"github.com/lib/pq"
 )
 
 // OrderService handles order operations
@@ -26,6 +30,32 @@ type CreateOrderRequest struct {
 	ShippingAddress string                   `json:"shipping_address" binding:"required"`
 	PaymentMethod   string                   `json:"payment_method" binding:"required"`
 	Items           []CreateOrderItemRequest `json:"items" binding:"required,min=1"`
+	CouponCode      string                   `json:"coupon_code"`
+
+	// Structured destination used to quote shipping and calculate tax
+	ShippingCountry    string `json:"shipping_country" binding:"required,len=2"`
+	ShippingRegion     string `json:"shipping_region"`
+	ShippingPostalCode string `json:"shipping_postal_code"`
+
+	// ShippingMethod is the code of a quoted shipping method; the cheapest quote is used when omitted
+	ShippingMethod string `json:"shipping_method"`
+}
+
+// OrderPlacedError is returned when a step after an order was committed fails. The order exists,
+// so the request must not be repeated to place it again; Cancelled reports whether the order was
+// cancelled, e.g. because its payment failed.
+type OrderPlacedError struct {
+	OrderID   uint
+	Cancelled bool
+	Err       error
+}
+
+func (e *OrderPlacedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OrderPlacedError) Unwrap() error {
+	return e.Err
 }
 
 // CreateOrderItemRequest represents an item in the order creation request
@@ -36,7 +66,8 @@ type CreateOrderItemRequest struct {
 
 // UpdateOrderStatusRequest represents the request to update order status
 type UpdateOrderStatusRequest struct {
-	Status string `json:"status" binding:"required,oneof=pending confirmed shipped delivered cancelled"`
+	Status string `json:"status" binding:"required,oneof=pending confirmed partially_shipped shipped delivered cancelled"`
+	Note   string `json:"note"`
 }
 
 // OrderFilter represents order filtering options
@@ -45,17 +76,30 @@ type OrderFilter struct {
 	Status    *string `json:"status"`
 	StartDate *string `json:"start_date"`
 	EndDate   *string `json:"end_date"`
+	SortBy    string  `json:"sort_by"`
+	SortOrder string  `json:"sort_order"`
+	Cursor    string  `json:"cursor"`
+	SkipCount bool    `json:"skip_count"`
 	Page      int     `json:"page"`
 	Limit     int     `json:"limit"`
 }
 
 // OrderListResponse represents the paginated order list response
 type OrderListResponse struct {
-	Orders []models.Order `json:"orders"`
-	Total  int            `json:"total"`
-	Page   int            `json:"page"`
-	Limit  int            `json:"limit"`
-	Pages  int            `json:"pages"`
+	Orders     []models.Order `json:"orders"`
+	Total      *int           `json:"total,omitempty"`
+	Page       int            `json:"page"`
+	Limit      int            `json:"limit"`
+	Pages      *int           `json:"pages,omitempty"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	HasMore    bool           `json:"has_more"`
+}
+
+// orderSortColumns lists the columns orders can be sorted by
+var orderSortColumns = map[string]sortColumn{
+	"created_at":   {expr: "o.created_at", cast: "timestamp"},
+	"updated_at":   {expr: "o.updated_at", cast: "timestamp"},
+	"total_amount": {expr: "o.total_amount", cast: "numeric"},
 }
 
 // CreateOrder creates a new order
@@ -67,17 +111,50 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*model
 	}
 	defer tx.Rollback()
 
-	// Calculate total amount and validate products
-	var totalAmount float64
+	order, err := s.placeOrder(tx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.finalizeOrder(order)
+}
+
+// placeOrder validates and prices an order, reserves its stock and creates it within the caller's
+// transaction, so other services can combine order creation with their own changes. The order is
+// created pending; finalizeOrder must be called once the transaction is committed.
+func (s *OrderService) placeOrder(tx *sql.Tx, userID uint, req *CreateOrderRequest) (*models.Order, error) {
+	// Lock the ordered products in a consistent order so concurrent orders reserve stock one at a time
+	productIDs := make([]int64, 0, len(req.Items))
+	requested := make(map[uint]int)
+	for _, item := range req.Items {
+		productIDs = append(productIDs, int64(item.ProductID))
+		requested[item.ProductID] += item.Quantity
+	}
+	if _, err := tx.Exec(
+		"SELECT id FROM products WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(productIDs),
+	); err != nil {
+		return nil, fmt.Errorf("failed to lock products: %w", err)
+	}
+
+	// Validate products and stock
 	var orderItems []models.OrderItem
+	var items []pricingItem
 
 	for _, item := range req.Items {
 		// Get product details
 		var product models.Product
 		err := tx.QueryRow(
-			"SELECT id, name, price, stock FROM products WHERE id = $1 AND is_active = true",
+			"SELECT id, name, price, "+productEffectivePriceExpr("")+", stock, COALESCE(category_id, 0), tax_class, weight, length, width, height FROM products WHERE id = $1 AND "+productVisibleCondition(""),
 			item.ProductID,
-		).Scan(&product.ID, &product.Name, &product.Price, &product.Stock)
+		).Scan(
+			&product.ID, &product.Name, &product.Price, &product.EffectivePrice, &product.Stock, &product.CategoryID, &product.TaxClass,
+			&product.Weight, &product.Length, &product.Width, &product.Height,
+		)
 
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -86,38 +163,74 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*model
 			return nil, fmt.Errorf("failed to get product: %w", err)
 		}
 
-		// Check stock availability
-		if product.Stock < item.Quantity {
+		// Check stock availability for all lines of the product
+		if product.Stock < requested[item.ProductID] {
 			return nil, fmt.Errorf("insufficient stock for product %s (available: %d, requested: %d)",
-				product.Name, product.Stock, item.Quantity)
+				product.Name, product.Stock, requested[item.ProductID])
 		}
 
-		// Calculate item total
-		itemTotal := product.Price * float64(item.Quantity)
-		totalAmount += itemTotal
-
-		// Create order item
-		orderItem := models.OrderItem{
+		// Items are priced at the price in effect now, including any running sale
+		orderItems = append(orderItems, models.OrderItem{
 			ProductID: item.ProductID,
 			Quantity:  item.Quantity,
-			Price:     product.Price,
+			Price:     product.EffectivePrice,
+		})
+		items = append(items, pricingItem{Product: product, Quantity: item.Quantity})
+	}
+
+	// Load the coupon; its row stays locked until commit so usage limits hold under concurrent checkouts
+	var coupon *models.Coupon
+	var err error
+	if code := normalizeCouponCode(req.CouponCode); code != "" {
+		coupon, err = loadCoupon(tx, "code = $1", code, true)
+		if err != nil {
+			return nil, err
 		}
-		orderItems = append(orderItems, orderItem)
+	}
+
+	// Price the order with the same pipeline as the cart
+	address := tax.Address{Country: req.ShippingCountry, Region: req.ShippingRegion, PostalCode: req.ShippingPostalCode}
+	address.Normalize()
+	pricing, err := priceItems(tx, &pricingRequest{
+		UserID:          userID,
+		Items:           items,
+		Coupon:          coupon,
+		Address:         &address,
+		ShippingMethod:  req.ShippingMethod,
+		RequireShipping: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pricing.CouponError != nil {
+		return nil, pricing.CouponError
+	}
+	for i := range orderItems {
+		orderItems[i].DiscountAmount = pricing.Lines[i].Discount
+		orderItems[i].TaxAmount = pricing.lineTaxAmount(i)
+	}
+	var shippingQuote ShippingQuote
+	if pricing.Shipping != nil {
+		shippingQuote = *pricing.Shipping
 	}
 
 	// Create order
 	var order models.Order
 	orderQuery := `
-		INSERT INTO orders (user_id, status, total_amount, shipping_address, payment_method, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
-		RETURNING id, user_id, status, total_amount, shipping_address, payment_method, created_at, updated_at
+		INSERT INTO orders (user_id, status, total_amount, subtotal_amount, discount_amount, shipping_address, payment_method,
+		                    shipping_country, shipping_region, shipping_postal_code, tax_amount, prices_include_tax,
+		                    shipping_method, shipping_method_name, shipping_carrier, shipping_amount, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW(), NOW())
+		RETURNING id, user_id, status, total_amount, discount_amount, shipping_address, payment_method, created_at, updated_at
 	`
 
 	err = tx.QueryRow(
 		orderQuery,
-		userID, "pending", totalAmount, req.ShippingAddress, req.PaymentMethod,
+		userID, "pending", pricing.TotalAmount, pricing.SubtotalAmount, pricing.DiscountAmount, req.ShippingAddress, req.PaymentMethod,
+		address.Country, address.Region, address.PostalCode, pricing.TaxAmount, pricing.PricesIncludeTax,
+		shippingQuote.Method, shippingQuote.Name, shippingQuote.Carrier, pricing.ShippingAmount,
 	).Scan(
-		&order.ID, &order.UserID, &order.Status, &order.TotalAmount,
+		&order.ID, &order.UserID, &order.Status, &order.TotalAmount, &order.DiscountAmount,
 		&order.ShippingAddress, &order.PaymentMethod, &order.CreatedAt, &order.UpdatedAt,
 	)
 
@@ -125,13 +238,19 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*model
 		return nil, fmt.Errorf("failed to create order: %w", err)
 	}
 
+	if err := recordOrderStatusChange(tx, order.ID, "", order.Status, &userID, "order placed"); err != nil {
+		return nil, err
+	}
+
 	// Create order items
-	for _, item := range orderItems {
+	itemIDs := make([]uint, len(orderItems))
+	for i, item := range orderItems {
 		itemQuery := `
-			INSERT INTO order_items (order_id, product_id, quantity, price, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, NOW(), NOW())
+			INSERT INTO order_items (order_id, product_id, quantity, price, discount_amount, tax_amount, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+			RETURNING id
 		`
-		_, err = tx.Exec(itemQuery, order.ID, item.ProductID, item.Quantity, item.Price)
+		err = tx.QueryRow(itemQuery, order.ID, item.ProductID, item.Quantity, item.Price, item.DiscountAmount, item.TaxAmount).Scan(&itemIDs[i])
 		if err != nil {
 			return nil, fmt.Errorf("failed to create order item: %w", err)
 		}
@@ -144,20 +263,62 @@ func (s *OrderService) CreateOrder(userID uint, req *CreateOrderRequest) (*model
 		}
 	}
 
-	// Commit transaction
-	if err = tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	// Persist discount and tax lines and count the coupon use
+	if err := insertOrderDiscounts(tx, order.ID, pricing.Discounts); err != nil {
+		return nil, err
+	}
+	if pricing.Tax != nil {
+		if err := insertOrderTaxLines(tx, order.ID, itemIDs, pricing.Tax); err != nil {
+			return nil, err
+		}
+	}
+	if coupon != nil {
+		if err := redeemCoupon(tx, coupon, userID, order.ID, pricing.CouponAmount); err != nil {
+			return nil, err
+		}
+	}
+
+	return &order, nil
+}
+
+// finalizeOrder takes payment for an order created by placeOrder once its transaction is committed.
+// Its errors are OrderPlacedErrors, since the order already exists.
+func (s *OrderService) finalizeOrder(order *models.Order) (*models.Order, error) {
+	// Authorize payment outside the transaction; a failed payment cancels the order,
+	// restoring stock and coupon uses. Orders with nothing to pay are confirmed directly.
+	if order.TotalAmount > 0 {
+		if err := authorizeOrderPayment(s.db, order); err != nil {
+			cancelErr := s.CancelOrder(order.ID, nil, err.Error())
+			if cancelErr != nil {
+				log.Printf("Warning: failed to cancel order %d after payment failure: %v", order.ID, cancelErr)
+			}
+			return nil, &OrderPlacedError{OrderID: order.ID, Cancelled: cancelErr == nil, Err: err}
+		}
+	} else {
+		confirmed, err := confirmOrder(s.db, order.ID, "nothing to pay")
+		if err != nil {
+			return nil, &OrderPlacedError{OrderID: order.ID, Err: err}
+		}
+		if confirmed {
+			notifyOrderStatus(s.db, order.ID, models.OrderStatusConfirmed)
+		}
 	}
 
 	// Get order with items
-	return s.GetOrder(order.ID)
+	placed, err := s.GetOrder(order.ID)
+	if err != nil {
+		return nil, &OrderPlacedError{OrderID: order.ID, Err: err}
+	}
+	return placed, nil
 }
 
 // GetOrder retrieves an order by ID
 func (s *OrderService) GetOrder(id uint) (*models.Order, error) {
 	var order models.Order
 	orderQuery := `
-		SELECT o.id, o.user_id, o.status, o.total_amount, o.shipping_address, o.payment_method, o.created_at, o.updated_at,
+		SELECT o.id, o.user_id, o.status, o.total_amount, o.subtotal_amount, o.discount_amount, o.shipping_address, o.payment_method, o.created_at, o.updated_at,
+		       o.shipping_country, o.shipping_region, o.shipping_postal_code, o.tax_amount, o.prices_include_tax,
+		       o.shipping_method, o.shipping_method_name, o.shipping_carrier, o.shipping_amount, o.refunded_amount,
 		       u.id, u.email, u.first_name, u.last_name, u.role, u.created_at, u.updated_at
 		FROM orders o
 		JOIN users u ON o.user_id = u.id
@@ -165,8 +326,10 @@ func (s *OrderService) GetOrder(id uint) (*models.Order, error) {
 	`
 
 	err := s.db.QueryRow(orderQuery, id).Scan(
-		&order.ID, &order.UserID, &order.Status, &order.TotalAmount,
+		&order.ID, &order.UserID, &order.Status, &order.TotalAmount, &order.SubtotalAmount, &order.DiscountAmount,
 		&order.ShippingAddress, &order.PaymentMethod, &order.CreatedAt, &order.UpdatedAt,
+		&order.ShippingCountry, &order.ShippingRegion, &order.ShippingPostalCode, &order.TaxAmount, &order.PricesIncludeTax,
+		&order.ShippingMethod, &order.ShippingMethodName, &order.ShippingCarrier, &order.ShippingAmount, &order.RefundedAmount,
 		&order.User.ID, &order.User.Email, &order.User.FirstName, &order.User.LastName,
 		&order.User.Role, &order.User.CreatedAt, &order.User.UpdatedAt,
 	)
@@ -180,7 +343,8 @@ func (s *OrderService) GetOrder(id uint) (*models.Order, error) {
 
 	// Get order items
 	itemsQuery := `
-		SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price, oi.created_at, oi.updated_at,
+		SELECT oi.id, oi.order_id, oi.product_id, oi.quantity, oi.price, oi.discount_amount, oi.tax_amount, oi.refunded_quantity,
+		       oi.created_at, oi.updated_at,
 		       p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, p.is_active, p.created_at, p.updated_at
 		FROM order_items oi
 		JOIN products p ON oi.product_id = p.id
@@ -196,8 +360,8 @@ func (s *OrderService) GetOrder(id uint) (*models.Order, error) {
 	for rows.Next() {
 		var item models.OrderItem
 		err := rows.Scan(
-			&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price,
-			&item.CreatedAt, &item.UpdatedAt,
+			&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.Price, &item.DiscountAmount, &item.TaxAmount,
+			&item.RefundedQuantity, &item.CreatedAt, &item.UpdatedAt,
 			&item.Product.ID, &item.Product.Name, &item.Product.Description, &item.Product.Price,
 			&item.Product.Stock, &item.Product.CategoryID, &item.Product.ImageURL, &item.Product.IsActive,
 			&item.Product.CreatedAt, &item.Product.UpdatedAt,
@@ -212,31 +376,58 @@ func (s *OrderService) GetOrder(id uint) (*models.Order, error) {
 		return nil, fmt.Errorf("error iterating order items: %w", err)
 	}
 
-	return &order, nil
-}
+	// Get discount lines
+	order.Discounts, err = loadOrderDiscounts(s.db, order.ID)
+	if err != nil {
+		return nil, err
+	}
 
-// UpdateOrderStatus updates the status of an order
-func (s *OrderService) UpdateOrderStatus(id uint, req *UpdateOrderStatusRequest) (*models.Order, error) {
-	// Check if order exists
-	_, err := s.GetOrder(id)
+	// Get tax lines, attached to their items and summed for the order
+	taxLines, err := loadOrderTaxLines(s.db, order.ID)
 	if err != nil {
 		return nil, err
 	}
+	for i := range order.OrderItems {
+		for _, line := range taxLines {
+			if line.OrderItemID != nil && *line.OrderItemID == order.OrderItems[i].ID {
+				order.OrderItems[i].TaxLines = append(order.OrderItems[i].TaxLines, line)
+			}
+		}
+	}
+	order.TaxLines = summarizeTaxLines(taxLines)
 
-	// Update order status
-	_, err = s.db.Exec(
-		"UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2",
-		req.Status, id,
-	)
+	// Get payments
+	order.Payments, err = loadOrderPayments(s.db, order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get shipments and count the units shipped per item
+	order.Shipments, err = loadOrderShipments(s.db, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, shipment := range order.Shipments {
+		for _, shipped := range shipment.Items {
+			for i := range order.OrderItems {
+				if order.OrderItems[i].ID == shipped.OrderItemID {
+					order.OrderItems[i].ShippedQuantity += shipped.Quantity
+				}
+			}
+		}
+	}
+
+	// Get refunds
+	order.Refunds, err = loadOrderRefunds(s.db, order.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update order status: %w", err)
+		return nil, err
 	}
 
-	// Get updated order
-	return s.GetOrder(id)
+	return &order, nil
 }
 
-// ListOrders retrieves a paginated list of orders with filtering
+// ListOrders retrieves a paginated list of orders with filtering.
+// Pages are addressed either by page number or by an opaque cursor returned from a previous call.
 func (s *OrderService) ListOrders(filter *OrderFilter) (*OrderListResponse, error) {
 	// Set default values
 	if filter.Page <= 0 {
@@ -249,6 +440,40 @@ func (s *OrderService) ListOrders(filter *OrderFilter) (*OrderListResponse, erro
 		filter.Limit = 100
 	}
 
+	// Resolve sorting, falling back to the cursor's sort when none is given
+	var cursor *pageCursor
+	if filter.Cursor != "" {
+		var err error
+		cursor, err = decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if filter.SortBy == "" {
+			filter.SortBy = cursor.Sort
+		}
+		if filter.SortOrder == "" {
+			filter.SortOrder = cursor.Order
+		}
+	}
+
+	if filter.SortBy == "" {
+		filter.SortBy = "created_at"
+	}
+	sortCol, ok := orderSortColumns[filter.SortBy]
+	if !ok {
+		return nil, errors.New("invalid sort field")
+	}
+
+	order, err := normalizeSortOrder(filter.SortOrder, "desc")
+	if err != nil {
+		return nil, err
+	}
+	filter.SortOrder = order
+
+	if cursor != nil && (cursor.Sort != filter.SortBy || cursor.Order != filter.SortOrder) {
+		return nil, errors.New("invalid cursor")
+	}
+
 	// Build WHERE clause
 	whereConditions := []string{}
 	args := []interface{}{}
@@ -280,36 +505,56 @@ func (s *OrderService) ListOrders(filter *OrderFilter) (*OrderListResponse, erro
 
 	whereClause := ""
 	if len(whereConditions) > 0 {
-		whereClause = "WHERE " + whereConditions[0]
-		for i := 1; i < len(whereConditions); i++ {
-			whereClause += " AND " + whereConditions[i]
-		}
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
 	}
 
-	// Count total orders
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM orders o %s", whereClause)
-	var total int
-	err := s.db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count orders: %w", err)
+	response := &OrderListResponse{
+		Page:  filter.Page,
+		Limit: filter.Limit,
 	}
 
-	// Calculate pagination
-	offset := (filter.Page - 1) * filter.Limit
-	pages := (total + filter.Limit - 1) / filter.Limit
-
-	// Get orders
+	// Count total orders unless the caller opted out
+	if !filter.SkipCount {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM orders o %s", whereClause)
+		var total int
+		err := s.db.QueryRow(countQuery, args...).Scan(&total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count orders: %w", err)
+		}
+		pages := (total + filter.Limit - 1) / filter.Limit
+		response.Total = &total
+		response.Pages = &pages
+	}
+
+	// Position the page either after the cursor or by offset
+	pagination := ""
+	if cursor != nil {
+		condition, cursorArgs := keysetCondition(sortCol, "o.id", filter.SortOrder, cursor, argIndex)
+		whereConditions = append(whereConditions, condition)
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+		args = append(args, cursorArgs...)
+		argIndex += len(cursorArgs)
+		pagination = fmt.Sprintf("LIMIT $%d", argIndex)
+		args = append(args, filter.Limit+1)
+	} else {
+		offset := (filter.Page - 1) * filter.Limit
+		pagination = fmt.Sprintf("LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+		args = append(args, filter.Limit+1, offset)
+	}
+
+	// Get orders, fetching one extra row to detect further pages
 	query := fmt.Sprintf(`
-		SELECT o.id, o.user_id, o.status, o.total_amount, o.shipping_address, o.payment_method, o.created_at, o.updated_at,
-		       u.id, u.email, u.first_name, u.last_name, u.role, u.created_at, u.updated_at
+		SELECT o.id, o.user_id, o.status, o.total_amount, o.subtotal_amount, o.discount_amount, o.shipping_address, o.payment_method, o.created_at, o.updated_at,
+		       o.shipping_country, o.shipping_region, o.shipping_postal_code, o.tax_amount, o.prices_include_tax,
+		       o.shipping_method, o.shipping_method_name, o.shipping_carrier, o.shipping_amount, o.refunded_amount,
+		       u.id, u.email, u.first_name, u.last_name, u.role, u.created_at, u.updated_at,
+		       (%s)::text
 		FROM orders o
 		JOIN users u ON o.user_id = u.id
 		%s
-		ORDER BY o.created_at DESC
-		LIMIT $%d OFFSET $%d
-	`, whereClause, argIndex, argIndex+1)
-
-	args = append(args, filter.Limit, offset)
+		%s
+		%s
+	`, sortCol.expr, whereClause, orderByClause(sortCol, "o.id", filter.SortOrder), pagination)
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
@@ -318,31 +563,44 @@ func (s *OrderService) ListOrders(filter *OrderFilter) (*OrderListResponse, erro
 	defer rows.Close()
 
 	var orders []models.Order
+	var sortValues []string
 	for rows.Next() {
 		var order models.Order
+		var sortValue string
 		err := rows.Scan(
-			&order.ID, &order.UserID, &order.Status, &order.TotalAmount,
+			&order.ID, &order.UserID, &order.Status, &order.TotalAmount, &order.SubtotalAmount, &order.DiscountAmount,
 			&order.ShippingAddress, &order.PaymentMethod, &order.CreatedAt, &order.UpdatedAt,
+			&order.ShippingCountry, &order.ShippingRegion, &order.ShippingPostalCode, &order.TaxAmount, &order.PricesIncludeTax,
+			&order.ShippingMethod, &order.ShippingMethodName, &order.ShippingCarrier, &order.ShippingAmount, &order.RefundedAmount,
 			&order.User.ID, &order.User.Email, &order.User.FirstName, &order.User.LastName,
 			&order.User.Role, &order.User.CreatedAt, &order.User.UpdatedAt,
+			&sortValue,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order: %w", err)
 		}
 		orders = append(orders, order)
+		sortValues = append(sortValues, sortValue)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating orders: %w", err)
 	}
 
-	return &OrderListResponse{
-		Orders: orders,
-		Total:  total,
-		Page:   filter.Page,
-		Limit:  filter.Limit,
-		Pages:  pages,
-	}, nil
+	if len(orders) > filter.Limit {
+		orders = orders[:filter.Limit]
+		last := orders[len(orders)-1]
+		response.HasMore = true
+		response.NextCursor = encodeCursor(&pageCursor{
+			Sort:  filter.SortBy,
+			Order: filter.SortOrder,
+			Value: sortValues[filter.Limit-1],
+			ID:    last.ID,
+		})
+	}
+
+	response.Orders = orders
+	return response, nil
 }
 
 // GetUserOrders retrieves orders for a specific user
@@ -355,8 +613,8 @@ func (s *OrderService) GetUserOrders(userID uint, page, limit int) (*OrderListRe
 	return s.ListOrders(filter)
 }
 
-// CancelOrder cancels an order and restores product stock
-func (s *OrderService) CancelOrder(id uint) error {
+// CancelOrder cancels an order, restores product stock and records who cancelled it and why
+func (s *OrderService) CancelOrder(id uint, actorID *uint, note string) error {
 	// Start a transaction
 	tx, err := s.db.Begin()
 	if err != nil {
@@ -367,7 +625,7 @@ func (s *OrderService) CancelOrder(id uint) error {
 	// Check if order exists and can be cancelled
 	var order models.Order
 	err = tx.QueryRow(
-		"SELECT id, status FROM orders WHERE id = $1",
+		"SELECT id, status FROM orders WHERE id = $1 FOR UPDATE",
 		id,
 	).Scan(&order.ID, &order.Status)
 
@@ -382,8 +640,8 @@ func (s *OrderService) CancelOrder(id uint) error {
 		return errors.New("order is already cancelled")
 	}
 
-	if order.Status == "delivered" {
-		return errors.New("cannot cancel delivered order")
+	if !canTransitionOrder(order.Status, models.OrderStatusCancelled) {
+		return fmt.Errorf("cannot cancel %s order", order.Status)
 	}
 
 	// Update order status to cancelled
@@ -392,10 +650,20 @@ func (s *OrderService) CancelOrder(id uint) error {
 		return fmt.Errorf("failed to cancel order: %w", err)
 	}
 
-	// Restore product stock
+	if err := recordOrderStatusChange(tx, id, order.Status, models.OrderStatusCancelled, actorID, note); err != nil {
+		return err
+	}
+
+	// Restore product stock, except units already restocked by refunds
 	_, err = tx.Exec(`
 		UPDATE products 
-		SET stock = stock + oi.quantity, updated_at = NOW()
+		SET stock = stock + oi.quantity - COALESCE((
+		        SELECT SUM(ri.quantity)
+		        FROM refund_items ri
+		        JOIN refunds r ON r.id = ri.refund_id
+		        WHERE ri.order_item_id = oi.id AND ri.restocked AND r.status = 'succeeded'
+		    ), 0),
+		    updated_at = NOW()
 		FROM order_items oi
 		WHERE oi.order_id = $1 AND oi.product_id = products.id
 	`, id)
@@ -403,11 +671,23 @@ func (s *OrderService) CancelOrder(id uint) error {
 		return fmt.Errorf("failed to restore product stock: %w", err)
 	}
 
+	// Give back coupon uses
+	if err := releaseCouponRedemptions(tx, id); err != nil {
+		return err
+	}
+
 	// Commit transaction
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	// Release the payment; the order stays cancelled if the provider fails and an admin can retry the void
+	if err := releaseOrderPayments(s.db, id); err != nil {
+		log.Printf("Warning: failed to release payments of cancelled order %d: %v", id, err)
+	}
+
+	notifyOrderStatus(s.db, id, models.OrderStatusCancelled)
+
 	return nil
 }
 
@@ -423,14 +703,22 @@ func (s *OrderService) GetOrderStatistics() (map[string]interface{}, error) {
 	}
 	stats["total_orders"] = totalOrders
 
-	// Total revenue
+	// Total revenue, net of refunds
 	var totalRevenue float64
-	err = s.db.QueryRow("SELECT COALESCE(SUM(total_amount), 0) FROM orders WHERE status != 'cancelled'").Scan(&totalRevenue)
+	err = s.db.QueryRow("SELECT COALESCE(SUM(total_amount - refunded_amount), 0) FROM orders WHERE status != 'cancelled'").Scan(&totalRevenue)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total revenue: %w", err)
 	}
 	stats["total_revenue"] = totalRevenue
 
+	// Total refunded
+	var totalRefunded float64
+	err = s.db.QueryRow("SELECT COALESCE(SUM(refunded_amount), 0) FROM orders").Scan(&totalRefunded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get total refunded: %w", err)
+	}
+	stats["total_refunded"] = totalRefunded
+
 	// Orders by status
 	statusQuery := `
 		SELECT status, COUNT(*) as count
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/notification"
+)
+
+// orderTransitions lists the statuses an order may move to from each status. Delivered and
+// cancelled orders are final; delivered orders are handled through returns instead.
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending:          {models.OrderStatusConfirmed, models.OrderStatusCancelled},
+	models.OrderStatusConfirmed:        {models.OrderStatusPartiallyShipped, models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusPartiallyShipped: {models.OrderStatusShipped},
+	models.OrderStatusShipped:          {models.OrderStatusDelivered},
+	models.OrderStatusDelivered:        {},
+	models.OrderStatusCancelled:        {},
+}
+
+// isFulfillmentStatus reports whether a status is derived from the order's shipments
+func isFulfillmentStatus(status string) bool {
+	return status == models.OrderStatusPartiallyShipped ||
+		status == models.OrderStatusShipped ||
+		status == models.OrderStatusDelivered
+}
+
+// canTransitionOrder reports whether an order may move from one status to another
+func canTransitionOrder(from, to string) bool {
+	for _, status := range orderTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrderTransition returns an error unless an order may move from one status to another
+func checkOrderTransition(from, to string) error {
+	if from == to {
+		return fmt.Errorf("invalid status transition: order is already %s", from)
+	}
+	if !canTransitionOrder(from, to) {
+		return fmt.Errorf("invalid status transition: order cannot move from %s to %s", from, to)
+	}
+	return nil
+}
+
+// UpdateOrderStatus moves an order to a new status following the order state machine.
+// Cancelling restores stock, coupon uses and payments. Shipped and delivered statuses follow
+// the order's shipments and cannot be set directly.
+func (s *OrderService) UpdateOrderStatus(id uint, req *UpdateOrderStatusRequest, actorID *uint) (*models.Order, error) {
+	if isFulfillmentStatus(req.Status) {
+		return nil, fmt.Errorf("invalid status transition: %s is set from the order's shipments", req.Status)
+	}
+	if req.Status == models.OrderStatusCancelled {
+		if err := s.CancelOrder(id, actorID, req.Note); err != nil {
+			return nil, err
+		}
+		return s.GetOrder(id)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the order so concurrent transitions are applied one at a time
+	var status string
+	err = tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if err := checkOrderTransition(status, req.Status); err != nil {
+		return nil, err
+	}
+
+	if err := setOrderStatus(tx, id, status, req.Status, actorID, req.Note); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	notifyOrderStatus(s.db, id, req.Status)
+
+	return s.GetOrder(id)
+}
+
+// GetOrderHistory retrieves the status changes of an order, oldest first
+func (s *OrderService) GetOrderHistory(id uint) ([]models.OrderStatusHistory, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1)", id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("order not found")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, actor_id, COALESCE(note, ''), created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.OrderStatusHistory{}
+	for rows.Next() {
+		var h models.OrderStatusHistory
+		var actorID sql.NullInt64
+		if err := rows.Scan(&h.ID, &h.OrderID, &h.FromStatus, &h.ToStatus, &actorID, &h.Note, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan order history: %w", err)
+		}
+		if actorID.Valid {
+			id := uint(actorID.Int64)
+			h.ActorID = &id
+		}
+		history = append(history, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order history: %w", err)
+	}
+
+	return history, nil
+}
+
+// setOrderStatus moves an order to a new status and records the change in its history
+func setOrderStatus(q querier, orderID uint, from, to string, actorID *uint, note string) error {
+	_, err := q.Exec("UPDATE orders SET status = $1, updated_at = NOW() WHERE id = $2", to, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+	return recordOrderStatusChange(q, orderID, from, to, actorID, note)
+}
+
+// recordOrderStatusChange appends a status change to the order's history. An empty from
+// status records the order's creation and a nil actor a change made by the system.
+func recordOrderStatusChange(q querier, orderID uint, from, to string, actorID *uint, note string) error {
+	_, err := q.Exec(`
+		INSERT INTO order_status_history (order_id, from_status, to_status, actor_id, note, created_at)
+		VALUES ($1, NULLIF($2, ''), $3, $4, NULLIF($5, ''), NOW())
+	`, orderID, from, to, actorID, note)
+	if err != nil {
+		return fmt.Errorf("failed to record order status change: %w", err)
+	}
+	return nil
+}
+
+var (
+	notifierOnce sync.Once
+	notifier     notification.Notifier
+)
+
+// getNotifier returns the notifier configured with NOTIFIER, falling back to logging messages
+func getNotifier() notification.Notifier {
+	notifierOnce.Do(func() {
+		n, err := notification.NewFromEnv()
+		if err != nil {
+			log.Printf("Warning: failed to configure notifier: %v", err)
+			log.Println("Falling back to log notifier")
+			n = &notification.LogNotifier{}
+		}
+		notifier = n
+	})
+	return notifier
+}
+
+// orderStatusSubjects holds the subject of the message sent to the customer for each status
+var orderStatusSubjects = map[string]string{
+	models.OrderStatusConfirmed:        "Your order #%d is confirmed",
+	models.OrderStatusPartiallyShipped: "Part of your order #%d has shipped",
+	models.OrderStatusShipped:          "Your order #%d has shipped",
+	models.OrderStatusDelivered:        "Your order #%d has been delivered",
+	models.OrderStatusCancelled:        "Your order #%d has been cancelled",
+}
+
+// notifyOrderStatus tells the customer about their order's new status in the background.
+// It is called after the change is committed; delivery failures are only logged.
+func notifyOrderStatus(db *sql.DB, orderID uint, status string) {
+	subject, ok := orderStatusSubjects[status]
+	if !ok {
+		return
+	}
+
+	go func() {
+		msg := &notification.Message{
+			Subject: fmt.Sprintf(subject, orderID),
+			Body:    fmt.Sprintf("The status of order #%d is now %s.", orderID, status),
+		}
+		err := db.QueryRow(`
+			SELECT u.id, u.email FROM orders o JOIN users u ON u.id = o.user_id WHERE o.id = $1
+		`, orderID).Scan(&msg.UserID, &msg.Email)
+		if err != nil {
+			log.Printf("Warning: failed to load customer of order %d for notification: %v", orderID, err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := getNotifier().Send(ctx, msg); err != nil {
+			log.Printf("Warning: failed to notify customer of order %d: %v", orderID, err)
+		}
+	}()
+}
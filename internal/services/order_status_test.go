@@ -0,0 +1,87 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+func TestCheckOrderTransition(t *testing.T) {
+	tests := []struct {
+		from string
+		to   string
+		err  string
+	}{
+		{from: models.OrderStatusPending, to: models.OrderStatusConfirmed},
+		{from: models.OrderStatusPending, to: models.OrderStatusCancelled},
+		{from: models.OrderStatusConfirmed, to: models.OrderStatusShipped},
+		{from: models.OrderStatusConfirmed, to: models.OrderStatusCancelled},
+		{from: models.OrderStatusConfirmed, to: models.OrderStatusPartiallyShipped},
+		{from: models.OrderStatusPartiallyShipped, to: models.OrderStatusShipped},
+		{from: models.OrderStatusShipped, to: models.OrderStatusDelivered},
+		{from: models.OrderStatusPending, to: models.OrderStatusShipped, err: "invalid status transition: order cannot move from pending to shipped"},
+		{from: models.OrderStatusPending, to: models.OrderStatusDelivered, err: "invalid status transition: order cannot move from pending to delivered"},
+		{from: models.OrderStatusConfirmed, to: models.OrderStatusPending, err: "invalid status transition: order cannot move from confirmed to pending"},
+		{from: models.OrderStatusPending, to: models.OrderStatusPartiallyShipped, err: "invalid status transition: order cannot move from pending to partially_shipped"},
+		{from: models.OrderStatusPartiallyShipped, to: models.OrderStatusCancelled, err: "invalid status transition: order cannot move from partially_shipped to cancelled"},
+		{from: models.OrderStatusPartiallyShipped, to: models.OrderStatusDelivered, err: "invalid status transition: order cannot move from partially_shipped to delivered"},
+		{from: models.OrderStatusShipped, to: models.OrderStatusCancelled, err: "invalid status transition: order cannot move from shipped to cancelled"},
+		{from: models.OrderStatusDelivered, to: models.OrderStatusCancelled, err: "invalid status transition: order cannot move from delivered to cancelled"},
+		{from: models.OrderStatusDelivered, to: models.OrderStatusShipped, err: "invalid status transition: order cannot move from delivered to shipped"},
+		{from: models.OrderStatusCancelled, to: models.OrderStatusPending, err: "invalid status transition: order cannot move from cancelled to pending"},
+		{from: models.OrderStatusPending, to: models.OrderStatusPending, err: "invalid status transition: order is already pending"},
+		{from: models.OrderStatusCancelled, to: models.OrderStatusCancelled, err: "invalid status transition: order is already cancelled"},
+		{from: models.OrderStatusPending, to: "refunded", err: "invalid status transition: order cannot move from pending to refunded"},
+		{from: "refunded", to: models.OrderStatusCancelled, err: "invalid status transition: order cannot move from refunded to cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			err := checkOrderTransition(tt.from, tt.to)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				if !canTransitionOrder(tt.from, tt.to) {
+					t.Errorf("canTransitionOrder(%q, %q) = false, want true", tt.from, tt.to)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestOrderTransitionsAreKnown(t *testing.T) {
+	for from, targets := range orderTransitions {
+		for _, to := range targets {
+			if _, ok := orderTransitions[to]; !ok {
+				t.Errorf("%s can move to %s, which has no transitions listed", from, to)
+			}
+		}
+	}
+}
+
+func TestIsFulfillmentStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   bool
+	}{
+		{status: models.OrderStatusPending},
+		{status: models.OrderStatusConfirmed},
+		{status: models.OrderStatusPartiallyShipped, want: true},
+		{status: models.OrderStatusShipped, want: true},
+		{status: models.OrderStatusDelivered, want: true},
+		{status: models.OrderStatusCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := isFulfillmentStatus(tt.status); got != tt.want {
+				t.Errorf("isFulfillmentStatus(%q) = %v, want %v", tt.status, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sortColumn describes a sortable column used for ordering and keyset pagination
+type sortColumn struct {
+	expr string // SQL expression the rows are ordered by
+	cast string // Postgres type the cursor value is cast back to
+}
+
+// pageCursor is the decoded form of an opaque pagination cursor
+type pageCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// encodeCursor encodes the position of the last returned row into an opaque cursor
+func encodeCursor(c *pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes an opaque cursor produced by encodeCursor
+func decodeCursor(s string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == 0 {
+		return nil, errors.New("invalid cursor")
+	}
+
+	return &c, nil
+}
+
+// normalizeSortOrder validates a sort direction and applies the default
+func normalizeSortOrder(order string, defaultOrder string) (string, error) {
+	if order == "" {
+		return defaultOrder, nil
+	}
+
+	order = strings.ToLower(order)
+	if order != "asc" && order != "desc" {
+		return "", errors.New("invalid sort order")
+	}
+	return order, nil
+}
+
+// keysetCondition builds the WHERE condition that selects rows after the cursor.
+// Rows are ordered by (column, id) in the same direction, so a row comparison is enough.
+func keysetCondition(col sortColumn, idExpr, order string, cursor *pageCursor, argIndex int) (string, []interface{}) {
+	op := "<"
+	if order == "asc" {
+		op = ">"
+	}
+
+	condition := fmt.Sprintf("(%s, %s) %s ($%d::%s, $%d)", col.expr, idExpr, op, argIndex, col.cast, argIndex+1)
+	return condition, []interface{}{cursor.Value, cursor.ID}
+}
+
+// orderByClause builds the ORDER BY clause for a sort column with an id tiebreaker
+func orderByClause(col sortColumn, idExpr, order string) string {
+	direction := strings.ToUpper(order)
+	return fmt.Sprintf("ORDER BY %s %s, %s %s", col.expr, direction, idExpr, direction)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor pageCursor
+	}{
+		{name: "price", cursor: pageCursor{Sort: "price", Order: "asc", Value: "19.99", ID: 42}},
+		{name: "time", cursor: pageCursor{Sort: "created_at", Order: "desc", Value: "2024-05-01T10:00:00.123456Z", ID: 7}},
+		{name: "text with separators", cursor: pageCursor{Sort: "name", Order: "asc", Value: `Café "Deluxe", 50/50 & more`, ID: 1}},
+		{name: "empty value", cursor: pageCursor{Sort: "name", Order: "desc", ID: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded := encodeCursor(&tt.cursor)
+			if _, err := base64.RawURLEncoding.DecodeString(encoded); err != nil {
+				t.Fatalf("cursor %q is not URL-safe base64: %v", encoded, err)
+			}
+
+			decoded, err := decodeCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeCursor(%q) error: %v", encoded, err)
+			}
+			if !reflect.DeepEqual(*decoded, tt.cursor) {
+				t.Errorf("decoded %+v, want %+v", *decoded, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "empty", cursor: ""},
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "padded base64", cursor: base64.URLEncoding.EncodeToString([]byte(`{"s":"price","o":"asc","v":"1","id":1}`))},
+		{name: "not JSON", cursor: encode("price:1:1")},
+		{name: "no id", cursor: encode(`{"s":"price","o":"asc","v":"1"}`)},
+		{name: "zero id", cursor: encode(`{"s":"price","o":"asc","v":"1","id":0}`)},
+		{name: "negative id", cursor: encode(`{"s":"price","o":"asc","v":"1","id":-1}`)},
+		{name: "id of the wrong type", cursor: encode(`{"s":"price","o":"asc","v":"1","id":"1"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := decodeCursor(tt.cursor); err == nil {
+				t.Errorf("decodeCursor(%q) = %+v, want an error", tt.cursor, cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/payment"
+	"github.com/lib/pq"
+)
+
+var (
+	paymentProviderOnce sync.Once
+	paymentProvider     payment.PaymentProvider
+)
+
+// getPaymentProvider returns the provider configured with PAYMENT_PROVIDER, falling back to the simulated gateway
+func getPaymentProvider() payment.PaymentProvider {
+	paymentProviderOnce.Do(func() {
+		provider, err := payment.NewFromEnv()
+		if err != nil {
+			log.Printf("Warning: failed to configure payment provider: %v", err)
+			log.Println("Falling back to simulated payment provider")
+			provider, _ = payment.NewSimulatedProvider(payment.OutcomeSucceed)
+		}
+		paymentProvider = provider
+	})
+	return paymentProvider
+}
+
+// paymentColumns lists the payment columns in the order scanned by loadPayments
+const paymentColumns = `id, order_id, provider, COALESCE(provider_reference, ''), method, status, amount,
+	captured_amount, refunded_amount, currency, COALESCE(failure_reason, ''), created_at, updated_at`
+
+// PaymentService handles capturing and voiding order payments
+type PaymentService struct {
+	db *sql.DB
+}
+
+// NewPaymentService creates a new payment service
+func NewPaymentService() *PaymentService {
+	return &PaymentService{
+		db: database.GetDB(),
+	}
+}
+
+// CapturePaymentRequest represents the request to capture an authorized payment.
+// The full authorized amount is captured when Amount is omitted.
+type CapturePaymentRequest struct {
+	Amount *float64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+// CapturePayment collects the order's authorized payment
+func (s *PaymentService) CapturePayment(orderID uint, req *CapturePaymentRequest) (*models.Payment, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the authorization so it cannot be captured or voided twice
+	p, err := loadAuthorizedPayment(tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	amount := p.Amount
+	if req.Amount != nil {
+		amount = roundCents(*req.Amount)
+		if amount > p.Amount {
+			return nil, errors.New("invalid payment: capture amount exceeds authorized amount")
+		}
+	}
+
+	if err := capturePayment(tx, p, amount); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return loadPayment(s.db, p.ID)
+}
+
+// VoidPayment releases the order's authorized payment without collecting it
+func (s *PaymentService) VoidPayment(orderID uint) (*models.Payment, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := loadAuthorizedPayment(tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := voidPayment(tx, p); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return loadPayment(s.db, p.ID)
+}
+
+// authorizeOrderPayment asks the provider to authorize the order total and records the attempt.
+// The order is confirmed on success; declines and timeouts are recorded and returned as errors.
+func authorizeOrderPayment(db *sql.DB, order *models.Order) error {
+	provider := getPaymentProvider()
+	currency := payment.Currency()
+
+	// Record the attempt first so a crash during the provider call leaves a trace
+	var paymentID uint
+	err := db.QueryRow(`
+		INSERT INTO payments (order_id, provider, method, status, amount, currency, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING id
+	`, order.ID, provider.Name(), order.PaymentMethod, models.PaymentStatusPending, order.TotalAmount, currency).Scan(&paymentID)
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), payment.Timeout())
+	defer cancel()
+	result, authErr := provider.Authorize(ctx, &payment.AuthorizeRequest{
+		OrderID:       order.ID,
+		Amount:        order.TotalAmount,
+		Currency:      currency,
+		PaymentMethod: order.PaymentMethod,
+	})
+
+	if authErr != nil {
+		status := models.PaymentStatusFailed
+		reason := authErr.Error()
+		if errors.Is(authErr, payment.ErrDeclined) {
+			status = models.PaymentStatusDeclined
+			if result != nil && result.Message != "" {
+				reason = result.Message
+			}
+		}
+		if _, err := db.Exec(`
+			UPDATE payments SET status = $1, failure_reason = $2, updated_at = NOW() WHERE id = $3
+		`, status, reason, paymentID); err != nil {
+			log.Printf("Warning: failed to record payment %d failure: %v", paymentID, err)
+		}
+
+		if status == models.PaymentStatusDeclined {
+			return fmt.Errorf("payment declined: %s", reason)
+		}
+		return providerError("authorization", authErr)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE payments SET status = $1, provider_reference = $2, updated_at = NOW() WHERE id = $3
+	`, models.PaymentStatusAuthorized, result.Reference, paymentID)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	// The order may have been cancelled while the provider was authorizing
+	var orderStatus string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", order.ID).Scan(&orderStatus); err != nil {
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+	confirmed := false
+	if orderStatus == models.OrderStatusPending {
+		if confirmed, err = confirmOrder(tx, order.ID, "payment authorized"); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if confirmed {
+		notifyOrderStatus(db, order.ID, models.OrderStatusConfirmed)
+	}
+
+	// Release the funds held for a cancelled order; the authorization is recorded first so a
+	// failed void can be retried
+	if orderStatus == models.OrderStatusCancelled {
+		return releasePayment(db, order.ID, paymentID)
+	}
+
+	return nil
+}
+
+// confirmOrder moves a pending order to confirmed and records the change in its history.
+// It reports whether the order was confirmed; orders that are no longer pending are left as is.
+func confirmOrder(q querier, orderID uint, note string) (bool, error) {
+	result, err := q.Exec(
+		"UPDATE orders SET status = 'confirmed', updated_at = NOW() WHERE id = $1 AND status = 'pending'",
+		orderID,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm order: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, nil
+	}
+
+	err = recordOrderStatusChange(q, orderID, models.OrderStatusPending, models.OrderStatusConfirmed, nil, note)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseOrderPayments voids the authorized payments of a cancelled order and refunds captured ones.
+// Each payment is released and recorded in its own transaction, so the provider calls that
+// succeeded stay recorded when a later one fails, and a retry only releases what is left.
+func releaseOrderPayments(db *sql.DB, orderID uint) error {
+	paymentIDs, err := orderPaymentIDs(db, orderID, models.PaymentStatusAuthorized, models.PaymentStatusCaptured)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, paymentID := range paymentIDs {
+		if err := releasePayment(db, orderID, paymentID); err != nil {
+			errs = append(errs, fmt.Errorf("payment %d: %w", paymentID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// releasePayment voids an authorized payment or refunds what is left of a captured one, and records it
+func releasePayment(db *sql.DB, orderID, paymentID uint) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Released by another request since it was listed
+	payments, err := loadPayments(tx, `
+		WHERE id = $1 AND status IN ('authorized', 'captured') FOR UPDATE
+	`, paymentID)
+	if err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+	p := &payments[0]
+
+	if p.Status == models.PaymentStatusAuthorized {
+		if err := voidPayment(tx, p); err != nil {
+			return err
+		}
+	} else {
+		amount := roundCents(p.CapturedAmount - p.RefundedAmount)
+		if amount <= 0 {
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), payment.Timeout())
+		result, err := getPaymentProvider().Refund(ctx, p.ProviderReference, amount)
+		cancel()
+		if err != nil {
+			return providerError("refund", err)
+		}
+		refund := &models.Refund{
+			OrderID:           orderID,
+			PaymentID:         &p.ID,
+			Amount:            amount,
+			Reason:            "order cancelled",
+			Status:            models.RefundStatusSucceeded,
+			ProviderReference: result.Reference,
+		}
+		if err := insertRefund(tx, refund); err != nil {
+			return err
+		}
+		if err := recordPaymentRefund(tx, p, amount); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// captureOrderPayments captures the full amount of the order's authorized payments, each in its
+// own transaction so a capture the provider made stays recorded when a later one fails
+func captureOrderPayments(db *sql.DB, orderID uint) error {
+	paymentIDs, err := orderPaymentIDs(db, orderID, models.PaymentStatusAuthorized)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, paymentID := range paymentIDs {
+		if err := captureAuthorizedPayment(db, paymentID); err != nil {
+			errs = append(errs, fmt.Errorf("payment %d: %w", paymentID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// captureAuthorizedPayment captures the full amount of a payment unless it is no longer authorized
+func captureAuthorizedPayment(db *sql.DB, paymentID uint) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	payments, err := loadPayments(tx, "WHERE id = $1 AND status = 'authorized' FOR UPDATE", paymentID)
+	if err != nil {
+		return err
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+
+	if err := capturePayment(tx, &payments[0], payments[0].Amount); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// orderPaymentIDs lists the IDs of the order's payments in one of the statuses, oldest first
+func orderPaymentIDs(q querier, orderID uint, statuses ...string) ([]uint, error) {
+	rows, err := q.Query(
+		"SELECT id FROM payments WHERE order_id = $1 AND status = ANY($2) ORDER BY id", orderID, pq.Array(statuses),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payments: %w", err)
+	}
+
+	return ids, nil
+}
+
+// capturePayment collects an amount of an authorized payment at the provider and records it
+func capturePayment(q querier, p *models.Payment, amount float64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), payment.Timeout())
+	defer cancel()
+	if _, err := getPaymentProvider().Capture(ctx, p.ProviderReference, amount); err != nil {
+		return providerError("capture", err)
+	}
+
+	_, err := q.Exec(`
+		UPDATE payments SET status = $1, captured_amount = $2, updated_at = NOW() WHERE id = $3
+	`, models.PaymentStatusCaptured, amount, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// voidPayment voids an authorized payment at the provider and records it
+func voidPayment(q querier, p *models.Payment) error {
+	ctx, cancel := context.WithTimeout(context.Background(), payment.Timeout())
+	defer cancel()
+	if _, err := getPaymentProvider().Void(ctx, p.ProviderReference); err != nil {
+		return providerError("void", err)
+	}
+
+	_, err := q.Exec(`
+		UPDATE payments SET status = $1, updated_at = NOW() WHERE id = $2
+	`, models.PaymentStatusVoided, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// providerError turns a provider failure into a service error
+func providerError(operation string, err error) error {
+	switch {
+	case errors.Is(err, payment.ErrDeclined):
+		return fmt.Errorf("payment declined: %s was refused by the provider", operation)
+	case errors.Is(err, payment.ErrTimeout):
+		return errors.New("payment provider timed out")
+	default:
+		return fmt.Errorf("payment %s failed: %w", operation, err)
+	}
+}
+
+// loadAuthorizedPayment locks the order's authorized payment
+func loadAuthorizedPayment(q querier, orderID uint) (*models.Payment, error) {
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1)", orderID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("order not found")
+	}
+
+	payments, err := loadPayments(q, `
+		WHERE order_id = $1 AND status = 'authorized' ORDER BY id DESC LIMIT 1 FOR UPDATE
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		return nil, errors.New("no authorized payment for this order")
+	}
+	return &payments[0], nil
+}
+
+// loadPayment retrieves a payment by ID
+func loadPayment(q querier, id uint) (*models.Payment, error) {
+	payments, err := loadPayments(q, "WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		return nil, errors.New("payment not found")
+	}
+	return &payments[0], nil
+}
+
+// loadOrderPayments retrieves the payments of an order, oldest first
+func loadOrderPayments(q querier, orderID uint) ([]models.Payment, error) {
+	return loadPayments(q, "WHERE order_id = $1 ORDER BY id", orderID)
+}
+
+// loadPayments retrieves the payments matching a WHERE clause and its arguments
+func loadPayments(q querier, where string, args ...interface{}) ([]models.Payment, error) {
+	rows, err := q.Query("SELECT "+paymentColumns+" FROM payments "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query payments: %w", err)
+	}
+	defer rows.Close()
+
+	var payments []models.Payment
+	for rows.Next() {
+		var p models.Payment
+		err := rows.Scan(
+			&p.ID, &p.OrderID, &p.Provider, &p.ProviderReference, &p.Method, &p.Status, &p.Amount,
+			&p.CapturedAmount, &p.RefundedAmount, &p.Currency, &p.FailureReason, &p.CreatedAt, &p.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payment: %w", err)
+		}
+		payments = append(payments, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating payments: %w", err)
+	}
+
+	return payments, nil
+}
@@ -0,0 +1,485 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/payment"
+)
+
+// webhookRetryDelay is the delay before the first retry; it doubles with every failed attempt
+const webhookRetryDelay = 30 * time.Second
+
+// webhookEventColumns lists the webhook event columns in the order scanned by loadWebhookEvents
+const webhookEventColumns = `id, provider, event_id, event_type, payload, status, attempts,
+	COALESCE(last_error, ''), next_attempt_at, received_at, processed_at`
+
+// PaymentWebhookService receives provider webhooks and applies them to payments and orders
+type PaymentWebhookService struct {
+	db          *sql.DB
+	interval    time.Duration
+	maxAttempts int
+}
+
+// NewPaymentWebhookService creates a new payment webhook service
+func NewPaymentWebhookService() *PaymentWebhookService {
+	interval := 10 * time.Second
+	if value := os.Getenv("PAYMENT_WEBHOOK_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			interval = parsed
+		}
+	}
+
+	maxAttempts := 5
+	if value := os.Getenv("PAYMENT_WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			maxAttempts = parsed
+		}
+	}
+
+	return &PaymentWebhookService{
+		db:          database.GetDB(),
+		interval:    interval,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// WebhookEventFilter represents webhook event filtering options
+type WebhookEventFilter struct {
+	Provider string
+	Status   string
+	Page     int
+	Limit    int
+}
+
+// WebhookEventListResponse represents the paginated webhook event list response
+type WebhookEventListResponse struct {
+	Events []models.PaymentWebhookEvent `json:"events"`
+	Total  int                          `json:"total"`
+	Page   int                          `json:"page"`
+	Limit  int                          `json:"limit"`
+	Pages  int                          `json:"pages"`
+}
+
+// ReceiveWebhook verifies and stores a raw webhook and applies it in the background.
+// It reports whether the event had already been received, in which case nothing is done.
+func (s *PaymentWebhookService) ReceiveWebhook(providerName string, header http.Header, body []byte) (*models.PaymentWebhookEvent, bool, error) {
+	provider := getPaymentProvider()
+	if provider.Name() != providerName {
+		return nil, false, errors.New("unknown payment provider")
+	}
+	webhooks, ok := provider.(payment.WebhookProvider)
+	if !ok {
+		return nil, false, errors.New("unknown payment provider")
+	}
+
+	if err := webhooks.VerifyWebhook(header, body); err != nil {
+		return nil, false, payment.ErrInvalidSignature
+	}
+	event, err := webhooks.ParseWebhook(body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Store the raw event; the unique key on provider and event ID drops redeliveries
+	var id uint
+	err = s.db.QueryRow(`
+		INSERT INTO payment_webhook_events (provider, event_id, event_type, payload, status, next_attempt_at, received_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		ON CONFLICT (provider, event_id) DO NOTHING
+		RETURNING id
+	`, providerName, event.ID, event.Type, string(body), models.WebhookEventPending).Scan(&id)
+	if err == sql.ErrNoRows {
+		existing, err := loadWebhookEvent(s.db, "provider = $1 AND event_id = $2", providerName, event.ID)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to store webhook event: %w", err)
+	}
+
+	go s.processEvent(id)
+
+	stored, err := loadWebhookEvent(s.db, "id = $1", id)
+	if err != nil {
+		return nil, false, err
+	}
+	return stored, false, nil
+}
+
+// ListWebhookEvents retrieves received webhook events, newest first
+func (s *PaymentWebhookService) ListWebhookEvents(filter *WebhookEventFilter) (*WebhookEventListResponse, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	where := "WHERE ($1 = '' OR provider = $1) AND ($2 = '' OR status = $2)"
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM payment_webhook_events "+where, filter.Provider, filter.Status).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count webhook events: %w", err)
+	}
+
+	events, err := loadWebhookEvents(s.db, where+" ORDER BY received_at DESC, id DESC LIMIT $3 OFFSET $4",
+		filter.Provider, filter.Status, filter.Limit, (filter.Page-1)*filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookEventListResponse{
+		Events: events,
+		Total:  total,
+		Page:   filter.Page,
+		Limit:  filter.Limit,
+		Pages:  (total + filter.Limit - 1) / filter.Limit,
+	}, nil
+}
+
+// GetWebhookEvent retrieves a webhook event by ID
+func (s *PaymentWebhookService) GetWebhookEvent(id uint) (*models.PaymentWebhookEvent, error) {
+	return loadWebhookEvent(s.db, "id = $1", id)
+}
+
+// ReplayWebhookEvent applies a stored event again, whatever its status, and returns the outcome.
+// Applying an event is idempotent, so replaying an already processed event is safe.
+func (s *PaymentWebhookService) ReplayWebhookEvent(id uint) (*models.PaymentWebhookEvent, error) {
+	result, err := s.db.Exec(`
+		UPDATE payment_webhook_events
+		SET status = $1, attempts = 0, last_error = NULL, next_attempt_at = NOW(), processed_at = NULL
+		WHERE id = $2 AND status != $3
+	`, models.WebhookEventPending, id, models.WebhookEventProcessing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reset webhook event: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		event, err := loadWebhookEvent(s.db, "id = $1", id)
+		if err != nil {
+			return nil, err
+		}
+		if event.Status == models.WebhookEventProcessing {
+			return nil, errors.New("webhook event is being processed")
+		}
+	}
+
+	s.processEvent(id)
+	return loadWebhookEvent(s.db, "id = $1", id)
+}
+
+// ProcessDueEvents applies every pending event whose next attempt is due and returns the number processed
+func (s *PaymentWebhookService) ProcessDueEvents() (int, error) {
+	rows, err := s.db.Query(`
+		SELECT id FROM payment_webhook_events
+		WHERE status = $1 AND next_attempt_at <= NOW()
+		ORDER BY received_at, id
+	`, models.WebhookEventPending)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query due webhook events: %w", err)
+	}
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating webhook events: %w", err)
+	}
+
+	processed := 0
+	for _, id := range ids {
+		if s.processEvent(id) {
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// StartWebhookWorker retries due webhook events periodically in the background. Events left
+// processing by a restart are retried. The interval is configured with PAYMENT_WEBHOOK_INTERVAL (default 10s).
+func (s *PaymentWebhookService) StartWebhookWorker() {
+	_, err := s.db.Exec(
+		"UPDATE payment_webhook_events SET status = $1, next_attempt_at = NOW() WHERE status = $2",
+		models.WebhookEventPending, models.WebhookEventProcessing,
+	)
+	if err != nil {
+		log.Printf("Warning: failed to resume interrupted webhook events: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			processed, err := s.ProcessDueEvents()
+			if err != nil {
+				log.Printf("Warning: failed to process payment webhook events: %v", err)
+			} else if processed > 0 {
+				log.Printf("Processed %d payment webhook events", processed)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// processEvent claims a due pending event and applies it, scheduling a retry with exponential
+// backoff on failure. It reports whether the event was claimed.
+func (s *PaymentWebhookService) processEvent(id uint) bool {
+	// Claim the event so concurrent workers skip it
+	var providerName, payload string
+	var attempts int
+	err := s.db.QueryRow(`
+		UPDATE payment_webhook_events
+		SET status = $1, attempts = attempts + 1
+		WHERE id = $2 AND status = $3 AND next_attempt_at <= NOW()
+		RETURNING provider, payload, attempts
+	`, models.WebhookEventProcessing, id, models.WebhookEventPending).Scan(&providerName, &payload, &attempts)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Printf("Warning: failed to claim webhook event %d: %v", id, err)
+		}
+		return false
+	}
+
+	applyErr := s.applyEvent(providerName, []byte(payload))
+	if applyErr == nil {
+		_, err = s.db.Exec(`
+			UPDATE payment_webhook_events SET status = $1, last_error = NULL, processed_at = NOW() WHERE id = $2
+		`, models.WebhookEventProcessed, id)
+	} else {
+		status := models.WebhookEventPending
+		if attempts >= s.maxAttempts {
+			status = models.WebhookEventFailed
+		}
+		delay := webhookRetryDelay * time.Duration(1<<min(attempts-1, 10))
+		_, err = s.db.Exec(`
+			UPDATE payment_webhook_events
+			SET status = $1, last_error = $2, next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+			WHERE id = $4
+		`, status, applyErr.Error(), delay.Seconds(), id)
+	}
+	if err != nil {
+		log.Printf("Warning: failed to record webhook event %d outcome: %v", id, err)
+	}
+	return true
+}
+
+// applyEvent parses a stored event and applies it to its payment
+func (s *PaymentWebhookService) applyEvent(providerName string, payload []byte) error {
+	provider := getPaymentProvider()
+	webhooks, ok := provider.(payment.WebhookProvider)
+	if !ok || provider.Name() != providerName {
+		return fmt.Errorf("payment provider %s is not configured", providerName)
+	}
+	event, err := webhooks.ParseWebhook(payload)
+	if err != nil {
+		return err
+	}
+	return applyPaymentEvent(s.db, providerName, event)
+}
+
+// applyPaymentEvent moves a payment and its order to the state reported by a provider event.
+// Transitions only move forward, so stale and repeated events have no effect.
+func applyPaymentEvent(db *sql.DB, providerName string, event *payment.Event) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Find the payment by its reference, or the order's unresolved attempt, e.g. after an authorization timed out
+	payments, err := loadPayments(tx, "WHERE provider = $1 AND provider_reference = $2 FOR UPDATE", providerName, event.Reference)
+	if err != nil {
+		return err
+	}
+	if len(payments) == 0 && event.OrderID != 0 {
+		payments, err = loadPayments(tx, `
+			WHERE order_id = $1 AND provider = $2 AND status IN ('pending', 'failed')
+			ORDER BY id DESC LIMIT 1 FOR UPDATE
+		`, event.OrderID, providerName)
+		if err != nil {
+			return err
+		}
+	}
+	if len(payments) == 0 {
+		return errors.New("payment not found for event")
+	}
+	p := &payments[0]
+	confirmed := false
+	unresolved := p.Status == models.PaymentStatusPending || p.Status == models.PaymentStatusFailed
+
+	var orderStatus string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", p.OrderID).Scan(&orderStatus); err != nil {
+		return fmt.Errorf("failed to load order: %w", err)
+	}
+
+	switch event.Type {
+	case payment.EventAuthorized, payment.EventCaptured:
+		if unresolved {
+			p.ProviderReference = event.Reference
+			if err := updatePaymentStatus(tx, p.ID, models.PaymentStatusAuthorized, event.Reference); err != nil {
+				return err
+			}
+			p.Status = models.PaymentStatusAuthorized
+		}
+		if event.Type == payment.EventCaptured && (p.Status == models.PaymentStatusAuthorized || p.Status == models.PaymentStatusCaptured) {
+			// The event carries the total captured; no amount means the full authorization
+			captured := p.Amount
+			if event.Amount > 0 {
+				captured = roundCents(event.Amount)
+			}
+			_, err := tx.Exec(`
+				UPDATE payments SET status = $1, captured_amount = $2, updated_at = NOW() WHERE id = $3
+			`, models.PaymentStatusCaptured, math.Max(p.CapturedAmount, captured), p.ID)
+			if err != nil {
+				return fmt.Errorf("failed to update payment: %w", err)
+			}
+			p.Status = models.PaymentStatusCaptured
+		}
+
+		switch {
+		case orderStatus == "pending":
+			if confirmed, err = confirmOrder(tx, p.OrderID, "payment confirmed by provider webhook"); err != nil {
+				return err
+			}
+		case orderStatus == "cancelled" && p.Status == models.PaymentStatusAuthorized:
+			// The order was given up, e.g. after the authorization timed out; release the funds
+			if err := voidPayment(tx, p); err != nil {
+				return err
+			}
+		}
+
+	case payment.EventVoided:
+		if p.Status == models.PaymentStatusAuthorized || unresolved {
+			if err := updatePaymentStatus(tx, p.ID, models.PaymentStatusVoided, ""); err != nil {
+				return err
+			}
+		}
+
+	case payment.EventRefunded:
+		if p.Status == models.PaymentStatusCaptured || p.Status == models.PaymentStatusRefunded {
+			// The event carries the total refunded; no amount means a full refund
+			refunded := p.CapturedAmount
+			if event.Amount > 0 {
+				refunded = math.Min(p.CapturedAmount, roundCents(event.Amount))
+			}
+
+			// Record refunds issued directly at the provider, e.g. from its dashboard
+			if amount := roundCents(refunded - p.RefundedAmount); amount > 0 {
+				refund := &models.Refund{
+					OrderID:   p.OrderID,
+					PaymentID: &p.ID,
+					Amount:    amount,
+					Reason:    "refunded at payment provider",
+					Status:    models.RefundStatusSucceeded,
+				}
+				if err := insertRefund(tx, refund); err != nil {
+					return err
+				}
+				if err := recordPaymentRefund(tx, p, amount); err != nil {
+					return err
+				}
+			}
+		}
+
+	case payment.EventFailed:
+		if p.Status == models.PaymentStatusPending {
+			_, err := tx.Exec(`
+				UPDATE payments SET status = $1, failure_reason = $2, updated_at = NOW() WHERE id = $3
+			`, models.PaymentStatusFailed, event.Message, p.ID)
+			if err != nil {
+				return fmt.Errorf("failed to update payment: %w", err)
+			}
+		}
+
+	default:
+		log.Printf("Ignoring payment webhook event %s of unknown type %s", event.ID, event.Type)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if confirmed {
+		notifyOrderStatus(db, p.OrderID, models.OrderStatusConfirmed)
+	}
+
+	return nil
+}
+
+// updatePaymentStatus sets a payment's status and, when given, its provider reference
+func updatePaymentStatus(q querier, id uint, status, reference string) error {
+	_, err := q.Exec(`
+		UPDATE payments SET status = $1, provider_reference = COALESCE(NULLIF($2, ''), provider_reference), updated_at = NOW()
+		WHERE id = $3
+	`, status, reference, id)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+	return nil
+}
+
+// loadWebhookEvent retrieves the webhook event matching a condition
+func loadWebhookEvent(q querier, condition string, args ...interface{}) (*models.PaymentWebhookEvent, error) {
+	events, err := loadWebhookEvents(q, "WHERE "+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, errors.New("webhook event not found")
+	}
+	return &events[0], nil
+}
+
+// loadWebhookEvents retrieves the webhook events matching a WHERE clause and its arguments
+func loadWebhookEvents(q querier, where string, args ...interface{}) ([]models.PaymentWebhookEvent, error) {
+	rows, err := q.Query("SELECT "+webhookEventColumns+" FROM payment_webhook_events "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.PaymentWebhookEvent{}
+	for rows.Next() {
+		var event models.PaymentWebhookEvent
+		var processedAt sql.NullTime
+		err := rows.Scan(
+			&event.ID, &event.Provider, &event.EventID, &event.EventType, &event.Payload, &event.Status,
+			&event.Attempts, &event.LastError, &event.NextAttemptAt, &event.ReceivedAt, &processedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+		if processedAt.Valid {
+			event.ProcessedAt = &processedAt.Time
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+// errPriceScheduleApplied signals that another worker already applied a price schedule
+var errPriceScheduleApplied = errors.New("price schedule already applied")
+
+// PricingService handles sale pricing and scheduled price changes
+type PricingService struct {
+	db               *sql.DB
+	productService   *ProductService
+	scheduleInterval time.Duration
+}
+
+// NewPricingService creates a new pricing service
+func NewPricingService() *PricingService {
+	interval := time.Minute
+	if value := os.Getenv("PRICE_SCHEDULE_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			interval = parsed
+		}
+	}
+
+	return &PricingService{
+		db:               database.GetDB(),
+		productService:   NewProductService(),
+		scheduleInterval: interval,
+	}
+}
+
+// ProductPricingRequest represents the request to set a product's compare-at price and sale.
+// Omitted fields are cleared.
+type ProductPricingRequest struct {
+	CompareAtPrice *float64   `json:"compare_at_price" binding:"omitempty,gt=0"`
+	SalePrice      *float64   `json:"sale_price" binding:"omitempty,gt=0"`
+	SaleStartsAt   *time.Time `json:"sale_starts_at"`
+	SaleEndsAt     *time.Time `json:"sale_ends_at"`
+}
+
+// CreatePriceScheduleRequest represents the request to schedule a future list price change
+type CreatePriceScheduleRequest struct {
+	Price          float64   `json:"price" binding:"required,gt=0"`
+	CompareAtPrice *float64  `json:"compare_at_price" binding:"omitempty,gt=0"`
+	EffectiveAt    time.Time `json:"effective_at" binding:"required"`
+}
+
+// SetProductPricing replaces a product's compare-at price and sale
+func (s *PricingService) SetProductPricing(productID uint, req *ProductPricingRequest, actorID uint) (*models.Product, error) {
+	if req.SalePrice == nil && (req.SaleStartsAt != nil || req.SaleEndsAt != nil) {
+		return nil, errors.New("invalid pricing: sale window requires sale_price")
+	}
+	if req.SaleStartsAt != nil && req.SaleEndsAt != nil && !req.SaleEndsAt.After(*req.SaleStartsAt) {
+		return nil, errors.New("invalid pricing: sale_ends_at must be after sale_starts_at")
+	}
+
+	err := s.productService.changeProduct(productID, models.ProductRevisionPricing, actorID, func(tx *sql.Tx, before productState) error {
+		if before["deleted_at"] != nil {
+			return errors.New("product not found")
+		}
+		if price, _ := before["price"].(float64); req.SalePrice != nil && *req.SalePrice >= price {
+			return errors.New("invalid pricing: sale_price must be lower than price")
+		}
+
+		_, err := tx.Exec(`
+			UPDATE products
+			SET compare_at_price = $1, sale_price = $2, sale_starts_at = $3, sale_ends_at = $4, updated_at = NOW()
+			WHERE id = $5
+		`, req.CompareAtPrice, req.SalePrice, req.SaleStartsAt, req.SaleEndsAt, productID)
+		if err != nil {
+			return fmt.Errorf("failed to update product pricing: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.productService.GetProductForAdmin(productID)
+}
+
+// CreatePriceSchedule schedules a list price change for a product
+func (s *PricingService) CreatePriceSchedule(productID uint, req *CreatePriceScheduleRequest, actorID uint) (*models.PriceSchedule, error) {
+	if !req.EffectiveAt.After(time.Now()) {
+		return nil, errors.New("invalid price schedule: effective_at must be in the future")
+	}
+
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = $1 AND deleted_at IS NULL)", productID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("product not found")
+	}
+
+	var schedule models.PriceSchedule
+	var createdBy sql.NullInt64
+	err = s.db.QueryRow(`
+		INSERT INTO product_price_schedules (product_id, price, compare_at_price, effective_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, 0), NOW())
+		RETURNING id, product_id, price, compare_at_price, effective_at, applied_at, created_by, created_at
+	`, productID, req.Price, req.CompareAtPrice, req.EffectiveAt, actorID).Scan(
+		&schedule.ID, &schedule.ProductID, &schedule.Price, &schedule.CompareAtPrice,
+		&schedule.EffectiveAt, &schedule.AppliedAt, &createdBy, &schedule.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create price schedule: %w", err)
+	}
+	if createdBy.Valid {
+		id := uint(createdBy.Int64)
+		schedule.CreatedBy = &id
+	}
+
+	return &schedule, nil
+}
+
+// ListPriceSchedules retrieves the pending and applied price schedules of a product, soonest first
+func (s *PricingService) ListPriceSchedules(productID uint) ([]models.PriceSchedule, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("product not found")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, product_id, price, compare_at_price, effective_at, applied_at, created_by, created_at
+		FROM product_price_schedules
+		WHERE product_id = $1
+		ORDER BY effective_at, id
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price schedules: %w", err)
+	}
+	defer rows.Close()
+
+	schedules := []models.PriceSchedule{}
+	for rows.Next() {
+		var schedule models.PriceSchedule
+		var createdBy sql.NullInt64
+		err := rows.Scan(
+			&schedule.ID, &schedule.ProductID, &schedule.Price, &schedule.CompareAtPrice,
+			&schedule.EffectiveAt, &schedule.AppliedAt, &createdBy, &schedule.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan price schedule: %w", err)
+		}
+		if createdBy.Valid {
+			id := uint(createdBy.Int64)
+			schedule.CreatedBy = &id
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+// DeletePriceSchedule cancels a pending price schedule; applied schedules are kept as history
+func (s *PricingService) DeletePriceSchedule(productID, scheduleID uint) error {
+	var appliedAt sql.NullTime
+	err := s.db.QueryRow(
+		"SELECT applied_at FROM product_price_schedules WHERE id = $1 AND product_id = $2",
+		scheduleID, productID,
+	).Scan(&appliedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("price schedule not found")
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+	if appliedAt.Valid {
+		return errPriceScheduleApplied
+	}
+
+	result, err := s.db.Exec(
+		"DELETE FROM product_price_schedules WHERE id = $1 AND applied_at IS NULL",
+		scheduleID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete price schedule: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errPriceScheduleApplied
+	}
+
+	return nil
+}
+
+// ApplyDuePriceSchedules applies every pending price schedule whose effective time has passed,
+// oldest first, and returns the number applied. Each change is recorded as a product revision.
+func (s *PricingService) ApplyDuePriceSchedules() (int, error) {
+	rows, err := s.db.Query(`
+		SELECT id, product_id, price, compare_at_price, COALESCE(created_by, 0)
+		FROM product_price_schedules
+		WHERE applied_at IS NULL AND effective_at <= NOW()
+		ORDER BY effective_at, id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query due price schedules: %w", err)
+	}
+
+	var due []models.PriceSchedule
+	var actors []uint
+	for rows.Next() {
+		var schedule models.PriceSchedule
+		var actorID uint
+		if err := rows.Scan(&schedule.ID, &schedule.ProductID, &schedule.Price, &schedule.CompareAtPrice, &actorID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan price schedule: %w", err)
+		}
+		due = append(due, schedule)
+		actors = append(actors, actorID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating price schedules: %w", err)
+	}
+
+	applied := 0
+	for i, schedule := range due {
+		err := s.productService.changeProduct(schedule.ProductID, models.ProductRevisionSchedule, actors[i], func(tx *sql.Tx, before productState) error {
+			// Claim the schedule; the product row lock serializes concurrent workers
+			result, err := tx.Exec(
+				"UPDATE product_price_schedules SET applied_at = NOW() WHERE id = $1 AND applied_at IS NULL",
+				schedule.ID,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to claim price schedule: %w", err)
+			}
+			if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+				return errPriceScheduleApplied
+			}
+
+			_, err = tx.Exec(`
+				UPDATE products
+				SET price = $1, compare_at_price = COALESCE($2, compare_at_price), updated_at = NOW()
+				WHERE id = $3
+			`, schedule.Price, schedule.CompareAtPrice, schedule.ProductID)
+			if err != nil {
+				return fmt.Errorf("failed to apply scheduled price: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			if err == errPriceScheduleApplied {
+				continue
+			}
+			return applied, fmt.Errorf("failed to apply price schedule %d: %w", schedule.ID, err)
+		}
+		applied++
+	}
+
+	return applied, nil
+}
+
+// StartPriceScheduler applies due price schedules now and then periodically in the background.
+// The interval is configured with PRICE_SCHEDULE_INTERVAL (default 1m).
+func (s *PricingService) StartPriceScheduler() {
+	go func() {
+		ticker := time.NewTicker(s.scheduleInterval)
+		defer ticker.Stop()
+
+		for {
+			applied, err := s.ApplyDuePriceSchedules()
+			if err != nil {
+				log.Printf("Warning: failed to apply price schedules: %v", err)
+			} else if applied > 0 {
+				log.Printf("Applied %d scheduled price changes", applied)
+			}
+			<-ticker.C
+		}
+	}()
+}
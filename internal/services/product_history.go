@@ -0,0 +1,457 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+// productState is a snapshot of the tracked fields of a product, keyed by field name.
+// Values are JSON compatible so snapshots can be stored and compared as JSON.
+type productState map[string]interface{}
+
+// ProductRevisionListResponse represents the paginated revision history of a product
+type ProductRevisionListResponse struct {
+	Revisions []models.ProductRevision `json:"revisions"`
+	Total     int                      `json:"total"`
+	Page      int                      `json:"page"`
+	Limit     int                      `json:"limit"`
+	Pages     int                      `json:"pages"`
+}
+
+// loadProductState loads the tracked fields of a product and locks its row until the transaction ends
+func loadProductState(q querier, productID uint) (productState, error) {
+	var sku, name, description, imageURL, status, taxClass string
+	var price float64
+	var stock int
+	var categoryID uint
+	var isActive bool
+	var publishAt, unpublishAt, deletedAt sql.NullTime
+	var compareAtPrice, salePrice sql.NullFloat64
+	var saleStartsAt, saleEndsAt sql.NullTime
+	var weight, length, width, height float64
+
+	err := q.QueryRow(`
+		SELECT COALESCE(sku, ''), name, COALESCE(description, ''), price, stock, COALESCE(category_id, 0),
+		       COALESCE(image_url, ''), is_active, status, publish_at, unpublish_at, deleted_at,
+		       compare_at_price, sale_price, sale_starts_at, sale_ends_at, tax_class,
+		       weight, length, width, height
+		FROM products
+		WHERE id = $1
+		FOR UPDATE
+	`, productID).Scan(
+		&sku, &name, &description, &price, &stock, &categoryID,
+		&imageURL, &isActive, &status, &publishAt, &unpublishAt, &deletedAt,
+		&compareAtPrice, &salePrice, &saleStartsAt, &saleEndsAt, &taxClass,
+		&weight, &length, &width, &height,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("product not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	state := productState{
+		"sku":              sku,
+		"name":             name,
+		"description":      description,
+		"price":            price,
+		"stock":            stock,
+		"category_id":      categoryID,
+		"image_url":        imageURL,
+		"is_active":        isActive,
+		"status":           status,
+		"publish_at":       stateTime(publishAt),
+		"unpublish_at":     stateTime(unpublishAt),
+		"deleted_at":       stateTime(deletedAt),
+		"compare_at_price": stateFloat(compareAtPrice),
+		"sale_price":       stateFloat(salePrice),
+		"sale_starts_at":   stateTime(saleStartsAt),
+		"sale_ends_at":     stateTime(saleEndsAt),
+		"tax_class":        taxClass,
+		"weight":           weight,
+		"length":           length,
+		"width":            width,
+		"height":           height,
+	}
+
+	attrs, err := loadProductAttributes(q, []uint{productID})
+	if err != nil {
+		return nil, err
+	}
+	for _, attr := range attrs[productID] {
+		state["attributes."+attr.Code] = attr.Value
+	}
+
+	return state, nil
+}
+
+// stateTime formats a nullable timestamp for a product state
+func stateTime(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time.UTC().Format(time.RFC3339Nano)
+}
+
+// stateFloat formats a nullable number for a product state
+func stateFloat(f sql.NullFloat64) interface{} {
+	if !f.Valid {
+		return nil
+	}
+	return f.Float64
+}
+
+// parseStateTime reads a timestamp from a product state
+func parseStateTime(value interface{}) (*time.Time, error) {
+	s, ok := value.(string)
+	if !ok || s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp in revision: %w", err)
+	}
+	return &t, nil
+}
+
+// diffProductStates lists the fields whose values differ between two states, ordered by field name
+func diffProductStates(before, after productState) []models.ProductFieldChange {
+	fields := make(map[string]bool)
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	changes := []models.ProductFieldChange{}
+	for _, field := range names {
+		beforeJSON, _ := json.Marshal(before[field])
+		afterJSON, _ := json.Marshal(after[field])
+		if !bytes.Equal(beforeJSON, afterJSON) {
+			changes = append(changes, models.ProductFieldChange{
+				Field:  field,
+				Before: before[field],
+				After:  after[field],
+			})
+		}
+	}
+
+	return changes
+}
+
+// recordProductRevision stores a revision for the difference between two states of a product.
+// Nothing is recorded when no tracked field changed. actorID 0 records a system change.
+func recordProductRevision(q querier, productID uint, action string, actorID uint, before, after productState, rollbackOf *int) error {
+	changes := diffProductStates(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision changes: %w", err)
+	}
+	snapshotJSON, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision snapshot: %w", err)
+	}
+
+	// The product row is locked by loadProductState, so revision numbers cannot collide
+	_, err = q.Exec(`
+		INSERT INTO product_revisions (product_id, revision, action, changes, snapshot, actor_id, rollback_of, created_at)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, NULLIF($5, 0), $6, NOW()
+		FROM product_revisions
+		WHERE product_id = $1
+	`, productID, action, changesJSON, snapshotJSON, actorID, rollbackOf)
+	if err != nil {
+		return fmt.Errorf("failed to record product revision: %w", err)
+	}
+
+	return nil
+}
+
+// ListProductRevisions retrieves the revision history of a product, newest first
+func (s *ProductService) ListProductRevisions(productID uint, page, limit int) (*ProductRevisionListResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)", productID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("product not found")
+	}
+
+	var total int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM product_revisions WHERE product_id = $1", productID).Scan(&total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count product revisions: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, product_id, revision, action, changes, actor_id, rollback_of, created_at
+		FROM product_revisions
+		WHERE product_id = $1
+		ORDER BY revision DESC
+		LIMIT $2 OFFSET $3
+	`, productID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []models.ProductRevision{}
+	for rows.Next() {
+		var revision models.ProductRevision
+		var changesJSON []byte
+		var actorID sql.NullInt64
+		var rollbackOf sql.NullInt64
+		err := rows.Scan(
+			&revision.ID, &revision.ProductID, &revision.Revision, &revision.Action, &changesJSON,
+			&actorID, &rollbackOf, &revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product revision: %w", err)
+		}
+
+		if err := json.Unmarshal(changesJSON, &revision.Changes); err != nil {
+			return nil, fmt.Errorf("failed to decode revision changes: %w", err)
+		}
+		if actorID.Valid {
+			id := uint(actorID.Int64)
+			revision.ActorID = &id
+		}
+		if rollbackOf.Valid {
+			n := int(rollbackOf.Int64)
+			revision.RollbackOf = &n
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating product revisions: %w", err)
+	}
+
+	return &ProductRevisionListResponse{
+		Revisions: revisions,
+		Total:     total,
+		Page:      page,
+		Limit:     limit,
+		Pages:     (total + limit - 1) / limit,
+	}, nil
+}
+
+// RollbackProduct restores a product's catalog fields, pricing and attributes to their state after the given revision.
+// Stock and soft deletion are not rolled back; they are managed through stock updates and restore.
+func (s *ProductService) RollbackProduct(productID uint, revision int, actorID uint) (*models.Product, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := loadProductState(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshotJSON []byte
+	err = tx.QueryRow(
+		"SELECT snapshot FROM product_revisions WHERE product_id = $1 AND revision = $2",
+		productID, revision,
+	).Scan(&snapshotJSON)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("revision not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	var target productState
+	decoder := json.NewDecoder(bytes.NewReader(snapshotJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&target); err != nil {
+		return nil, fmt.Errorf("failed to decode revision snapshot: %w", err)
+	}
+
+	sku, _ := target["sku"].(string)
+	name, _ := target["name"].(string)
+	description, _ := target["description"].(string)
+	imageURL, _ := target["image_url"].(string)
+	status, _ := target["status"].(string)
+	taxClass, _ := target["tax_class"].(string)
+	isActive, _ := target["is_active"].(bool)
+	price, err := stateNumber(target["price"])
+	if err != nil {
+		return nil, err
+	}
+	categoryNumber, err := stateNumber(target["category_id"])
+	if err != nil {
+		return nil, err
+	}
+	categoryID := uint(categoryNumber)
+	publishAt, err := parseStateTime(target["publish_at"])
+	if err != nil {
+		return nil, err
+	}
+	unpublishAt, err := parseStateTime(target["unpublish_at"])
+	if err != nil {
+		return nil, err
+	}
+	compareAtPrice, err := stateNullableNumber(target["compare_at_price"])
+	if err != nil {
+		return nil, err
+	}
+	salePrice, err := stateNullableNumber(target["sale_price"])
+	if err != nil {
+		return nil, err
+	}
+	saleStartsAt, err := parseStateTime(target["sale_starts_at"])
+	if err != nil {
+		return nil, err
+	}
+	saleEndsAt, err := parseStateTime(target["sale_ends_at"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Revisions recorded before shipping data existed keep the current values
+	shipping := make([]*float64, 4)
+	for i, field := range []string{"weight", "length", "width", "height"} {
+		if shipping[i], err = stateNullableNumber(target[field]); err != nil {
+			return nil, err
+		}
+	}
+
+	// The category and SKU of the revision must still be usable
+	if categoryID > 0 {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)", categoryID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if !exists {
+			return nil, errors.New("revision category no longer exists")
+		}
+	}
+	if taxClass != "" {
+		var exists bool
+		if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM tax_classes WHERE code = $1)", taxClass).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if !exists {
+			return nil, errors.New("revision tax class no longer exists")
+		}
+	}
+	if sku != "" {
+		var taken bool
+		err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1 AND id != $2)", sku, productID).Scan(&taken)
+		if err != nil {
+			return nil, fmt.Errorf("database error: %w", err)
+		}
+		if taken {
+			return nil, errors.New("product with this SKU already exists")
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE products
+		SET sku = NULLIF($1, ''), name = $2, description = $3, price = $4, category_id = NULLIF($5, 0),
+		    image_url = $6, is_active = $7, status = $8, publish_at = $9, unpublish_at = $10,
+		    compare_at_price = $11, sale_price = $12, sale_starts_at = $13, sale_ends_at = $14,
+		    tax_class = COALESCE(NULLIF($15, ''), tax_class), weight = COALESCE($16, weight),
+		    length = COALESCE($17, length), width = COALESCE($18, width), height = COALESCE($19, height),
+		    updated_at = NOW()
+		WHERE id = $20
+	`, sku, name, description, price, categoryID, imageURL, isActive, status, publishAt, unpublishAt,
+		compareAtPrice, salePrice, saleStartsAt, saleEndsAt, taxClass,
+		shipping[0], shipping[1], shipping[2], shipping[3], productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to roll back product: %w", err)
+	}
+
+	// Replace attribute values with those of the revision
+	attrInput := make(map[string]interface{})
+	for field, value := range target {
+		if code, ok := strings.CutPrefix(field, "attributes."); ok {
+			if number, isNumber := value.(json.Number); isNumber {
+				value, _ = number.Float64()
+			}
+			attrInput[code] = value
+		}
+	}
+	if _, err := tx.Exec("DELETE FROM product_attribute_values WHERE product_id = $1", productID); err != nil {
+		return nil, fmt.Errorf("failed to clear attribute values: %w", err)
+	}
+	attrValues, err := validateProductAttributes(tx, categoryID, attrInput)
+	if err != nil {
+		return nil, err
+	}
+	if err := saveProductAttributes(tx, productID, attrValues); err != nil {
+		return nil, err
+	}
+
+	after, err := loadProductState(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordProductRevision(tx, productID, models.ProductRevisionRollback, actorID, before, after, &revision); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetProductForAdmin(productID)
+}
+
+// stateNumber reads a number from a decoded product state
+func stateNumber(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.Float64()
+	case float64:
+		return v, nil
+	case nil:
+		return 0, nil
+	}
+	return 0, fmt.Errorf("invalid number in revision: %v", value)
+}
+
+// stateNullableNumber reads an optional number from a decoded product state
+func stateNullableNumber(value interface{}) (*float64, error) {
+	if value == nil {
+		return nil, nil
+	}
+	n, err := stateNumber(value)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
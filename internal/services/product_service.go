@@ -4,10 +4,13 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"strings"
+	"time"
 
 	"github.com/Code-byme/e-commerce/internal/database"
 	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/tax"
 )
 
 // ProductService handles product operations
@@ -24,16 +27,33 @@ func NewProductService() *ProductService {
 
 // CreateProductRequest represents the request to create a product
 type CreateProductRequest struct {
+	SKU         string  `json:"sku"`
 	Name        string  `json:"name" binding:"required"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price" binding:"required,gt=0"`
 	Stock       int     `json:"stock" binding:"required,gte=0"`
 	CategoryID  uint    `json:"category_id"`
 	ImageURL    string  `json:"image_url"`
+	TaxClass    string  `json:"tax_class"`
+
+	// Shipping weight (kg) and dimensions (cm)
+	Weight float64 `json:"weight" binding:"gte=0"`
+	Length float64 `json:"length" binding:"gte=0"`
+	Width  float64 `json:"width" binding:"gte=0"`
+	Height float64 `json:"height" binding:"gte=0"`
+
+	// Lifecycle; products are published immediately unless another status is given
+	Status      string     `json:"status" binding:"omitempty,oneof=draft scheduled published archived"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
+
+	// Attributes holds attribute values keyed by attribute code
+	Attributes map[string]interface{} `json:"attributes"`
 }
 
 // UpdateProductRequest represents the request to update a product
 type UpdateProductRequest struct {
+	SKU         *string  `json:"sku"`
 	Name        *string  `json:"name"`
 	Description *string  `json:"description"`
 	Price       *float64 `json:"price"`
@@ -41,30 +61,156 @@ type UpdateProductRequest struct {
 	CategoryID  *uint    `json:"category_id"`
 	ImageURL    *string  `json:"image_url"`
 	IsActive    *bool    `json:"is_active"`
+	TaxClass    *string  `json:"tax_class"`
+
+	// Shipping weight (kg) and dimensions (cm)
+	Weight *float64 `json:"weight" binding:"omitempty,gte=0"`
+	Length *float64 `json:"length" binding:"omitempty,gte=0"`
+	Width  *float64 `json:"width" binding:"omitempty,gte=0"`
+	Height *float64 `json:"height" binding:"omitempty,gte=0"`
+
+	// Attributes holds attribute values keyed by attribute code; a null value removes the attribute
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+// ProductStatusRequest represents the request to change a product's lifecycle status and publish window
+type ProductStatusRequest struct {
+	Status      string     `json:"status" binding:"required,oneof=draft scheduled published archived"`
+	PublishAt   *time.Time `json:"publish_at"`
+	UnpublishAt *time.Time `json:"unpublish_at"`
 }
 
 // ProductFilter represents product filtering options
 type ProductFilter struct {
-	CategoryID *uint    `json:"category_id"`
-	MinPrice   *float64 `json:"min_price"`
-	MaxPrice   *float64 `json:"max_price"`
-	Search     *string  `json:"search"`
-	IsActive   *bool    `json:"is_active"`
-	Page       int      `json:"page"`
-	Limit      int      `json:"limit"`
+	CategoryID *uint             `json:"category_id"`
+	MinPrice   *float64          `json:"min_price"`
+	MaxPrice   *float64          `json:"max_price"`
+	Search     *string           `json:"search"`
+	IsActive   *bool             `json:"is_active"`
+	Status     string            `json:"status"`
+	Attributes []AttributeFilter `json:"attributes"`
+	SortBy     string            `json:"sort_by"`
+	SortOrder  string            `json:"sort_order"`
+	Cursor     string            `json:"cursor"`
+	SkipCount  bool              `json:"skip_count"`
+	Page       int               `json:"page"`
+	Limit      int               `json:"limit"`
+
+	// IncludeHidden lists drafts, scheduled, archived and inactive products (admin only)
+	IncludeHidden bool `json:"include_hidden"`
+	// IncludeDeleted also lists soft-deleted products; only honored with IncludeHidden
+	IncludeDeleted bool `json:"include_deleted"`
 }
 
 // ProductListResponse represents the paginated product list response
 type ProductListResponse struct {
-	Products []models.Product `json:"products"`
-	Total    int              `json:"total"`
-	Page     int              `json:"page"`
-	Limit    int              `json:"limit"`
-	Pages    int              `json:"pages"`
+	Products   []models.Product `json:"products"`
+	Total      *int             `json:"total,omitempty"`
+	Page       int              `json:"page"`
+	Limit      int              `json:"limit"`
+	Pages      *int             `json:"pages,omitempty"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+	HasMore    bool             `json:"has_more"`
+}
+
+// productSortColumns lists the columns products can be sorted by
+var productSortColumns = map[string]sortColumn{
+	"created_at": {expr: "p.created_at", cast: "timestamp"},
+	"price":      {expr: productEffectivePriceExpr("p."), cast: "numeric"},
+	"name":       {expr: "p.name", cast: "text"},
+	"popularity": {expr: "COALESCE((SELECT SUM(oi.quantity) FROM order_items oi WHERE oi.product_id = p.id), 0)", cast: "bigint"},
+}
+
+// productVisibleCondition returns the SQL condition matching products customers can see:
+// active, not deleted, published (or scheduled) and inside the publish window.
+// alias is the table alias prefix, e.g. "p." or "".
+func productVisibleCondition(alias string) string {
+	return fmt.Sprintf(
+		"(%[1]sdeleted_at IS NULL AND %[1]sis_active = true AND %[1]sstatus IN ('published', 'scheduled')"+
+			" AND (%[1]spublish_at IS NULL OR %[1]spublish_at <= NOW()) AND (%[1]sunpublish_at IS NULL OR %[1]sunpublish_at > NOW()))",
+		alias,
+	)
+}
+
+// normalizeProductSchedule validates a lifecycle status and publish window.
+// Published products with a future publish time become scheduled, and products published
+// without a publish time record the current time.
+func normalizeProductSchedule(status string, publishAt, unpublishAt *time.Time) (string, *time.Time, error) {
+	now := time.Now()
+
+	switch status {
+	case "", models.ProductStatusPublished:
+		status = models.ProductStatusPublished
+		if publishAt == nil {
+			publishAt = &now
+		} else if publishAt.After(now) {
+			status = models.ProductStatusScheduled
+		}
+	case models.ProductStatusScheduled:
+		if publishAt == nil {
+			return "", nil, errors.New("invalid product schedule: publish_at is required for scheduled products")
+		}
+	case models.ProductStatusDraft, models.ProductStatusArchived:
+	default:
+		return "", nil, errors.New("invalid product status")
+	}
+
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return "", nil, errors.New("invalid product schedule: unpublish_at must be after publish_at")
+	}
+
+	return status, publishAt, nil
+}
+
+// applyEffectiveStatus reports scheduled products whose publish time has passed as published
+func applyEffectiveStatus(product *models.Product) {
+	if product.Status == models.ProductStatusScheduled && product.PublishAt != nil && !product.PublishAt.After(time.Now()) {
+		product.Status = models.ProductStatusPublished
+	}
+}
+
+// productEffectivePriceExpr returns the SQL expression for the price customers pay:
+// the sale price while its sale window is open and it undercuts the list price, otherwise the list price.
+// It mirrors applyEffectivePrice. alias is the table alias prefix, e.g. "p." or "".
+func productEffectivePriceExpr(alias string) string {
+	return fmt.Sprintf(
+		"(CASE WHEN %[1]ssale_price IS NOT NULL AND %[1]ssale_price < %[1]sprice"+
+			" AND (%[1]ssale_starts_at IS NULL OR %[1]ssale_starts_at <= NOW()) AND (%[1]ssale_ends_at IS NULL OR %[1]ssale_ends_at > NOW())"+
+			" THEN %[1]ssale_price ELSE %[1]sprice END)",
+		alias,
+	)
+}
+
+// applyEffectivePrice sets the price customers pay and the discount against the higher of
+// the list price and the compare-at price
+func applyEffectivePrice(product *models.Product) {
+	now := time.Now()
+
+	product.EffectivePrice = product.Price
+	if product.SalePrice != nil && *product.SalePrice < product.Price &&
+		(product.SaleStartsAt == nil || !product.SaleStartsAt.After(now)) &&
+		(product.SaleEndsAt == nil || product.SaleEndsAt.After(now)) {
+		product.EffectivePrice = *product.SalePrice
+	}
+
+	reference := product.Price
+	if product.CompareAtPrice != nil && *product.CompareAtPrice > reference {
+		reference = *product.CompareAtPrice
+	}
+	product.DiscountPercent = 0
+	if reference > 0 && product.EffectivePrice < reference {
+		product.DiscountPercent = math.Round((reference-product.EffectivePrice)/reference*1000) / 10
+	}
 }
 
 // CreateProduct creates a new product
-func (s *ProductService) CreateProduct(req *CreateProductRequest) (*models.Product, error) {
+func (s *ProductService) CreateProduct(req *CreateProductRequest, actorID uint) (*models.Product, error) {
+	// Validate lifecycle status and publish window
+	status, publishAt, err := normalizeProductSchedule(req.Status, req.PublishAt, req.UnpublishAt)
+	if err != nil {
+		return nil, err
+	}
+
 	// Check if category exists if category_id is provided
 	if req.CategoryID > 0 {
 		var category models.Category
@@ -77,43 +223,130 @@ func (s *ProductService) CreateProduct(req *CreateProductRequest) (*models.Produ
 		}
 	}
 
+	// Check if the tax class exists, defaulting to the standard class
+	taxClass := req.TaxClass
+	if taxClass == "" {
+		taxClass = tax.DefaultClass
+	}
+	if err := checkTaxClassExists(s.db, taxClass); err != nil {
+		return nil, err
+	}
+
+	// Check if SKU is already taken
+	if req.SKU != "" {
+		if err := s.checkSKUAvailable(req.SKU, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	// Start a transaction so the product and its attributes are stored together
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Validate attribute values against the category
+	attrValues, err := validateProductAttributes(tx, req.CategoryID, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create product
 	var product models.Product
 	query := `
-		INSERT INTO products (name, description, price, stock, category_id, image_url, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-		RETURNING id, name, description, price, stock, category_id, image_url, is_active, created_at, updated_at
+		INSERT INTO products (name, description, price, stock, category_id, image_url, sku, is_active,
+		                      status, publish_at, unpublish_at, tax_class, weight, length, width, height, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9, $10, $11, $12, $13, $14, $15, $16, NOW(), NOW())
+		RETURNING id, name, description, price, stock, category_id, image_url, COALESCE(sku, ''), is_active, created_at, updated_at,
+		          status, publish_at, unpublish_at, deleted_at, compare_at_price, sale_price, sale_starts_at, sale_ends_at, tax_class, weight, length, width, height
 	`
 
-	err := s.db.QueryRow(
+	err = tx.QueryRow(
 		query,
-		req.Name, req.Description, req.Price, req.Stock, req.CategoryID, req.ImageURL, true,
+		req.Name, req.Description, req.Price, req.Stock, req.CategoryID, req.ImageURL, req.SKU, true,
+		status, publishAt, req.UnpublishAt, taxClass, req.Weight, req.Length, req.Width, req.Height,
 	).Scan(
 		&product.ID, &product.Name, &product.Description, &product.Price, &product.Stock,
-		&product.CategoryID, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.CategoryID, &product.ImageURL, &product.SKU, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.Status, &product.PublishAt, &product.UnpublishAt, &product.DeletedAt,
+		&product.CompareAtPrice, &product.SalePrice, &product.SaleStartsAt, &product.SaleEndsAt, &product.TaxClass,
+		&product.Weight, &product.Length, &product.Width, &product.Height,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
 
+	// Store attribute values
+	if err := saveProductAttributes(tx, product.ID, attrValues); err != nil {
+		return nil, err
+	}
+	if req.CategoryID > 0 {
+		if err := syncProductAttributesWithCategory(tx, product.ID, req.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record the initial revision
+	after, err := loadProductState(tx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordProductRevision(tx, product.ID, models.ProductRevisionCreate, actorID, nil, after, nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	attrs, err := loadProductAttributes(s.db, []uint{product.ID})
+	if err != nil {
+		return nil, err
+	}
+	product.Attributes = attrs[product.ID]
+	applyEffectiveStatus(&product)
+	applyEffectivePrice(&product)
+
 	return &product, nil
 }
 
-// GetProduct retrieves a product by ID
+// GetProduct retrieves a product by ID if it is visible to customers
 func (s *ProductService) GetProduct(id uint) (*models.Product, error) {
+	return s.getProduct(id, true)
+}
+
+// GetProductForAdmin retrieves a product by ID regardless of its lifecycle status
+func (s *ProductService) GetProductForAdmin(id uint) (*models.Product, error) {
+	return s.getProduct(id, false)
+}
+
+// getProduct retrieves a product by ID, optionally restricted to products visible to customers
+func (s *ProductService) getProduct(id uint, visibleOnly bool) (*models.Product, error) {
+	visibility := ""
+	if visibleOnly {
+		visibility = "AND " + productVisibleCondition("p.")
+	}
+
 	var product models.Product
-	query := `
-		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, p.is_active, p.created_at, p.updated_at,
+	query := fmt.Sprintf(`
+		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, COALESCE(p.sku, ''), p.is_active, p.created_at, p.updated_at,
+		       p.status, p.publish_at, p.unpublish_at, p.deleted_at,
+		       p.compare_at_price, p.sale_price, p.sale_starts_at, p.sale_ends_at, p.tax_class, p.weight, p.length, p.width, p.height,
 		       c.id, c.name, c.description, c.created_at, c.updated_at
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
-		WHERE p.id = $1
-	`
+		WHERE p.id = $1 %s
+	`, visibility)
 
 	err := s.db.QueryRow(query, id).Scan(
 		&product.ID, &product.Name, &product.Description, &product.Price, &product.Stock,
-		&product.CategoryID, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.CategoryID, &product.ImageURL, &product.SKU, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.Status, &product.PublishAt, &product.UnpublishAt, &product.DeletedAt,
+		&product.CompareAtPrice, &product.SalePrice, &product.SaleStartsAt, &product.SaleEndsAt, &product.TaxClass,
+		&product.Weight, &product.Length, &product.Width, &product.Height,
 		&product.Category.ID, &product.Category.Name, &product.Category.Description,
 		&product.Category.CreatedAt, &product.Category.UpdatedAt,
 	)
@@ -125,13 +358,27 @@ func (s *ProductService) GetProduct(id uint) (*models.Product, error) {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 
+	attrs, err := loadProductAttributes(s.db, []uint{product.ID})
+	if err != nil {
+		return nil, err
+	}
+	product.Attributes = attrs[product.ID]
+
+	images, err := loadProductImages(s.db, getMediaStorage(), []uint{product.ID})
+	if err != nil {
+		return nil, err
+	}
+	product.Images = images[product.ID]
+	applyEffectiveStatus(&product)
+	applyEffectivePrice(&product)
+
 	return &product, nil
 }
 
 // UpdateProduct updates an existing product
-func (s *ProductService) UpdateProduct(id uint, req *UpdateProductRequest) (*models.Product, error) {
+func (s *ProductService) UpdateProduct(id uint, req *UpdateProductRequest, actorID uint) (*models.Product, error) {
 	// Check if product exists
-	existingProduct, err := s.GetProduct(id)
+	existingProduct, err := s.GetProductForAdmin(id)
 	if err != nil {
 		return nil, err
 	}
@@ -148,11 +395,31 @@ func (s *ProductService) UpdateProduct(id uint, req *UpdateProductRequest) (*mod
 		}
 	}
 
+	// Check if the tax class exists if it is being updated
+	if req.TaxClass != nil {
+		if err := checkTaxClassExists(s.db, *req.TaxClass); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check if SKU is being updated to one that is already taken
+	if req.SKU != nil && *req.SKU != "" {
+		if err := s.checkSKUAvailable(*req.SKU, id); err != nil {
+			return nil, err
+		}
+	}
+
 	// Build dynamic update query
 	updates := []string{}
 	args := []interface{}{}
 	argIndex := 1
 
+	if req.SKU != nil {
+		updates = append(updates, fmt.Sprintf("sku = NULLIF($%d, '')", argIndex))
+		args = append(args, *req.SKU)
+		argIndex++
+	}
+
 	if req.Name != nil {
 		updates = append(updates, fmt.Sprintf("name = $%d", argIndex))
 		args = append(args, *req.Name)
@@ -195,47 +462,211 @@ func (s *ProductService) UpdateProduct(id uint, req *UpdateProductRequest) (*mod
 		argIndex++
 	}
 
-	if len(updates) == 0 {
+	if req.TaxClass != nil {
+		updates = append(updates, fmt.Sprintf("tax_class = $%d", argIndex))
+		args = append(args, *req.TaxClass)
+		argIndex++
+	}
+
+	if req.Weight != nil {
+		updates = append(updates, fmt.Sprintf("weight = $%d", argIndex))
+		args = append(args, *req.Weight)
+		argIndex++
+	}
+
+	if req.Length != nil {
+		updates = append(updates, fmt.Sprintf("length = $%d", argIndex))
+		args = append(args, *req.Length)
+		argIndex++
+	}
+
+	if req.Width != nil {
+		updates = append(updates, fmt.Sprintf("width = $%d", argIndex))
+		args = append(args, *req.Width)
+		argIndex++
+	}
+
+	if req.Height != nil {
+		updates = append(updates, fmt.Sprintf("height = $%d", argIndex))
+		args = append(args, *req.Height)
+		argIndex++
+	}
+
+	if len(updates) == 0 && len(req.Attributes) == 0 {
 		return existingProduct, nil
 	}
 
+	// Start a transaction so the product and its attributes are updated together
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Capture the current state for the revision log
+	before, err := loadProductState(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate attribute values against the effective category
+	categoryID := existingProduct.CategoryID
+	if req.CategoryID != nil {
+		categoryID = *req.CategoryID
+	}
+	attrValues, err := validateProductAttributes(tx, categoryID, req.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
 	updates = append(updates, "updated_at = NOW()")
 	args = append(args, id)
 
-	query := fmt.Sprintf("UPDATE products SET %s WHERE id = $%d RETURNING id, name, description, price, stock, category_id, image_url, is_active, created_at, updated_at",
+	query := fmt.Sprintf("UPDATE products SET %s WHERE id = $%d RETURNING id, name, description, price, stock, category_id, image_url, COALESCE(sku, ''), is_active, created_at, updated_at, status, publish_at, unpublish_at, deleted_at, compare_at_price, sale_price, sale_starts_at, sale_ends_at, tax_class, weight, length, width, height",
 		strings.Join(updates, ", "), argIndex)
 
 	var product models.Product
-	err = s.db.QueryRow(query, args...).Scan(
+	err = tx.QueryRow(query, args...).Scan(
 		&product.ID, &product.Name, &product.Description, &product.Price, &product.Stock,
-		&product.CategoryID, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.CategoryID, &product.ImageURL, &product.SKU, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+		&product.Status, &product.PublishAt, &product.UnpublishAt, &product.DeletedAt,
+		&product.CompareAtPrice, &product.SalePrice, &product.SaleStartsAt, &product.SaleEndsAt, &product.TaxClass,
+		&product.Weight, &product.Length, &product.Width, &product.Height,
 	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
 
+	// Store attribute values and drop those that no longer apply to the category
+	if err := saveProductAttributes(tx, id, attrValues); err != nil {
+		return nil, err
+	}
+	if len(attrValues) > 0 || req.CategoryID != nil {
+		if err := syncProductAttributesWithCategory(tx, id, categoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Record what changed
+	after, err := loadProductState(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordProductRevision(tx, id, models.ProductRevisionUpdate, actorID, before, after, nil); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	attrs, err := loadProductAttributes(s.db, []uint{product.ID})
+	if err != nil {
+		return nil, err
+	}
+	product.Attributes = attrs[product.ID]
+	applyEffectiveStatus(&product)
+	applyEffectivePrice(&product)
+
 	return &product, nil
 }
 
-// DeleteProduct deletes a product
-func (s *ProductService) DeleteProduct(id uint) error {
-	// Check if product exists
-	_, err := s.GetProduct(id)
+// SetProductStatus changes a product's lifecycle status and publish window
+func (s *ProductService) SetProductStatus(id uint, req *ProductStatusRequest, actorID uint) (*models.Product, error) {
+	status, publishAt, err := normalizeProductSchedule(req.Status, req.PublishAt, req.UnpublishAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.changeProduct(id, models.ProductRevisionStatus, actorID, func(tx *sql.Tx, before productState) error {
+		_, err := tx.Exec(`
+			UPDATE products SET status = $1, publish_at = $2, unpublish_at = $3, updated_at = NOW()
+			WHERE id = $4
+		`, status, publishAt, req.UnpublishAt, id)
+		if err != nil {
+			return fmt.Errorf("failed to update product status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetProductForAdmin(id)
+}
+
+// DeleteProduct soft deletes a product; it stays resolvable from existing orders and can be restored
+func (s *ProductService) DeleteProduct(id uint, actorID uint) error {
+	return s.changeProduct(id, models.ProductRevisionDelete, actorID, func(tx *sql.Tx, before productState) error {
+		if before["deleted_at"] != nil {
+			return errors.New("product not found")
+		}
+
+		_, err := tx.Exec("UPDATE products SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1", id)
+		if err != nil {
+			return fmt.Errorf("failed to delete product: %w", err)
+		}
+		return nil
+	})
+}
+
+// RestoreProduct restores a soft-deleted product
+func (s *ProductService) RestoreProduct(id uint, actorID uint) (*models.Product, error) {
+	err := s.changeProduct(id, models.ProductRevisionRestore, actorID, func(tx *sql.Tx, before productState) error {
+		if before["deleted_at"] == nil {
+			return errors.New("product is not deleted")
+		}
+
+		_, err := tx.Exec("UPDATE products SET deleted_at = NULL, updated_at = NOW() WHERE id = $1", id)
+		if err != nil {
+			return fmt.Errorf("failed to restore product: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetProductForAdmin(id)
+}
+
+// changeProduct applies a change to a product in a transaction and records the resulting revision
+func (s *ProductService) changeProduct(id uint, action string, actorID uint, apply func(tx *sql.Tx, before productState) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := loadProductState(tx, id)
 	if err != nil {
 		return err
 	}
 
-	// Soft delete by setting is_active to false
-	_, err = s.db.Exec("UPDATE products SET is_active = false, updated_at = NOW() WHERE id = $1", id)
+	if err := apply(tx, before); err != nil {
+		return err
+	}
+
+	after, err := loadProductState(tx, id)
 	if err != nil {
-		return fmt.Errorf("failed to delete product: %w", err)
+		return err
+	}
+	if err := recordProductRevision(tx, id, action, actorID, before, after, nil); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
 	return nil
 }
 
-// ListProducts retrieves a paginated list of products with filtering
+// ListProducts retrieves a paginated list of products with filtering.
+// Pages are addressed either by page number or by an opaque cursor returned from a previous call.
 func (s *ProductService) ListProducts(filter *ProductFilter) (*ProductListResponse, error) {
 	// Set default values
 	if filter.Page <= 0 {
@@ -248,73 +679,103 @@ func (s *ProductService) ListProducts(filter *ProductFilter) (*ProductListRespon
 		filter.Limit = 100
 	}
 
-	// Build WHERE clause
-	whereConditions := []string{}
-	args := []interface{}{}
-	argIndex := 1
-
-	if filter.CategoryID != nil {
-		whereConditions = append(whereConditions, fmt.Sprintf("p.category_id = $%d", argIndex))
-		args = append(args, *filter.CategoryID)
-		argIndex++
+	// Resolve sorting, falling back to the cursor's sort when none is given
+	var cursor *pageCursor
+	if filter.Cursor != "" {
+		var err error
+		cursor, err = decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if filter.SortBy == "" {
+			filter.SortBy = cursor.Sort
+		}
+		if filter.SortOrder == "" {
+			filter.SortOrder = cursor.Order
+		}
 	}
 
-	if filter.MinPrice != nil {
-		whereConditions = append(whereConditions, fmt.Sprintf("p.price >= $%d", argIndex))
-		args = append(args, *filter.MinPrice)
-		argIndex++
+	if filter.SortBy == "" {
+		filter.SortBy = "created_at"
+	}
+	sortCol, ok := productSortColumns[filter.SortBy]
+	if !ok {
+		return nil, errors.New("invalid sort field")
 	}
 
-	if filter.MaxPrice != nil {
-		whereConditions = append(whereConditions, fmt.Sprintf("p.price <= $%d", argIndex))
-		args = append(args, *filter.MaxPrice)
-		argIndex++
+	defaultOrder := "desc"
+	if filter.SortBy == "name" {
+		defaultOrder = "asc"
 	}
+	order, err := normalizeSortOrder(filter.SortOrder, defaultOrder)
+	if err != nil {
+		return nil, err
+	}
+	filter.SortOrder = order
 
-	if filter.Search != nil && *filter.Search != "" {
-		whereConditions = append(whereConditions, fmt.Sprintf("(p.name ILIKE $%d OR p.description ILIKE $%d)", argIndex, argIndex))
-		args = append(args, "%"+*filter.Search+"%")
-		argIndex++
+	if cursor != nil && (cursor.Sort != filter.SortBy || cursor.Order != filter.SortOrder) {
+		return nil, errors.New("invalid cursor")
 	}
 
-	if filter.IsActive != nil {
-		whereConditions = append(whereConditions, fmt.Sprintf("p.is_active = $%d", argIndex))
-		args = append(args, *filter.IsActive)
-		argIndex++
-	} else {
-		// Default to active products only
-		whereConditions = append(whereConditions, "p.is_active = true")
+	// Build WHERE clause
+	whereConditions, args, err := s.buildProductConditions(filter)
+	if err != nil {
+		return nil, err
 	}
+	argIndex := len(args) + 1
 
 	whereClause := ""
 	if len(whereConditions) > 0 {
 		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
 	}
 
-	// Count total products
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products p %s", whereClause)
-	var total int
-	err := s.db.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count products: %w", err)
+	response := &ProductListResponse{
+		Page:  filter.Page,
+		Limit: filter.Limit,
 	}
 
-	// Calculate pagination
-	offset := (filter.Page - 1) * filter.Limit
-	pages := (total + filter.Limit - 1) / filter.Limit
+	// Count total products unless the caller opted out
+	if !filter.SkipCount {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products p %s", whereClause)
+		var total int
+		err := s.db.QueryRow(countQuery, args...).Scan(&total)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count products: %w", err)
+		}
+		pages := (total + filter.Limit - 1) / filter.Limit
+		response.Total = &total
+		response.Pages = &pages
+	}
 
-	// Get products
+	// Position the page either after the cursor or by offset
+	pagination := ""
+	if cursor != nil {
+		condition, cursorArgs := keysetCondition(sortCol, "p.id", filter.SortOrder, cursor, argIndex)
+		whereConditions = append(whereConditions, condition)
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+		args = append(args, cursorArgs...)
+		argIndex += len(cursorArgs)
+		pagination = fmt.Sprintf("LIMIT $%d", argIndex)
+		args = append(args, filter.Limit+1)
+	} else {
+		offset := (filter.Page - 1) * filter.Limit
+		pagination = fmt.Sprintf("LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
+		args = append(args, filter.Limit+1, offset)
+	}
+
+	// Get products, fetching one extra row to detect further pages
 	query := fmt.Sprintf(`
-		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, p.is_active, p.created_at, p.updated_at,
-		       c.id, c.name, c.description, c.created_at, c.updated_at
+		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, COALESCE(p.sku, ''), p.is_active, p.created_at, p.updated_at,
+		       p.status, p.publish_at, p.unpublish_at, p.deleted_at,
+		       p.compare_at_price, p.sale_price, p.sale_starts_at, p.sale_ends_at, p.tax_class, p.weight, p.length, p.width, p.height,
+		       c.id, c.name, c.description, c.created_at, c.updated_at,
+		       (%s)::text
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
 		%s
-		ORDER BY p.created_at DESC
-		LIMIT $%d OFFSET $%d
-	`, whereClause, argIndex, argIndex+1)
-
-	args = append(args, filter.Limit, offset)
+		%s
+		%s
+	`, sortCol.expr, whereClause, orderByClause(sortCol, "p.id", filter.SortOrder), pagination)
 
 	rows, err := s.db.Query(query, args...)
 	if err != nil {
@@ -323,42 +784,151 @@ func (s *ProductService) ListProducts(filter *ProductFilter) (*ProductListRespon
 	defer rows.Close()
 
 	var products []models.Product
+	var sortValues []string
 	for rows.Next() {
 		var product models.Product
+		var sortValue string
 		err := rows.Scan(
 			&product.ID, &product.Name, &product.Description, &product.Price, &product.Stock,
-			&product.CategoryID, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+			&product.CategoryID, &product.ImageURL, &product.SKU, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+			&product.Status, &product.PublishAt, &product.UnpublishAt, &product.DeletedAt,
+			&product.CompareAtPrice, &product.SalePrice, &product.SaleStartsAt, &product.SaleEndsAt, &product.TaxClass,
+			&product.Weight, &product.Length, &product.Width, &product.Height,
 			&product.Category.ID, &product.Category.Name, &product.Category.Description,
 			&product.Category.CreatedAt, &product.Category.UpdatedAt,
+			&sortValue,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
+		applyEffectiveStatus(&product)
+		applyEffectivePrice(&product)
 		products = append(products, product)
+		sortValues = append(sortValues, sortValue)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, fmt.Errorf("error iterating products: %w", err)
 	}
 
-	return &ProductListResponse{
-		Products: products,
-		Total:    total,
-		Page:     filter.Page,
-		Limit:    filter.Limit,
-		Pages:    pages,
-	}, nil
+	if len(products) > filter.Limit {
+		products = products[:filter.Limit]
+		last := products[len(products)-1]
+		response.HasMore = true
+		response.NextCursor = encodeCursor(&pageCursor{
+			Sort:  filter.SortBy,
+			Order: filter.SortOrder,
+			Value: sortValues[filter.Limit-1],
+			ID:    last.ID,
+		})
+	}
+
+	// Attach attribute values and galleries for the returned page
+	productIDs := make([]uint, len(products))
+	for i := range products {
+		productIDs[i] = products[i].ID
+	}
+	attrs, err := loadProductAttributes(s.db, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	images, err := loadProductImages(s.db, getMediaStorage(), productIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Attributes = attrs[products[i].ID]
+		products[i].Images = images[products[i].ID]
+	}
+
+	response.Products = products
+	return response, nil
 }
 
-// UpdateStock updates product stock
-func (s *ProductService) UpdateStock(id uint, quantity int) error {
-	_, err := s.db.Exec("UPDATE products SET stock = stock + $1, updated_at = NOW() WHERE id = $2", quantity, id)
+// buildProductConditions builds the WHERE conditions and arguments for a product filter
+func (s *ProductService) buildProductConditions(filter *ProductFilter) ([]string, []interface{}, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.CategoryID != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("p.category_id = $%d", argIndex))
+		args = append(args, *filter.CategoryID)
+		argIndex++
+	}
+
+	if filter.MinPrice != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("%s >= $%d", productEffectivePriceExpr("p."), argIndex))
+		args = append(args, *filter.MinPrice)
+		argIndex++
+	}
+
+	if filter.MaxPrice != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("%s <= $%d", productEffectivePriceExpr("p."), argIndex))
+		args = append(args, *filter.MaxPrice)
+		argIndex++
+	}
+
+	if filter.Search != nil && *filter.Search != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("(p.name ILIKE $%d OR p.description ILIKE $%d)", argIndex, argIndex))
+		args = append(args, "%"+*filter.Search+"%")
+		argIndex++
+	}
+
+	if filter.IsActive != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("p.is_active = $%d", argIndex))
+		args = append(args, *filter.IsActive)
+		argIndex++
+	}
+
+	if filter.Status != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("p.status = $%d", argIndex))
+		args = append(args, filter.Status)
+		argIndex++
+	}
+
+	// Default to products visible to customers
+	if !filter.IncludeHidden {
+		whereConditions = append(whereConditions, productVisibleCondition("p."))
+	} else if !filter.IncludeDeleted {
+		whereConditions = append(whereConditions, "p.deleted_at IS NULL")
+	}
+
+	// Attribute filters
+	attrConditions, attrArgs, err := attributeFilterConditions(s.db, filter.Attributes, argIndex)
 	if err != nil {
-		return fmt.Errorf("failed to update stock: %w", err)
+		return nil, nil, err
+	}
+	whereConditions = append(whereConditions, attrConditions...)
+	args = append(args, attrArgs...)
+
+	return whereConditions, args, nil
+}
+
+// checkSKUAvailable returns an error if another product already uses the SKU
+func (s *ProductService) checkSKUAvailable(sku string, excludeID uint) error {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1 AND id != $2)", sku, excludeID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if exists {
+		return errors.New("product with this SKU already exists")
 	}
 	return nil
 }
 
+// UpdateStock adjusts product stock by quantity
+func (s *ProductService) UpdateStock(id uint, quantity int, actorID uint) error {
+	return s.changeProduct(id, models.ProductRevisionStock, actorID, func(tx *sql.Tx, before productState) error {
+		_, err := tx.Exec("UPDATE products SET stock = stock + $1, updated_at = NOW() WHERE id = $2", quantity, id)
+		if err != nil {
+			return fmt.Errorf("failed to update stock: %w", err)
+		}
+		return nil
+	})
+}
+
 // GetProductsByCategory retrieves products by category ID
 func (s *ProductService) GetProductsByCategory(categoryID uint, limit int) ([]models.Product, error) {
 	if limit <= 0 {
@@ -366,11 +936,13 @@ func (s *ProductService) GetProductsByCategory(categoryID uint, limit int) ([]mo
 	}
 
 	query := `
-		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, p.is_active, p.created_at, p.updated_at,
+		SELECT p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, COALESCE(p.sku, ''), p.is_active, p.created_at, p.updated_at,
+		       p.status, p.publish_at, p.unpublish_at, p.deleted_at,
+		       p.compare_at_price, p.sale_price, p.sale_starts_at, p.sale_ends_at, p.tax_class, p.weight, p.length, p.width, p.height,
 		       c.id, c.name, c.description, c.created_at, c.updated_at
 		FROM products p
 		LEFT JOIN categories c ON p.category_id = c.id
-		WHERE p.category_id = $1 AND p.is_active = true
+		WHERE p.category_id = $1 AND ` + productVisibleCondition("p.") + `
 		ORDER BY p.created_at DESC
 		LIMIT $2
 	`
@@ -386,13 +958,18 @@ func (s *ProductService) GetProductsByCategory(categoryID uint, limit int) ([]mo
 		var product models.Product
 		err := rows.Scan(
 			&product.ID, &product.Name, &product.Description, &product.Price, &product.Stock,
-			&product.CategoryID, &product.ImageURL, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+			&product.CategoryID, &product.ImageURL, &product.SKU, &product.IsActive, &product.CreatedAt, &product.UpdatedAt,
+			&product.Status, &product.PublishAt, &product.UnpublishAt, &product.DeletedAt,
+			&product.CompareAtPrice, &product.SalePrice, &product.SaleStartsAt, &product.SaleEndsAt, &product.TaxClass,
+			&product.Weight, &product.Length, &product.Width, &product.Height,
 			&product.Category.ID, &product.Category.Name, &product.Category.Description,
 			&product.Category.CreatedAt, &product.Category.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
+		applyEffectiveStatus(&product)
+		applyEffectivePrice(&product)
 		products = append(products, product)
 	}
 
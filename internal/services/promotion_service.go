@@ -0,0 +1,549 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/lib/pq"
+)
+
+// PromotionService handles automatic promotion management
+type PromotionService struct {
+	db *sql.DB
+}
+
+// NewPromotionService creates a new promotion service
+func NewPromotionService() *PromotionService {
+	return &PromotionService{
+		db: database.GetDB(),
+	}
+}
+
+// PromotionRequest represents the request to create or replace a promotion
+type PromotionRequest struct {
+	Name        string                `json:"name" binding:"required"`
+	Description string                `json:"description"`
+	Type        string                `json:"type" binding:"required,oneof=buy_x_get_y volume_tier bundle spend_threshold"`
+	Priority    int                   `json:"priority"`
+	Stackable   *bool                 `json:"stackable"`
+	IsActive    *bool                 `json:"is_active"`
+	StartsAt    *time.Time            `json:"starts_at"`
+	EndsAt      *time.Time            `json:"ends_at"`
+	Rules       models.PromotionRules `json:"rules"`
+	ProductIDs  []int64               `json:"product_ids"`
+	CategoryIDs []int64               `json:"category_ids"`
+}
+
+// PromotionListResponse represents the paginated promotion list response
+type PromotionListResponse struct {
+	Promotions []models.Promotion `json:"promotions"`
+	Total      int                `json:"total"`
+	Page       int                `json:"page"`
+	Limit      int                `json:"limit"`
+	Pages      int                `json:"pages"`
+}
+
+// pricedLine is a cart or order line as seen by promotions and coupons
+type pricedLine struct {
+	ProductID  uint
+	CategoryID uint
+	UnitPrice  float64
+	Quantity   int
+	Amount     float64
+	// Discount is the total of the promotion discounts allocated to the line
+	Discount float64
+	// CouponDiscount is the line's share of the coupon discount, used as a tax base reduction
+	CouponDiscount float64
+	TaxClass       string
+}
+
+// remaining returns the line amount left after promotion discounts
+func (l pricedLine) remaining() float64 {
+	return l.Amount - l.Discount
+}
+
+// net returns the line amount left after promotion and coupon discounts
+func (l pricedLine) net() float64 {
+	return l.Amount - l.Discount - l.CouponDiscount
+}
+
+const promotionColumns = `id, name, COALESCE(description, ''), type, priority, stackable, is_active, starts_at, ends_at,
+	rules, product_ids, category_ids, created_at, updated_at`
+
+// scanPromotion scans a promotion row selected with promotionColumns
+func scanPromotion(scanner interface{ Scan(...interface{}) error }, promotion *models.Promotion) error {
+	var rulesJSON []byte
+	err := scanner.Scan(
+		&promotion.ID, &promotion.Name, &promotion.Description, &promotion.Type, &promotion.Priority,
+		&promotion.Stackable, &promotion.IsActive, &promotion.StartsAt, &promotion.EndsAt, &rulesJSON,
+		pq.Array(&promotion.ProductIDs), pq.Array(&promotion.CategoryIDs), &promotion.CreatedAt, &promotion.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(rulesJSON, &promotion.Rules); err != nil {
+		return fmt.Errorf("failed to decode promotion rules: %w", err)
+	}
+	if promotion.ProductIDs == nil {
+		promotion.ProductIDs = []int64{}
+	}
+	if promotion.CategoryIDs == nil {
+		promotion.CategoryIDs = []int64{}
+	}
+	return nil
+}
+
+// validatePromotionRequest checks the type-specific rules of a promotion request and drops rules of other types
+func validatePromotionRequest(req *PromotionRequest) error {
+	rules := req.Rules
+	switch req.Type {
+	case models.PromotionTypeBuyXGetY:
+		if rules.BuyQuantity <= 0 || rules.GetQuantity <= 0 {
+			return errors.New("invalid promotion: buy_quantity and get_quantity must be greater than 0")
+		}
+		if rules.DiscountPercent == 0 {
+			rules.DiscountPercent = 100
+		}
+		if rules.DiscountPercent < 0 || rules.DiscountPercent > 100 {
+			return errors.New("invalid promotion: discount_percent must be between 0 and 100")
+		}
+		req.Rules = models.PromotionRules{
+			BuyQuantity: rules.BuyQuantity, GetQuantity: rules.GetQuantity, DiscountPercent: rules.DiscountPercent,
+		}
+	case models.PromotionTypeVolumeTier:
+		if len(rules.Tiers) == 0 {
+			return errors.New("invalid promotion: tiers are required")
+		}
+		for _, tier := range rules.Tiers {
+			if tier.MinQuantity <= 0 || tier.Percent <= 0 || tier.Percent > 100 {
+				return errors.New("invalid promotion: tiers need a min_quantity above 0 and a percent between 0 and 100")
+			}
+		}
+		req.Rules = models.PromotionRules{Tiers: rules.Tiers}
+	case models.PromotionTypeBundle:
+		if len(rules.BundleItems) == 0 || rules.BundlePrice <= 0 {
+			return errors.New("invalid promotion: bundle_items and a bundle_price above 0 are required")
+		}
+		seen := make(map[uint]bool)
+		for _, item := range rules.BundleItems {
+			if item.ProductID == 0 || item.Quantity <= 0 {
+				return errors.New("invalid promotion: bundle items need a product_id and a quantity above 0")
+			}
+			if seen[item.ProductID] {
+				return errors.New("invalid promotion: bundle items must be distinct products")
+			}
+			seen[item.ProductID] = true
+		}
+		req.Rules = models.PromotionRules{BundleItems: rules.BundleItems, BundlePrice: rules.BundlePrice}
+	case models.PromotionTypeSpendThreshold:
+		if len(rules.Thresholds) == 0 {
+			return errors.New("invalid promotion: thresholds are required")
+		}
+		for _, threshold := range rules.Thresholds {
+			if threshold.MinSubtotal < 0 || (threshold.Amount > 0) == (threshold.Percent > 0) || threshold.Amount < 0 ||
+				threshold.Percent < 0 || threshold.Percent > 100 {
+				return errors.New("invalid promotion: thresholds need a min_subtotal and either an amount or a percent")
+			}
+		}
+		req.Rules = models.PromotionRules{Thresholds: rules.Thresholds}
+	default:
+		return errors.New("invalid promotion: unknown type")
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return errors.New("invalid promotion: ends_at must be after starts_at")
+	}
+	if req.ProductIDs == nil {
+		req.ProductIDs = []int64{}
+	}
+	if req.CategoryIDs == nil {
+		req.CategoryIDs = []int64{}
+	}
+
+	return nil
+}
+
+// CreatePromotion creates a new promotion
+func (s *PromotionService) CreatePromotion(req *PromotionRequest) (*models.Promotion, error) {
+	if err := validatePromotionRequest(req); err != nil {
+		return nil, err
+	}
+
+	rulesJSON, err := json.Marshal(req.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode promotion rules: %w", err)
+	}
+	stackable := true
+	if req.Stackable != nil {
+		stackable = *req.Stackable
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var promotion models.Promotion
+	query := fmt.Sprintf(`
+		INSERT INTO promotions (name, description, type, priority, stackable, is_active, starts_at, ends_at,
+		                        rules, product_ids, category_ids, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW(), NOW())
+		RETURNING %s
+	`, promotionColumns)
+	err = scanPromotion(s.db.QueryRow(
+		query,
+		req.Name, req.Description, req.Type, req.Priority, stackable, isActive, req.StartsAt, req.EndsAt,
+		rulesJSON, pq.Array(req.ProductIDs), pq.Array(req.CategoryIDs),
+	), &promotion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create promotion: %w", err)
+	}
+
+	return &promotion, nil
+}
+
+// GetPromotion retrieves a promotion by ID
+func (s *PromotionService) GetPromotion(id uint) (*models.Promotion, error) {
+	var promotion models.Promotion
+	query := fmt.Sprintf("SELECT %s FROM promotions WHERE id = $1", promotionColumns)
+	if err := scanPromotion(s.db.QueryRow(query, id), &promotion); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	return &promotion, nil
+}
+
+// UpdatePromotion replaces a promotion's settings
+func (s *PromotionService) UpdatePromotion(id uint, req *PromotionRequest) (*models.Promotion, error) {
+	if err := validatePromotionRequest(req); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.GetPromotion(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rulesJSON, err := json.Marshal(req.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode promotion rules: %w", err)
+	}
+	stackable := existing.Stackable
+	if req.Stackable != nil {
+		stackable = *req.Stackable
+	}
+	isActive := existing.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var promotion models.Promotion
+	query := fmt.Sprintf(`
+		UPDATE promotions
+		SET name = $1, description = $2, type = $3, priority = $4, stackable = $5, is_active = $6, starts_at = $7,
+		    ends_at = $8, rules = $9, product_ids = $10, category_ids = $11, updated_at = NOW()
+		WHERE id = $12
+		RETURNING %s
+	`, promotionColumns)
+	err = scanPromotion(s.db.QueryRow(
+		query,
+		req.Name, req.Description, req.Type, req.Priority, stackable, isActive, req.StartsAt, req.EndsAt,
+		rulesJSON, pq.Array(req.ProductIDs), pq.Array(req.CategoryIDs), id,
+	), &promotion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("promotion not found")
+		}
+		return nil, fmt.Errorf("failed to update promotion: %w", err)
+	}
+
+	return &promotion, nil
+}
+
+// DeletePromotion deletes a promotion. Orders keep their discount lines.
+func (s *PromotionService) DeletePromotion(id uint) error {
+	result, err := s.db.Exec("DELETE FROM promotions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete promotion: %w", err)
+	}
+
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("promotion not found")
+	}
+
+	return nil
+}
+
+// ListPromotions retrieves a paginated list of promotions in evaluation order
+func (s *PromotionService) ListPromotions(page, limit int) (*PromotionListResponse, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM promotions").Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count promotions: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM promotions ORDER BY priority DESC, id ASC LIMIT $1 OFFSET $2", promotionColumns)
+	rows, err := s.db.Query(query, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	defer rows.Close()
+
+	promotions := []models.Promotion{}
+	for rows.Next() {
+		var promotion models.Promotion
+		if err := scanPromotion(rows, &promotion); err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating promotions: %w", err)
+	}
+
+	return &PromotionListResponse{
+		Promotions: promotions,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		Pages:      (total + limit - 1) / limit,
+	}, nil
+}
+
+// loadActivePromotions loads the promotions currently running, in evaluation order
+func loadActivePromotions(q querier) ([]models.Promotion, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM promotions
+		WHERE is_active = true AND (starts_at IS NULL OR starts_at <= NOW()) AND (ends_at IS NULL OR ends_at > NOW())
+		ORDER BY priority DESC, id ASC
+	`, promotionColumns)
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query promotions: %w", err)
+	}
+	defer rows.Close()
+
+	var promotions []models.Promotion
+	for rows.Next() {
+		var promotion models.Promotion
+		if err := scanPromotion(rows, &promotion); err != nil {
+			return nil, fmt.Errorf("failed to scan promotion: %w", err)
+		}
+		promotions = append(promotions, promotion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating promotions: %w", err)
+	}
+
+	return promotions, nil
+}
+
+// applyPromotions evaluates promotions in order against the lines, adds each promotion's
+// per-line discounts to the lines and returns a discount line per applied promotion
+func applyPromotions(promotions []models.Promotion, lines []pricedLine) []models.OrderDiscount {
+	discounts := []models.OrderDiscount{}
+	for i := range promotions {
+		promotion := &promotions[i]
+		if !promotion.Stackable && len(discounts) > 0 {
+			continue
+		}
+
+		shares := evaluatePromotion(promotion, lines)
+		total := 0.0
+		for j, share := range shares {
+			share = math.Min(roundCents(share), roundCents(lines[j].remaining()))
+			if share <= 0 {
+				continue
+			}
+			lines[j].Discount = roundCents(lines[j].Discount + share)
+			total += share
+		}
+		if total <= 0 {
+			continue
+		}
+
+		promotionID := promotion.ID
+		description := promotion.Description
+		if description == "" {
+			description = promotion.Name
+		}
+		discounts = append(discounts, models.OrderDiscount{
+			Source:      models.DiscountSourcePromotion,
+			PromotionID: &promotionID,
+			Description: description,
+			Type:        promotion.Type,
+			Amount:      roundCents(total),
+		})
+
+		if !promotion.Stackable {
+			break
+		}
+	}
+	return discounts
+}
+
+// evaluatePromotion returns the discount a promotion gives on each line
+func evaluatePromotion(promotion *models.Promotion, lines []pricedLine) []float64 {
+	shares := make([]float64, len(lines))
+	rules := promotion.Rules
+
+	switch promotion.Type {
+	case models.PromotionTypeBuyXGetY:
+		// Pool eligible units, most expensive first; in every group of buy+get units the cheapest get units are discounted
+		type unit struct {
+			line  int
+			price float64
+		}
+		var units []unit
+		for i, line := range lines {
+			if !promotionAppliesTo(promotion, line) || line.Quantity == 0 {
+				continue
+			}
+			price := line.remaining() / float64(line.Quantity)
+			for n := 0; n < line.Quantity; n++ {
+				units = append(units, unit{line: i, price: price})
+			}
+		}
+		sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+
+		groupSize := rules.BuyQuantity + rules.GetQuantity
+		for start := 0; start+groupSize <= len(units); start += groupSize {
+			for _, u := range units[start+rules.BuyQuantity : start+groupSize] {
+				shares[u.line] += u.price * rules.DiscountPercent / 100
+			}
+		}
+
+	case models.PromotionTypeVolumeTier:
+		for i, line := range lines {
+			if !promotionAppliesTo(promotion, line) {
+				continue
+			}
+			best := 0.0
+			bestMin := 0
+			for _, tier := range rules.Tiers {
+				if line.Quantity >= tier.MinQuantity && tier.MinQuantity > bestMin {
+					best = tier.Percent
+					bestMin = tier.MinQuantity
+				}
+			}
+			shares[i] = line.remaining() * best / 100
+		}
+
+	case models.PromotionTypeBundle:
+		// Count complete bundles across the lines of each bundle product
+		quantities := make(map[uint]int)
+		unitPrices := make(map[uint]float64)
+		for _, line := range lines {
+			if line.Quantity == 0 {
+				continue
+			}
+			quantities[line.ProductID] += line.Quantity
+			if _, ok := unitPrices[line.ProductID]; !ok {
+				unitPrices[line.ProductID] = line.remaining() / float64(line.Quantity)
+			}
+		}
+		bundles := -1
+		regular := 0.0
+		for _, item := range rules.BundleItems {
+			count := quantities[item.ProductID] / item.Quantity
+			if bundles < 0 || count < bundles {
+				bundles = count
+			}
+			regular += unitPrices[item.ProductID] * float64(item.Quantity)
+		}
+		if bundles <= 0 || regular <= rules.BundlePrice {
+			break
+		}
+
+		// Spread the saving over the bundled units in proportion to their price
+		saving := float64(bundles) * (regular - rules.BundlePrice)
+		for _, item := range rules.BundleItems {
+			itemShare := saving * unitPrices[item.ProductID] * float64(item.Quantity) / regular
+			left := item.Quantity * bundles
+			for i, line := range lines {
+				if line.ProductID != item.ProductID || left == 0 {
+					continue
+				}
+				used := line.Quantity
+				if used > left {
+					used = left
+				}
+				shares[i] += itemShare * float64(used) / float64(item.Quantity*bundles)
+				left -= used
+			}
+		}
+
+	case models.PromotionTypeSpendThreshold:
+		eligible := 0.0
+		for _, line := range lines {
+			if promotionAppliesTo(promotion, line) {
+				eligible += line.remaining()
+			}
+		}
+		var best *models.PromotionThreshold
+		for i := range rules.Thresholds {
+			threshold := &rules.Thresholds[i]
+			if eligible >= threshold.MinSubtotal && eligible > 0 && (best == nil || threshold.MinSubtotal > best.MinSubtotal) {
+				best = threshold
+			}
+		}
+		if best == nil {
+			break
+		}
+
+		amount := best.Amount
+		if best.Percent > 0 {
+			amount = eligible * best.Percent / 100
+		}
+		amount = math.Min(amount, eligible)
+		for i, line := range lines {
+			if promotionAppliesTo(promotion, line) {
+				shares[i] = amount * line.remaining() / eligible
+			}
+		}
+	}
+
+	return shares
+}
+
+// promotionAppliesTo reports whether a line matches a promotion's restrictions
+func promotionAppliesTo(promotion *models.Promotion, line pricedLine) bool {
+	if len(promotion.ProductIDs) == 0 && len(promotion.CategoryIDs) == 0 {
+		return true
+	}
+	for _, id := range promotion.ProductIDs {
+		if uint(id) == line.ProductID {
+			return true
+		}
+	}
+	for _, id := range promotion.CategoryIDs {
+		if uint(id) == line.CategoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// roundCents rounds an amount to whole cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+func TestApplyPromotions(t *testing.T) {
+	volume := models.Promotion{
+		ID: 1, Name: "Volume", Description: "10% off 2 or more", Type: models.PromotionTypeVolumeTier, Stackable: true,
+		Rules: models.PromotionRules{Tiers: []models.PromotionTier{{MinQuantity: 2, Percent: 10}}},
+	}
+	spend := models.Promotion{
+		ID: 2, Name: "Spend 100", Type: models.PromotionTypeSpendThreshold, Stackable: true,
+		Rules: models.PromotionRules{Thresholds: []models.PromotionThreshold{{MinSubtotal: 100, Amount: 20}}},
+	}
+	halfOff := models.Promotion{
+		ID: 3, Name: "Half off", Type: models.PromotionTypeSpendThreshold,
+		Rules: models.PromotionRules{Thresholds: []models.PromotionThreshold{{MinSubtotal: 100, Percent: 50}}},
+	}
+	bigSpend := models.Promotion{
+		ID: 4, Name: "Spend 1000", Type: models.PromotionTypeSpendThreshold,
+		Rules: models.PromotionRules{Thresholds: []models.PromotionThreshold{{MinSubtotal: 1000, Percent: 50}}},
+	}
+	takeAll := models.Promotion{
+		ID: 5, Name: "Take 120", Type: models.PromotionTypeSpendThreshold, Stackable: true,
+		Rules: models.PromotionRules{Thresholds: []models.PromotionThreshold{{MinSubtotal: 100, Amount: 120}}},
+	}
+	takeAllAgain := models.Promotion{
+		ID: 6, Name: "Spend 10, take 120", Type: models.PromotionTypeSpendThreshold, Stackable: true,
+		Rules: models.PromotionRules{Thresholds: []models.PromotionThreshold{{MinSubtotal: 10, Amount: 120}}},
+	}
+	tenOff := models.Promotion{
+		ID: 7, Name: "Spend 100, save 10", Type: models.PromotionTypeSpendThreshold,
+		Rules: models.PromotionRules{Thresholds: []models.PromotionThreshold{{MinSubtotal: 100, Amount: 10}}},
+	}
+	secondHalfOff := models.Promotion{
+		ID: 8, Name: "Second half off", Type: models.PromotionTypeBuyXGetY, Stackable: true,
+		Rules: models.PromotionRules{BuyQuantity: 1, GetQuantity: 1, DiscountPercent: 50},
+	}
+	pair := models.Promotion{
+		ID: 9, Name: "Pair", Type: models.PromotionTypeBundle, Stackable: true,
+		Rules: models.PromotionRules{
+			BundleItems: []models.PromotionBundleItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 1}},
+			BundlePrice: 90,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		promotions []models.Promotion
+		discounts  []uint
+		amounts    []float64
+		lines      []float64
+	}{
+		{
+			name:       "no promotions",
+			promotions: nil,
+			lines:      []float64{0, 0},
+		},
+		{
+			name:       "stackable promotions apply in order to what is left",
+			promotions: []models.Promotion{volume, spend},
+			discounts:  []uint{1, 2},
+			amounts:    []float64{10, 20},
+			lines:      []float64{22.86, 7.14},
+		},
+		{
+			name:       "non-stackable promotion first stops the rest",
+			promotions: []models.Promotion{halfOff, volume, spend},
+			discounts:  []uint{3},
+			amounts:    []float64{75},
+			lines:      []float64{50, 25},
+		},
+		{
+			name:       "non-stackable promotion after an applied one is skipped",
+			promotions: []models.Promotion{volume, halfOff, spend},
+			discounts:  []uint{1, 2},
+			amounts:    []float64{10, 20},
+			lines:      []float64{22.86, 7.14},
+		},
+		{
+			name:       "non-stackable promotion that does not apply does not block",
+			promotions: []models.Promotion{bigSpend, volume},
+			discounts:  []uint{1},
+			amounts:    []float64{10},
+			lines:      []float64{10, 0},
+		},
+		{
+			name:       "the first of two non-stackable promotions wins",
+			promotions: []models.Promotion{tenOff, halfOff},
+			discounts:  []uint{7},
+			amounts:    []float64{10},
+			lines:      []float64{6.67, 3.33},
+		},
+		{
+			name:       "priority order decides between non-stackable promotions",
+			promotions: []models.Promotion{halfOff, tenOff},
+			discounts:  []uint{3},
+			amounts:    []float64{75},
+			lines:      []float64{50, 25},
+		},
+		{
+			name:       "buy x get y then a volume tier on what is left",
+			promotions: []models.Promotion{secondHalfOff, volume},
+			discounts:  []uint{8, 1},
+			amounts:    []float64{25, 7.5},
+			lines:      []float64{32.5, 0},
+		},
+		{
+			name:       "bundle then a spend threshold on what is left",
+			promotions: []models.Promotion{pair, spend},
+			discounts:  []uint{9, 2},
+			amounts:    []float64{10, 20},
+			lines:      []float64{18.57, 11.43},
+		},
+		{
+			name:       "discounts are capped at the amount left and thresholds use it",
+			promotions: []models.Promotion{takeAll, spend, takeAllAgain, volume},
+			discounts:  []uint{5, 6},
+			amounts:    []float64{120, 30},
+			lines:      []float64{100, 50},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := []pricedLine{
+				{ProductID: 1, CategoryID: 10, UnitPrice: 50, Quantity: 2, Amount: 100},
+				{ProductID: 2, CategoryID: 20, UnitPrice: 50, Quantity: 1, Amount: 50},
+			}
+			promotions := append([]models.Promotion(nil), tt.promotions...)
+
+			discounts := applyPromotions(promotions, lines)
+			if len(discounts) != len(tt.discounts) {
+				t.Fatalf("got %d discounts, want %d: %+v", len(discounts), len(tt.discounts), discounts)
+			}
+			for i, discount := range discounts {
+				if discount.PromotionID == nil || *discount.PromotionID != tt.discounts[i] {
+					t.Errorf("discount %d promotion = %v, want %d", i, discount.PromotionID, tt.discounts[i])
+				}
+				if discount.Amount != tt.amounts[i] {
+					t.Errorf("discount %d amount = %v, want %v", i, discount.Amount, tt.amounts[i])
+				}
+				if discount.Source != models.DiscountSourcePromotion {
+					t.Errorf("discount %d source = %q, want %q", i, discount.Source, models.DiscountSourcePromotion)
+				}
+			}
+			for i, line := range lines {
+				if line.Discount != tt.lines[i] {
+					t.Errorf("line %d discount = %v, want %v", i, line.Discount, tt.lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluatePromotion(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion models.Promotion
+		lines     []pricedLine
+		shares    []float64
+	}{
+		{
+			name: "buy x get y discounts the cheapest unit of each group",
+			promotion: models.Promotion{Type: models.PromotionTypeBuyXGetY, Rules: models.PromotionRules{
+				BuyQuantity: 2, GetQuantity: 1, DiscountPercent: 50,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+			},
+			shares: []float64{0, 5},
+		},
+		{
+			name: "buy x get y groups units most expensive first",
+			promotion: models.Promotion{Type: models.PromotionTypeBuyXGetY, Rules: models.PromotionRules{
+				BuyQuantity: 1, GetQuantity: 1, DiscountPercent: 100,
+			}},
+			lines: []pricedLine{
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+			},
+			shares: []float64{10, 30},
+		},
+		{
+			name: "buy x get y ignores an incomplete group",
+			promotion: models.Promotion{Type: models.PromotionTypeBuyXGetY, Rules: models.PromotionRules{
+				BuyQuantity: 3, GetQuantity: 2, DiscountPercent: 100,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+			},
+			shares: []float64{0, 0},
+		},
+		{
+			name: "buy x get y counts restricted products only",
+			promotion: models.Promotion{Type: models.PromotionTypeBuyXGetY, ProductIDs: []int64{2}, Rules: models.PromotionRules{
+				BuyQuantity: 1, GetQuantity: 1, DiscountPercent: 100,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+			},
+			shares: []float64{0, 10},
+		},
+		{
+			name: "buy x get y prices units after earlier discounts",
+			promotion: models.Promotion{Type: models.PromotionTypeBuyXGetY, Rules: models.PromotionRules{
+				BuyQuantity: 1, GetQuantity: 1, DiscountPercent: 100,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60, Discount: 20},
+			},
+			shares: []float64{20},
+		},
+		{
+			name: "bundle saving is spread in proportion to price",
+			promotion: models.Promotion{Type: models.PromotionTypeBundle, Rules: models.PromotionRules{
+				BundleItems: []models.PromotionBundleItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 1}},
+				BundlePrice: 35,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+			},
+			shares: []float64{7.5, 2.5},
+		},
+		{
+			name: "bundle counts complete sets only",
+			promotion: models.Promotion{Type: models.PromotionTypeBundle, Rules: models.PromotionRules{
+				BundleItems: []models.PromotionBundleItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 1}},
+				BundlePrice: 35,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 1, Amount: 10},
+			},
+			shares: []float64{3.75, 1.25},
+		},
+		{
+			name: "bundle with several units of a product",
+			promotion: models.Promotion{Type: models.PromotionTypeBundle, Rules: models.PromotionRules{
+				BundleItems: []models.PromotionBundleItem{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 1}},
+				BundlePrice: 60,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+			},
+			shares: []float64{8.57, 1.43},
+		},
+		{
+			name: "bundle missing a product",
+			promotion: models.Promotion{Type: models.PromotionTypeBundle, Rules: models.PromotionRules{
+				BundleItems: []models.PromotionBundleItem{{ProductID: 1, Quantity: 1}, {ProductID: 3, Quantity: 1}},
+				BundlePrice: 20,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+			},
+			shares: []float64{0, 0},
+		},
+		{
+			name: "bundle priced above its items",
+			promotion: models.Promotion{Type: models.PromotionTypeBundle, Rules: models.PromotionRules{
+				BundleItems: []models.PromotionBundleItem{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 1}},
+				BundlePrice: 45,
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 2, Amount: 20},
+			},
+			shares: []float64{0, 0},
+		},
+		{
+			name: "volume tier picks the highest tier reached per line",
+			promotion: models.Promotion{Type: models.PromotionTypeVolumeTier, Rules: models.PromotionRules{
+				Tiers: []models.PromotionTier{{MinQuantity: 3, Percent: 10}, {MinQuantity: 2, Percent: 5}},
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, UnitPrice: 10, Quantity: 3, Amount: 30},
+				{ProductID: 3, UnitPrice: 10, Quantity: 1, Amount: 10},
+			},
+			shares: []float64{3, 3, 0},
+		},
+		{
+			name: "spend threshold counts restricted categories only",
+			promotion: models.Promotion{Type: models.PromotionTypeSpendThreshold, CategoryIDs: []int64{10}, Rules: models.PromotionRules{
+				Thresholds: []models.PromotionThreshold{{MinSubtotal: 50, Percent: 10}, {MinSubtotal: 100, Percent: 20}},
+			}},
+			lines: []pricedLine{
+				{ProductID: 1, CategoryID: 10, UnitPrice: 30, Quantity: 2, Amount: 60},
+				{ProductID: 2, CategoryID: 20, UnitPrice: 50, Quantity: 1, Amount: 50},
+			},
+			shares: []float64{6, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares := evaluatePromotion(&tt.promotion, tt.lines)
+			if len(shares) != len(tt.shares) {
+				t.Fatalf("got %d shares, want %d", len(shares), len(tt.shares))
+			}
+			for i, share := range shares {
+				if roundCents(share) != tt.shares[i] {
+					t.Errorf("line %d share = %v, want %v", i, share, tt.shares[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyPromotionsDescription(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        string
+	}{
+		{name: "description", description: "Spend 100, save 20", want: "Spend 100, save 20"},
+		{name: "falls back to the name", want: "Spend 100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promotions := []models.Promotion{{
+				ID: 1, Name: "Spend 100", Description: tt.description, Type: models.PromotionTypeSpendThreshold,
+				Rules: models.PromotionRules{Thresholds: []models.PromotionThreshold{{MinSubtotal: 100, Amount: 20}}},
+			}}
+			lines := []pricedLine{{ProductID: 1, Quantity: 1, Amount: 100}}
+
+			discounts := applyPromotions(promotions, lines)
+			if len(discounts) != 1 {
+				t.Fatalf("got %d discounts, want 1", len(discounts))
+			}
+			if discounts[0].Description != tt.want {
+				t.Errorf("description = %q, want %q", discounts[0].Description, tt.want)
+			}
+		})
+	}
+}
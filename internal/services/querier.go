@@ -0,0 +1,12 @@
+package services
+
+import (
+	"database/sql"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx so helpers can run inside or outside a transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
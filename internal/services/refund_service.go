@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/payment"
+	"github.com/lib/pq"
+)
+
+// RefundService handles refunding orders through the payment provider
+type RefundService struct {
+	db *sql.DB
+}
+
+// NewRefundService creates a new refund service
+func NewRefundService() *RefundService {
+	return &RefundService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateRefundRequest represents the request to refund an order.
+// With Items, each line is refunded at its paid value unless an item amount is given; without
+// Items, Amount is refunded at the order level, and omitting both refunds everything left.
+type CreateRefundRequest struct {
+	Reason string              `json:"reason" binding:"required"`
+	Amount *float64            `json:"amount" binding:"omitempty,gt=0"`
+	Items  []RefundItemRequest `json:"items" binding:"dive"`
+}
+
+// RefundItemRequest represents an order line to refund
+type RefundItemRequest struct {
+	OrderItemID uint     `json:"order_item_id" binding:"required"`
+	Quantity    int      `json:"quantity" binding:"required,gt=0"`
+	Amount      *float64 `json:"amount" binding:"omitempty,gt=0"`
+	Restock     bool     `json:"restock"`
+}
+
+// refundLine is a validated order line to refund
+type refundLine struct {
+	item      models.RefundItem
+	productID uint
+}
+
+// CreateRefund refunds (part of) an order's captured payment through the provider, restocking
+// the requested lines. Refunds the provider rejects are recorded as failed.
+func (s *RefundService) CreateRefund(orderID uint, req *CreateRefundRequest, actorID uint) (*models.Refund, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	refund, orderStatus, err := s.createRefund(tx, orderID, req, actorID)
+	if err != nil {
+		if refund != nil {
+			tx.Rollback()
+			s.recordFailedRefund(refund)
+		}
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if orderStatus != "" {
+		notifyOrderStatus(s.db, orderID, orderStatus)
+	}
+
+	refunds, err := loadRefunds(s.db, "WHERE id = $1", refund.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &refunds[0], nil
+}
+
+// createRefund refunds (part of) an order's captured payment within tx, so callers can complete
+// other changes in the same transaction. Refunded units no longer need shipping, so the order's
+// fulfillment status is derived again; the new status is returned when it changed, for the caller
+// to notify once tx is committed. The provider is called after everything else is written,
+// leaving only the commit. When the provider rejects the refund, the failed refund is returned
+// with the error; the caller records it with recordFailedRefund once tx is rolled back.
+func (s *RefundService) createRefund(tx *sql.Tx, orderID uint, req *CreateRefundRequest, actorID uint) (*models.Refund, string, error) {
+	if req.Amount != nil && len(req.Items) > 0 {
+		return nil, "", errors.New("invalid refund: amount cannot be combined with items; set per-item amounts instead")
+	}
+
+	// Lock the order and its captured payment so concurrent refunds cannot exceed it
+	var orderStatus string
+	var pricesIncludeTax bool
+	err := tx.QueryRow(
+		"SELECT status, prices_include_tax FROM orders WHERE id = $1 FOR UPDATE", orderID,
+	).Scan(&orderStatus, &pricesIncludeTax)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, "", errors.New("order not found")
+		}
+		return nil, "", fmt.Errorf("database error: %w", err)
+	}
+
+	payments, err := loadPayments(tx, `
+		WHERE order_id = $1 AND status IN ('captured', 'refunded') ORDER BY id DESC LIMIT 1 FOR UPDATE
+	`, orderID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(payments) == 0 {
+		return nil, "", errors.New("order has no captured payment")
+	}
+	p := &payments[0]
+	refundable := roundCents(p.CapturedAmount - p.RefundedAmount)
+	if refundable <= 0 {
+		return nil, "", errors.New("invalid refund: order is fully refunded")
+	}
+
+	lines, err := loadRefundLines(tx, orderID, req.Items, pricesIncludeTax)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Work out the amount
+	var amount float64
+	switch {
+	case len(lines) > 0:
+		for _, line := range lines {
+			amount += line.item.Amount
+		}
+	case req.Amount != nil:
+		amount = *req.Amount
+	default:
+		amount = refundable
+	}
+	amount = roundCents(amount)
+	if amount <= 0 {
+		return nil, "", errors.New("invalid refund: amount must be positive")
+	}
+	if amount > refundable {
+		return nil, "", fmt.Errorf("invalid refund: amount exceeds the refundable %.2f", refundable)
+	}
+
+	// Count refunded units and put restocked ones back
+	for _, line := range lines {
+		_, err := tx.Exec(`
+			UPDATE order_items SET refunded_quantity = refunded_quantity + $1, updated_at = NOW() WHERE id = $2
+		`, line.item.Quantity, line.item.OrderItemID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to update order item: %w", err)
+		}
+		if line.item.Restocked {
+			_, err := tx.Exec("UPDATE products SET stock = stock + $1, updated_at = NOW() WHERE id = $2",
+				line.item.Quantity, line.productID)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to restock product: %w", err)
+			}
+		}
+	}
+
+	if err := recordPaymentRefund(tx, p, amount); err != nil {
+		return nil, "", err
+	}
+
+	// Units refunded before they shipped no longer hold up the order's fulfillment
+	newStatus := ""
+	if len(lines) > 0 {
+		note := "items refunded"
+		if req.Reason != "" {
+			note = "items refunded: " + req.Reason
+		}
+		status, err := syncOrderFulfillment(tx, orderID, orderStatus, &actorID, note)
+		if err != nil {
+			return nil, "", err
+		}
+		if status != orderStatus {
+			newStatus = status
+		}
+	}
+
+	refund := &models.Refund{
+		OrderID:   orderID,
+		PaymentID: &p.ID,
+		Amount:    amount,
+		Reason:    req.Reason,
+		CreatedBy: &actorID,
+	}
+
+	// Issue the refund; the payment row stays locked until it is recorded
+	ctx, cancel := context.WithTimeout(context.Background(), payment.Timeout())
+	defer cancel()
+	result, err := getPaymentProvider().Refund(ctx, p.ProviderReference, amount)
+	if err != nil {
+		refund.Status = models.RefundStatusFailed
+		refund.FailureReason = err.Error()
+		return refund, "", providerError("refund", err)
+	}
+
+	refund.Status = models.RefundStatusSucceeded
+	refund.ProviderReference = result.Reference
+	for _, line := range lines {
+		refund.Items = append(refund.Items, line.item)
+	}
+	if err := insertRefund(tx, refund); err != nil {
+		return nil, "", err
+	}
+
+	return refund, newStatus, nil
+}
+
+// recordFailedRefund records a refund the provider rejected. It must run after the transaction
+// that attempted the refund is rolled back, as that transaction locks the order.
+func (s *RefundService) recordFailedRefund(refund *models.Refund) {
+	if err := insertRefund(s.db, refund); err != nil {
+		log.Printf("Warning: failed to record failed refund for order %d: %v", refund.OrderID, err)
+	}
+}
+
+// ListOrderRefunds retrieves the refunds of an order, oldest first
+func (s *RefundService) ListOrderRefunds(orderID uint) ([]models.Refund, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1)", orderID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("order not found")
+	}
+	return loadOrderRefunds(s.db, orderID)
+}
+
+// loadRefundLines locks and validates the order lines of a refund request, pricing each
+// line at its paid value per unit unless an amount is given
+func loadRefundLines(q querier, orderID uint, items []RefundItemRequest, pricesIncludeTax bool) ([]refundLine, error) {
+	lines := make([]refundLine, 0, len(items))
+	seen := make(map[uint]bool)
+
+	for _, item := range items {
+		if seen[item.OrderItemID] {
+			return nil, fmt.Errorf("invalid refund: order item %d is listed more than once", item.OrderItemID)
+		}
+		seen[item.OrderItemID] = true
+
+		var productID uint
+		var quantity, refundedQuantity int
+		var price, discount, taxAmount float64
+		err := q.QueryRow(`
+			SELECT product_id, quantity, refunded_quantity, price, discount_amount, tax_amount
+			FROM order_items
+			WHERE id = $1 AND order_id = $2
+			FOR UPDATE
+		`, item.OrderItemID, orderID).Scan(&productID, &quantity, &refundedQuantity, &price, &discount, &taxAmount)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("invalid refund: order item %d not found", item.OrderItemID)
+			}
+			return nil, fmt.Errorf("failed to load order item: %w", err)
+		}
+
+		if remaining := quantity - refundedQuantity; item.Quantity > remaining {
+			return nil, fmt.Errorf("invalid refund: only %d units of order item %d can be refunded", remaining, item.OrderItemID)
+		}
+
+		value := refundableValue(price, discount, taxAmount, quantity, item.Quantity, pricesIncludeTax)
+
+		amount := value
+		if item.Amount != nil {
+			amount = roundCents(*item.Amount)
+			if amount > value {
+				return nil, fmt.Errorf("invalid refund: amount for order item %d exceeds its paid value %.2f", item.OrderItemID, value)
+			}
+		}
+
+		lines = append(lines, refundLine{
+			item: models.RefundItem{
+				OrderItemID: item.OrderItemID,
+				Quantity:    item.Quantity,
+				Amount:      amount,
+				Restocked:   item.Restock,
+			},
+			productID: productID,
+		})
+	}
+
+	return lines, nil
+}
+
+// refundableValue returns the paid value of some units of an order line: its price less
+// discounts, plus tax when it was charged on top, shared evenly over the line's quantity
+func refundableValue(price, discount, taxAmount float64, quantity, units int, pricesIncludeTax bool) float64 {
+	paid := price*float64(quantity) - discount
+	if !pricesIncludeTax {
+		paid += taxAmount
+	}
+	return roundCents(paid * float64(units) / float64(quantity))
+}
+
+// recordPaymentRefund adds a successful refund to its payment and order totals
+func recordPaymentRefund(q querier, p *models.Payment, amount float64) error {
+	refunded := roundCents(p.RefundedAmount + amount)
+	status := p.Status
+	if refunded >= p.CapturedAmount {
+		status = models.PaymentStatusRefunded
+	}
+	_, err := q.Exec(`
+		UPDATE payments SET status = $1, refunded_amount = $2, updated_at = NOW() WHERE id = $3
+	`, status, refunded, p.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	_, err = q.Exec(`
+		UPDATE orders SET refunded_amount = refunded_amount + $1, updated_at = NOW() WHERE id = $2
+	`, amount, p.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order refunded amount: %w", err)
+	}
+	return nil
+}
+
+// insertRefund stores a refund and its items, setting their IDs
+func insertRefund(q querier, refund *models.Refund) error {
+	err := q.QueryRow(`
+		INSERT INTO refunds (order_id, payment_id, amount, reason, status, provider_reference, failure_reason, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), $8, NOW())
+		RETURNING id, created_at
+	`, refund.OrderID, refund.PaymentID, refund.Amount, refund.Reason, refund.Status,
+		refund.ProviderReference, refund.FailureReason, refund.CreatedBy,
+	).Scan(&refund.ID, &refund.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+
+	for i := range refund.Items {
+		item := &refund.Items[i]
+		item.RefundID = refund.ID
+		err := q.QueryRow(`
+			INSERT INTO refund_items (refund_id, order_item_id, quantity, amount, restocked)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, refund.ID, item.OrderItemID, item.Quantity, item.Amount, item.Restocked).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create refund item: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadOrderRefunds retrieves the refunds of an order with their items, oldest first
+func loadOrderRefunds(q querier, orderID uint) ([]models.Refund, error) {
+	return loadRefunds(q, "WHERE order_id = $1 ORDER BY id", orderID)
+}
+
+// loadRefunds retrieves the refunds matching a WHERE clause with their items
+func loadRefunds(q querier, where string, args ...interface{}) ([]models.Refund, error) {
+	rows, err := q.Query(`
+		SELECT id, order_id, payment_id, amount, reason, status, COALESCE(provider_reference, ''),
+		       COALESCE(failure_reason, ''), created_by, created_at
+		FROM refunds `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refunds: %w", err)
+	}
+
+	var refunds []models.Refund
+	index := make(map[uint]int)
+	for rows.Next() {
+		var refund models.Refund
+		var paymentID, createdBy sql.NullInt64
+		err := rows.Scan(
+			&refund.ID, &refund.OrderID, &paymentID, &refund.Amount, &refund.Reason, &refund.Status,
+			&refund.ProviderReference, &refund.FailureReason, &createdBy, &refund.CreatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan refund: %w", err)
+		}
+		if paymentID.Valid {
+			id := uint(paymentID.Int64)
+			refund.PaymentID = &id
+		}
+		if createdBy.Valid {
+			id := uint(createdBy.Int64)
+			refund.CreatedBy = &id
+		}
+		index[refund.ID] = len(refunds)
+		refunds = append(refunds, refund)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating refunds: %w", err)
+	}
+	if len(refunds) == 0 {
+		return refunds, nil
+	}
+
+	ids := make([]int64, 0, len(refunds))
+	for _, refund := range refunds {
+		ids = append(ids, int64(refund.ID))
+	}
+	itemRows, err := q.Query(`
+		SELECT id, refund_id, order_item_id, quantity, amount, restocked
+		FROM refund_items
+		WHERE refund_id = ANY($1)
+		ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query refund items: %w", err)
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item models.RefundItem
+		if err := itemRows.Scan(&item.ID, &item.RefundID, &item.OrderItemID, &item.Quantity, &item.Amount, &item.Restocked); err != nil {
+			return nil, fmt.Errorf("failed to scan refund item: %w", err)
+		}
+		i := index[item.RefundID]
+		refunds[i].Items = append(refunds[i].Items, item)
+	}
+
+	if err = itemRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating refund items: %w", err)
+	}
+
+	return refunds, nil
+}
@@ -0,0 +1,33 @@
+package services
+
+import "testing"
+
+func TestRefundableValue(t *testing.T) {
+	tests := []struct {
+		name             string
+		price            float64
+		discount         float64
+		taxAmount        float64
+		quantity         int
+		units            int
+		pricesIncludeTax bool
+		want             float64
+	}{
+		{name: "whole line with tax on top", price: 10, discount: 3, taxAmount: 2.7, quantity: 3, units: 3, want: 29.7},
+		{name: "one unit shares discount and tax", price: 10, discount: 3, taxAmount: 2.7, quantity: 3, units: 1, want: 9.9},
+		{name: "included tax is not added", price: 12, taxAmount: 4, quantity: 2, units: 1, pricesIncludeTax: true, want: 12},
+		{name: "included tax with a discount", price: 12, discount: 4, taxAmount: 3.33, quantity: 2, units: 2, pricesIncludeTax: true, want: 20},
+		{name: "one unit rounds to cents", price: 10, discount: 1, quantity: 3, units: 1, want: 9.67},
+		{name: "the other units round to the rest", price: 10, discount: 1, quantity: 3, units: 2, want: 19.33},
+		{name: "fully discounted line", price: 10, discount: 20, quantity: 2, units: 1, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := refundableValue(tt.price, tt.discount, tt.taxAmount, tt.quantity, tt.units, tt.pricesIncludeTax)
+			if got != tt.want {
+				t.Errorf("refundableValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/lib/pq"
+)
+
+// ReturnService handles return requests (RMAs) from request to refund
+type ReturnService struct {
+	db            *sql.DB
+	refundService *RefundService
+}
+
+// NewReturnService creates a new return service
+func NewReturnService() *ReturnService {
+	return &ReturnService{
+		db:            database.GetDB(),
+		refundService: NewRefundService(),
+	}
+}
+
+// CreateReturnRequest represents a customer's request to return items of a delivered order
+type CreateReturnRequest struct {
+	OrderID uint                `json:"order_id" binding:"required"`
+	Reason  string              `json:"reason" binding:"required"`
+	Items   []ReturnItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+// ReturnItemRequest represents an order line to return
+type ReturnItemRequest struct {
+	OrderItemID uint   `json:"order_item_id" binding:"required"`
+	Quantity    int    `json:"quantity" binding:"required,gt=0"`
+	Reason      string `json:"reason"`
+}
+
+// ApproveReturnRequest represents the request to approve a return, attaching its shipping label
+type ApproveReturnRequest struct {
+	Note           string `json:"note"`
+	LabelURL       string `json:"label_url" binding:"omitempty,url"`
+	Carrier        string `json:"carrier"`
+	TrackingNumber string `json:"tracking_number"`
+}
+
+// RejectReturnRequest represents the request to reject a return
+type RejectReturnRequest struct {
+	Note string `json:"note" binding:"required"`
+}
+
+// ReceiveReturnRequest represents the inspection of a received return.
+// Items not listed are restocked only if Restock is set on the request.
+type ReceiveReturnRequest struct {
+	Note    string                    `json:"note"`
+	Restock bool                      `json:"restock"`
+	Items   []ReturnInspectionRequest `json:"items" binding:"dive"`
+}
+
+// ReturnInspectionRequest represents the inspection result of a returned line
+type ReturnInspectionRequest struct {
+	ReturnItemID uint   `json:"return_item_id" binding:"required"`
+	Condition    string `json:"condition"`
+	Restock      bool   `json:"restock"`
+}
+
+// ReturnFilter represents return filtering options
+type ReturnFilter struct {
+	UserID  *uint
+	OrderID *uint
+	Status  string
+	Page    int
+	Limit   int
+}
+
+// ReturnListResponse represents the paginated return list response
+type ReturnListResponse struct {
+	Returns []models.Return `json:"returns"`
+	Total   int             `json:"total"`
+	Page    int             `json:"page"`
+	Limit   int             `json:"limit"`
+	Pages   int             `json:"pages"`
+}
+
+// CreateReturn requests the return of items of one of the user's delivered orders
+func (s *ReturnService) CreateReturn(userID uint, req *CreateReturnRequest) (*models.Return, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the order so concurrent requests cannot return the same units twice
+	var ownerID uint
+	var status string
+	err = tx.QueryRow("SELECT user_id, status FROM orders WHERE id = $1 FOR UPDATE", req.OrderID).Scan(&ownerID, &status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if ownerID != userID {
+		return nil, errors.New("order not found")
+	}
+	if status != "delivered" {
+		return nil, errors.New("invalid return: only delivered orders can be returned")
+	}
+
+	var ret models.Return
+	err = tx.QueryRow(`
+		INSERT INTO returns (order_id, user_id, status, reason, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		RETURNING id
+	`, req.OrderID, userID, models.ReturnStatusRequested, strings.TrimSpace(req.Reason)).Scan(&ret.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create return: %w", err)
+	}
+
+	seen := make(map[uint]bool)
+	for _, item := range req.Items {
+		if seen[item.OrderItemID] {
+			return nil, fmt.Errorf("invalid return: order item %d is listed more than once", item.OrderItemID)
+		}
+		seen[item.OrderItemID] = true
+
+		// Units still returnable: ordered, less refunded and less those in other open returns
+		var productID uint
+		var returnable int
+		err := tx.QueryRow(`
+			SELECT oi.product_id, oi.quantity - oi.refunded_quantity - COALESCE((
+			           SELECT SUM(ri.quantity)
+			           FROM return_items ri
+			           JOIN returns r ON r.id = ri.return_id
+			           WHERE ri.order_item_id = oi.id AND r.status IN ('requested', 'approved', 'received')
+			       ), 0)
+			FROM order_items oi
+			WHERE oi.id = $1 AND oi.order_id = $2
+		`, item.OrderItemID, req.OrderID).Scan(&productID, &returnable)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, fmt.Errorf("invalid return: order item %d not found", item.OrderItemID)
+			}
+			return nil, fmt.Errorf("failed to load order item: %w", err)
+		}
+		if item.Quantity > returnable {
+			return nil, fmt.Errorf("invalid return: only %d units of order item %d can be returned", max(returnable, 0), item.OrderItemID)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO return_items (return_id, order_item_id, product_id, quantity, reason)
+			VALUES ($1, $2, $3, $4, $5)
+		`, ret.ID, item.OrderItemID, productID, item.Quantity, strings.TrimSpace(item.Reason))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create return item: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetReturn(ret.ID)
+}
+
+// ListReturns retrieves returns, newest first
+func (s *ReturnService) ListReturns(filter *ReturnFilter) (*ReturnListResponse, error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+
+	// Build WHERE clause
+	whereConditions := []string{}
+	args := []interface{}{}
+	argIndex := 1
+
+	if filter.UserID != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("user_id = $%d", argIndex))
+		args = append(args, *filter.UserID)
+		argIndex++
+	}
+
+	if filter.OrderID != nil {
+		whereConditions = append(whereConditions, fmt.Sprintf("order_id = $%d", argIndex))
+		args = append(args, *filter.OrderID)
+		argIndex++
+	}
+
+	if filter.Status != "" {
+		whereConditions = append(whereConditions, fmt.Sprintf("status = $%d", argIndex))
+		args = append(args, filter.Status)
+		argIndex++
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM returns "+whereClause, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count returns: %w", err)
+	}
+
+	args = append(args, filter.Limit, (filter.Page-1)*filter.Limit)
+	returns, err := loadReturns(s.db, fmt.Sprintf("%s ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d",
+		whereClause, argIndex, argIndex+1), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReturnListResponse{
+		Returns: returns,
+		Total:   total,
+		Page:    filter.Page,
+		Limit:   filter.Limit,
+		Pages:   (total + filter.Limit - 1) / filter.Limit,
+	}, nil
+}
+
+// GetReturn retrieves a return by ID
+func (s *ReturnService) GetReturn(id uint) (*models.Return, error) {
+	returns, err := loadReturns(s.db, "WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(returns) == 0 {
+		return nil, errors.New("return not found")
+	}
+	return &returns[0], nil
+}
+
+// ApproveReturn approves a requested return and attaches its shipping label
+func (s *ReturnService) ApproveReturn(id uint, req *ApproveReturnRequest) (*models.Return, error) {
+	err := s.transitionReturn(id, "approved", []string{models.ReturnStatusRequested}, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE returns
+			SET status = $1, admin_note = NULLIF($2, ''), label_url = NULLIF($3, ''), carrier = NULLIF($4, ''),
+			    tracking_number = NULLIF($5, ''), approved_at = NOW(), updated_at = NOW()
+			WHERE id = $6
+		`, models.ReturnStatusApproved, req.Note, req.LabelURL, req.Carrier, req.TrackingNumber, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReturn(id)
+}
+
+// RejectReturn rejects a requested return
+func (s *ReturnService) RejectReturn(id uint, req *RejectReturnRequest) (*models.Return, error) {
+	err := s.transitionReturn(id, "rejected", []string{models.ReturnStatusRequested}, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE returns SET status = $1, admin_note = $2, updated_at = NOW() WHERE id = $3
+		`, models.ReturnStatusRejected, req.Note, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReturn(id)
+}
+
+// CancelReturn withdraws a return that has not been shipped back yet
+func (s *ReturnService) CancelReturn(id uint) (*models.Return, error) {
+	err := s.transitionReturn(id, "cancelled", []string{models.ReturnStatusRequested, models.ReturnStatusApproved}, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE returns SET status = $1, updated_at = NOW() WHERE id = $2
+		`, models.ReturnStatusCancelled, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReturn(id)
+}
+
+// ReceiveReturn records the receipt and inspection of an approved return
+func (s *ReturnService) ReceiveReturn(id uint, req *ReceiveReturnRequest) (*models.Return, error) {
+	err := s.transitionReturn(id, "received", []string{models.ReturnStatusApproved}, func(tx *sql.Tx) error {
+		_, err := tx.Exec("UPDATE return_items SET restock = $1 WHERE return_id = $2", req.Restock, id)
+		if err != nil {
+			return fmt.Errorf("failed to update return items: %w", err)
+		}
+
+		for _, item := range req.Items {
+			result, err := tx.Exec(`
+				UPDATE return_items SET condition = NULLIF($1, ''), restock = $2 WHERE id = $3 AND return_id = $4
+			`, strings.TrimSpace(item.Condition), item.Restock, item.ReturnItemID, id)
+			if err != nil {
+				return fmt.Errorf("failed to update return item: %w", err)
+			}
+			if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+				return fmt.Errorf("invalid return: return item %d not found", item.ReturnItemID)
+			}
+		}
+
+		_, err = tx.Exec(`
+			UPDATE returns SET status = $1, admin_note = COALESCE(NULLIF($2, ''), admin_note), received_at = NOW(), updated_at = NOW()
+			WHERE id = $3
+		`, models.ReturnStatusReceived, req.Note, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReturn(id)
+}
+
+// CompleteReturn refunds a received return at the paid value of its lines, restocking the lines
+// inspection marked for restocking, and completes it. The refund is made in the same transaction,
+// so a return is either completed with its refund recorded or left received without one.
+func (s *ReturnService) CompleteReturn(id uint, actorID uint) (*models.Return, error) {
+	var failedRefund *models.Refund
+	var orderID uint
+	var orderStatus string
+	err := s.transitionReturn(id, "completed", []string{models.ReturnStatusReceived}, func(tx *sql.Tx) error {
+		ret, err := loadReturns(tx, "WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		refundReq := returnRefundRequest(&ret[0])
+
+		// The refund also derives the order's fulfillment status again
+		orderID = ret[0].OrderID
+		refund, newStatus, err := s.refundService.createRefund(tx, orderID, refundReq, actorID)
+		if err != nil {
+			failedRefund = refund
+			return err
+		}
+		orderStatus = newStatus
+
+		_, err = tx.Exec(`
+			UPDATE returns SET status = $1, refund_id = $2, completed_at = NOW(), updated_at = NOW() WHERE id = $3
+		`, models.ReturnStatusCompleted, refund.ID, id)
+		return err
+	})
+	if failedRefund != nil {
+		s.refundService.recordFailedRefund(failedRefund)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if orderStatus != "" {
+		notifyOrderStatus(s.db, orderID, orderStatus)
+	}
+	return s.GetReturn(id)
+}
+
+// returnRefundRequest builds the refund of a return's lines, restocking the lines inspection
+// marked for restocking
+func returnRefundRequest(ret *models.Return) *CreateRefundRequest {
+	req := &CreateRefundRequest{
+		Reason: fmt.Sprintf("Return %s: %s", ret.RMANumber, ret.Reason),
+	}
+	for _, item := range ret.Items {
+		req.Items = append(req.Items, RefundItemRequest{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			Restock:     item.Restock,
+		})
+	}
+	return req
+}
+
+// transitionReturn locks a return, checks that it is in one of the allowed statuses and applies a change.
+// outcome names the change in error messages, e.g. "approved".
+func (s *ReturnService) transitionReturn(id uint, outcome string, from []string, apply func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM returns WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("return not found")
+		}
+		return fmt.Errorf("database error: %w", err)
+	}
+
+	allowed := false
+	for _, candidate := range from {
+		if status == candidate {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("return cannot be %s while %s", outcome, status)
+	}
+
+	if err := apply(tx); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// loadReturns retrieves the returns matching a WHERE clause with their items
+func loadReturns(q querier, where string, args ...interface{}) ([]models.Return, error) {
+	rows, err := q.Query(`
+		SELECT id, order_id, user_id, status, reason, COALESCE(admin_note, ''), COALESCE(label_url, ''),
+		       COALESCE(carrier, ''), COALESCE(tracking_number, ''), refund_id, created_at, updated_at,
+		       approved_at, received_at, completed_at
+		FROM returns `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query returns: %w", err)
+	}
+
+	returns := []models.Return{}
+	index := make(map[uint]int)
+	for rows.Next() {
+		var ret models.Return
+		var refundID sql.NullInt64
+		var approvedAt, receivedAt, completedAt sql.NullTime
+		err := rows.Scan(
+			&ret.ID, &ret.OrderID, &ret.UserID, &ret.Status, &ret.Reason, &ret.AdminNote, &ret.LabelURL,
+			&ret.Carrier, &ret.TrackingNumber, &refundID, &ret.CreatedAt, &ret.UpdatedAt,
+			&approvedAt, &receivedAt, &completedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan return: %w", err)
+		}
+		ret.RMANumber = fmt.Sprintf("RMA-%06d", ret.ID)
+		if refundID.Valid {
+			id := uint(refundID.Int64)
+			ret.RefundID = &id
+		}
+		if approvedAt.Valid {
+			ret.ApprovedAt = &approvedAt.Time
+		}
+		if receivedAt.Valid {
+			ret.ReceivedAt = &receivedAt.Time
+		}
+		if completedAt.Valid {
+			ret.CompletedAt = &completedAt.Time
+		}
+		ret.Items = []models.ReturnItem{}
+		index[ret.ID] = len(returns)
+		returns = append(returns, ret)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating returns: %w", err)
+	}
+	if len(returns) == 0 {
+		return returns, nil
+	}
+
+	ids := make([]int64, 0, len(returns))
+	for _, ret := range returns {
+		ids = append(ids, int64(ret.ID))
+	}
+	itemRows, err := q.Query(`
+		SELECT id, return_id, order_item_id, product_id, quantity, COALESCE(reason, ''), COALESCE(condition, ''), restock
+		FROM return_items
+		WHERE return_id = ANY($1)
+		ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query return items: %w", err)
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item models.ReturnItem
+		err := itemRows.Scan(&item.ID, &item.ReturnID, &item.OrderItemID, &item.ProductID, &item.Quantity,
+			&item.Reason, &item.Condition, &item.Restock)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan return item: %w", err)
+		}
+		i := index[item.ReturnID]
+		returns[i].Items = append(returns[i].Items, item)
+	}
+
+	if err = itemRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating return items: %w", err)
+	}
+
+	return returns, nil
+}
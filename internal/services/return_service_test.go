@@ -0,0 +1,35 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Code-byme/e-commerce/internal/models"
+)
+
+func TestReturnRefundRequest(t *testing.T) {
+	ret := &models.Return{
+		ID:        7,
+		RMANumber: "RMA-000007",
+		Reason:    "Arrived damaged",
+		Items: []models.ReturnItem{
+			{ID: 1, OrderItemID: 11, ProductID: 3, Quantity: 2, Condition: "resellable", Restock: true},
+			{ID: 2, OrderItemID: 12, ProductID: 4, Quantity: 1, Condition: "damaged"},
+		},
+	}
+
+	req := returnRefundRequest(ret)
+	if want := "Return RMA-000007: Arrived damaged"; req.Reason != want {
+		t.Errorf("reason = %q, want %q", req.Reason, want)
+	}
+	if req.Amount != nil {
+		t.Errorf("amount = %v, want the lines' paid value", *req.Amount)
+	}
+	want := []RefundItemRequest{
+		{OrderItemID: 11, Quantity: 2, Restock: true},
+		{OrderItemID: 12, Quantity: 1},
+	}
+	if !reflect.DeepEqual(req.Items, want) {
+		t.Errorf("items = %+v, want %+v", req.Items, want)
+	}
+}
@@ -1,14 +1,16 @@
 package services
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/seed"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // SeedService handles seeding the database with sample data
@@ -23,61 +25,52 @@ func NewSeedService() *SeedService {
 	}
 }
 
-// FakeStoreProduct represents a product from FakeStore API
-type FakeStoreProduct struct {
-	ID          int     `json:"id"`
-	Title       string  `json:"title"`
-	Price       float64 `json:"price"`
-	Description string  `json:"description"`
-	Category    string  `json:"category"`
-	Image       string  `json:"image"`
-	Rating      struct {
-		Rate  float64 `json:"rate"`
-		Count int     `json:"count"`
-	} `json:"rating"`
+// SeedSummary counts the records written by a seeding run
+type SeedSummary struct {
+	Users      int
+	Categories int
+	Products   int
+	Orders     int
+	Skipped    int
 }
 
 // SeedCategories creates default categories
 func (s *SeedService) SeedCategories() error {
-	categories := []struct {
-		name        string
-		description string
-	}{
-		{"Electronics", "Electronic devices and gadgets"},
-		{"Clothing", "Fashion and apparel"},
-		{"Home & Garden", "Home improvement and garden supplies"},
-		{"Sports", "Sports equipment and accessories"},
-		{"Books", "Books and literature"},
-		{"Toys", "Toys and games"},
-		{"Automotive", "Automotive parts and accessories"},
-		{"Health", "Health and beauty products"},
-		{"Jewelry", "Jewelry and accessories"},
-		{"Food", "Food and beverages"},
-	}
+	_, err := s.seedCategories(seed.DefaultCategories())
+	return err
+}
 
+// seedCategories creates the categories that do not exist yet
+func (s *SeedService) seedCategories(categories []seed.Category) (int, error) {
+	created := 0
 	for _, cat := range categories {
+		if cat.Name == "" {
+			return created, fmt.Errorf("category name is required")
+		}
+
 		// Check if category already exists
 		var exists bool
-		err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE name = $1)", cat.name).Scan(&exists)
+		err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM categories WHERE name = $1)", cat.Name).Scan(&exists)
 		if err != nil {
-			return fmt.Errorf("failed to check category existence: %w", err)
+			return created, fmt.Errorf("failed to check category existence: %w", err)
 		}
 
 		if !exists {
 			_, err = s.db.Exec(
 				"INSERT INTO categories (name, description, created_at, updated_at) VALUES ($1, $2, NOW(), NOW())",
-				cat.name, cat.description,
+				cat.Name, cat.Description,
 			)
 			if err != nil {
-				return fmt.Errorf("failed to insert category %s: %w", cat.name, err)
+				return created, fmt.Errorf("failed to insert category %s: %w", cat.Name, err)
 			}
-			fmt.Printf("Created category: %s\n", cat.name)
+			created++
+			fmt.Printf("Created category: %s\n", cat.Name)
 		} else {
-			fmt.Printf("Category already exists: %s\n", cat.name)
+			fmt.Printf("Category already exists: %s\n", cat.Name)
 		}
 	}
 
-	return nil
+	return created, nil
 }
 
 // GetCategoryIDByName gets category ID by name
@@ -90,107 +83,366 @@ func (s *SeedService) GetCategoryIDByName(name string) (uint, error) {
 	return id, nil
 }
 
-// MapFakeStoreCategory maps FakeStore categories to our categories
-func (s *SeedService) MapFakeStoreCategory(fakeCategory string) string {
-	categoryMap := map[string]string{
-		"electronics":      "Electronics",
-		"jewelery":         "Jewelry",
-		"men's clothing":   "Clothing",
-		"women's clothing": "Clothing",
-		"home":             "Home & Garden",
-		"sports":           "Sports",
-		"books":            "Books",
-		"toys":             "Toys",
-		"automotive":       "Automotive",
-		"health":           "Health",
-		"food":             "Food",
-	}
-
-	if mapped, exists := categoryMap[strings.ToLower(fakeCategory)]; exists {
-		return mapped
-	}
-	return "Electronics" // Default fallback
-}
-
 // SeedProducts fetches products from FakeStore API and stores them
 func (s *SeedService) SeedProducts(limit int) error {
-	// First, ensure categories exist
-	if err := s.SeedCategories(); err != nil {
-		return fmt.Errorf("failed to seed categories: %w", err)
-	}
+	_, err := s.Seed(context.Background(), seed.NewFakeStoreSource("", limit))
+	return err
+}
 
-	// Fetch products from FakeStore API
-	url := fmt.Sprintf("https://fakestoreapi.com/products?limit=%d", limit)
-	resp, err := http.Get(url)
+// Seed loads a dataset from the source and writes it to the database.
+// Existing users (by email), categories (by name) and products (by SKU, or name without one)
+// are left untouched, and orders are only created for users that have none, so re-running a
+// seed is safe.
+func (s *SeedService) Seed(ctx context.Context, source seed.Source) (*SeedSummary, error) {
+	dataset, err := source.Load(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to fetch products from API: %w", err)
+		return nil, fmt.Errorf("failed to load seed data from %s: %w", source.Name(), err)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+	fmt.Printf("Loaded %d users, %d categories, %d products and %d orders from %s\n",
+		len(dataset.Users), len(dataset.Categories), len(dataset.Products), len(dataset.Orders), source.Name())
+
+	summary := &SeedSummary{}
+
+	if summary.Categories, err = s.seedCategories(dataset.Categories); err != nil {
+		return nil, fmt.Errorf("failed to seed categories: %w", err)
 	}
 
-	var fakeProducts []FakeStoreProduct
-	if err := json.Unmarshal(body, &fakeProducts); err != nil {
-		return fmt.Errorf("failed to unmarshal products: %w", err)
+	if err := s.seedUsers(ctx, dataset.Users, summary); err != nil {
+		return nil, fmt.Errorf("failed to seed users: %w", err)
 	}
 
-	fmt.Printf("Fetched %d products from FakeStore API\n", len(fakeProducts))
+	if err := s.seedProducts(ctx, dataset.Products, summary); err != nil {
+		return nil, fmt.Errorf("failed to seed products: %w", err)
+	}
 
-	// Insert products into database
-	insertedCount := 0
-	for _, fakeProduct := range fakeProducts {
-		// Map category
-		categoryName := s.MapFakeStoreCategory(fakeProduct.Category)
-		categoryID, err := s.GetCategoryIDByName(categoryName)
-		if err != nil {
-			fmt.Printf("Warning: Could not find category %s for product %s, skipping\n", categoryName, fakeProduct.Title)
+	if err := s.seedOrders(ctx, dataset.Orders, summary); err != nil {
+		return nil, fmt.Errorf("failed to seed orders: %w", err)
+	}
+
+	fmt.Printf("\nSuccessfully inserted %d users, %d categories, %d products and %d orders (%d skipped)\n",
+		summary.Users, summary.Categories, summary.Products, summary.Orders, summary.Skipped)
+	return summary, nil
+}
+
+// seedUsers creates the users that do not exist yet
+func (s *SeedService) seedUsers(ctx context.Context, users []seed.User, summary *SeedSummary) error {
+	// Hash each distinct password once; generated datasets share a single password
+	hashes := map[string]string{}
+
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if user.Email == "" || user.Password == "" {
+			fmt.Printf("Warning: Skipping user without email or password\n")
+			summary.Skipped++
 			continue
 		}
 
-		// Check if product already exists (by title)
 		var exists bool
-		err = s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE name = $1)", fakeProduct.Title).Scan(&exists)
+		err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", user.Email).Scan(&exists)
 		if err != nil {
-			fmt.Printf("Warning: Could not check product existence for %s: %v\n", fakeProduct.Title, err)
+			return fmt.Errorf("failed to check user existence: %w", err)
+		}
+		if exists {
+			fmt.Printf("User already exists: %s\n", user.Email)
 			continue
 		}
 
-		if exists {
-			fmt.Printf("Product already exists: %s\n", fakeProduct.Title)
+		hash, ok := hashes[user.Password]
+		if !ok {
+			hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return fmt.Errorf("failed to hash password: %w", err)
+			}
+			hash = string(hashed)
+			hashes[user.Password] = hash
+		}
+
+		role := user.Role
+		if role == "" {
+			role = "customer"
+		}
+
+		_, err = s.db.Exec(
+			"INSERT INTO users (email, password, first_name, last_name, role, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, NOW(), NOW())",
+			user.Email, hash, user.FirstName, user.LastName, role,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert user %s: %w", user.Email, err)
+		}
+
+		summary.Users++
+		fmt.Printf("Created user: %s (%s)\n", user.Email, role)
+	}
+
+	return nil
+}
+
+// seedProducts creates the products that do not exist yet
+func (s *SeedService) seedProducts(ctx context.Context, products []seed.Product, summary *SeedSummary) error {
+	categoryIDs := map[string]uint{}
+
+	for _, product := range products {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if product.Name == "" || product.Price <= 0 || product.Stock < 0 {
+			fmt.Printf("Warning: Skipping invalid product %q\n", product.Name)
+			summary.Skipped++
 			continue
 		}
 
-		// Generate realistic stock based on rating count
-		stock := fakeProduct.Rating.Count
-		if stock == 0 {
-			stock = 50 // Default stock
+		// Resolve category
+		var categoryID *uint
+		if product.Category != "" {
+			id, ok := categoryIDs[product.Category]
+			if !ok {
+				var err error
+				id, err = s.GetCategoryIDByName(product.Category)
+				if err != nil {
+					fmt.Printf("Warning: Could not find category %s for product %s, skipping\n", product.Category, product.Name)
+					summary.Skipped++
+					continue
+				}
+				categoryIDs[product.Category] = id
+			}
+			categoryID = &id
 		}
-		if stock > 200 {
-			stock = 200 // Cap at reasonable amount
+
+		// Check if product already exists (by SKU, falling back to name)
+		var exists bool
+		var err error
+		if product.SKU != "" {
+			err = s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE sku = $1)", product.SKU).Scan(&exists)
+		} else {
+			err = s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE name = $1)", product.Name).Scan(&exists)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check product existence for %s: %w", product.Name, err)
+		}
+
+		if exists {
+			fmt.Printf("Product already exists: %s\n", product.Name)
+			continue
 		}
 
 		// Insert product
 		_, err = s.db.Exec(`
-			INSERT INTO products (name, description, price, stock, category_id, image_url, is_active, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
-		`, fakeProduct.Title, fakeProduct.Description, fakeProduct.Price, stock, categoryID, fakeProduct.Image, true)
+			INSERT INTO products (name, description, price, stock, category_id, image_url, sku, is_active, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NOW(), NOW())
+		`, product.Name, product.Description, product.Price, product.Stock, categoryID, product.ImageURL, product.SKU, true)
 
 		if err != nil {
-			fmt.Printf("Warning: Failed to insert product %s: %v\n", fakeProduct.Title, err)
+			fmt.Printf("Warning: Failed to insert product %s: %v\n", product.Name, err)
+			summary.Skipped++
 			continue
 		}
 
-		insertedCount++
-		fmt.Printf("Inserted product: %s (Price: $%.2f, Stock: %d)\n", fakeProduct.Title, fakeProduct.Price, stock)
+		summary.Products++
+		fmt.Printf("Inserted product: %s (Price: $%.2f, Stock: %d)\n", product.Name, product.Price, product.Stock)
 	}
 
-	fmt.Printf("\nSuccessfully inserted %d new products\n", insertedCount)
 	return nil
 }
 
+// seedOrders creates orders for users that do not have any yet
+func (s *SeedService) seedOrders(ctx context.Context, orders []seed.Order, summary *SeedSummary) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	// Users that already had orders before this run are skipped entirely
+	userIDs := map[string]uint{}
+	skipUsers := map[string]bool{}
+
+	for i, order := range orders {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		userID, ok := userIDs[order.UserEmail]
+		if !ok && !skipUsers[order.UserEmail] {
+			var hasOrders bool
+			err := s.db.QueryRow(`
+				SELECT u.id, EXISTS(SELECT 1 FROM orders o WHERE o.user_id = u.id)
+				FROM users u
+				WHERE u.email = $1
+			`, order.UserEmail).Scan(&userID, &hasOrders)
+			if err != nil && err != sql.ErrNoRows {
+				return fmt.Errorf("failed to look up user %s: %w", order.UserEmail, err)
+			}
+			if err == sql.ErrNoRows {
+				fmt.Printf("Warning: Could not find user %s for order %d, skipping\n", order.UserEmail, i+1)
+			} else if hasOrders {
+				fmt.Printf("User already has orders: %s\n", order.UserEmail)
+			}
+			if err == sql.ErrNoRows || hasOrders {
+				skipUsers[order.UserEmail] = true
+			} else {
+				userIDs[order.UserEmail] = userID
+			}
+		}
+		if skipUsers[order.UserEmail] {
+			summary.Skipped++
+			continue
+		}
+
+		if err := s.insertSeedOrder(userIDs[order.UserEmail], order); err != nil {
+			fmt.Printf("Warning: Failed to insert order %d for %s: %v\n", i+1, order.UserEmail, err)
+			summary.Skipped++
+			continue
+		}
+
+		summary.Orders++
+	}
+
+	fmt.Printf("Inserted %d orders\n", summary.Orders)
+	return nil
+}
+
+// seedOrderStatusPaths lists the statuses a seeded order went through to reach each status it can
+// be seeded with. Partially shipped orders cannot be seeded, as a seeded order ships in one shipment.
+var seedOrderStatusPaths = map[string][]string{
+	models.OrderStatusPending:   {models.OrderStatusPending},
+	models.OrderStatusConfirmed: {models.OrderStatusPending, models.OrderStatusConfirmed},
+	models.OrderStatusShipped:   {models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusShipped},
+	models.OrderStatusDelivered: {models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusShipped, models.OrderStatusDelivered},
+	models.OrderStatusCancelled: {models.OrderStatusPending, models.OrderStatusCancelled},
+}
+
+// insertSeedOrder writes an order and its items at current product prices without touching stock.
+// The order gets the status history leading to its status, and shipped and delivered orders a
+// shipment of all their items, as if it had been placed and fulfilled through the API.
+func (s *SeedService) insertSeedOrder(userID uint, order seed.Order) error {
+	if len(order.Items) == 0 {
+		return fmt.Errorf("order has no items")
+	}
+
+	status := order.Status
+	if status == "" {
+		status = models.OrderStatusPending
+	}
+	statusPath, ok := seedOrderStatusPaths[status]
+	if !ok {
+		return fmt.Errorf("invalid order status: %s", status)
+	}
+	createdAt := time.Now()
+	if order.CreatedAt != nil {
+		createdAt = *order.CreatedAt
+	}
+	shippingAddress := order.ShippingAddress
+	if strings.TrimSpace(shippingAddress) == "" {
+		shippingAddress = "Not provided"
+	}
+	paymentMethod := order.PaymentMethod
+	if paymentMethod == "" {
+		paymentMethod = "credit_card"
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	type resolvedItem struct {
+		productID uint
+		quantity  int
+		price     float64
+	}
+
+	var items []resolvedItem
+	var totalAmount float64
+	for _, item := range order.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("invalid quantity for product %s", item.Product)
+		}
+
+		var productID uint
+		var price float64
+		err := tx.QueryRow(`
+			SELECT id, price FROM products
+			WHERE sku = $1 OR (name = $1 AND sku IS NULL)
+			ORDER BY (sku = $1) DESC NULLS LAST, id
+			LIMIT 1
+		`, item.Product).Scan(&productID, &price)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("product not found: %s", item.Product)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up product %s: %w", item.Product, err)
+		}
+
+		items = append(items, resolvedItem{productID: productID, quantity: item.Quantity, price: price})
+		totalAmount += price * float64(item.Quantity)
+	}
+	totalAmount = roundCents(totalAmount)
+
+	// Seeded orders have no discounts, shipping or tax, so the subtotal is the total
+	var orderID uint
+	err = tx.QueryRow(`
+		INSERT INTO orders (user_id, status, total_amount, subtotal_amount, discount_amount, shipping_amount, tax_amount,
+		                    shipping_address, payment_method, created_at, updated_at)
+		VALUES ($1, $2, $3, $3, 0, 0, 0, $4, $5, $6, $6)
+		RETURNING id
+	`, userID, status, totalAmount, shippingAddress, paymentMethod, createdAt).Scan(&orderID)
+	if err != nil {
+		return fmt.Errorf("failed to insert order: %w", err)
+	}
+
+	orderItemIDs := make([]uint, len(items))
+	for i, item := range items {
+		err := tx.QueryRow(`
+			INSERT INTO order_items (order_id, product_id, quantity, price, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $5)
+			RETURNING id
+		`, orderID, item.productID, item.quantity, item.price, createdAt).Scan(&orderItemIDs[i])
+		if err != nil {
+			return fmt.Errorf("failed to insert order item: %w", err)
+		}
+	}
+
+	from := ""
+	for _, to := range statusPath {
+		_, err := tx.Exec(`
+			INSERT INTO order_status_history (order_id, from_status, to_status, note, created_at)
+			VALUES ($1, NULLIF($2, ''), $3, 'seeded', $4)
+		`, orderID, from, to, createdAt)
+		if err != nil {
+			return fmt.Errorf("failed to record order status history: %w", err)
+		}
+		from = to
+	}
+
+	if status == models.OrderStatusShipped || status == models.OrderStatusDelivered {
+		var shipmentID uint
+		err := tx.QueryRow(`
+			INSERT INTO shipments (order_id, carrier, status, shipped_at, delivered_at, created_at, updated_at)
+			VALUES ($1, 'Standard', $2, $3, CASE WHEN $2 = 'delivered' THEN $3 END, $3, $3)
+			RETURNING id
+		`, orderID, status, createdAt).Scan(&shipmentID)
+		if err != nil {
+			return fmt.Errorf("failed to insert shipment: %w", err)
+		}
+
+		for i, item := range items {
+			_, err := tx.Exec(`
+				INSERT INTO shipment_items (shipment_id, order_item_id, product_id, quantity)
+				VALUES ($1, $2, $3, $4)
+			`, shipmentID, orderItemIDs[i], item.productID, item.quantity)
+			if err != nil {
+				return fmt.Errorf("failed to insert shipment item: %w", err)
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
 // SeedSampleData seeds the database with sample data
 func (s *SeedService) SeedSampleData() error {
 	fmt.Println("Starting database seeding...")
@@ -206,23 +458,22 @@ func (s *SeedService) SeedSampleData() error {
 
 // GetDatabaseStats returns statistics about the database
 func (s *SeedService) GetDatabaseStats() error {
-	var categoryCount, productCount int
-
-	// Count categories
-	err := s.db.QueryRow("SELECT COUNT(*) FROM categories").Scan(&categoryCount)
-	if err != nil {
-		return fmt.Errorf("failed to count categories: %w", err)
-	}
+	var userCount, categoryCount, productCount, orderCount int
 
-	// Count products
-	err = s.db.QueryRow("SELECT COUNT(*) FROM products").Scan(&productCount)
+	// Count users, categories, products and orders
+	err := s.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM users), (SELECT COUNT(*) FROM categories),
+		       (SELECT COUNT(*) FROM products), (SELECT COUNT(*) FROM orders)
+	`).Scan(&userCount, &categoryCount, &productCount, &orderCount)
 	if err != nil {
-		return fmt.Errorf("failed to count products: %w", err)
+		return fmt.Errorf("failed to count records: %w", err)
 	}
 
 	fmt.Printf("\nDatabase Statistics:\n")
+	fmt.Printf("Users: %d\n", userCount)
 	fmt.Printf("Categories: %d\n", categoryCount)
 	fmt.Printf("Products: %d\n", productCount)
+	fmt.Printf("Orders: %d\n", orderCount)
 
 	// Show categories with product counts
 	rows, err := s.db.Query(`
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/lib/pq"
+)
+
+// ShipmentService handles shipping order items and tracking their delivery
+type ShipmentService struct {
+	db *sql.DB
+}
+
+// NewShipmentService creates a new shipment service
+func NewShipmentService() *ShipmentService {
+	return &ShipmentService{
+		db: database.GetDB(),
+	}
+}
+
+// CreateShipmentRequest represents the request to ship order items.
+// Every unit not shipped yet is included when Items is omitted.
+type CreateShipmentRequest struct {
+	Carrier        string                `json:"carrier" binding:"required"`
+	TrackingNumber string                `json:"tracking_number"`
+	TrackingURL    string                `json:"tracking_url" binding:"omitempty,url"`
+	Items          []ShipmentItemRequest `json:"items" binding:"dive"`
+}
+
+// ShipmentItemRequest represents a quantity of an order line to ship
+type ShipmentItemRequest struct {
+	OrderItemID uint `json:"order_item_id" binding:"required"`
+	Quantity    int  `json:"quantity" binding:"required,gt=0"`
+}
+
+// UpdateShipmentRequest represents the request to correct a shipment's tracking details
+type UpdateShipmentRequest struct {
+	Carrier        *string `json:"carrier" binding:"omitempty,min=1"`
+	TrackingNumber *string `json:"tracking_number"`
+	TrackingURL    *string `json:"tracking_url" binding:"omitempty,url"`
+}
+
+// CreateShipment ships items of a confirmed order, and the order becomes partially shipped or
+// shipped depending on what remains. Once the shipment is recorded, the order's authorized
+// payments are captured; a capture that fails is retried by the next shipment or through the
+// payment capture endpoint.
+func (s *ShipmentService) CreateShipment(orderID uint, req *CreateShipmentRequest, actorID uint) (*models.Shipment, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the order so concurrent shipments cannot send the same units twice
+	var status string
+	err = tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("order not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	if status != models.OrderStatusConfirmed && status != models.OrderStatusPartiallyShipped {
+		return nil, fmt.Errorf("order cannot be shipped while %s", status)
+	}
+
+	lines, err := loadUnshippedLines(tx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	shipment := &models.Shipment{
+		OrderID:        orderID,
+		Carrier:        strings.TrimSpace(req.Carrier),
+		TrackingNumber: strings.TrimSpace(req.TrackingNumber),
+		TrackingURL:    req.TrackingURL,
+		Status:         models.ShipmentStatusShipped,
+	}
+
+	if len(req.Items) == 0 {
+		for _, line := range lines {
+			if line.remaining > 0 {
+				shipment.Items = append(shipment.Items, models.ShipmentItem{
+					OrderItemID: line.id, ProductID: line.productID, Quantity: line.remaining,
+				})
+			}
+		}
+		if len(shipment.Items) == 0 {
+			return nil, errors.New("invalid shipment: nothing left to ship")
+		}
+	} else {
+		requested := make(map[uint]int)
+		for _, item := range req.Items {
+			requested[item.OrderItemID] += item.Quantity
+		}
+		for _, item := range req.Items {
+			quantity, ok := requested[item.OrderItemID]
+			if !ok {
+				continue // already added
+			}
+			delete(requested, item.OrderItemID)
+
+			line, found := findUnshippedLine(lines, item.OrderItemID)
+			if !found {
+				return nil, fmt.Errorf("invalid shipment: order item %d is not part of this order", item.OrderItemID)
+			}
+			if quantity > line.remaining {
+				return nil, fmt.Errorf("invalid shipment: only %d units of order item %d remain to be shipped", line.remaining, item.OrderItemID)
+			}
+			shipment.Items = append(shipment.Items, models.ShipmentItem{
+				OrderItemID: line.id, ProductID: line.productID, Quantity: quantity,
+			})
+		}
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO shipments (order_id, carrier, tracking_number, tracking_url, status, shipped_at, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NOW(), NOW(), NOW())
+		RETURNING id
+	`, orderID, shipment.Carrier, shipment.TrackingNumber, shipment.TrackingURL, shipment.Status).Scan(&shipment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shipment: %w", err)
+	}
+
+	for _, item := range shipment.Items {
+		_, err := tx.Exec(`
+			INSERT INTO shipment_items (shipment_id, order_item_id, product_id, quantity)
+			VALUES ($1, $2, $3, $4)
+		`, shipment.ID, item.OrderItemID, item.ProductID, item.Quantity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create shipment item: %w", err)
+		}
+	}
+
+	note := fmt.Sprintf("shipment %d sent with %s", shipment.ID, shipment.Carrier)
+	newStatus, err := syncOrderFulfillment(tx, orderID, status, &actorID, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Collect the payment now the package has left the warehouse
+	if err := captureOrderPayments(s.db, orderID); err != nil {
+		log.Printf("Warning: failed to capture payments of order %d after shipment %d: %v", orderID, shipment.ID, err)
+	}
+
+	if newStatus != status {
+		notifyOrderStatus(s.db, orderID, newStatus)
+	}
+
+	return s.GetShipment(shipment.ID)
+}
+
+// UpdateShipment corrects a shipment's carrier and tracking details
+func (s *ShipmentService) UpdateShipment(id uint, req *UpdateShipmentRequest) (*models.Shipment, error) {
+	result, err := s.db.Exec(`
+		UPDATE shipments
+		SET carrier = COALESCE($1, carrier),
+		    tracking_number = CASE WHEN $2::text IS NULL THEN tracking_number ELSE NULLIF($2, '') END,
+		    tracking_url = CASE WHEN $3::text IS NULL THEN tracking_url ELSE NULLIF($3, '') END,
+		    updated_at = NOW()
+		WHERE id = $4
+	`, req.Carrier, req.TrackingNumber, req.TrackingURL, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shipment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, errors.New("shipment not found")
+	}
+
+	return s.GetShipment(id)
+}
+
+// MarkShipmentDelivered records the delivery of a shipment. The order is delivered once all of
+// its items have shipped and every shipment has arrived.
+func (s *ShipmentService) MarkShipmentDelivered(id uint, actorID uint) (*models.Shipment, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var orderID uint
+	if err := tx.QueryRow("SELECT order_id FROM shipments WHERE id = $1", id).Scan(&orderID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("shipment not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// Lock the order first, as shipment creation does, then the shipment
+	var orderStatus, status string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&orderStatus); err != nil {
+		return nil, fmt.Errorf("failed to load order: %w", err)
+	}
+	if err := tx.QueryRow("SELECT status FROM shipments WHERE id = $1 FOR UPDATE", id).Scan(&status); err != nil {
+		return nil, fmt.Errorf("failed to load shipment: %w", err)
+	}
+	if status == models.ShipmentStatusDelivered {
+		return nil, errors.New("shipment is already delivered")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE shipments SET status = $1, delivered_at = NOW(), updated_at = NOW() WHERE id = $2
+	`, models.ShipmentStatusDelivered, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shipment: %w", err)
+	}
+
+	newStatus, err := syncOrderFulfillment(tx, orderID, orderStatus, &actorID, fmt.Sprintf("shipment %d delivered", id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if newStatus != orderStatus {
+		notifyOrderStatus(s.db, orderID, newStatus)
+	}
+
+	return s.GetShipment(id)
+}
+
+// GetShipment retrieves a shipment with its items
+func (s *ShipmentService) GetShipment(id uint) (*models.Shipment, error) {
+	shipments, err := loadShipments(s.db, "WHERE id = $1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(shipments) == 0 {
+		return nil, errors.New("shipment not found")
+	}
+	return &shipments[0], nil
+}
+
+// ListOrderShipments retrieves the shipments of an order, oldest first
+func (s *ShipmentService) ListOrderShipments(orderID uint) ([]models.Shipment, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = $1)", orderID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("order not found")
+	}
+
+	shipments, err := loadOrderShipments(s.db, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if shipments == nil {
+		shipments = []models.Shipment{}
+	}
+	return shipments, nil
+}
+
+// unshippedLine is an order line with the number of units still to be shipped
+type unshippedLine struct {
+	id        uint
+	productID uint
+	remaining int
+}
+
+// loadUnshippedLines retrieves an order's lines with the units neither shipped nor refunded yet
+func loadUnshippedLines(q querier, orderID uint) ([]unshippedLine, error) {
+	rows, err := q.Query(`
+		SELECT oi.id, oi.product_id,
+		       oi.quantity - oi.refunded_quantity - COALESCE((
+		           SELECT SUM(si.quantity) FROM shipment_items si WHERE si.order_item_id = oi.id
+		       ), 0)
+		FROM order_items oi
+		WHERE oi.order_id = $1
+		ORDER BY oi.id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order items: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []unshippedLine
+	for rows.Next() {
+		var line unshippedLine
+		if err := rows.Scan(&line.id, &line.productID, &line.remaining); err != nil {
+			return nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		lines = append(lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order items: %w", err)
+	}
+
+	return lines, nil
+}
+
+// findUnshippedLine finds an order line by ID
+func findUnshippedLine(lines []unshippedLine, id uint) (unshippedLine, bool) {
+	for _, line := range lines {
+		if line.id == id {
+			return line, true
+		}
+	}
+	return unshippedLine{}, false
+}
+
+// syncOrderFulfillment derives the order's status from its shipments: partially shipped while
+// units remain to be shipped, shipped once everything is on its way and delivered once every
+// shipment has arrived. Refunded units need no shipping, and an order whose units were all
+// refunded before any shipped is cancelled. It returns the resulting status.
+func syncOrderFulfillment(q querier, orderID uint, current string, actorID *uint, note string) (string, error) {
+	lines, err := loadUnshippedLines(q, orderID)
+	if err != nil {
+		return current, err
+	}
+	fullyShipped := true
+	for _, line := range lines {
+		if line.remaining > 0 {
+			fullyShipped = false
+		}
+	}
+
+	var shipments, undelivered int
+	err = q.QueryRow(`
+		SELECT COUNT(*), COUNT(*) FILTER (WHERE status <> 'delivered') FROM shipments WHERE order_id = $1
+	`, orderID).Scan(&shipments, &undelivered)
+	if err != nil {
+		return current, fmt.Errorf("failed to count shipments: %w", err)
+	}
+	status := models.OrderStatusPartiallyShipped
+	switch {
+	case shipments == 0 && (!fullyShipped || len(lines) == 0):
+		return current, nil
+	case shipments == 0:
+		status = models.OrderStatusCancelled
+	case fullyShipped && undelivered == 0:
+		status = models.OrderStatusDelivered
+	case fullyShipped:
+		status = models.OrderStatusShipped
+	}
+
+	if status == current || !canTransitionOrder(current, status) {
+		return current, nil
+	}
+	if err := setOrderStatus(q, orderID, current, status, actorID, note); err != nil {
+		return current, err
+	}
+	return status, nil
+}
+
+// loadOrderShipments retrieves the shipments of an order, oldest first
+func loadOrderShipments(q querier, orderID uint) ([]models.Shipment, error) {
+	return loadShipments(q, "WHERE order_id = $1 ORDER BY id", orderID)
+}
+
+// loadShipments retrieves the shipments matching a WHERE clause and its arguments, with their items
+func loadShipments(q querier, where string, args ...interface{}) ([]models.Shipment, error) {
+	rows, err := q.Query(`
+		SELECT id, order_id, carrier, COALESCE(tracking_number, ''), COALESCE(tracking_url, ''), status,
+		       shipped_at, delivered_at, created_at, updated_at
+		FROM shipments `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipments: %w", err)
+	}
+
+	var shipments []models.Shipment
+	index := make(map[uint]int)
+	for rows.Next() {
+		var shipment models.Shipment
+		err := rows.Scan(
+			&shipment.ID, &shipment.OrderID, &shipment.Carrier, &shipment.TrackingNumber, &shipment.TrackingURL,
+			&shipment.Status, &shipment.ShippedAt, &shipment.DeliveredAt, &shipment.CreatedAt, &shipment.UpdatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan shipment: %w", err)
+		}
+		index[shipment.ID] = len(shipments)
+		shipments = append(shipments, shipment)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipments: %w", err)
+	}
+	if len(shipments) == 0 {
+		return shipments, nil
+	}
+
+	ids := make([]int64, 0, len(shipments))
+	for _, shipment := range shipments {
+		ids = append(ids, int64(shipment.ID))
+	}
+	itemRows, err := q.Query(`
+		SELECT id, shipment_id, order_item_id, product_id, quantity
+		FROM shipment_items
+		WHERE shipment_id = ANY($1)
+		ORDER BY id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipment items: %w", err)
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item models.ShipmentItem
+		if err := itemRows.Scan(&item.ID, &item.ShipmentID, &item.OrderItemID, &item.ProductID, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan shipment item: %w", err)
+		}
+		i := index[item.ShipmentID]
+		shipments[i].Items = append(shipments[i].Items, item)
+	}
+
+	if err = itemRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipment items: %w", err)
+	}
+
+	return shipments, nil
+}
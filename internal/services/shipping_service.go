@@ -0,0 +1,462 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/shipping"
+)
+
+var (
+	shippingProviderOnce sync.Once
+	shippingProvider     shipping.ShippingRateProvider
+)
+
+// getShippingProvider returns the rate provider configured with SHIPPING_PROVIDER, falling back to the rate tables
+func getShippingProvider() shipping.ShippingRateProvider {
+	shippingProviderOnce.Do(func() {
+		provider, err := shipping.NewFromEnv(database.GetDB())
+		if err != nil {
+			log.Printf("Warning: failed to configure shipping provider: %v", err)
+			log.Println("Falling back to shipping rate tables")
+			provider = shipping.NewTableProvider(database.GetDB())
+		}
+		shippingProvider = provider
+	})
+	return shippingProvider
+}
+
+// ShippingQuote is a shipping option with any free shipping discount applied
+type ShippingQuote struct {
+	shipping.Quote
+	Discount float64 `json:"discount"`
+	Total    float64 `json:"total"`
+}
+
+// ShippingService handles shipping zones and methods
+type ShippingService struct {
+	db *sql.DB
+}
+
+// NewShippingService creates a new shipping service
+func NewShippingService() *ShippingService {
+	return &ShippingService{
+		db: database.GetDB(),
+	}
+}
+
+// ShippingZoneRequest represents the request to create or replace a shipping zone
+type ShippingZoneRequest struct {
+	Name      string                    `json:"name" binding:"required"`
+	Locations []ShippingLocationRequest `json:"locations" binding:"required,min=1,dive"`
+}
+
+// ShippingLocationRequest represents a destination covered by a shipping zone
+type ShippingLocationRequest struct {
+	Country          string `json:"country" binding:"required,len=2"`
+	Region           string `json:"region"`
+	PostalCodePrefix string `json:"postal_code_prefix"`
+}
+
+// ShippingMethodRequest represents the request to create or replace a shipping method
+type ShippingMethodRequest struct {
+	Code             string   `json:"code" binding:"required,max=50"`
+	Name             string   `json:"name" binding:"required"`
+	Carrier          string   `json:"carrier"`
+	Type             string   `json:"type" binding:"required,oneof=flat_rate weight_based free_over_threshold"`
+	BaseRate         float64  `json:"base_rate" binding:"gte=0"`
+	PerKgRate        float64  `json:"per_kg_rate" binding:"gte=0"`
+	FreeThreshold    *float64 `json:"free_threshold" binding:"omitempty,gte=0"`
+	MinWeight        *float64 `json:"min_weight" binding:"omitempty,gte=0"`
+	MaxWeight        *float64 `json:"max_weight" binding:"omitempty,gt=0"`
+	EstimatedDaysMin *int     `json:"estimated_days_min" binding:"omitempty,gte=0"`
+	EstimatedDaysMax *int     `json:"estimated_days_max" binding:"omitempty,gte=0"`
+	Position         int      `json:"position"`
+	IsActive         *bool    `json:"is_active"`
+}
+
+const shippingMethodColumns = `id, zone_id, code, name, COALESCE(carrier, ''), type, base_rate, per_kg_rate, free_threshold,
+	min_weight, max_weight, estimated_days_min, estimated_days_max, position, is_active, created_at, updated_at`
+
+// scanShippingMethod scans a shipping method row selected with shippingMethodColumns
+func scanShippingMethod(scanner interface{ Scan(...interface{}) error }, method *models.ShippingMethod) error {
+	var daysMin, daysMax sql.NullInt64
+	err := scanner.Scan(
+		&method.ID, &method.ZoneID, &method.Code, &method.Name, &method.Carrier, &method.Type,
+		&method.BaseRate, &method.PerKgRate, &method.FreeThreshold, &method.MinWeight, &method.MaxWeight,
+		&daysMin, &daysMax, &method.Position, &method.IsActive, &method.CreatedAt, &method.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	if daysMin.Valid {
+		days := int(daysMin.Int64)
+		method.EstimatedDaysMin = &days
+	}
+	if daysMax.Valid {
+		days := int(daysMax.Int64)
+		method.EstimatedDaysMax = &days
+	}
+	return nil
+}
+
+// ListShippingZones retrieves all shipping zones with their locations and methods
+func (s *ShippingService) ListShippingZones() ([]models.ShippingZone, error) {
+	rows, err := s.db.Query("SELECT id FROM shipping_zones ORDER BY name, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipping zones: %w", err)
+	}
+
+	var ids []uint
+	for rows.Next() {
+		var id uint
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan shipping zone: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipping zones: %w", err)
+	}
+
+	zones := []models.ShippingZone{}
+	for _, id := range ids {
+		zone, err := s.GetShippingZone(id)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, *zone)
+	}
+
+	return zones, nil
+}
+
+// GetShippingZone retrieves a shipping zone with its locations and methods
+func (s *ShippingService) GetShippingZone(id uint) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	err := s.db.QueryRow(
+		"SELECT id, name, created_at, updated_at FROM shipping_zones WHERE id = $1", id,
+	).Scan(&zone.ID, &zone.Name, &zone.CreatedAt, &zone.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("shipping zone not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	// Get locations
+	rows, err := s.db.Query(`
+		SELECT id, zone_id, country, COALESCE(region, ''), COALESCE(postal_code_prefix, '')
+		FROM shipping_zone_locations
+		WHERE zone_id = $1
+		ORDER BY country, region NULLS FIRST, postal_code_prefix NULLS FIRST
+	`, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipping zone locations: %w", err)
+	}
+	defer rows.Close()
+
+	zone.Locations = []models.ShippingZoneLocation{}
+	for rows.Next() {
+		var location models.ShippingZoneLocation
+		if err := rows.Scan(&location.ID, &location.ZoneID, &location.Country, &location.Region, &location.PostalCodePrefix); err != nil {
+			return nil, fmt.Errorf("failed to scan shipping zone location: %w", err)
+		}
+		zone.Locations = append(zone.Locations, location)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipping zone locations: %w", err)
+	}
+
+	// Get methods
+	methodRows, err := s.db.Query(
+		fmt.Sprintf("SELECT %s FROM shipping_methods WHERE zone_id = $1 ORDER BY position, id", shippingMethodColumns), id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipping methods: %w", err)
+	}
+	defer methodRows.Close()
+
+	zone.Methods = []models.ShippingMethod{}
+	for methodRows.Next() {
+		var method models.ShippingMethod
+		if err := scanShippingMethod(methodRows, &method); err != nil {
+			return nil, fmt.Errorf("failed to scan shipping method: %w", err)
+		}
+		zone.Methods = append(zone.Methods, method)
+	}
+	if err = methodRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipping methods: %w", err)
+	}
+
+	return &zone, nil
+}
+
+// CreateShippingZone creates a shipping zone with its locations
+func (s *ShippingService) CreateShippingZone(req *ShippingZoneRequest) (*models.ShippingZone, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uint
+	err = tx.QueryRow(
+		"INSERT INTO shipping_zones (name, created_at, updated_at) VALUES ($1, NOW(), NOW()) RETURNING id",
+		req.Name,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shipping zone: %w", err)
+	}
+
+	if err := replaceShippingZoneLocations(tx, id, req.Locations); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetShippingZone(id)
+}
+
+// UpdateShippingZone renames a shipping zone and replaces its locations
+func (s *ShippingService) UpdateShippingZone(id uint, req *ShippingZoneRequest) (*models.ShippingZone, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE shipping_zones SET name = $1, updated_at = NOW() WHERE id = $2", req.Name, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shipping zone: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return nil, errors.New("shipping zone not found")
+	}
+
+	if err := replaceShippingZoneLocations(tx, id, req.Locations); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return s.GetShippingZone(id)
+}
+
+// replaceShippingZoneLocations replaces the locations of a zone
+func replaceShippingZoneLocations(tx *sql.Tx, zoneID uint, locations []ShippingLocationRequest) error {
+	if _, err := tx.Exec("DELETE FROM shipping_zone_locations WHERE zone_id = $1", zoneID); err != nil {
+		return fmt.Errorf("failed to clear shipping zone locations: %w", err)
+	}
+
+	seen := make(map[shipping.Address]bool)
+	for _, location := range locations {
+		address := shipping.Address{Country: location.Country, Region: location.Region, PostalCode: location.PostalCodePrefix}
+		address.Normalize()
+		if seen[address] {
+			return errors.New("invalid shipping zone: duplicate location")
+		}
+		seen[address] = true
+
+		_, err := tx.Exec(`
+			INSERT INTO shipping_zone_locations (zone_id, country, region, postal_code_prefix)
+			VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		`, zoneID, address.Country, address.Region, address.PostalCode)
+		if err != nil {
+			return fmt.Errorf("failed to create shipping zone location: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteShippingZone deletes a shipping zone with its locations and methods
+func (s *ShippingService) DeleteShippingZone(id uint) error {
+	result, err := s.db.Exec("DELETE FROM shipping_zones WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete shipping zone: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("shipping zone not found")
+	}
+
+	return nil
+}
+
+// validateShippingMethodRequest checks the values of a shipping method request
+func (s *ShippingService) validateShippingMethodRequest(req *ShippingMethodRequest, excludeID uint) error {
+	req.Code = strings.ToLower(strings.TrimSpace(req.Code))
+	if req.Code == "" {
+		return errors.New("invalid shipping method: code is required")
+	}
+	if req.Type == shipping.MethodFreeOverThreshold && req.FreeThreshold == nil {
+		return errors.New("invalid shipping method: free_over_threshold requires free_threshold")
+	}
+	if req.MinWeight != nil && req.MaxWeight != nil && *req.MaxWeight < *req.MinWeight {
+		return errors.New("invalid shipping method: max_weight must not be below min_weight")
+	}
+	if req.EstimatedDaysMin != nil && req.EstimatedDaysMax != nil && *req.EstimatedDaysMax < *req.EstimatedDaysMin {
+		return errors.New("invalid shipping method: estimated_days_max must not be below estimated_days_min")
+	}
+
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM shipping_methods WHERE code = $1 AND id != $2)", req.Code, excludeID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if exists {
+		return errors.New("shipping method code already exists")
+	}
+
+	return nil
+}
+
+// CreateShippingMethod adds a shipping method to a zone
+func (s *ShippingService) CreateShippingMethod(zoneID uint, req *ShippingMethodRequest) (*models.ShippingMethod, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM shipping_zones WHERE id = $1)", zoneID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return nil, errors.New("shipping zone not found")
+	}
+	if err := s.validateShippingMethodRequest(req, 0); err != nil {
+		return nil, err
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var method models.ShippingMethod
+	query := fmt.Sprintf(`
+		INSERT INTO shipping_methods (zone_id, code, name, carrier, type, base_rate, per_kg_rate, free_threshold,
+		                              min_weight, max_weight, estimated_days_min, estimated_days_max, position, is_active,
+		                              created_at, updated_at)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
+		RETURNING %s
+	`, shippingMethodColumns)
+	err := scanShippingMethod(s.db.QueryRow(
+		query,
+		zoneID, req.Code, req.Name, req.Carrier, req.Type, req.BaseRate, req.PerKgRate, req.FreeThreshold,
+		req.MinWeight, req.MaxWeight, req.EstimatedDaysMin, req.EstimatedDaysMax, req.Position, isActive,
+	), &method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shipping method: %w", err)
+	}
+
+	return &method, nil
+}
+
+// UpdateShippingMethod replaces a shipping method's settings
+func (s *ShippingService) UpdateShippingMethod(id uint, req *ShippingMethodRequest) (*models.ShippingMethod, error) {
+	var isActive bool
+	if err := s.db.QueryRow("SELECT is_active FROM shipping_methods WHERE id = $1", id).Scan(&isActive); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("shipping method not found")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if err := s.validateShippingMethodRequest(req, id); err != nil {
+		return nil, err
+	}
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var method models.ShippingMethod
+	query := fmt.Sprintf(`
+		UPDATE shipping_methods
+		SET code = $1, name = $2, carrier = NULLIF($3, ''), type = $4, base_rate = $5, per_kg_rate = $6,
+		    free_threshold = $7, min_weight = $8, max_weight = $9, estimated_days_min = $10, estimated_days_max = $11,
+		    position = $12, is_active = $13, updated_at = NOW()
+		WHERE id = $14
+		RETURNING %s
+	`, shippingMethodColumns)
+	err := scanShippingMethod(s.db.QueryRow(
+		query,
+		req.Code, req.Name, req.Carrier, req.Type, req.BaseRate, req.PerKgRate, req.FreeThreshold,
+		req.MinWeight, req.MaxWeight, req.EstimatedDaysMin, req.EstimatedDaysMax, req.Position, isActive, id,
+	), &method)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update shipping method: %w", err)
+	}
+
+	return &method, nil
+}
+
+// DeleteShippingMethod deletes a shipping method; orders keep the method code and name they were placed with
+func (s *ShippingService) DeleteShippingMethod(id uint) error {
+	result, err := s.db.Exec("DELETE FROM shipping_methods WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete shipping method: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("shipping method not found")
+	}
+
+	return nil
+}
+
+// quoteShipping asks the configured provider for the shipping options of the items. With a free
+// shipping coupon every option is fully discounted.
+func quoteShipping(address shipping.Address, items []shipping.Item, subtotal float64, freeShipping bool) ([]ShippingQuote, error) {
+	address.Normalize()
+	quotes, err := getShippingProvider().Quote(context.Background(), &shipping.QuoteRequest{
+		Address:  address,
+		Items:    items,
+		Subtotal: roundCents(subtotal),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to quote shipping: %w", err)
+	}
+
+	result := make([]ShippingQuote, 0, len(quotes))
+	for _, quote := range quotes {
+		option := ShippingQuote{Quote: quote, Total: quote.Cost}
+		if freeShipping {
+			option.Discount = quote.Cost
+			option.Total = 0
+		}
+		result = append(result, option)
+	}
+	return result, nil
+}
+
+// selectShippingQuote picks the requested method from the quotes, or the cheapest when none is requested.
+// It returns nil when nothing can be quoted and no method was requested.
+func selectShippingQuote(quotes []ShippingQuote, method string) (*ShippingQuote, error) {
+	method = strings.ToLower(strings.TrimSpace(method))
+
+	var selected *ShippingQuote
+	for i := range quotes {
+		if method != "" {
+			if quotes[i].Method == method {
+				return &quotes[i], nil
+			}
+			continue
+		}
+		if selected == nil || quotes[i].Cost < selected.Cost {
+			selected = &quotes[i]
+		}
+	}
+
+	if method != "" {
+		return nil, errors.New("shipping method not available for this address")
+	}
+	return selected, nil
+}
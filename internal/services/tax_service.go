@@ -0,0 +1,413 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/tax"
+)
+
+var (
+	taxCalculatorOnce sync.Once
+	taxCalculator     tax.TaxCalculator
+)
+
+// getTaxCalculator returns the tax calculator configured with TAX_PROVIDER, falling back to the rate tables
+func getTaxCalculator() tax.TaxCalculator {
+	taxCalculatorOnce.Do(func() {
+		calculator, err := tax.NewFromEnv(database.GetDB())
+		if err != nil {
+			log.Printf("Warning: failed to configure tax provider: %v", err)
+			log.Println("Falling back to tax rate tables")
+			calculator = tax.NewTableCalculator(database.GetDB())
+		}
+		taxCalculator = calculator
+	})
+	return taxCalculator
+}
+
+// TaxService handles tax classes and rate tables
+type TaxService struct {
+	db *sql.DB
+}
+
+// NewTaxService creates a new tax service
+func NewTaxService() *TaxService {
+	return &TaxService{
+		db: database.GetDB(),
+	}
+}
+
+// TaxClassRequest represents the request to create a tax class
+type TaxClassRequest struct {
+	Code        string `json:"code" binding:"required,max=50"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// TaxRateRequest represents the request to create or replace a tax rate
+type TaxRateRequest struct {
+	TaxClass         string  `json:"tax_class" binding:"required"`
+	Country          string  `json:"country" binding:"required,len=2"`
+	Region           string  `json:"region"`
+	PostalCodePrefix string  `json:"postal_code_prefix"`
+	Name             string  `json:"name" binding:"required"`
+	Rate             float64 `json:"rate" binding:"gte=0,lte=100"`
+}
+
+// TaxRateFilter represents tax rate filtering options
+type TaxRateFilter struct {
+	TaxClass string
+	Country  string
+}
+
+const taxRateColumns = `id, tax_class, country, COALESCE(region, ''), COALESCE(postal_code_prefix, ''), name, rate, created_at, updated_at`
+
+// scanTaxRate scans a tax rate row selected with taxRateColumns
+func scanTaxRate(scanner interface{ Scan(...interface{}) error }, rate *models.TaxRate) error {
+	return scanner.Scan(
+		&rate.ID, &rate.TaxClass, &rate.Country, &rate.Region, &rate.PostalCodePrefix,
+		&rate.Name, &rate.Rate, &rate.CreatedAt, &rate.UpdatedAt,
+	)
+}
+
+// checkTaxClassExists returns an error when no tax class has the given code
+func checkTaxClassExists(q querier, code string) error {
+	var exists bool
+	if err := q.QueryRow("SELECT EXISTS(SELECT 1 FROM tax_classes WHERE code = $1)", code).Scan(&exists); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return errors.New("tax class not found")
+	}
+	return nil
+}
+
+// ListTaxClasses retrieves all tax classes
+func (s *TaxService) ListTaxClasses() ([]models.TaxClass, error) {
+	rows, err := s.db.Query("SELECT code, name, COALESCE(description, ''), created_at, updated_at FROM tax_classes ORDER BY code")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax classes: %w", err)
+	}
+	defer rows.Close()
+
+	classes := []models.TaxClass{}
+	for rows.Next() {
+		var class models.TaxClass
+		if err := rows.Scan(&class.Code, &class.Name, &class.Description, &class.CreatedAt, &class.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tax class: %w", err)
+		}
+		classes = append(classes, class)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tax classes: %w", err)
+	}
+
+	return classes, nil
+}
+
+// CreateTaxClass creates a new tax class
+func (s *TaxService) CreateTaxClass(req *TaxClassRequest) (*models.TaxClass, error) {
+	code := strings.ToLower(strings.TrimSpace(req.Code))
+	if code == "" {
+		return nil, errors.New("invalid tax class: code is required")
+	}
+
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM tax_classes WHERE code = $1)", code).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if exists {
+		return nil, errors.New("tax class already exists")
+	}
+
+	var class models.TaxClass
+	err := s.db.QueryRow(`
+		INSERT INTO tax_classes (code, name, description, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		RETURNING code, name, COALESCE(description, ''), created_at, updated_at
+	`, code, req.Name, req.Description).Scan(&class.Code, &class.Name, &class.Description, &class.CreatedAt, &class.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tax class: %w", err)
+	}
+
+	return &class, nil
+}
+
+// DeleteTaxClass deletes a tax class and its rates. The standard class and classes assigned to products are kept.
+func (s *TaxService) DeleteTaxClass(code string) error {
+	if code == tax.DefaultClass {
+		return errors.New("tax class is in use")
+	}
+	if err := checkTaxClassExists(s.db, code); err != nil {
+		return err
+	}
+
+	var inUse bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE tax_class = $1)", code).Scan(&inUse); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if inUse {
+		return errors.New("tax class is in use")
+	}
+
+	if _, err := s.db.Exec("DELETE FROM tax_classes WHERE code = $1", code); err != nil {
+		return fmt.Errorf("failed to delete tax class: %w", err)
+	}
+
+	return nil
+}
+
+// ListTaxRates retrieves tax rates, optionally filtered by class and country
+func (s *TaxService) ListTaxRates(filter *TaxRateFilter) ([]models.TaxRate, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+
+	if filter.TaxClass != "" {
+		args = append(args, filter.TaxClass)
+		whereConditions = append(whereConditions, fmt.Sprintf("tax_class = $%d", len(args)))
+	}
+	if filter.Country != "" {
+		args = append(args, strings.ToUpper(filter.Country))
+		whereConditions = append(whereConditions, fmt.Sprintf("country = $%d", len(args)))
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = "WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM tax_rates
+		%s
+		ORDER BY country, region NULLS FIRST, postal_code_prefix NULLS FIRST, tax_class, name, id
+	`, taxRateColumns, whereClause)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax rates: %w", err)
+	}
+	defer rows.Close()
+
+	rates := []models.TaxRate{}
+	for rows.Next() {
+		var rate models.TaxRate
+		if err := scanTaxRate(rows, &rate); err != nil {
+			return nil, fmt.Errorf("failed to scan tax rate: %w", err)
+		}
+		rates = append(rates, rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tax rates: %w", err)
+	}
+
+	return rates, nil
+}
+
+// normalizeTaxRateRequest normalizes the jurisdiction of a tax rate request and checks its class
+func (s *TaxService) normalizeTaxRateRequest(req *TaxRateRequest) error {
+	address := tax.Address{Country: req.Country, Region: req.Region, PostalCode: req.PostalCodePrefix}
+	address.Normalize()
+	req.Country, req.Region, req.PostalCodePrefix = address.Country, address.Region, address.PostalCode
+	req.Name = strings.TrimSpace(req.Name)
+
+	if req.Name == "" {
+		return errors.New("invalid tax rate: name is required")
+	}
+	return checkTaxClassExists(s.db, req.TaxClass)
+}
+
+// checkTaxRateUnique rejects a second rate with the same name for the same class and jurisdiction
+func (s *TaxService) checkTaxRateUnique(req *TaxRateRequest, excludeID uint) error {
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM tax_rates
+			WHERE tax_class = $1 AND country = $2 AND COALESCE(region, '') = $3
+			  AND COALESCE(postal_code_prefix, '') = $4 AND name = $5 AND id != $6
+		)
+	`, req.TaxClass, req.Country, req.Region, req.PostalCodePrefix, req.Name, excludeID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if exists {
+		return errors.New("tax rate already exists")
+	}
+	return nil
+}
+
+// CreateTaxRate creates a new tax rate
+func (s *TaxService) CreateTaxRate(req *TaxRateRequest) (*models.TaxRate, error) {
+	if err := s.normalizeTaxRateRequest(req); err != nil {
+		return nil, err
+	}
+	if err := s.checkTaxRateUnique(req, 0); err != nil {
+		return nil, err
+	}
+
+	var rate models.TaxRate
+	query := fmt.Sprintf(`
+		INSERT INTO tax_rates (tax_class, country, region, postal_code_prefix, name, rate, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, NOW(), NOW())
+		RETURNING %s
+	`, taxRateColumns)
+	err := scanTaxRate(s.db.QueryRow(
+		query, req.TaxClass, req.Country, req.Region, req.PostalCodePrefix, req.Name, req.Rate,
+	), &rate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tax rate: %w", err)
+	}
+
+	return &rate, nil
+}
+
+// UpdateTaxRate replaces a tax rate
+func (s *TaxService) UpdateTaxRate(id uint, req *TaxRateRequest) (*models.TaxRate, error) {
+	if err := s.normalizeTaxRateRequest(req); err != nil {
+		return nil, err
+	}
+	if err := s.checkTaxRateUnique(req, id); err != nil {
+		return nil, err
+	}
+
+	var rate models.TaxRate
+	query := fmt.Sprintf(`
+		UPDATE tax_rates
+		SET tax_class = $1, country = $2, region = NULLIF($3, ''), postal_code_prefix = NULLIF($4, ''),
+		    name = $5, rate = $6, updated_at = NOW()
+		WHERE id = $7
+		RETURNING %s
+	`, taxRateColumns)
+	err := scanTaxRate(s.db.QueryRow(
+		query, req.TaxClass, req.Country, req.Region, req.PostalCodePrefix, req.Name, req.Rate, id,
+	), &rate)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("tax rate not found")
+		}
+		return nil, fmt.Errorf("failed to update tax rate: %w", err)
+	}
+
+	return &rate, nil
+}
+
+// DeleteTaxRate deletes a tax rate
+func (s *TaxService) DeleteTaxRate(id uint) error {
+	result, err := s.db.Exec("DELETE FROM tax_rates WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tax rate: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return errors.New("tax rate not found")
+	}
+
+	return nil
+}
+
+// calculateLineTaxes taxes priced lines, net of all discounts, for a destination address.
+// Lines must already carry their promotion and coupon discounts.
+func calculateLineTaxes(address tax.Address, lines []pricedLine) (*tax.Result, error) {
+	req := &tax.Request{
+		Address:          address,
+		Lines:            make([]tax.Line, len(lines)),
+		PricesIncludeTax: tax.PricesIncludeTax(),
+	}
+	for i, line := range lines {
+		req.Lines[i] = tax.Line{
+			ProductID: line.ProductID,
+			TaxClass:  line.TaxClass,
+			Quantity:  line.Quantity,
+			Amount:    roundCents(line.net()),
+		}
+	}
+
+	result, err := getTaxCalculator().Calculate(context.Background(), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate tax: %w", err)
+	}
+	return result, nil
+}
+
+// insertOrderTaxLines stores the taxes charged on each order item; itemIDs is aligned with the result lines
+func insertOrderTaxLines(tx *sql.Tx, orderID uint, itemIDs []uint, result *tax.Result) error {
+	for i, line := range result.Lines {
+		for _, t := range line.Taxes {
+			_, err := tx.Exec(`
+				INSERT INTO order_tax_lines (order_id, order_item_id, name, jurisdiction, rate, taxable_amount, amount, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+			`, orderID, itemIDs[i], t.Name, t.Jurisdiction, t.Rate, line.TaxableAmount, t.Amount)
+			if err != nil {
+				return fmt.Errorf("failed to create order tax line: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadOrderTaxLines retrieves the tax lines of an order, oldest first
+func loadOrderTaxLines(q querier, orderID uint) ([]models.OrderTaxLine, error) {
+	rows, err := q.Query(`
+		SELECT id, order_id, order_item_id, name, jurisdiction, rate, taxable_amount, amount, created_at
+		FROM order_tax_lines
+		WHERE order_id = $1
+		ORDER BY id
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order tax lines: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []models.OrderTaxLine{}
+	for rows.Next() {
+		var line models.OrderTaxLine
+		var itemID sql.NullInt64
+		err := rows.Scan(
+			&line.ID, &line.OrderID, &itemID, &line.Name, &line.Jurisdiction,
+			&line.Rate, &line.TaxableAmount, &line.Amount, &line.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order tax line: %w", err)
+		}
+		if itemID.Valid {
+			id := uint(itemID.Int64)
+			line.OrderItemID = &id
+		}
+		lines = append(lines, line)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating order tax lines: %w", err)
+	}
+
+	return lines, nil
+}
+
+// summarizeTaxLines sums tax lines per tax name, jurisdiction and rate
+func summarizeTaxLines(lines []models.OrderTaxLine) []models.OrderTaxLine {
+	summary := []models.OrderTaxLine{}
+	index := make(map[string]int)
+	for _, line := range lines {
+		key := fmt.Sprintf("%s|%s|%g", line.Name, line.Jurisdiction, line.Rate)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(summary)
+			summary = append(summary, models.OrderTaxLine{
+				OrderID: line.OrderID, Name: line.Name, Jurisdiction: line.Jurisdiction, Rate: line.Rate,
+			})
+			i = len(summary) - 1
+		}
+		summary[i].TaxableAmount = roundCents(summary[i].TaxableAmount + line.TaxableAmount)
+		summary[i].Amount = roundCents(summary[i].Amount + line.Amount)
+	}
+	return summary
+}
@@ -0,0 +1,691 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Code-byme/e-commerce/internal/database"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/notification"
+)
+
+// maxWishlistAlertsPerRun is the most wishlist notifications of each kind sent in one run of the worker
+const maxWishlistAlertsPerRun = 100
+
+// WishlistService handles wishlists, the saved for later list and wishlist notifications
+type WishlistService struct {
+	db       *sql.DB
+	shareURL string
+	interval time.Duration
+}
+
+// NewWishlistService creates a new wishlist service. Share links start with WISHLIST_SHARE_URL;
+// back in stock and price drop notifications are checked every WISHLIST_ALERT_INTERVAL (default 15m).
+func NewWishlistService() *WishlistService {
+	shareURL := os.Getenv("WISHLIST_SHARE_URL")
+	if shareURL == "" {
+		shareURL = "http://localhost:8080/wishlists/shared"
+	}
+
+	interval := 15 * time.Minute
+	if value := os.Getenv("WISHLIST_ALERT_INTERVAL"); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+			interval = parsed
+		}
+	}
+
+	return &WishlistService{
+		db:       database.GetDB(),
+		shareURL: strings.TrimSuffix(shareURL, "/"),
+		interval: interval,
+	}
+}
+
+// CreateWishlistRequest represents the request to create a wishlist
+type CreateWishlistRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	IsPublic bool   `json:"is_public"`
+}
+
+// UpdateWishlistRequest represents the request to rename a wishlist or change its visibility
+type UpdateWishlistRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=100"`
+	IsPublic *bool   `json:"is_public"`
+}
+
+// AddWishlistItemRequest represents the request to add a product to a wishlist. Adding a product
+// already on the wishlist updates its quantity and subscriptions.
+type AddWishlistItemRequest struct {
+	ProductID         uint `json:"product_id" binding:"required"`
+	Quantity          int  `json:"quantity" binding:"omitempty,min=1"`
+	NotifyBackInStock bool `json:"notify_back_in_stock"`
+	NotifyPriceDrop   bool `json:"notify_price_drop"`
+}
+
+// UpdateWishlistItemRequest represents the request to change a wishlist item's quantity or subscriptions
+type UpdateWishlistItemRequest struct {
+	Quantity          *int  `json:"quantity" binding:"omitempty,min=1"`
+	NotifyBackInStock *bool `json:"notify_back_in_stock"`
+	NotifyPriceDrop   *bool `json:"notify_price_drop"`
+}
+
+// wishlistColumns are the wishlist columns scanned by scanWishlist
+const wishlistColumns = `w.id, w.user_id, w.name, w.kind, w.is_public, w.share_token, w.created_at, w.updated_at,
+	(SELECT COUNT(*) FROM wishlist_items wi WHERE wi.wishlist_id = w.id)`
+
+// scanWishlist scans a row of wishlistColumns
+func (s *WishlistService) scanWishlist(scanner interface{ Scan(...interface{}) error }) (*models.Wishlist, error) {
+	var wishlist models.Wishlist
+	err := scanner.Scan(
+		&wishlist.ID, &wishlist.UserID, &wishlist.Name, &wishlist.Kind, &wishlist.IsPublic, &wishlist.ShareToken,
+		&wishlist.CreatedAt, &wishlist.UpdatedAt, &wishlist.ItemCount,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if wishlist.IsPublic {
+		wishlist.ShareURL = s.shareURL + "/" + wishlist.ShareToken
+	}
+	return &wishlist, nil
+}
+
+// ListWishlists returns the user's wishlists, including the saved for later list
+func (s *WishlistService) ListWishlists(userID uint) ([]models.Wishlist, error) {
+	rows, err := s.db.Query("SELECT "+wishlistColumns+" FROM wishlists w WHERE w.user_id = $1 ORDER BY w.created_at, w.id", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wishlists: %w", err)
+	}
+	defer rows.Close()
+
+	wishlists := []models.Wishlist{}
+	for rows.Next() {
+		wishlist, err := s.scanWishlist(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist: %w", err)
+		}
+		wishlists = append(wishlists, *wishlist)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wishlists: %w", err)
+	}
+
+	return wishlists, nil
+}
+
+// CreateWishlist creates a named wishlist for the user
+func (s *WishlistService) CreateWishlist(userID uint, req *CreateWishlistRequest) (*models.Wishlist, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("invalid wishlist: name is required")
+	}
+
+	var id uint
+	err := s.db.QueryRow(`
+		INSERT INTO wishlists (user_id, name, kind, is_public, share_token, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+		RETURNING id
+	`, userID, name, models.WishlistKindWishlist, req.IsPublic, randomHex(16)).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wishlist: %w", err)
+	}
+
+	return s.GetWishlist(userID, id)
+}
+
+// GetWishlist returns one of the user's wishlists with its items
+func (s *WishlistService) GetWishlist(userID, id uint) (*models.Wishlist, error) {
+	wishlist, err := s.scanWishlist(s.db.QueryRow(
+		"SELECT "+wishlistColumns+" FROM wishlists w WHERE w.id = $1 AND w.user_id = $2", id, userID,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("wishlist not found")
+		}
+		return nil, fmt.Errorf("failed to get wishlist: %w", err)
+	}
+
+	if wishlist.Items, err = s.loadItems(wishlist.ID); err != nil {
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// GetSharedWishlist returns a public wishlist by its share token, without its owner
+func (s *WishlistService) GetSharedWishlist(token string) (*models.Wishlist, error) {
+	wishlist, err := s.scanWishlist(s.db.QueryRow(
+		"SELECT "+wishlistColumns+" FROM wishlists w WHERE w.share_token = $1 AND w.is_public = true", token,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("wishlist not found")
+		}
+		return nil, fmt.Errorf("failed to get wishlist: %w", err)
+	}
+
+	if wishlist.Items, err = s.loadItems(wishlist.ID); err != nil {
+		return nil, err
+	}
+
+	// Only the owner sees who it belongs to and their subscriptions
+	wishlist.UserID = 0
+	wishlist.ShareToken = ""
+	wishlist.ShareURL = ""
+	for i := range wishlist.Items {
+		wishlist.Items[i].AddedPrice = nil
+		wishlist.Items[i].NotifyBackInStock = false
+		wishlist.Items[i].NotifyPriceDrop = false
+		wishlist.Items[i].PriceAlertPrice = nil
+	}
+	return wishlist, nil
+}
+
+// UpdateWishlist renames a wishlist or changes whether it can be viewed through its share link
+func (s *WishlistService) UpdateWishlist(userID, id uint, req *UpdateWishlistRequest) (*models.Wishlist, error) {
+	var name *string
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		if trimmed == "" {
+			return nil, errors.New("invalid wishlist: name is required")
+		}
+		name = &trimmed
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE wishlists SET name = COALESCE($1, name), is_public = COALESCE($2, is_public), updated_at = NOW()
+		WHERE id = $3 AND user_id = $4
+	`, name, req.IsPublic, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update wishlist: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, errors.New("wishlist not found")
+	}
+
+	return s.GetWishlist(userID, id)
+}
+
+// DeleteWishlist deletes one of the user's wishlists with its items
+func (s *WishlistService) DeleteWishlist(userID, id uint) error {
+	result, err := s.db.Exec("DELETE FROM wishlists WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete wishlist: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return errors.New("wishlist not found")
+	}
+	return nil
+}
+
+// AddItem adds a product to one of the user's wishlists, or updates it when already there.
+// Subscribing to price drops notifies the user when the product gets cheaper than it is now.
+func (s *WishlistService) AddItem(userID, wishlistID uint, req *AddWishlistItemRequest) (*models.Wishlist, error) {
+	if err := s.checkOwnership(userID, wishlistID); err != nil {
+		return nil, err
+	}
+
+	var price float64
+	err := s.db.QueryRow(
+		"SELECT "+productEffectivePriceExpr("")+" FROM products WHERE id = $1 AND "+productVisibleCondition(""),
+		req.ProductID,
+	).Scan(&price)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("product not found or inactive")
+		}
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = 1
+	}
+
+	var alertPrice *float64
+	if req.NotifyPriceDrop {
+		alertPrice = &price
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO wishlist_items (wishlist_id, product_id, quantity, added_price, notify_back_in_stock, notify_price_drop, price_alert_price, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (wishlist_id, product_id) DO UPDATE SET
+			quantity = EXCLUDED.quantity,
+			notify_back_in_stock = EXCLUDED.notify_back_in_stock,
+			out_of_stock_at = CASE WHEN EXCLUDED.notify_back_in_stock THEN wishlist_items.out_of_stock_at END,
+			notify_price_drop = EXCLUDED.notify_price_drop,
+			price_alert_price = EXCLUDED.price_alert_price,
+			updated_at = NOW()
+	`, wishlistID, req.ProductID, quantity, price, req.NotifyBackInStock, req.NotifyPriceDrop, alertPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add wishlist item: %w", err)
+	}
+
+	if err := touchWishlist(s.db, wishlistID); err != nil {
+		return nil, err
+	}
+	return s.GetWishlist(userID, wishlistID)
+}
+
+// UpdateItem changes a wishlist item's quantity or notification subscriptions
+func (s *WishlistService) UpdateItem(userID, wishlistID, itemID uint, req *UpdateWishlistItemRequest) (*models.Wishlist, error) {
+	if err := s.checkOwnership(userID, wishlistID); err != nil {
+		return nil, err
+	}
+
+	// Subscribing to price drops again starts from the current price
+	result, err := s.db.Exec(`
+		UPDATE wishlist_items wi SET
+			quantity = COALESCE($1, wi.quantity),
+			notify_back_in_stock = COALESCE($2, wi.notify_back_in_stock),
+			out_of_stock_at = CASE WHEN COALESCE($2, wi.notify_back_in_stock) THEN wi.out_of_stock_at END,
+			notify_price_drop = COALESCE($3, wi.notify_price_drop),
+			price_alert_price = CASE
+				WHEN $3::boolean IS TRUE THEN `+productEffectivePriceExpr("p.")+`
+				WHEN $3::boolean IS FALSE THEN NULL
+				ELSE wi.price_alert_price END,
+			updated_at = NOW()
+		FROM products p
+		WHERE wi.id = $4 AND wi.wishlist_id = $5 AND p.id = wi.product_id
+	`, req.Quantity, req.NotifyBackInStock, req.NotifyPriceDrop, itemID, wishlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update wishlist item: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, errors.New("wishlist item not found")
+	}
+
+	if err := touchWishlist(s.db, wishlistID); err != nil {
+		return nil, err
+	}
+	return s.GetWishlist(userID, wishlistID)
+}
+
+// RemoveItem removes an item from one of the user's wishlists
+func (s *WishlistService) RemoveItem(userID, wishlistID, itemID uint) (*models.Wishlist, error) {
+	if err := s.checkOwnership(userID, wishlistID); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Exec("DELETE FROM wishlist_items WHERE id = $1 AND wishlist_id = $2", itemID, wishlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove wishlist item: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, errors.New("wishlist item not found")
+	}
+
+	if err := touchWishlist(s.db, wishlistID); err != nil {
+		return nil, err
+	}
+	return s.GetWishlist(userID, wishlistID)
+}
+
+// MoveItemToCart adds a wishlist item to the user's cart with its quantity and removes it from
+// the wishlist. The item stays on the wishlist when it cannot be added, e.g. for lack of stock.
+func (s *WishlistService) MoveItemToCart(userID, wishlistID, itemID uint) (*models.CartResponse, error) {
+	if err := s.checkOwnership(userID, wishlistID); err != nil {
+		return nil, err
+	}
+
+	cartService := NewCartService()
+	owner := CartOwner{UserID: userID}
+	cart, err := cartService.GetOrCreateCart(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the item so a concurrent move waits and then finds it gone instead of adding it twice
+	var productID uint
+	var quantity int
+	err = tx.QueryRow(
+		"SELECT product_id, quantity FROM wishlist_items WHERE id = $1 AND wishlist_id = $2 FOR UPDATE", itemID, wishlistID,
+	).Scan(&productID, &quantity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("wishlist item not found")
+		}
+		return nil, fmt.Errorf("failed to get wishlist item: %w", err)
+	}
+
+	if err := addCartItem(tx, cart.ID, productID, quantity); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM wishlist_items WHERE id = $1", itemID); err != nil {
+		return nil, fmt.Errorf("failed to remove wishlist item: %w", err)
+	}
+	if err := touchWishlist(tx, wishlistID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return cartService.GetCart(owner)
+}
+
+// SaveForLater moves an item out of the user's cart into their saved for later list, which is
+// created on first use. It returns the updated cart.
+func (s *WishlistService) SaveForLater(userID, cartItemID uint) (*models.CartResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var cartID, productID uint
+	var quantity int
+	var addedPrice *float64
+	err = tx.QueryRow(`
+		SELECT ci.cart_id, ci.product_id, ci.quantity, ci.added_price
+		FROM cart_items ci
+		JOIN carts c ON ci.cart_id = c.id
+		WHERE ci.id = $1 AND c.user_id = $2
+		FOR UPDATE OF ci
+	`, cartItemID, userID).Scan(&cartID, &productID, &quantity, &addedPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("cart item not found or not owned by user")
+		}
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	wishlistID, err := savedForLaterList(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO wishlist_items (wishlist_id, product_id, quantity, added_price, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (wishlist_id, product_id) DO UPDATE SET quantity = wishlist_items.quantity + EXCLUDED.quantity, updated_at = NOW()
+	`, wishlistID, productID, quantity, addedPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save item for later: %w", err)
+	}
+
+	if _, err = tx.Exec("DELETE FROM cart_items WHERE id = $1", cartItemID); err != nil {
+		return nil, fmt.Errorf("failed to remove cart item: %w", err)
+	}
+	if _, err = tx.Exec("UPDATE carts SET updated_at = NOW() WHERE id = $1", cartID); err != nil {
+		return nil, fmt.Errorf("failed to update cart: %w", err)
+	}
+	if err := touchWishlist(tx, wishlistID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return NewCartService().GetCart(CartOwner{UserID: userID})
+}
+
+// savedForLaterList returns the user's saved for later list, creating it when needed
+func savedForLaterList(q querier, userID uint) (uint, error) {
+	var id uint
+	err := q.QueryRow(`
+		INSERT INTO wishlists (user_id, name, kind, share_token, created_at, updated_at)
+		VALUES ($1, 'Saved for later', $2, $3, NOW(), NOW())
+		ON CONFLICT (user_id) WHERE kind = 'saved_for_later' DO UPDATE SET updated_at = NOW()
+		RETURNING id
+	`, userID, models.WishlistKindSavedForLater, randomHex(16)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load saved for later list: %w", err)
+	}
+	return id, nil
+}
+
+// checkOwnership verifies that a wishlist belongs to the user
+func (s *WishlistService) checkOwnership(userID, wishlistID uint) error {
+	var exists bool
+	err := s.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM wishlists WHERE id = $1 AND user_id = $2)", wishlistID, userID,
+	).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	if !exists {
+		return errors.New("wishlist not found")
+	}
+	return nil
+}
+
+// touchWishlist updates a wishlist's timestamp after its items changed
+func touchWishlist(q querier, wishlistID uint) error {
+	if _, err := q.Exec("UPDATE wishlists SET updated_at = NOW() WHERE id = $1", wishlistID); err != nil {
+		return fmt.Errorf("failed to update wishlist: %w", err)
+	}
+	return nil
+}
+
+// loadItems loads a wishlist's items with their products at current prices
+func (s *WishlistService) loadItems(wishlistID uint) ([]models.WishlistItem, error) {
+	rows, err := s.db.Query(`
+		SELECT wi.id, wi.wishlist_id, wi.product_id, wi.quantity, wi.added_price, wi.notify_back_in_stock, wi.notify_price_drop,
+		       wi.price_alert_price, wi.created_at, wi.updated_at,
+		       p.id, p.name, p.description, p.price, p.stock, p.category_id, p.image_url, p.is_active, p.created_at, p.updated_at,
+		       p.compare_at_price, p.sale_price, p.sale_starts_at, p.sale_ends_at,
+		       `+productVisibleCondition("p.")+`
+		FROM wishlist_items wi
+		JOIN products p ON wi.product_id = p.id
+		WHERE wi.wishlist_id = $1
+		ORDER BY wi.created_at ASC, wi.id
+	`, wishlistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wishlist items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.WishlistItem{}
+	for rows.Next() {
+		var item models.WishlistItem
+		err := rows.Scan(
+			&item.ID, &item.WishlistID, &item.ProductID, &item.Quantity, &item.AddedPrice, &item.NotifyBackInStock, &item.NotifyPriceDrop,
+			&item.PriceAlertPrice, &item.CreatedAt, &item.UpdatedAt,
+			&item.Product.ID, &item.Product.Name, &item.Product.Description, &item.Product.Price,
+			&item.Product.Stock, &item.Product.CategoryID, &item.Product.ImageURL, &item.Product.IsActive,
+			&item.Product.CreatedAt, &item.Product.UpdatedAt,
+			&item.Product.CompareAtPrice, &item.Product.SalePrice, &item.Product.SaleStartsAt, &item.Product.SaleEndsAt,
+			&item.Available,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist item: %w", err)
+		}
+		applyEffectivePrice(&item.Product)
+		item.InStock = item.Available && item.Product.Stock > 0
+		items = append(items, item)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wishlist items: %w", err)
+	}
+
+	return items, nil
+}
+
+// wishlistAlert is a pending back in stock or price drop notification for a wishlist item
+type wishlistAlert struct {
+	ItemID       uint
+	UserID       uint
+	Email        string
+	ProductName  string
+	WishlistName string
+	AlertPrice   float64
+	CurrentPrice float64
+}
+
+// SendWishlistAlerts notifies subscribers of wishlist items that are back in stock or got cheaper.
+// It returns the number of notifications sent.
+func (s *WishlistService) SendWishlistAlerts() (int, error) {
+	// Remember subscribed items seen out of stock, so they are announced once they are back
+	_, err := s.db.Exec(`
+		UPDATE wishlist_items wi SET out_of_stock_at = NOW()
+		FROM products p
+		WHERE p.id = wi.product_id AND wi.notify_back_in_stock AND wi.out_of_stock_at IS NULL
+		  AND NOT (` + productVisibleCondition("p.") + ` AND p.stock > 0)
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark out of stock wishlist items: %w", err)
+	}
+
+	sent := 0
+
+	backInStock, err := s.loadAlerts(
+		"wi.notify_back_in_stock AND wi.out_of_stock_at IS NOT NULL AND " + productVisibleCondition("p.") + " AND p.stock > 0",
+	)
+	if err != nil {
+		return sent, err
+	}
+	for _, alert := range backInStock {
+		msg := &notification.Message{
+			UserID:  alert.UserID,
+			Email:   alert.Email,
+			Subject: fmt.Sprintf("%s is back in stock", alert.ProductName),
+			Body:    fmt.Sprintf("%s from your wishlist \"%s\" is back in stock.", alert.ProductName, alert.WishlistName),
+		}
+		ok, err := s.sendAlert(
+			"UPDATE wishlist_items SET out_of_stock_at = NULL, alert_attempted_at = NOW() WHERE id = $1 AND out_of_stock_at IS NOT NULL RETURNING id",
+			msg, alert.ItemID,
+		)
+		if err != nil {
+			log.Printf("Warning: failed to send back in stock notification for wishlist item %d: %v", alert.ItemID, err)
+		} else if ok {
+			sent++
+		}
+	}
+
+	priceDrops, err := s.loadAlerts(
+		"wi.notify_price_drop AND wi.price_alert_price IS NOT NULL AND " + productVisibleCondition("p.") +
+			" AND ROUND(" + productEffectivePriceExpr("p.") + ", 2) < wi.price_alert_price",
+	)
+	if err != nil {
+		return sent, err
+	}
+	for _, alert := range priceDrops {
+		msg := &notification.Message{
+			UserID:  alert.UserID,
+			Email:   alert.Email,
+			Subject: fmt.Sprintf("Price drop on %s", alert.ProductName),
+			Body: fmt.Sprintf("%s from your wishlist \"%s\" dropped from %.2f to %.2f.",
+				alert.ProductName, alert.WishlistName, alert.AlertPrice, alert.CurrentPrice),
+		}
+		// Later notifications need the price to drop below this one
+		ok, err := s.sendAlert(
+			"UPDATE wishlist_items SET price_alert_price = $2, alert_attempted_at = NOW() WHERE id = $1 AND price_alert_price > $2 RETURNING id",
+			msg, alert.ItemID, roundCents(alert.CurrentPrice),
+		)
+		if err != nil {
+			log.Printf("Warning: failed to send price drop notification for wishlist item %d: %v", alert.ItemID, err)
+		} else if ok {
+			sent++
+		}
+	}
+
+	return sent, nil
+}
+
+// loadAlerts loads the wishlist items matching an alert condition with their owners, least
+// recently tried first
+func (s *WishlistService) loadAlerts(condition string) ([]wishlistAlert, error) {
+	rows, err := s.db.Query(`
+		SELECT wi.id, u.id, u.email, p.name, w.name, COALESCE(wi.price_alert_price, 0), `+productEffectivePriceExpr("p.")+`
+		FROM wishlist_items wi
+		JOIN wishlists w ON w.id = wi.wishlist_id
+		JOIN users u ON u.id = w.user_id
+		JOIN products p ON p.id = wi.product_id
+		WHERE `+condition+`
+		ORDER BY wi.alert_attempted_at NULLS FIRST, wi.id
+		LIMIT $1
+	`, maxWishlistAlertsPerRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query wishlist alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []wishlistAlert
+	for rows.Next() {
+		var alert wishlistAlert
+		err := rows.Scan(
+			&alert.ItemID, &alert.UserID, &alert.Email, &alert.ProductName, &alert.WishlistName,
+			&alert.AlertPrice, &alert.CurrentPrice,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan wishlist alert: %w", err)
+		}
+		alerts = append(alerts, alert)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wishlist alerts: %w", err)
+	}
+
+	return alerts, nil
+}
+
+// sendAlert claims an alert with the given update and sends its notification, committing the
+// claim only once the message is sent so a failed delivery is retried on the next run. The claim
+// takes the item ID as its first argument; a failed attempt is recorded on the item so it is
+// retried after the others. It returns false when another worker claimed the alert first.
+func (s *WishlistService) sendAlert(claim string, msg *notification.Message, args ...interface{}) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id uint
+	if err := tx.QueryRow(claim, args...).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim wishlist alert: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := getNotifier().Send(ctx, msg); err != nil {
+		// Release the claim's lock before recording the attempt on the same row
+		tx.Rollback()
+		if _, recordErr := s.db.Exec("UPDATE wishlist_items SET alert_attempted_at = NOW() WHERE id = $1", args[0]); recordErr != nil {
+			log.Printf("Warning: failed to record notification attempt for wishlist item %v: %v", args[0], recordErr)
+		}
+		return false, fmt.Errorf("failed to send notification: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// StartAlertWorker sends back in stock and price drop notifications now and then periodically in
+// the background. The interval is configured with WISHLIST_ALERT_INTERVAL (default 15m).
+func (s *WishlistService) StartAlertWorker() {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			sent, err := s.SendWishlistAlerts()
+			if err != nil {
+				log.Printf("Warning: %v", err)
+			}
+			if sent > 0 {
+				log.Printf("Sent %d wishlist notifications", sent)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,100 @@
+package shipping
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// VolumetricDivisor converts a parcel volume in cubic centimetres to a billable weight in kilograms
+const VolumetricDivisor = 5000
+
+// Address is the destination a shipment is quoted for
+type Address struct {
+	Country    string `json:"country"`
+	Region     string `json:"region"`
+	PostalCode string `json:"postal_code"`
+}
+
+// Normalize upper-cases the country and region and trims all fields
+func (a *Address) Normalize() {
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.Region = strings.ToUpper(strings.TrimSpace(a.Region))
+	a.PostalCode = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(a.PostalCode), " ", ""))
+}
+
+// Item is a product line to ship; weight is in kilograms and dimensions in centimetres per unit
+type Item struct {
+	ProductID uint
+	Quantity  int
+	Weight    float64
+	Length    float64
+	Width     float64
+	Height    float64
+}
+
+// BillableWeight returns the greater of the actual and volumetric weight of all units
+func (i Item) BillableWeight() float64 {
+	volumetric := i.Length * i.Width * i.Height / VolumetricDivisor
+	return math.Max(i.Weight, volumetric) * float64(i.Quantity)
+}
+
+// QuoteRequest asks for the shipping options of a set of items.
+// Subtotal is the merchandise amount after discounts, used for free shipping thresholds.
+type QuoteRequest struct {
+	Address  Address
+	Items    []Item
+	Subtotal float64
+}
+
+// Weight returns the total billable weight of the request items
+func (r *QuoteRequest) Weight() float64 {
+	total := 0.0
+	for _, item := range r.Items {
+		total += item.BillableWeight()
+	}
+	return math.Round(total*1000) / 1000
+}
+
+// Quote is a shipping option and its cost
+type Quote struct {
+	Method       string  `json:"method"`
+	Name         string  `json:"name"`
+	Carrier      string  `json:"carrier,omitempty"`
+	Cost         float64 `json:"cost"`
+	EstimatedMin *int    `json:"estimated_days_min,omitempty"`
+	EstimatedMax *int    `json:"estimated_days_max,omitempty"`
+}
+
+// ShippingRateProvider quotes shipping options. Implementations may use local rate tables or a carrier API.
+type ShippingRateProvider interface {
+	Quote(ctx context.Context, req *QuoteRequest) ([]Quote, error)
+}
+
+// NewFromEnv creates the shipping rate provider selected by the SHIPPING_PROVIDER environment variable
+func NewFromEnv(db *sql.DB) (ShippingRateProvider, error) {
+	provider := strings.ToLower(getEnv("SHIPPING_PROVIDER", "table"))
+
+	switch provider {
+	case "table":
+		return NewTableProvider(db), nil
+	default:
+		return nil, fmt.Errorf("unknown shipping provider: %s", provider)
+	}
+}
+
+// roundCents rounds an amount to whole cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
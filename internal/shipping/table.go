@@ -0,0 +1,114 @@
+package shipping
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+)
+
+// Shipping method types of the table provider
+const (
+	MethodFlatRate          = "flat_rate"
+	MethodWeightBased       = "weight_based"
+	MethodFreeOverThreshold = "free_over_threshold"
+)
+
+// TableProvider quotes the methods configured for the shipping zone of the destination.
+// A zone covers locations given by country, optional region and optional postal code prefix;
+// the zone with the most specific matching location serves the address.
+type TableProvider struct {
+	db *sql.DB
+}
+
+// NewTableProvider creates a rate table provider
+func NewTableProvider(db *sql.DB) *TableProvider {
+	return &TableProvider{db: db}
+}
+
+// Quote returns the cost of every active method of the destination zone that accepts the shipment weight
+func (p *TableProvider) Quote(ctx context.Context, req *QuoteRequest) ([]Quote, error) {
+	address := req.Address
+	address.Normalize()
+
+	quotes := []Quote{}
+	if address.Country == "" {
+		return quotes, nil
+	}
+
+	// Find the zone with the most specific location matching the address
+	var zoneID uint
+	err := p.db.QueryRowContext(ctx, `
+		SELECT zone_id
+		FROM shipping_zone_locations
+		WHERE country = $1
+		  AND (region IS NULL OR region = $2)
+		  AND (postal_code_prefix IS NULL OR $3 LIKE postal_code_prefix || '%')
+		ORDER BY (region IS NOT NULL) DESC, LENGTH(COALESCE(postal_code_prefix, '')) DESC, zone_id
+		LIMIT 1
+	`, address.Country, address.Region, address.PostalCode).Scan(&zoneID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return quotes, nil
+		}
+		return nil, fmt.Errorf("failed to find shipping zone: %w", err)
+	}
+
+	weight := req.Weight()
+	rows, err := p.db.QueryContext(ctx, `
+		SELECT code, name, COALESCE(carrier, ''), type, base_rate, per_kg_rate, free_threshold,
+		       estimated_days_min, estimated_days_max
+		FROM shipping_methods
+		WHERE zone_id = $1 AND is_active = true
+		  AND (min_weight IS NULL OR $2 >= min_weight)
+		  AND (max_weight IS NULL OR $2 <= max_weight)
+		ORDER BY position, id
+	`, zoneID, weight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query shipping methods: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var quote Quote
+		var methodType string
+		var baseRate, perKgRate float64
+		var freeThreshold sql.NullFloat64
+		var daysMin, daysMax sql.NullInt64
+		err := rows.Scan(
+			&quote.Method, &quote.Name, &quote.Carrier, &methodType, &baseRate, &perKgRate, &freeThreshold,
+			&daysMin, &daysMax,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan shipping method: %w", err)
+		}
+
+		switch methodType {
+		case MethodWeightBased:
+			// Weight is charged per started kilogram
+			quote.Cost = baseRate + perKgRate*math.Ceil(weight)
+		default:
+			quote.Cost = baseRate
+		}
+		if freeThreshold.Valid && req.Subtotal >= freeThreshold.Float64 {
+			quote.Cost = 0
+		}
+		quote.Cost = roundCents(quote.Cost)
+
+		if daysMin.Valid {
+			days := int(daysMin.Int64)
+			quote.EstimatedMin = &days
+		}
+		if daysMax.Valid {
+			days := int(daysMax.Int64)
+			quote.EstimatedMax = &days
+		}
+		quotes = append(quotes, quote)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating shipping methods: %w", err)
+	}
+
+	return quotes, nil
+}
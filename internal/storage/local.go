@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage stores media objects on the local filesystem
+type LocalStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage creates a filesystem storage rooted at dir whose objects are served under baseURL
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	return &LocalStorage{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// Dir returns the directory objects are stored in
+func (s *LocalStorage) Dir() string {
+	return s.dir
+}
+
+// BaseURL returns the URL prefix objects are served under
+func (s *LocalStorage) BaseURL() string {
+	return s.baseURL
+}
+
+// Put writes data to a file under the storage directory
+func (s *LocalStorage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create media directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write media file: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the file stored under key; missing files are ignored
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete media file: %w", err)
+	}
+
+	return nil
+}
+
+// URL returns the public URL of the object stored under key
+func (s *LocalStorage) URL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+// path resolves key to a file path, rejecting keys that escape the storage directory
+func (s *LocalStorage) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", errors.New("invalid media key")
+	}
+	return filepath.Join(s.dir, cleaned), nil
+}
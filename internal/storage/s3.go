@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config holds the configuration for an S3-compatible storage backend
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.amazonaws.com or http://localhost:9000 for MinIO
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PublicURL       string // optional public URL prefix, defaults to the bucket URL
+}
+
+// S3Storage stores media objects in an S3-compatible bucket using path-style requests
+type S3Storage struct {
+	config *S3Config
+	client *http.Client
+}
+
+// NewS3Storage creates a new S3-compatible storage backend
+func NewS3Storage(config *S3Config) (*S3Storage, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("S3 endpoint and bucket are required")
+	}
+	if config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, errors.New("S3 credentials are required")
+	}
+
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	if config.PublicURL == "" {
+		config.PublicURL = config.Endpoint + "/" + config.Bucket
+	}
+	config.PublicURL = strings.TrimRight(config.PublicURL, "/")
+
+	return &S3Storage{
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Put uploads data to the bucket under key
+func (s *S3Storage) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, data)
+
+	return s.do(req)
+}
+
+// Delete removes the object stored under key
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, nil)
+
+	return s.do(req)
+}
+
+// URL returns the public URL of the object stored under key
+func (s *S3Storage) URL(key string) string {
+	return s.config.PublicURL + "/" + key
+}
+
+// newRequest builds a path-style request for an object
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, body []byte) (*http.Request, error) {
+	rawURL := s.config.Endpoint + "/" + s.config.Bucket + "/" + uriEncode(key, false)
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build S3 request: %w", err)
+	}
+	req.ContentLength = int64(len(body))
+	return req, nil
+}
+
+// do executes a signed request and converts non-2xx responses into errors
+func (s *S3Storage) do(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3 request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 %s %s returned %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (s *S3Storage) sign(req *http.Request, payload []byte) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	req.Header.Set("Host", req.URL.Host)
+
+	// Canonical headers, sorted by lowercase name
+	headerNames := []string{}
+	headerValues := map[string]string{}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		headerNames = append(headerNames, lower)
+		headerValues[lower] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	headerNames = append(headerNames, "host")
+	headerValues["host"] = req.URL.Host
+	sort.Strings(headerNames)
+	headerNames = uniqueStrings(headerNames)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headerValues[name] + "\n")
+	}
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, s.config.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+// canonicalQuery encodes query parameters in the order required by Signature Version 4
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		for _, value := range values[key] {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes every byte except unreserved characters, optionally keeping slashes
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// uniqueStrings removes adjacent duplicates from a sorted slice
+func uniqueStrings(sorted []string) []string {
+	result := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
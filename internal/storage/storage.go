@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Storage stores uploaded media objects and resolves their public URLs
+type Storage interface {
+	// Put stores data under key, replacing any existing object
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the object stored under key
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL of the object stored under key
+	URL(key string) string
+}
+
+// NewFromEnv creates the storage backend selected by the STORAGE_BACKEND environment variable
+func NewFromEnv() (Storage, error) {
+	backend := strings.ToLower(getEnv("STORAGE_BACKEND", "local"))
+
+	switch backend {
+	case "local":
+		return NewLocalStorage(
+			getEnv("MEDIA_LOCAL_DIR", "./uploads"),
+			getEnv("MEDIA_BASE_URL", "/media"),
+		), nil
+	case "s3":
+		config := &S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          getEnv("S3_REGION", "us-east-1"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		}
+		return NewS3Storage(config)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", backend)
+	}
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,121 @@
+package tax
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// TableCalculator calculates taxes from the tax_rates table.
+// Rates are keyed by tax class, country, optional region and optional postal code prefix.
+// Every differently named matching rate applies (e.g. a federal and a state tax); among
+// rates with the same name only the most specific one applies.
+type TableCalculator struct {
+	db *sql.DB
+}
+
+// NewTableCalculator creates a rate table calculator
+func NewTableCalculator(db *sql.DB) *TableCalculator {
+	return &TableCalculator{db: db}
+}
+
+// rateRow is a matching tax rate and how specific its match is
+type rateRow struct {
+	tax         Tax
+	specificity int
+}
+
+// Calculate returns the taxes of each line at the request address
+func (c *TableCalculator) Calculate(ctx context.Context, req *Request) (*Result, error) {
+	address := req.Address
+	address.Normalize()
+
+	result := &Result{Lines: make([]LineResult, len(req.Lines)), PricesIncludeTax: req.PricesIncludeTax}
+	ratesByClass := make(map[string][]Tax)
+
+	for i, line := range req.Lines {
+		class := line.TaxClass
+		if class == "" {
+			class = DefaultClass
+		}
+
+		rates, ok := ratesByClass[class]
+		if !ok {
+			var err error
+			rates, err = c.lookupRates(ctx, class, address)
+			if err != nil {
+				return nil, err
+			}
+			ratesByClass[class] = rates
+		}
+
+		result.Lines[i] = applyRates(line.Amount, rates, req.PricesIncludeTax)
+		result.TaxAmount += result.Lines[i].TaxAmount
+	}
+
+	result.TaxAmount = roundCents(result.TaxAmount)
+	return result, nil
+}
+
+// lookupRates finds the rates of a tax class that apply at an address
+func (c *TableCalculator) lookupRates(ctx context.Context, class string, address Address) ([]Tax, error) {
+	if address.Country == "" {
+		return []Tax{}, nil
+	}
+
+	rows, err := c.db.QueryContext(ctx, `
+		SELECT name, country, COALESCE(region, ''), COALESCE(postal_code_prefix, ''), rate
+		FROM tax_rates
+		WHERE tax_class = $1 AND country = $2
+		  AND (region IS NULL OR region = $3)
+		  AND (postal_code_prefix IS NULL OR $4 LIKE postal_code_prefix || '%')
+		ORDER BY name, id
+	`, class, address.Country, address.Region, address.PostalCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tax rates: %w", err)
+	}
+	defer rows.Close()
+
+	best := make(map[string]rateRow)
+	var names []string
+	for rows.Next() {
+		var name, country, region, postalPrefix string
+		var rate float64
+		if err := rows.Scan(&name, &country, &region, &postalPrefix, &rate); err != nil {
+			return nil, fmt.Errorf("failed to scan tax rate: %w", err)
+		}
+
+		// A region match outranks any postal prefix; longer prefixes outrank shorter ones
+		specificity := len(postalPrefix)
+		jurisdiction := []string{country}
+		if region != "" {
+			specificity += 1000
+			jurisdiction = append(jurisdiction, region)
+		}
+		if postalPrefix != "" {
+			jurisdiction = append(jurisdiction, postalPrefix+"*")
+		}
+
+		current, seen := best[name]
+		if !seen {
+			names = append(names, name)
+		}
+		if !seen || specificity > current.specificity {
+			best[name] = rateRow{
+				tax:         Tax{Name: name, Jurisdiction: strings.Join(jurisdiction, "-"), Rate: rate},
+				specificity: specificity,
+			}
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tax rates: %w", err)
+	}
+
+	rates := make([]Tax, 0, len(names))
+	for _, name := range names {
+		rates = append(rates, best[name].tax)
+	}
+	return rates, nil
+}
@@ -0,0 +1,138 @@
+package tax
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultClass is the tax class of products without an explicit class
+const DefaultClass = "standard"
+
+// Address is the destination taxes are calculated for
+type Address struct {
+	Country    string `json:"country"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+}
+
+// Normalize upper-cases the country and region and trims all fields
+func (a *Address) Normalize() {
+	a.Country = strings.ToUpper(strings.TrimSpace(a.Country))
+	a.Region = strings.ToUpper(strings.TrimSpace(a.Region))
+	a.PostalCode = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(a.PostalCode), " ", ""))
+}
+
+// Line is a taxable line; Amount is the line total after discounts
+type Line struct {
+	ProductID uint
+	TaxClass  string
+	Quantity  int
+	Amount    float64
+}
+
+// Request asks for the taxes of a set of lines shipped to an address
+type Request struct {
+	Address          Address
+	Lines            []Line
+	PricesIncludeTax bool
+}
+
+// Tax is a single tax charged on a line
+type Tax struct {
+	Name         string  `json:"name"`
+	Jurisdiction string  `json:"jurisdiction"`
+	Rate         float64 `json:"rate"`
+	Amount       float64 `json:"amount"`
+}
+
+// LineResult holds the taxes of one request line
+type LineResult struct {
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+	Taxes         []Tax   `json:"taxes"`
+}
+
+// Result holds the taxes of a request; Lines is aligned with the request lines
+type Result struct {
+	Lines            []LineResult `json:"lines"`
+	TaxAmount        float64      `json:"tax_amount"`
+	PricesIncludeTax bool         `json:"prices_include_tax"`
+}
+
+// TaxCalculator calculates taxes. Implementations may look up local rate tables or call an external provider.
+type TaxCalculator interface {
+	Calculate(ctx context.Context, req *Request) (*Result, error)
+}
+
+// NewFromEnv creates the tax calculator selected by the TAX_PROVIDER environment variable
+func NewFromEnv(db *sql.DB) (TaxCalculator, error) {
+	provider := strings.ToLower(getEnv("TAX_PROVIDER", "table"))
+
+	switch provider {
+	case "table":
+		return NewTableCalculator(db), nil
+	case "none":
+		return NoTax{}, nil
+	default:
+		return nil, fmt.Errorf("unknown tax provider: %s", provider)
+	}
+}
+
+// PricesIncludeTax reports whether catalog prices include tax, set with TAX_PRICES_INCLUDE_TAX
+func PricesIncludeTax() bool {
+	include, _ := strconv.ParseBool(os.Getenv("TAX_PRICES_INCLUDE_TAX"))
+	return include
+}
+
+// NoTax is a calculator that charges no tax
+type NoTax struct{}
+
+// Calculate returns a zero tax result
+func (NoTax) Calculate(ctx context.Context, req *Request) (*Result, error) {
+	result := &Result{Lines: make([]LineResult, len(req.Lines)), PricesIncludeTax: req.PricesIncludeTax}
+	for i, line := range req.Lines {
+		result.Lines[i] = LineResult{TaxableAmount: line.Amount, Taxes: []Tax{}}
+	}
+	return result, nil
+}
+
+// applyRates taxes a line amount at the given rates (in percent). With inclusive prices
+// the tax is extracted from the amount; otherwise it is added on top.
+func applyRates(amount float64, rates []Tax, inclusive bool) LineResult {
+	total := 0.0
+	for _, rate := range rates {
+		total += rate.Rate
+	}
+
+	base := amount
+	if inclusive && total > 0 {
+		base = amount / (1 + total/100)
+	}
+
+	line := LineResult{TaxableAmount: roundCents(base), Taxes: []Tax{}}
+	for _, rate := range rates {
+		rate.Amount = roundCents(base * rate.Rate / 100)
+		line.TaxAmount += rate.Amount
+		line.Taxes = append(line.Taxes, rate)
+	}
+	line.TaxAmount = roundCents(line.TaxAmount)
+	return line
+}
+
+// roundCents rounds an amount to whole cents
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
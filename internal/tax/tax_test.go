@@ -0,0 +1,113 @@
+package tax
+
+import "testing"
+
+func TestApplyRates(t *testing.T) {
+	state := Tax{Name: "State", Jurisdiction: "US-CA", Rate: 6.25}
+	county := Tax{Name: "County", Jurisdiction: "US-CA-LA", Rate: 2.5}
+
+	tests := []struct {
+		name      string
+		amount    float64
+		rates     []Tax
+		inclusive bool
+		taxable   float64
+		tax       float64
+		amounts   []float64
+	}{
+		{
+			name:    "exclusive adds tax on top",
+			amount:  100,
+			rates:   []Tax{{Name: "VAT", Rate: 20}},
+			taxable: 100,
+			tax:     20,
+			amounts: []float64{20},
+		},
+		{
+			name:      "inclusive extracts tax from the amount",
+			amount:    120,
+			rates:     []Tax{{Name: "VAT", Rate: 20}},
+			inclusive: true,
+			taxable:   100,
+			tax:       20,
+			amounts:   []float64{20},
+		},
+		{
+			name:    "exclusive rounds half cents up per tax",
+			amount:  10,
+			rates:   []Tax{state, county},
+			taxable: 10,
+			tax:     0.88,
+			amounts: []float64{0.63, 0.25},
+		},
+		{
+			name:      "inclusive splits the amount on the combined rate",
+			amount:    10,
+			rates:     []Tax{state, county},
+			inclusive: true,
+			taxable:   9.2,
+			tax:       0.8,
+			amounts:   []float64{0.57, 0.23},
+		},
+		{
+			name:      "inclusive rounds the taxable amount and tax to cents",
+			amount:    10,
+			rates:     []Tax{{Name: "Sales", Rate: 7}},
+			inclusive: true,
+			taxable:   9.35,
+			tax:       0.65,
+			amounts:   []float64{0.65},
+		},
+		{
+			name:    "total is the sum of the rounded taxes",
+			amount:  1.09,
+			rates:   []Tax{{Name: "A", Rate: 5}, {Name: "B", Rate: 5}},
+			taxable: 1.09,
+			tax:     0.1,
+			amounts: []float64{0.05, 0.05},
+		},
+		{
+			name:      "no rates charge no tax",
+			amount:    19.99,
+			inclusive: true,
+			taxable:   19.99,
+			tax:       0,
+			amounts:   []float64{},
+		},
+		{
+			name:      "zero rates keep the inclusive amount",
+			amount:    19.99,
+			rates:     []Tax{{Name: "Exempt", Rate: 0}},
+			inclusive: true,
+			taxable:   19.99,
+			tax:       0,
+			amounts:   []float64{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line := applyRates(tt.amount, tt.rates, tt.inclusive)
+			if line.TaxableAmount != tt.taxable {
+				t.Errorf("taxable amount = %v, want %v", line.TaxableAmount, tt.taxable)
+			}
+			if line.TaxAmount != tt.tax {
+				t.Errorf("tax amount = %v, want %v", line.TaxAmount, tt.tax)
+			}
+			if line.Taxes == nil {
+				t.Fatal("taxes = nil, want a list")
+			}
+			if len(line.Taxes) != len(tt.amounts) {
+				t.Fatalf("got %d taxes, want %d", len(line.Taxes), len(tt.amounts))
+			}
+			for i, tax := range line.Taxes {
+				if tax.Amount != tt.amounts[i] {
+					t.Errorf("tax %q amount = %v, want %v", tax.Name, tax.Amount, tt.amounts[i])
+				}
+				if tax.Name != tt.rates[i].Name || tax.Rate != tt.rates[i].Rate {
+					t.Errorf("tax %d = %q at %v, want %q at %v", i, tax.Name, tax.Rate, tt.rates[i].Name, tt.rates[i].Rate)
+				}
+			}
+		})
+	}
+}
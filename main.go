@@ -6,11 +6,14 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/Code-byme/e-commerce/internal/database"
 	"github.com/Code-byme/e-commerce/internal/handlers"
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/Code-byme/e-commerce/internal/storage"
 	"github.com/Code-byme/e-commerce/pkg/middleware"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -43,10 +46,52 @@ func main() {
 	categoryHandler := handlers.NewCategoryHandler()
 	orderHandler := handlers.NewOrderHandler()
 	cartHandler := handlers.NewCartHandler()
+	attributeHandler := handlers.NewAttributeHandler()
+	mediaHandler := handlers.NewMediaHandler()
+	importHandler := handlers.NewImportHandler()
+	pricingHandler := handlers.NewPricingHandler()
+	couponHandler := handlers.NewCouponHandler()
+	promotionHandler := handlers.NewPromotionHandler()
+	taxHandler := handlers.NewTaxHandler()
+	shippingHandler := handlers.NewShippingHandler()
+	paymentHandler := handlers.NewPaymentHandler()
+	refundHandler := handlers.NewRefundHandler()
+	returnHandler := handlers.NewReturnHandler()
+	shipmentHandler := handlers.NewShipmentHandler()
+	cartRecoveryHandler := handlers.NewCartRecoveryHandler()
+	wishlistHandler := handlers.NewWishlistHandler()
+
+	// Resume import jobs interrupted by a restart
+	importHandler.ImportService().ResumePendingJobs()
+
+	// Apply scheduled price changes in the background
+	pricingHandler.PricingService().StartPriceScheduler()
+
+	// Retry payment webhook events in the background
+	paymentHandler.WebhookService().StartWebhookWorker()
+
+	// Expire idle carts and send abandoned cart notifications in the background
+	cartRecoveryHandler.CartRecoveryService().StartWorker()
+
+	// Send wishlist back in stock and price drop notifications in the background
+	wishlistHandler.WishlistService().StartAlertWorker()
+
+	// Make order creation and checkout safe to retry, expiring stored responses in the background
+	idempotencyService := services.NewIdempotencyService()
+	idempotencyService.StartCleanupWorker()
+	idempotency := middleware.IdempotencyMiddleware(idempotencyService)
 
 	// Public routes
 	r.GET("/health", handlers.HealthCheck)
 
+	// Payment provider webhooks, authenticated by their signature
+	r.POST("/webhooks/payments/:provider", paymentHandler.ReceivePaymentWebhook)
+
+	// Serve locally stored media files
+	if local, ok := mediaHandler.MediaService().Storage().(*storage.LocalStorage); ok && strings.HasPrefix(local.BaseURL(), "/") {
+		r.Static(local.BaseURL(), local.Dir())
+	}
+
 	// Authentication routes
 	auth := r.Group("/auth")
 	{
@@ -59,6 +104,7 @@ func main() {
 	{
 		products.GET("", productHandler.ListProducts)
 		products.GET("/:id", productHandler.GetProduct)
+		products.GET("/:id/images", mediaHandler.ListProductImages)
 		products.GET("/category/:category_id", productHandler.GetProductsByCategory)
 	}
 
@@ -68,6 +114,33 @@ func main() {
 		categories.GET("", categoryHandler.ListCategories)
 		categories.GET("/:id", categoryHandler.GetCategory)
 		categories.GET("/:id/with-products", categoryHandler.GetCategoryWithProductCount)
+		categories.GET("/:id/attributes", attributeHandler.ListCategoryAttributes)
+	}
+
+	// Public attribute routes
+	attributes := r.Group("/attributes")
+	{
+		attributes.GET("", attributeHandler.ListAttributes)
+		attributes.GET("/:id", attributeHandler.GetAttribute)
+	}
+
+	// Public shared wishlist route
+	r.GET("/wishlists/shared/:token", wishlistHandler.GetSharedWishlist)
+
+	// Cart routes, open to guests identified by the X-Cart-Token header
+	cart := r.Group("/api/cart")
+	cart.Use(middleware.OptionalAuthMiddleware())
+	{
+		cart.GET("", cartHandler.GetCart)
+		cart.POST("/items", cartHandler.AddToCart)
+		cart.PUT("/items/:item_id", cartHandler.UpdateCartItem)
+		cart.DELETE("/items/:item_id", cartHandler.RemoveFromCart)
+		cart.DELETE("", cartHandler.ClearCart)
+		cart.POST("/revalidate", cartHandler.RevalidateCart)
+		cart.POST("/checkout", idempotency, cartHandler.CheckoutCart)
+		cart.POST("/coupon", cartHandler.ApplyCoupon)
+		cart.DELETE("/coupon", cartHandler.RemoveCoupon)
+		cart.POST("/shipping-quotes", cartHandler.ShippingQuotes)
 	}
 
 	// Protected routes
@@ -75,6 +148,10 @@ func main() {
 	protected.Use(middleware.AuthMiddleware())
 	{
 		protected.GET("/profile", authHandler.GetProfile)
+		protected.POST("/profile/claim-guest-orders", authHandler.ClaimGuestOrders)
+
+		// Protected abandoned cart restore route, for the cart's user
+		protected.POST("/cart/restore/:token", cartRecoveryHandler.RestoreCart)
 
 		// Protected product routes (admin only)
 		protected.POST("/products", productHandler.CreateProduct)
@@ -82,27 +159,126 @@ func main() {
 		protected.DELETE("/products/:id", productHandler.DeleteProduct)
 		protected.PATCH("/products/:id/stock", productHandler.UpdateStock)
 
+		// Protected product lifecycle routes (admin only)
+		protected.GET("/products", middleware.RoleMiddleware("admin"), productHandler.AdminListProducts)
+		protected.GET("/products/:id", middleware.RoleMiddleware("admin"), productHandler.AdminGetProduct)
+		protected.PUT("/products/:id/status", middleware.RoleMiddleware("admin"), productHandler.UpdateProductStatus)
+		protected.POST("/products/:id/restore", middleware.RoleMiddleware("admin"), productHandler.RestoreProduct)
+		protected.GET("/products/:id/history", middleware.RoleMiddleware("admin"), productHandler.GetProductHistory)
+		protected.POST("/products/:id/history/:revision/rollback", middleware.RoleMiddleware("admin"), productHandler.RollbackProduct)
+
+		// Protected product pricing routes (admin only)
+		protected.PUT("/products/:id/pricing", middleware.RoleMiddleware("admin"), pricingHandler.UpdateProductPricing)
+		protected.GET("/products/:id/price-schedules", middleware.RoleMiddleware("admin"), pricingHandler.ListPriceSchedules)
+		protected.POST("/products/:id/price-schedules", middleware.RoleMiddleware("admin"), pricingHandler.CreatePriceSchedule)
+		protected.DELETE("/products/:id/price-schedules/:schedule_id", middleware.RoleMiddleware("admin"), pricingHandler.DeletePriceSchedule)
+
+		// Protected product image routes (admin only)
+		protected.POST("/products/:id/images", middleware.RoleMiddleware("admin"), mediaHandler.UploadProductImage)
+		protected.POST("/products/:id/images/reorder", middleware.RoleMiddleware("admin"), mediaHandler.ReorderProductImages)
+		protected.PUT("/products/:id/images/:image_id", middleware.RoleMiddleware("admin"), mediaHandler.UpdateProductImage)
+		protected.DELETE("/products/:id/images/:image_id", middleware.RoleMiddleware("admin"), mediaHandler.DeleteProductImage)
+
+		// Protected product import/export routes (admin only)
+		protected.POST("/products/import", middleware.RoleMiddleware("admin"), importHandler.ImportProducts)
+		protected.GET("/products/import/:job_id", middleware.RoleMiddleware("admin"), importHandler.GetImportJob)
+		protected.GET("/products/import/:job_id/report", middleware.RoleMiddleware("admin"), importHandler.DownloadImportReport)
+		protected.GET("/products/export", middleware.RoleMiddleware("admin"), importHandler.ExportProducts)
+
 		// Protected category routes (admin only)
 		protected.POST("/categories", categoryHandler.CreateCategory)
 		protected.PUT("/categories/:id", categoryHandler.UpdateCategory)
 		protected.DELETE("/categories/:id", categoryHandler.DeleteCategory)
 
+		// Protected attribute routes (admin only)
+		protected.POST("/attributes", middleware.RoleMiddleware("admin"), attributeHandler.CreateAttribute)
+		protected.PUT("/attributes/:id", middleware.RoleMiddleware("admin"), attributeHandler.UpdateAttribute)
+		protected.DELETE("/attributes/:id", middleware.RoleMiddleware("admin"), attributeHandler.DeleteAttribute)
+		protected.POST("/categories/:id/attributes", middleware.RoleMiddleware("admin"), attributeHandler.AssignCategoryAttribute)
+		protected.DELETE("/categories/:id/attributes/:attribute_id", middleware.RoleMiddleware("admin"), attributeHandler.RemoveCategoryAttribute)
+
 		// Protected order routes
-		protected.POST("/orders", orderHandler.CreateOrder)
+		protected.POST("/orders", idempotency, orderHandler.CreateOrder)
 		protected.GET("/orders", orderHandler.ListOrders)
 		protected.GET("/orders/my", orderHandler.GetUserOrders)
 		protected.GET("/orders/:id", orderHandler.GetOrder)
-		protected.PUT("/orders/:id/status", orderHandler.UpdateOrderStatus)
+		protected.PUT("/orders/:id/status", middleware.RoleMiddleware("admin"), orderHandler.UpdateOrderStatus)
+		protected.GET("/orders/:id/history", orderHandler.GetOrderHistory)
 		protected.DELETE("/orders/:id", orderHandler.CancelOrder)
 		protected.GET("/orders/statistics", orderHandler.GetOrderStatistics)
+		protected.POST("/orders/:id/payments/capture", middleware.RoleMiddleware("admin"), paymentHandler.CapturePayment)
+		protected.POST("/orders/:id/payments/void", middleware.RoleMiddleware("admin"), paymentHandler.VoidPayment)
+		protected.GET("/orders/:id/shipments", shipmentHandler.ListOrderShipments)
+		protected.POST("/orders/:id/shipments", middleware.RoleMiddleware("admin"), shipmentHandler.CreateShipment)
+		protected.GET("/orders/:id/refunds", middleware.RoleMiddleware("admin"), refundHandler.ListOrderRefunds)
+		protected.POST("/orders/:id/refunds", middleware.RoleMiddleware("admin"), refundHandler.CreateRefund)
+
+		// Protected shipment routes (admin only)
+		protected.PUT("/shipments/:id", middleware.RoleMiddleware("admin"), shipmentHandler.UpdateShipment)
+		protected.POST("/shipments/:id/deliver", middleware.RoleMiddleware("admin"), shipmentHandler.MarkShipmentDelivered)
+
+		// Protected return routes; customers manage their own returns and admins process them
+		protected.POST("/returns", returnHandler.CreateReturn)
+		protected.GET("/returns", returnHandler.ListReturns)
+		protected.GET("/returns/:id", returnHandler.GetReturn)
+		protected.DELETE("/returns/:id", returnHandler.CancelReturn)
+		protected.PUT("/returns/:id/approve", middleware.RoleMiddleware("admin"), returnHandler.ApproveReturn)
+		protected.PUT("/returns/:id/reject", middleware.RoleMiddleware("admin"), returnHandler.RejectReturn)
+		protected.POST("/returns/:id/receive", middleware.RoleMiddleware("admin"), returnHandler.ReceiveReturn)
+		protected.POST("/returns/:id/complete", middleware.RoleMiddleware("admin"), returnHandler.CompleteReturn)
+
+		// Protected payment webhook event routes (admin only)
+		protected.GET("/payments/webhook-events", middleware.RoleMiddleware("admin"), paymentHandler.ListWebhookEvents)
+		protected.GET("/payments/webhook-events/:id", middleware.RoleMiddleware("admin"), paymentHandler.GetWebhookEvent)
+		protected.POST("/payments/webhook-events/:id/replay", middleware.RoleMiddleware("admin"), paymentHandler.ReplayWebhookEvent)
+
+		// Protected wishlist routes; saving for later moves a cart item to the saved for later list
+		protected.GET("/wishlists", wishlistHandler.ListWishlists)
+		protected.POST("/wishlists", wishlistHandler.CreateWishlist)
+		protected.GET("/wishlists/:id", wishlistHandler.GetWishlist)
+		protected.PUT("/wishlists/:id", wishlistHandler.UpdateWishlist)
+		protected.DELETE("/wishlists/:id", wishlistHandler.DeleteWishlist)
+		protected.POST("/wishlists/:id/items", wishlistHandler.AddWishlistItem)
+		protected.PUT("/wishlists/:id/items/:item_id", wishlistHandler.UpdateWishlistItem)
+		protected.DELETE("/wishlists/:id/items/:item_id", wishlistHandler.RemoveWishlistItem)
+		protected.POST("/wishlists/:id/items/:item_id/move-to-cart", wishlistHandler.MoveWishlistItemToCart)
+		protected.POST("/cart/items/:item_id/save-for-later", wishlistHandler.SaveForLater)
+
+		// Protected abandoned cart report (admin only)
+		protected.GET("/carts/abandoned", middleware.RoleMiddleware("admin"), cartRecoveryHandler.GetAbandonedCartReport)
+
+		// Protected coupon routes (admin only)
+		protected.GET("/coupons", middleware.RoleMiddleware("admin"), couponHandler.ListCoupons)
+		protected.POST("/coupons", middleware.RoleMiddleware("admin"), couponHandler.CreateCoupon)
+		protected.GET("/coupons/:id", middleware.RoleMiddleware("admin"), couponHandler.GetCoupon)
+		protected.PUT("/coupons/:id", middleware.RoleMiddleware("admin"), couponHandler.UpdateCoupon)
+		protected.DELETE("/coupons/:id", middleware.RoleMiddleware("admin"), couponHandler.DeleteCoupon)
+
+		// Protected promotion routes (admin only)
+		protected.GET("/promotions", middleware.RoleMiddleware("admin"), promotionHandler.ListPromotions)
+		protected.POST("/promotions", middleware.RoleMiddleware("admin"), promotionHandler.CreatePromotion)
+		protected.GET("/promotions/:id", middleware.RoleMiddleware("admin"), promotionHandler.GetPromotion)
+		protected.PUT("/promotions/:id", middleware.RoleMiddleware("admin"), promotionHandler.UpdatePromotion)
+		protected.DELETE("/promotions/:id", middleware.RoleMiddleware("admin"), promotionHandler.DeletePromotion)
+
+		// Protected tax routes (admin only)
+		protected.GET("/tax/classes", middleware.RoleMiddleware("admin"), taxHandler.ListTaxClasses)
+		protected.POST("/tax/classes", middleware.RoleMiddleware("admin"), taxHandler.CreateTaxClass)
+		protected.DELETE("/tax/classes/:code", middleware.RoleMiddleware("admin"), taxHandler.DeleteTaxClass)
+		protected.GET("/tax/rates", middleware.RoleMiddleware("admin"), taxHandler.ListTaxRates)
+		protected.POST("/tax/rates", middleware.RoleMiddleware("admin"), taxHandler.CreateTaxRate)
+		protected.PUT("/tax/rates/:id", middleware.RoleMiddleware("admin"), taxHandler.UpdateTaxRate)
+		protected.DELETE("/tax/rates/:id", middleware.RoleMiddleware("admin"), taxHandler.DeleteTaxRate)
 
-		// Protected cart routes
-		protected.GET("/cart", cartHandler.GetCart)
-		protected.POST("/cart/items", cartHandler.AddToCart)
-		protected.PUT("/cart/items/:item_id", cartHandler.UpdateCartItem)
-		protected.DELETE("/cart/items/:item_id", cartHandler.RemoveFromCart)
-		protected.DELETE("/cart", cartHandler.ClearCart)
-		protected.POST("/cart/checkout", cartHandler.CheckoutCart)
+		// Protected shipping routes (admin only)
+		protected.GET("/shipping/zones", middleware.RoleMiddleware("admin"), shippingHandler.ListShippingZones)
+		protected.POST("/shipping/zones", middleware.RoleMiddleware("admin"), shippingHandler.CreateShippingZone)
+		protected.GET("/shipping/zones/:id", middleware.RoleMiddleware("admin"), shippingHandler.GetShippingZone)
+		protected.PUT("/shipping/zones/:id", middleware.RoleMiddleware("admin"), shippingHandler.UpdateShippingZone)
+		protected.DELETE("/shipping/zones/:id", middleware.RoleMiddleware("admin"), shippingHandler.DeleteShippingZone)
+		protected.POST("/shipping/zones/:id/methods", middleware.RoleMiddleware("admin"), shippingHandler.CreateShippingMethod)
+		protected.PUT("/shipping/methods/:id", middleware.RoleMiddleware("admin"), shippingHandler.UpdateShippingMethod)
+		protected.DELETE("/shipping/methods/:id", middleware.RoleMiddleware("admin"), shippingHandler.DeleteShippingMethod)
 	}
 
 	// Create HTTP server
@@ -54,6 +54,46 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
+// OptionalAuthMiddleware authenticates requests that carry a bearer token and lets requests
+// without an Authorization header through as guests. An invalid token is still rejected.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	authService := services.NewAuthService()
+
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
+			return
+		}
+
+		// Check if the header starts with "Bearer "
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Authorization header must start with 'Bearer '",
+			})
+			c.Abort()
+			return
+		}
+
+		// Validate token
+		claims, err := authService.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Invalid or expired token",
+			})
+			c.Abort()
+			return
+		}
+
+		// Set user information in context
+		c.Set("user_id", claims.UserID)
+		c.Set("user_email", claims.Email)
+		c.Set("user_role", claims.Role)
+
+		c.Next()
+	}
+}
+
 // RoleMiddleware creates middleware to check user roles
 func RoleMiddleware(requiredRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Code-byme/e-commerce/internal/handlers"
+	"github.com/Code-byme/e-commerce/internal/models"
+	"github.com/Code-byme/e-commerce/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyHeader is the request header carrying the client's idempotency key
+const IdempotencyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyMiddleware makes a route safe to retry. A request with an Idempotency-Key header is
+// performed once per user, or per guest cart token for guests, and key; retries replay the stored
+// response. Server errors are not stored so the request can be retried, unless the handler reports
+// an OrderPlacedError with c.Error: once an order exists, its response is stored whatever it is.
+// It must run after AuthMiddleware or OptionalAuthMiddleware.
+func IdempotencyMiddleware(idempotencyService *services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimSpace(c.GetHeader(IdempotencyHeader))
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		// Guests are identified by their cart; without a valid cart token there is nothing to check out
+		scope := services.IdempotencyScope{UserID: c.GetUint("user_id")}
+		if scope.UserID == 0 {
+			guestKey, err := services.VerifyCartToken(c.GetHeader(handlers.CartTokenHeader))
+			if err != nil {
+				c.Next()
+				return
+			}
+			scope.GuestKey = guestKey
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key must be at most 255 characters",
+			})
+			c.Abort()
+			return
+		}
+
+		// Fingerprint the body so a key cannot be reused for a different request
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Failed to read request body",
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+
+		record, err := idempotencyService.BeginRequest(
+			c.Request.Context(), scope, key, c.Request.Method, c.FullPath(), hex.EncodeToString(sum[:]),
+		)
+		if err != nil {
+			switch {
+			case strings.HasPrefix(err.Error(), "idempotency key was already used"):
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error": "Idempotency-Key was already used for a different request",
+				})
+			case strings.HasPrefix(err.Error(), "a request with this idempotency key is still in progress"):
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "A request with this Idempotency-Key is still in progress",
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error": "Failed to process Idempotency-Key",
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		// Replay the stored response
+		if record.Status == models.IdempotencyKeyCompleted {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(record.ResponseStatus, "application/json; charset=utf-8", record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// Forget the key if the handler panics so the request can be retried
+		completed := false
+		defer func() {
+			if !completed {
+				if err := idempotencyService.ReleaseRequest(record.ID); err != nil {
+					log.Printf("Warning: %v", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		if status := recorder.Status(); status >= http.StatusInternalServerError && !orderPlaced(c) {
+			if err := idempotencyService.ReleaseRequest(record.ID); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		} else if err := idempotencyService.CompleteRequest(record.ID, status, recorder.body.Bytes()); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		completed = true
+	}
+}
+
+// orderPlaced reports whether the handler created an order before failing
+func orderPlaced(c *gin.Context) bool {
+	for _, ginErr := range c.Errors {
+		var placedErr *services.OrderPlacedError
+		if errors.As(ginErr.Err, &placedErr) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseRecorder copies the response body as it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes the data to the response and the copy
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the string to the response and the copy
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+)
+
+// ResizeToFit scales src down so that it fits within maxWidth x maxHeight, preserving
+// the aspect ratio. Images that already fit are returned unchanged. Each destination
+// pixel is the average of the source pixels it covers, which keeps thumbnails smooth.
+func ResizeToFit(src image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxWidth && srcH <= maxHeight {
+		return src
+	}
+
+	scale := float64(maxWidth) / float64(srcW)
+	if s := float64(maxHeight) / float64(srcH); s < scale {
+		scale = s
+	}
+
+	dstW := int(float64(srcW)*scale + 0.5)
+	dstH := int(float64(srcH)*scale + 0.5)
+	if dstW < 1 {
+		dstW = 1
+	}
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := bounds.Min.Y + (y+1)*srcH/dstH
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := bounds.Min.X + (x+1)*srcW/dstW
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
@@ -2,6 +2,7 @@
 
 # Database Seeding Script for E-commerce Portfolio
 # This script fetches products from FakeStore API and stores them in your database
+# (use "go run cmd/seed/main.go -source fixtures" or "-source synthetic" to seed offline)
 
 echo "🛒 E-commerce Database Seeding Tool"
 echo "=================================="
@@ -98,6 +98,20 @@ if [ "$ADMIN_TOKEN" != "null" ] && [ "$ADMIN_TOKEN" != "" ]; then
     
     # Extract MacBook product ID
     MACBOOK_ID=$(echo "$MACBOOK_RESPONSE" | jq -r '.data.id')
+
+    # Every order must ship; create a shipping zone and method for the US
+    SHIPPING_ZONE_RESPONSE=$(curl -s -X POST "$BASE_URL/api/shipping/zones" \
+      -H "Content-Type: application/json" \
+      -H "Authorization: Bearer $ADMIN_TOKEN" \
+      -d '{"name": "Domestic", "locations": [{"country": "US"}]}')
+    
+    SHIPPING_ZONE_ID=$(echo "$SHIPPING_ZONE_RESPONSE" | jq -r '.data.id')
+    
+    echo "Shipping method:"
+    curl -s -X POST "$BASE_URL/api/shipping/zones/$SHIPPING_ZONE_ID/methods" \
+      -H "Content-Type: application/json" \
+      -H "Authorization: Bearer $ADMIN_TOKEN" \
+      -d '{"code": "ground", "name": "Ground", "carrier": "UPS", "type": "flat_rate", "base_rate": 5}' | jq '.'
     
     # Create AirPods product
     AIRPODS_RESPONSE=$(curl -s -X POST "$BASE_URL/api/products" \
@@ -226,6 +240,7 @@ if [ "$ADMIN_TOKEN" != "null" ] && [ "$ADMIN_TOKEN" != "" ]; then
           -H "Authorization: Bearer $CUSTOMER_TOKEN" \
           -d '{
             "shipping_address": "123 Main St, City, State 12345",
+            "shipping_country": "US",
             "payment_method": "credit_card"
           }')
         
@@ -254,8 +269,9 @@ if [ "$ADMIN_TOKEN" != "null" ] && [ "$ADMIN_TOKEN" != "" ]; then
           -H "Authorization: Bearer $CUSTOMER_TOKEN" \
           -d '{
             "shipping_address": "456 Oak St, City, State 12345",
+            "shipping_country": "US",
             "payment_method": "paypal"
-          }" | jq '.'
+          }' | jq '.'
         
         # Clear cart (should work even if empty)
         echo -e "\nClearing cart:"
@@ -83,6 +83,17 @@ ADMIN_RESPONSE=$(curl -s -X POST "$BASE_URL/auth/register" \
 if echo "$ADMIN_RESPONSE" | jq -e '.data.token' > /dev/null; then
     ADMIN_TOKEN=$(echo "$ADMIN_RESPONSE" | jq -r '.data.token')
     echo "✅ Admin registered successfully"
+
+    # Every order must ship; create a shipping zone and method for the US
+    SHIPPING_ZONE_ID=$(curl -s -X POST "$BASE_URL/api/shipping/zones" \
+      -H "Content-Type: application/json" \
+      -H "Authorization: Bearer $ADMIN_TOKEN" \
+      -d '{"name": "Domestic", "locations": [{"country": "US"}]}' | jq -r '.data.id')
+    curl -s -X POST "$BASE_URL/api/shipping/zones/$SHIPPING_ZONE_ID/methods" \
+      -H "Content-Type: application/json" \
+      -H "Authorization: Bearer $ADMIN_TOKEN" \
+      -d '{"code": "ground", "name": "Ground", "carrier": "UPS", "type": "flat_rate", "base_rate": 5}' > /dev/null
+    echo "✅ Shipping zone created"
 else
     echo "❌ Admin registration failed:"
     echo "$ADMIN_RESPONSE" | jq '.'
@@ -180,6 +191,7 @@ CHECKOUT_RESPONSE=$(curl -s -X POST "$BASE_URL/api/cart/checkout" \
   -H "Authorization: Bearer $CUSTOMER_TOKEN" \
   -d '{
     "shipping_address": "123 Main St, City, State 12345",
+    "shipping_country": "US",
     "payment_method": "credit_card"
   }')
 
@@ -240,17 +252,32 @@ TOTAL_ORDERS=$(echo "$ALL_ORDERS" | jq '.data.orders | length')
 echo "✅ Total orders in system: $TOTAL_ORDERS"
 
 echo ""
-echo "Updating order status (admin)..."
-UPDATE_STATUS_RESPONSE=$(curl -s -X PUT "$BASE_URL/api/orders/$ORDER_ID/status" \
+echo "Shipping the order (admin)..."
+SHIPMENT_RESPONSE=$(curl -s -X POST "$BASE_URL/api/orders/$ORDER_ID/shipments" \
   -H "Content-Type: application/json" \
   -H "Authorization: Bearer $ADMIN_TOKEN" \
-  -d '{"status": "shipped"}')
+  -d '{"carrier": "UPS", "tracking_number": "1Z999AA10123456784"}')
+
+if echo "$SHIPMENT_RESPONSE" | jq -e '.data' > /dev/null; then
+    SHIPMENT_ID=$(echo "$SHIPMENT_RESPONSE" | jq -r '.data.id')
+    echo "✅ Order shipped in shipment $SHIPMENT_ID"
+else
+    echo "❌ Failed to ship order:"
+    echo "$SHIPMENT_RESPONSE" | jq '.'
+fi
+
+echo ""
+echo "Marking the shipment as delivered (admin)..."
+DELIVER_RESPONSE=$(curl -s -X POST "$BASE_URL/api/shipments/$SHIPMENT_ID/deliver" \
+  -H "Authorization: Bearer $ADMIN_TOKEN")
 
-if echo "$UPDATE_STATUS_RESPONSE" | jq -e '.data' > /dev/null; then
-    echo "✅ Order status updated to 'shipped'"
+if echo "$DELIVER_RESPONSE" | jq -e '.data' > /dev/null; then
+    ORDER_STATUS=$(curl -s -X GET "$BASE_URL/api/orders/$ORDER_ID" \
+      -H "Authorization: Bearer $ADMIN_TOKEN" | jq -r '.data.status')
+    echo "✅ Shipment delivered; order status is now '$ORDER_STATUS'"
 else
-    echo "❌ Failed to update order status:"
-    echo "$UPDATE_STATUS_RESPONSE" | jq '.'
+    echo "❌ Failed to mark the shipment as delivered:"
+    echo "$DELIVER_RESPONSE" | jq '.'
 fi
 
 echo ""
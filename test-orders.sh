@@ -98,6 +98,20 @@ if [ "$ADMIN_TOKEN" != "null" ] && [ "$ADMIN_TOKEN" != "" ]; then
     
     # Extract MacBook product ID
     MACBOOK_ID=$(echo "$MACBOOK_RESPONSE" | jq -r '.data.id')
+
+    # Every order must ship; create a shipping zone and method for the US
+    SHIPPING_ZONE_RESPONSE=$(curl -s -X POST "$BASE_URL/api/shipping/zones" \
+      -H "Content-Type: application/json" \
+      -H "Authorization: Bearer $ADMIN_TOKEN" \
+      -d '{"name": "Domestic", "locations": [{"country": "US"}]}')
+    
+    SHIPPING_ZONE_ID=$(echo "$SHIPPING_ZONE_RESPONSE" | jq -r '.data.id')
+    
+    echo "Shipping method:"
+    curl -s -X POST "$BASE_URL/api/shipping/zones/$SHIPPING_ZONE_ID/methods" \
+      -H "Content-Type: application/json" \
+      -H "Authorization: Bearer $ADMIN_TOKEN" \
+      -d '{"code": "ground", "name": "Ground", "carrier": "UPS", "type": "flat_rate", "base_rate": 5}' | jq '.'
     
     echo -e "\n5. Customer browsing products..."
     
@@ -118,6 +132,7 @@ if [ "$ADMIN_TOKEN" != "null" ] && [ "$ADMIN_TOKEN" != "" ]; then
           -H "Authorization: Bearer $CUSTOMER_TOKEN" \
           -d "{
             \"shipping_address\": \"123 Main St, City, State 12345\",
+            \"shipping_country\": \"US\",
             \"payment_method\": \"credit_card\",
             \"items\": [
               {
@@ -156,26 +171,31 @@ if [ "$ADMIN_TOKEN" != "null" ] && [ "$ADMIN_TOKEN" != "" ]; then
         curl -s -X GET "$BASE_URL/api/orders" \
           -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.'
         
-        # Update order status
-        echo -e "\nUpdating order status to confirmed:"
-        curl -s -X PUT "$BASE_URL/api/orders/$ORDER_ID/status" \
-          -H "Content-Type: application/json" \
-          -H "Authorization: Bearer $ADMIN_TOKEN" \
-          -d '{"status": "confirmed"}' | jq '.'
+        # The order is confirmed once its payment is authorized
+        echo -e "\nOrder status after payment authorization (should be confirmed):"
+        curl -s -X GET "$BASE_URL/api/orders/$ORDER_ID" \
+          -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.data.status'
         
-        # Update order status to shipped
-        echo -e "\nUpdating order status to shipped:"
-        curl -s -X PUT "$BASE_URL/api/orders/$ORDER_ID/status" \
+        # Ship every item; this captures the payment and marks the order shipped
+        echo -e "\nShipping the order:"
+        SHIPMENT_RESPONSE=$(curl -s -X POST "$BASE_URL/api/orders/$ORDER_ID/shipments" \
           -H "Content-Type: application/json" \
           -H "Authorization: Bearer $ADMIN_TOKEN" \
-          -d '{"status": "shipped"}' | jq '.'
+          -d '{"carrier": "UPS", "tracking_number": "1Z999AA10123456784"}')
         
-        # Update order status to delivered
-        echo -e "\nUpdating order status to delivered:"
-        curl -s -X PUT "$BASE_URL/api/orders/$ORDER_ID/status" \
-          -H "Content-Type: application/json" \
-          -H "Authorization: Bearer $ADMIN_TOKEN" \
-          -d '{"status": "delivered"}' | jq '.'
+        echo "$SHIPMENT_RESPONSE" | jq '.'
+        
+        # Extract shipment ID
+        SHIPMENT_ID=$(echo "$SHIPMENT_RESPONSE" | jq -r '.data.id')
+        
+        # Deliver the shipment, which marks the order delivered
+        echo -e "\nMarking the shipment as delivered:"
+        curl -s -X POST "$BASE_URL/api/shipments/$SHIPMENT_ID/deliver" \
+          -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.'
+        
+        echo -e "\nOrder status after delivery (should be delivered):"
+        curl -s -X GET "$BASE_URL/api/orders/$ORDER_ID" \
+          -H "Authorization: Bearer $ADMIN_TOKEN" | jq '.data.status'
         
         echo -e "\n9. Order statistics (admin only)..."
         
@@ -192,6 +212,7 @@ if [ "$ADMIN_TOKEN" != "null" ] && [ "$ADMIN_TOKEN" != "" ]; then
           -H "Authorization: Bearer $CUSTOMER_TOKEN" \
           -d "{
             \"shipping_address\": \"456 Oak St, City, State 12345\",
+            \"shipping_country\": \"US\",
             \"payment_method\": \"paypal\",
             \"items\": [
               {